@@ -0,0 +1,25 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/rba100/aipipe/internal/util"
+)
+
+// writeAnnotations prints the problems found by a review as GitHub Actions
+// workflow commands. It returns an error if any of them are errors, so the
+// workflow step fails.
+func writeAnnotations(response string) error {
+	errors := 0
+	for _, annotation := range util.ParseAnnotations(response) {
+		fmt.Println(util.FormatGitHubAnnotation(annotation))
+		if annotation.Level == "error" {
+			errors++
+		}
+	}
+
+	if errors > 0 {
+		return fmt.Errorf("the review found %d error(s)", errors)
+	}
+	return nil
+}
@@ -0,0 +1,16 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/rba100/aipipe/internal/llm"
+)
+
+// checkCapability returns an error naming what needs a capability if the
+// model doesn't support it, rather than leave the model to fail at it
+func checkCapability(config *llm.Config, model string, what string, capability llm.Capability) error {
+	if !config.Supports(capability) {
+		return fmt.Errorf("%s not supported by %s, which doesn't accept %s", what, model, capability)
+	}
+	return nil
+}
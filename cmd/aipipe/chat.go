@@ -0,0 +1,329 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/rba100/aipipe/internal/display"
+	"github.com/rba100/aipipe/internal/history"
+	"github.com/rba100/aipipe/internal/i18n"
+	"github.com/rba100/aipipe/internal/llm"
+	"github.com/rba100/aipipe/internal/stream"
+	"github.com/rba100/aipipe/internal/util"
+	"github.com/spf13/pflag"
+)
+
+// chatHelp lists the commands understood by `aipipe chat`
+const chatHelp = `Commands:
+  /model [fast|default|reasoning|<name>]  show or change the model
+  /clear                                  start a new conversation
+  /exit                                   leave (or press Ctrl-D)
+`
+
+// chatTurn is a message from the user and the model's reply, and when each
+// was sent
+type chatTurn struct {
+	message string
+	reply   string
+	sent    time.Time
+	replied time.Time
+}
+
+// chatSession is a conversation with the model, whose messages are all sent
+// with each new one
+type chatSession struct {
+	config *llm.Config
+	client llm.LLMClient
+	turns  []chatTurn
+	// accessible is true for output suited to screen readers
+	accessible bool
+	// tokens counts the tokens the API reported for the conversation, and
+	// cost estimates what they cost
+	tokens int
+	cost   float64
+
+	// watcher tells when the config files change, which are then reloaded
+	// with the same --local and --accessible flags
+	watcher       *util.ConfigWatcher
+	local         bool
+	accessibleSet bool
+	// namedModel is the model chosen by name with /model, which is kept
+	// when the config is reloaded
+	namedModel string
+}
+
+// runChat implements `aipipe chat`, a conversation with the model on the
+// terminal. Replies are streamed and pretty printed, and each conversation
+// is recorded in the prompt history.
+func runChat(args []string) error {
+	flags := pflag.NewFlagSet("chat", pflag.ContinueOnError)
+	reasoningFlag := flags.BoolP("reasoning", "r", false, i18n.T("Use reasoning model"))
+	fastFlag := flags.BoolP("fast", "f", false, i18n.T("Use fast model"))
+	localFlag := flags.Bool("local", false, i18n.T("Use a model on the local Ollama server"))
+	accessibleFlag := flags.Bool("accessible", false, i18n.T("Label code blocks, headings and quotes in text rather than by colour, and avoid box-drawing characters and redrawn status lines, for screen readers and braille terminals"))
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() > 0 {
+		return fmt.Errorf("usage: aipipe chat [-r | -f] [--local] [--accessible]")
+	}
+	if *reasoningFlag && *fastFlag {
+		return errors.New(i18n.T("the --reasoning and --fast options cannot be used together"))
+	}
+	if !isTerminal(os.Stdin) {
+		return errors.New(i18n.T("aipipe chat needs a terminal; to ask about piped input, use aipipe without chat"))
+	}
+
+	apiConfig, err := loadAPIConfig(*localFlag)
+	if err != nil {
+		return err
+	}
+	model := llm.ModelTypeDefault
+	if *reasoningFlag {
+		model = llm.ModelTypeReasoning
+	}
+	if *fastFlag {
+		model = llm.ModelTypeFast
+	}
+
+	config := newLLMConfig(apiConfig, model, systemPrompt(queryOptions{isChat: true}))
+	config.IsStream = true
+	client, err := llm.NewClient(config)
+	if err != nil {
+		return err
+	}
+
+	session := &chatSession{
+		config:        config,
+		client:        client,
+		accessible:    isAccessible(flags, *accessibleFlag),
+		watcher:       util.NewConfigWatcher(),
+		local:         *localFlag,
+		accessibleSet: flags.Changed("accessible"),
+	}
+	defer session.record()
+
+	fmt.Println(i18n.T("Chatting with %s. Type /help for commands.", modelName(apiConfig, model)))
+	in := bufio.NewReader(os.Stdin)
+	for {
+		fmt.Print("\n" + session.prompt())
+		line, err := in.ReadString('\n')
+		if err == io.EOF && line == "" {
+			fmt.Println()
+			return nil
+		}
+		if err != nil && err != io.EOF {
+			return fmt.Errorf("error reading from the terminal: %v", err)
+		}
+
+		line = strings.TrimSpace(line)
+		if session.watcher.Changed() {
+			session.reloadConfig()
+		}
+		switch {
+		case line == "":
+			continue
+		case line == "/exit" || line == "/quit":
+			return nil
+		case line == "/help":
+			fmt.Print(i18n.T(chatHelp))
+		case line == "/clear":
+			session.record()
+			session.turns = nil
+			session.tokens = 0
+			session.cost = 0
+			fmt.Println(i18n.T("Started a new conversation."))
+		case line == "/model" || strings.HasPrefix(line, "/model "):
+			session.setModel(strings.TrimSpace(strings.TrimPrefix(line, "/model")))
+		case strings.HasPrefix(line, "/"):
+			fmt.Printf("%s\n%s", i18n.T("Unknown command %s.", line), i18n.T(chatHelp))
+		default:
+			message, err := attachFiles(line)
+			if err != nil {
+				fmt.Fprintln(os.Stderr, i18n.T("Error: %v", err))
+				continue
+			}
+			session.send(message)
+		}
+	}
+}
+
+// prompt returns the prompt for the user's next message, in the colour of
+// their messages
+func (s *chatSession) prompt() string {
+	if s.accessible {
+		return "> "
+	}
+	display.InitializeColors()
+	return display.RoleUserColor + ">" + display.ResetFormat + " "
+}
+
+// model returns the name of the model in use
+func (s *chatSession) model() string {
+	switch s.config.ModelType {
+	case llm.ModelTypeFast:
+		return s.config.FastModel
+	case llm.ModelTypeReasoning:
+		return s.config.ReasoningModel
+	default:
+		return s.config.DefaultModel
+	}
+}
+
+// setModel switches to the fast, default or reasoning model, or to a model
+// by name, or says which model is in use if name is empty
+func (s *chatSession) setModel(name string) {
+	switch name {
+	case "":
+	case "fast":
+		s.config.ModelType = llm.ModelTypeFast
+		s.namedModel = ""
+	case "default":
+		s.config.ModelType = llm.ModelTypeDefault
+		s.namedModel = ""
+	case "reasoning":
+		s.config.ModelType = llm.ModelTypeReasoning
+		s.namedModel = ""
+	default:
+		s.config.ModelType = llm.ModelTypeDefault
+		s.config.DefaultModel = name
+		s.namedModel = name
+	}
+	fmt.Println(i18n.T("Using %s.", s.model()))
+}
+
+// reloadConfig applies the settings in the config files after they have
+// changed: the API, models, highlighting and accessible output. What changed
+// is reported; if the new config can't be used, the old settings are kept.
+func (s *chatSession) reloadConfig() {
+	warnConfigProblems()
+	apiConfig, err := loadAPIConfig(s.local)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, i18n.T("Warning: config.yaml changed but can't be used, so the previous settings are kept: %v", err))
+		return
+	}
+	config := newLLMConfig(apiConfig, s.config.ModelType, systemPrompt(queryOptions{isChat: true}))
+	config.IsStream = true
+	if s.namedModel != "" {
+		config.DefaultModel = s.namedModel
+	}
+	client, err := llm.NewClient(config)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, i18n.T("Warning: config.yaml changed but can't be used, so the previous settings are kept: %v", err))
+		return
+	}
+
+	var changes []string
+	for _, setting := range []struct{ name, old, new string }{
+		{"endpoint", s.config.APIEndpoint, config.APIEndpoint},
+		{"provider", s.config.Provider, config.Provider},
+		{"defaultModel", s.config.DefaultModel, config.DefaultModel},
+		{"fastModel", s.config.FastModel, config.FastModel},
+		{"reasoningModel", s.config.ReasoningModel, config.ReasoningModel},
+	} {
+		if setting.old != setting.new {
+			changes = append(changes, fmt.Sprintf("%s %s → %s", setting.name, setting.old, setting.new))
+		}
+	}
+	s.config, s.client = config, client
+
+	configureHighlighter()
+	registerLanguageAliases()
+	registerParserPlugins()
+	if !s.accessibleSet {
+		accessible, err := util.GetAccessible()
+		if err != nil {
+			fmt.Fprintln(os.Stderr, i18n.T("Warning: Failed to load the accessible setting: %v", err))
+		} else if accessible != s.accessible {
+			s.accessible = accessible
+			changes = append(changes, fmt.Sprintf("accessible %t", accessible))
+		}
+	}
+
+	if len(changes) == 0 {
+		fmt.Fprintln(os.Stderr, i18n.T("Reloaded config.yaml."))
+		return
+	}
+	fmt.Fprintln(os.Stderr, i18n.T("Reloaded config.yaml: %s.", strings.Join(changes, ", ")))
+}
+
+// conversation returns the messages of the conversation so far
+func (s *chatSession) conversation() []llm.Message {
+	var messages []llm.Message
+	for _, turn := range s.turns {
+		messages = append(messages,
+			llm.Message{Role: "user", Content: turn.message},
+			llm.Message{Role: "assistant", Content: turn.reply})
+	}
+	return messages
+}
+
+// send sends a message with the conversation so far and prints the reply as
+// it streams in. A reply that fails is reported and left out of the
+// conversation. Ctrl-C stops the reply, keeping what had arrived.
+func (s *chatSession) send(message string) {
+	ctx, stop := interruptContext()
+	defer stop()
+
+	s.config.Conversation = s.conversation()
+	sent := time.Now()
+	var reply strings.Builder
+	var streamErr error
+	parts := bufferReply(ctx, stream.Tee(ctx, llm.Contents(s.client.CreateCompletionStream(ctx, message), &streamErr), func(part string) {
+		reply.WriteString(part)
+	}))
+
+	printer := newPrinter(queryOptions{accessible: s.accessible})
+	printer.SetGutter(display.RoleGutter("assistant", s.accessible))
+	for part := range util.StripThinkTagsStream(ctx, parts) {
+		printer.Print(part)
+	}
+	printer.Flush()
+	printer.Close()
+
+	if reply.Len() > 0 && !strings.HasSuffix(reply.String(), "\n") {
+		fmt.Println()
+	}
+	if streamErr != nil {
+		fmt.Fprintln(os.Stderr, i18n.T("Error: %v", streamErr))
+		return
+	}
+	if reply.Len() == 0 {
+		return
+	}
+	if ctx.Err() != nil {
+		fmt.Println(i18n.T("(interrupted)"))
+	}
+	s.turns = append(s.turns, chatTurn{message: message, reply: util.StripThinkTags(reply.String()), sent: sent, replied: time.Now()})
+	info := s.client.LastCompletionInfo()
+	s.tokens += usageTokens(info)
+	s.cost += usageCost(info)
+}
+
+// record adds the conversation to the prompt history, under its first
+// message, with its messages
+func (s *chatSession) record() {
+	if len(s.turns) == 0 {
+		return
+	}
+	var messages []history.Message
+	for i := range s.turns {
+		turn := &s.turns[i]
+		messages = append(messages,
+			history.Message{Role: "user", Content: turn.message, Time: &turn.sent},
+			history.Message{Role: "assistant", Content: turn.reply, Time: &turn.replied})
+	}
+	recordEntry(history.Entry{
+		Prompt:   s.turns[0].message,
+		Model:    s.model(),
+		Tokens:   s.tokens,
+		Cost:     s.cost,
+		Turns:    len(s.turns),
+		Messages: messages,
+	})
+}
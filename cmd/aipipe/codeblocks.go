@@ -0,0 +1,55 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/rba100/aipipe/internal/i18n"
+	"github.com/rba100/aipipe/internal/util"
+)
+
+// writeAllBlocks implements --all-blocks, writing every code block in the
+// response to w, separated by a blank line or a line of --block-separator,
+// or with --output writing each to a numbered file with the file header
+func writeAllBlocks(w io.Writer, response string, opts queryOptions, formatters map[string]string, header string) error {
+	blocks := util.ExtractAllCodeBlocks(response)
+	if len(blocks) == 0 {
+		return errors.New(i18n.T("the answer has no code blocks"))
+	}
+
+	for i, block := range blocks {
+		block = formatCodeBlock(block, formatters)
+
+		if opts.outputPath != "" {
+			path := util.NumberedPath(opts.outputPath, i+1)
+			if err := util.WriteFileAtomic(path, withHeader(block.Text, block.Type, path, header)+"\n", opts.appendOutput); err != nil {
+				return err
+			}
+			fmt.Fprintln(os.Stderr, i18n.T("Wrote %s", path))
+			continue
+		}
+
+		if i > 0 {
+			io.WriteString(w, opts.blockSeparator+"\n")
+		}
+		io.WriteString(w, block.Text+"\n")
+	}
+	return nil
+}
+
+// withHeader adds the file header to code written to path, as comments in the
+// code block's language or, if the block has none, the language of path's
+// extension
+func withHeader(code string, language string, path string, header string) string {
+	if language == "" {
+		language = strings.TrimPrefix(filepath.Ext(path), ".")
+	}
+	if language == "" {
+		language = strings.ToLower(filepath.Base(path))
+	}
+	return util.AddHeader(code, language, header)
+}
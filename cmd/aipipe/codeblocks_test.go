@@ -0,0 +1,67 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestWriteAllBlocks(t *testing.T) {
+	response := "First:\n```go\npackage main\n```\nThen:\n```\nmake\n```\n"
+	tests := []struct {
+		name      string
+		separator string
+		expected  string
+	}{
+		{"Blank line", "", "package main\n\nmake\n"},
+		{"Separator", "---", "package main\n---\nmake\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out strings.Builder
+			if err := writeAllBlocks(&out, response, queryOptions{blockSeparator: tt.separator}, nil, ""); err != nil {
+				t.Fatalf("writeAllBlocks() error = %v", err)
+			}
+			if out.String() != tt.expected {
+				t.Errorf("writeAllBlocks() wrote %q, want %q", out.String(), tt.expected)
+			}
+		})
+	}
+
+	if err := writeAllBlocks(&strings.Builder{}, "No code here", queryOptions{}, nil, ""); err == nil {
+		t.Error("writeAllBlocks() without code blocks succeeded, want an error")
+	}
+}
+
+func TestWriteAllBlocksToFiles(t *testing.T) {
+	dir := t.TempDir()
+	response := "```go\npackage main\n```\n```\nmake\n```\n"
+	opts := queryOptions{outputPath: filepath.Join(dir, "out.go")}
+
+	var out strings.Builder
+	if err := writeAllBlocks(&out, response, opts, nil, "Generated"); err != nil {
+		t.Fatalf("writeAllBlocks() error = %v", err)
+	}
+	if out.Len() != 0 {
+		t.Errorf("writeAllBlocks() with --output wrote %q to stdout", out.String())
+	}
+
+	// Each block is numbered, and given the header in its language's
+	// comments, or those of the file's extension if it has no language
+	expected := map[string]string{
+		"out-1.go": "// Generated\npackage main\n",
+		"out-2.go": "// Generated\nmake\n",
+	}
+	for name, content := range expected {
+		data, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			t.Errorf("Reading %s: %v", name, err)
+			continue
+		}
+		if string(data) != content {
+			t.Errorf("%s = %q, want %q", name, data, content)
+		}
+	}
+}
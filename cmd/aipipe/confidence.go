@@ -0,0 +1,61 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/rba100/aipipe/internal/i18n"
+	"github.com/rba100/aipipe/internal/util"
+)
+
+// exitLowConfidence is the exit status when the model's confidence score is
+// below --min-confidence, so scripts can tell it from other failures
+const exitLowConfidence = 3
+
+// exitError is an error that ends aipipe with a particular exit status
+type exitError struct {
+	status int
+	err    error
+}
+
+func (e *exitError) Error() string {
+	return e.err.Error()
+}
+
+func (e *exitError) Unwrap() error {
+	return e.err
+}
+
+// exitStatus returns the exit status for an error, which is 1 unless it says
+// otherwise
+func exitStatus(err error) int {
+	var exit *exitError
+	if errors.As(err, &exit) {
+		return exit.status
+	}
+	return 1
+}
+
+// checkConfidence removes the confidence score from a response, saying what
+// it was on stderr, and returns an error with the low confidence exit status
+// if it is below minConfidence. A response without a score counts as low
+// confidence.
+func checkConfidence(response string, minConfidence float64) (string, error) {
+	answer, score, ok := util.ExtractConfidenceScore(response)
+	if !ok {
+		return answer, &exitError{
+			status: exitLowConfidence,
+			err:    fmt.Errorf("the model didn't give a confidence score, so it is treated as below --min-confidence %g", minConfidence),
+		}
+	}
+
+	fmt.Fprintln(os.Stderr, i18n.T("Confidence: %g", score))
+	if score < minConfidence {
+		return answer, &exitError{
+			status: exitLowConfidence,
+			err:    fmt.Errorf("the model's confidence in its answer, %g, is below --min-confidence %g", score, minConfidence),
+		}
+	}
+	return answer, nil
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/rba100/aipipe/internal/i18n"
+	"github.com/rba100/aipipe/internal/util"
+	"github.com/spf13/pflag"
+)
+
+// runConfig implements `aipipe config validate`, checking the config file for
+// keys and values aipipe doesn't understand
+func runConfig(args []string) error {
+	flags := pflag.NewFlagSet("config", pflag.ContinueOnError)
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	switch flags.Arg(0) {
+	case "validate":
+		path, problems, err := util.ValidateConfig()
+		if err != nil {
+			return err
+		}
+		for _, problem := range problems {
+			fmt.Printf("%s: %s\n", path, problem)
+		}
+		if _, err := os.Stat(path); os.IsNotExist(err) {
+			fmt.Printf("There is no config file at %s\n", path)
+			return nil
+		}
+		if len(problems) > 0 {
+			return fmt.Errorf("found %d problem(s) in %s", len(problems), path)
+		}
+		fmt.Printf("%s is valid\n", path)
+		return nil
+
+	default:
+		return fmt.Errorf("unknown config command %q (expected validate)", flags.Arg(0))
+	}
+}
+
+// warnConfigProblems reports problems in the config file as warnings, since
+// misspelled keys are otherwise silently ignored
+func warnConfigProblems() {
+	path, problems, err := util.ValidateConfig()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, i18n.T("Warning: %v", err))
+		return
+	}
+	for _, problem := range problems {
+		fmt.Fprintln(os.Stderr, i18n.T("Warning: %s: %s", path, problem))
+	}
+}
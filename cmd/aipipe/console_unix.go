@@ -2,7 +2,42 @@
 
 package main
 
+import (
+	"fmt"
+	"os"
+	"os/exec"
+)
+
 // initConsole is a no-op on non-Windows platforms
 func initConsole() {
 	// Nothing to do on Unix-like systems
 }
+
+// openTerminal opens the controlling terminal, for asking the user questions
+// while stdin is a pipe
+func openTerminal() (*os.File, error) {
+	return os.OpenFile("/dev/tty", os.O_RDWR, 0)
+}
+
+// terminalSize returns the number of rows and columns in the terminal, or
+// zeros if they can't be determined
+func terminalSize() (int, int) {
+	terminal, err := openTerminal()
+	if err != nil {
+		return 0, 0
+	}
+	defer terminal.Close()
+
+	cmd := exec.Command("stty", "size")
+	cmd.Stdin = terminal
+	output, err := cmd.Output()
+	if err != nil {
+		return 0, 0
+	}
+
+	var rows, columns int
+	if _, err := fmt.Sscan(string(output), &rows, &columns); err != nil {
+		return 0, 0
+	}
+	return rows, columns
+}
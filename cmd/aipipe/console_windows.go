@@ -3,30 +3,68 @@
 package main
 
 import (
+	"os"
 	"syscall"
 	"unsafe"
 )
 
 var (
-	kernel32           = syscall.NewLazyDLL("kernel32.dll")
-	procSetConsoleMode = kernel32.NewProc("SetConsoleMode")
-	procGetConsoleMode = kernel32.NewProc("GetConsoleMode")
+	kernel32               = syscall.NewLazyDLL("kernel32.dll")
+	procSetConsoleMode     = kernel32.NewProc("SetConsoleMode")
+	procGetConsoleMode     = kernel32.NewProc("GetConsoleMode")
+	procSetConsoleOutputCP = kernel32.NewProc("SetConsoleOutputCP")
+
+	procGetConsoleScreenBufferInfo = kernel32.NewProc("GetConsoleScreenBufferInfo")
 )
 
 const (
 	ENABLE_VIRTUAL_TERMINAL_PROCESSING = 0x0004
 )
 
+// utf8CodePage is the Windows code page number of UTF-8
+const utf8CodePage = 65001
+
 // initConsole enables UTF-8 output and ANSI escape sequences on Windows console
 func initConsole() {
-	// Set stdout to UTF-8 mode
+	// Set the console to UTF-8, which cmd and Windows PowerShell don't use by
+	// default, so non-ASCII characters in answers aren't garbled
+	procSetConsoleOutputCP.Call(uintptr(utf8CodePage))
+
+	// Enable virtual terminal processing for ANSI escape sequences, on stderr
+	// too for the status line and previews
+	for _, handle := range []syscall.Handle{syscall.Stdout, syscall.Stderr} {
+		var mode uint32
+		if ok, _, _ := procGetConsoleMode.Call(uintptr(handle), uintptr(unsafe.Pointer(&mode))); ok == 0 {
+			// Not a console, such as when output is redirected
+			continue
+		}
+		procSetConsoleMode.Call(uintptr(handle), uintptr(mode|ENABLE_VIRTUAL_TERMINAL_PROCESSING))
+	}
+}
+
+// openTerminal opens the console, for asking the user questions while stdin
+// is a pipe
+func openTerminal() (*os.File, error) {
+	return os.OpenFile("CONIN$", os.O_RDWR, 0)
+}
+
+// consoleScreenBufferInfo mirrors CONSOLE_SCREEN_BUFFER_INFO
+type consoleScreenBufferInfo struct {
+	size              [2]int16
+	cursorPosition    [2]int16
+	attributes        uint16
+	window            [4]int16 // left, top, right, bottom
+	maximumWindowSize [2]int16
+}
+
+// terminalSize returns the number of rows and columns in the console window,
+// or zeros if they can't be determined
+func terminalSize() (int, int) {
+	var info consoleScreenBufferInfo
 	handle := syscall.Handle(syscall.Stdout)
-	
-	var mode uint32
-	procGetConsoleMode.Call(uintptr(handle), uintptr(unsafe.Pointer(&mode)))
-	
-	// Enable virtual terminal processing for ANSI escape sequences
-	mode |= ENABLE_VIRTUAL_TERMINAL_PROCESSING
-	
-	procSetConsoleMode.Call(uintptr(handle), uintptr(mode))
+	ok, _, _ := procGetConsoleScreenBufferInfo.Call(uintptr(handle), uintptr(unsafe.Pointer(&info)))
+	if ok == 0 {
+		return 0, 0
+	}
+	return int(info.window[3]-info.window[1]) + 1, int(info.window[2]-info.window[0]) + 1
 }
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/rba100/aipipe/internal/cron"
+	"github.com/rba100/aipipe/internal/i18n"
+	"github.com/rba100/aipipe/internal/llm"
+	"github.com/rba100/aipipe/internal/util"
+	"github.com/spf13/pflag"
+)
+
+// cronRetries is how many times the model is asked again for a cron
+// expression that doesn't parse
+const cronRetries = 2
+
+// runCron implements `aipipe cron [schedule]`, writing a cron expression for
+// a described schedule, checking it parses and showing when it will next run.
+// With --systemd it writes a systemd timer unit instead.
+func runCron(args []string) error {
+	flags := pflag.NewFlagSet("cron", pflag.ContinueOnError)
+	systemdFlag := flags.Bool("systemd", false, "Write a systemd timer unit instead of a cron expression")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	description := strings.Join(flags.Args(), " ")
+	if description == "" {
+		return fmt.Errorf("usage: aipipe cron [--systemd] <schedule>")
+	}
+
+	apiConfig, err := loadAPIConfig(false)
+	if err != nil {
+		return err
+	}
+	client, err := llm.NewClient(newLLMConfig(apiConfig, llm.ModelTypeDefault, llm.GetCronSystemPrompt()))
+	if err != nil {
+		return err
+	}
+
+	prompt := "Write a cron expression for: " + description + "\n"
+	for attempt := 0; ; attempt++ {
+		response, err := client.CreateCompletion(context.Background(), prompt)
+		if err != nil {
+			return err
+		}
+		expression, _, _ := strings.Cut(strings.TrimSpace(util.ExtractCodeBlock(util.StripThinkTags(response)).Text), "\n")
+
+		schedule, err := cron.Parse(expression)
+		if err == nil && *systemdFlag {
+			var event string
+			if event, err = schedule.Systemd(); err == nil {
+				printNextRuns(schedule)
+				fmt.Print(systemdTimer(description, event))
+				return nil
+			}
+		}
+		if err == nil {
+			printNextRuns(schedule)
+			fmt.Println(expression)
+			return nil
+		}
+
+		if attempt == cronRetries {
+			return fmt.Errorf("the model's cron expression %q is invalid: %v", expression, err)
+		}
+		fmt.Fprintln(os.Stderr, i18n.T("%q is invalid (%v), asking again", expression, err))
+		prompt += fmt.Sprintf("\nYou wrote %s, but %v. Write a corrected expression.\n", expression, err)
+	}
+}
+
+// printNextRuns shows on stderr when a schedule will next run, so it can be
+// checked against what was asked for
+func printNextRuns(schedule *cron.Schedule) {
+	runs := schedule.NextRuns(time.Now(), 3)
+	if len(runs) == 0 {
+		fmt.Fprintln(os.Stderr, i18n.T("Warning: This schedule never runs"))
+		return
+	}
+
+	fmt.Fprintln(os.Stderr, i18n.T("Next runs:"))
+	for _, run := range runs {
+		fmt.Fprintf(os.Stderr, "  %s\n", run.Format("Mon 2006-01-02 15:04 MST"))
+
+	}
+}
+
+// systemdTimer returns a systemd timer unit that runs on the calendar event
+func systemdTimer(description string, event string) string {
+	return "[Unit]\n" +
+		"Description=" + strings.Join(strings.Fields(description), " ") + "\n\n" +
+		"[Timer]\n" +
+		"OnCalendar=" + event + "\n" +
+		"Persistent=true\n\n" +
+		"[Install]\n" +
+		"WantedBy=timers.target\n"
+}
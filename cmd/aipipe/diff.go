@@ -0,0 +1,26 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/rba100/aipipe/internal/display"
+	"github.com/rba100/aipipe/internal/i18n"
+	"github.com/rba100/aipipe/internal/util"
+)
+
+// printDiff prints a word diff from the reference file at path to the answer,
+// in color if stdout is a terminal, saying on stderr if they are the same.
+// Accessible output marks changes in text rather than by color.
+func printDiff(path string, reference string, answer string, accessible bool) {
+	// A missing final newline isn't worth showing
+	reference = strings.TrimRight(reference, "\n")
+	answer = strings.TrimRight(answer, "\n")
+
+	if reference == answer {
+		fmt.Fprintln(os.Stderr, i18n.T("The answer is the same as %s", path))
+	}
+	os.Stdout.WriteString(display.FormatWordDiff(util.DiffWords(reference, answer), isTerminal(os.Stdout) && !accessible))
+	os.Stdout.WriteString("\n")
+}
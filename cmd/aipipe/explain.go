@@ -0,0 +1,27 @@
+package main
+
+import (
+	"strings"
+
+	"github.com/spf13/pflag"
+)
+
+// runExplain implements `aipipe explain [command]`, explaining a shell command
+// or an error message given as arguments or piped in
+func runExplain(args []string) error {
+	flags := pflag.NewFlagSet("explain", pflag.ContinueOnError)
+	reasoningFlag := flags.BoolP("reasoning", "r", false, "Use reasoning model instead of the fast model")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	opts := queryOptions{
+		isStream:    true,
+		isPretty:    true,
+		isReasoning: *reasoningFlag,
+		isFast:      !*reasoningFlag,
+		isExplain:   true,
+	}
+
+	return runAIQuery(opts, strings.Join(flags.Args(), " "), nil)
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/rba100/aipipe/internal/util"
+	"github.com/spf13/pflag"
+)
+
+// runExtract implements `aipipe extract`, printing the contents of fenced code
+// blocks found in stdin
+func runExtract(args []string) error {
+	flags := pflag.NewFlagSet("extract", pflag.ContinueOnError)
+	languageFlag := flags.StringP("lang", "l", "", "Only extract code blocks tagged with this language")
+	allFlag := flags.BoolP("all", "a", false, "Extract all matching code blocks instead of just the first")
+	separatorFlag := flags.String("block-separator", "", "With --all, put a line of this text between code blocks instead of a blank line")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	if flags.NArg() > 0 {
+		return fmt.Errorf("extract reads from stdin and takes no arguments")
+	}
+	if flags.Changed("block-separator") && !*allFlag {
+		return fmt.Errorf("the --block-separator option requires --all")
+	}
+
+	language := *languageFlag
+	selected := -1
+	found := false
+
+	// Stopping after the first block cancels the rest of the pipeline
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	for result := range util.ExtractCodeBlocksStream(ctx, streamInput(os.Stdin)) {
+		if language != "" && !strings.EqualFold(result.Type, language) {
+			continue
+		}
+
+		if selected >= 0 && result.Index != selected {
+			if !*allFlag {
+				// The first matching block is complete
+				break
+			}
+			os.Stdout.WriteString(*separatorFlag + "\n")
+		}
+
+		selected = result.Index
+		found = true
+		os.Stdout.WriteString(result.Text)
+	}
+
+	if !found {
+		return fmt.Errorf("no matching code block found")
+	}
+
+	return nil
+}
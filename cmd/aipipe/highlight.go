@@ -0,0 +1,49 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/rba100/aipipe/internal/display"
+	"github.com/rba100/aipipe/internal/i18n"
+	"github.com/rba100/aipipe/internal/parsing"
+	"github.com/spf13/pflag"
+)
+
+// runHighlight implements `aipipe highlight [file]`, printing a source file
+// (or stdin) with syntax highlighting
+func runHighlight(args []string) error {
+	flags := pflag.NewFlagSet("highlight", pflag.ContinueOnError)
+	languageFlag := flags.StringP("language", "l", "", "Language to highlight as (detected from the file extension by default)")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	if flags.NArg() > 1 {
+		return fmt.Errorf("highlight accepts at most one file")
+	}
+
+	path := flags.Arg(0)
+	language := strings.ToLower(*languageFlag)
+	if language == "" {
+		language = parsing.LanguageFromFilename(path)
+	}
+	if language == "" && path != "" && path != "-" {
+		fmt.Fprintln(os.Stderr, i18n.T("Warning: could not detect language of %s, use --language to set it", path))
+	}
+
+	code, err := readInput(path)
+	if err != nil {
+		return err
+	}
+
+	display.InitializeColors()
+	highlighter := display.NewSyntaxHighlighter()
+	os.Stdout.WriteString(highlighter.HighlightCode(code, language))
+	if !strings.HasSuffix(code, "\n") {
+		os.Stdout.WriteString("\n")
+	}
+
+	return nil
+}
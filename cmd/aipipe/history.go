@@ -0,0 +1,327 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rba100/aipipe/internal/display"
+	"github.com/rba100/aipipe/internal/history"
+	"github.com/rba100/aipipe/internal/i18n"
+	"github.com/rba100/aipipe/internal/llm"
+	"github.com/rba100/aipipe/internal/tokenizer"
+	"github.com/rba100/aipipe/internal/util"
+	"github.com/spf13/pflag"
+)
+
+// statsTop is how many models and snippets `aipipe history stats` lists
+const statsTop = 5
+
+// budgetBarWidth is how many cells wide the bar of `aipipe history show
+// --budget` is
+const budgetBarWidth = 40
+
+// budgetWarning is the fraction of a model's context window past which
+// `aipipe history show --budget` warns that a follow-up may be truncated
+const budgetWarning = 0.8
+
+// runHistory implements `aipipe history stats`, `aipipe history show`,
+// `aipipe history replay` and `aipipe history share`
+func runHistory(args []string) error {
+	usage := fmt.Errorf("usage: aipipe history stats [--weeks N] | show [ID] [--budget] [--model NAME] | replay [ID] [--accessible] | share [ID] [--public] [--to TARGET]")
+	if len(args) == 0 {
+		return usage
+	}
+	switch args[0] {
+	case "stats":
+		return runHistoryStats(args[1:])
+	case "show":
+		return runHistoryShow(args[1:])
+	case "replay":
+		return runHistoryReplay(args[1:])
+	case "share":
+		return runHistoryShare(args[1:])
+	default:
+		return usage
+	}
+}
+
+// runHistoryStats implements `aipipe history stats`, summarising the prompt
+// history: how many prompts were run each week, the tokens they used and the
+// models and snippets used most
+func runHistoryStats(args []string) error {
+	flags := pflag.NewFlagSet("history stats", pflag.ContinueOnError)
+	weeksFlag := flags.Int("weeks", 12, "How many weeks of prompts to chart")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 0 || *weeksFlag <= 0 {
+		return fmt.Errorf("usage: aipipe history stats [--weeks N]")
+	}
+	log, err := history.DefaultLog()
+	if err != nil {
+		return err
+	}
+	entries, err := log.List()
+	if err != nil {
+		return err
+	}
+	if len(entries) == 0 {
+		fmt.Println("The prompt history is empty.")
+		return nil
+	}
+
+	stats := history.ComputeStats(entries, time.Now(), *weeksFlag)
+	fmt.Printf("Prompts:        %d since %s\n", stats.Prompts, entries[0].Time.Local().Format("2006-01-02"))
+	fmt.Printf("Tokens:         %d, where the API reported them\n", stats.Tokens)
+	fmt.Printf("Cost:           $%.4f, estimated where the model's price was known\n", stats.Cost)
+	fmt.Printf("Turns:          %.1f requests to the model for each prompt answered\n", stats.AverageTurns)
+
+	total, busiest := 0, 0
+	for _, count := range stats.Weekly {
+		total += count
+		busiest = max(busiest, count)
+	}
+	fmt.Printf("\nPrompts a week over the last %d weeks, this week last:\n", *weeksFlag)
+	fmt.Printf("  %s  average %.1f, most %d, this week %d\n", display.Sparkline(stats.Weekly), float64(total)/float64(*weeksFlag), busiest, stats.Weekly[len(stats.Weekly)-1])
+
+	printCounts("Model", stats.Models)
+	printCounts("Snippet", stats.Snippets)
+	return nil
+}
+
+// runHistoryShow implements `aipipe history show`, printing a prompt or
+// conversation from the history, the latest unless an id is given. With
+// --budget it estimates the tokens of each message and charts how much of the
+// model's context window the conversation fills.
+func runHistoryShow(args []string) error {
+	flags := pflag.NewFlagSet("history show", pflag.ContinueOnError)
+	budgetFlag := flags.Bool("budget", false, "Show the tokens of each message against the model's context window")
+	modelFlag := flags.String("model", "", "The model to measure the budget against, instead of the one that answered")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() > 1 {
+		return fmt.Errorf("usage: aipipe history show [ID] [--budget] [--model NAME]")
+	}
+
+	id, entry, err := loadHistoryEntry(flags.Args())
+	if err != nil {
+		return err
+	}
+	if id == 0 {
+		fmt.Println("The prompt history is empty.")
+		return nil
+	}
+	messages := entry.Conversation()
+	printEntryHeader(id, entry)
+
+	if !*budgetFlag {
+		for _, message := range messages {
+			fmt.Printf("\n%s:\n%s\n", message.Role, strings.TrimSpace(message.Content))
+		}
+		return nil
+	}
+
+	model := *modelFlag
+	if model == "" {
+		model = entry.Model
+	}
+	if model == "" {
+		if apiConfig, err := util.GetAPIConfig(); err == nil {
+			model = apiConfig.DefaultModel
+		}
+	}
+
+	// Context windows configured in the config file take precedence
+	userConfig := &util.APIConfig{}
+	if err := util.LoadUserConfig(userConfig); err != nil {
+		return err
+	}
+	printBudget(messages, model, userConfig.ContextWindows)
+	return nil
+}
+
+// runHistoryReplay implements `aipipe history replay`, showing a
+// conversation from the history again, the latest unless an id is given:
+// each message under a header naming who sent it and when, with its markdown
+// pretty printed beside a gutter in the colour of its role
+func runHistoryReplay(args []string) error {
+	flags := pflag.NewFlagSet("history replay", pflag.ContinueOnError)
+	accessibleFlag := flags.Bool("accessible", false, i18n.T("Label code blocks, headings and quotes in text rather than by colour, and avoid box-drawing characters and redrawn status lines, for screen readers and braille terminals"))
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() > 1 {
+		return fmt.Errorf("usage: aipipe history replay [ID] [--accessible]")
+	}
+
+	id, entry, err := loadHistoryEntry(flags.Args())
+	if err != nil {
+		return err
+	}
+	if id == 0 {
+		fmt.Println("The prompt history is empty.")
+		return nil
+	}
+	printEntryHeader(id, entry)
+
+	accessible := isAccessible(flags, *accessibleFlag)
+	display.InitializeColors()
+	for _, message := range entry.Conversation() {
+		fmt.Printf("\n%s\n", display.FormatMessageHeader(message.Role, message.SentAt(), accessible))
+		printer := newPrinter(queryOptions{accessible: accessible})
+		printer.SetGutter(display.RoleGutter(message.Role, accessible))
+		printer.Print(strings.TrimSpace(message.Content) + "\n")
+		printer.Flush()
+		printer.Close()
+	}
+	return nil
+}
+
+// runHistoryShare implements `aipipe history share`, uploading a
+// conversation from the history as markdown, the latest unless an id is
+// given, and printing its URL. It goes to a secret GitHub gist, created with
+// the token in $GITHUB_TOKEN or $GH_TOKEN, unless --to or the shareTarget
+// config key names a paste service's endpoint instead.
+func runHistoryShare(args []string) error {
+	flags := pflag.NewFlagSet("history share", pflag.ContinueOnError)
+	publicFlag := flags.Bool("public", false, "Make the gist public rather than secret")
+	toFlag := flags.String("to", "", "Where to upload: \"gist\" or a paste service's endpoint URL (default: the shareTarget config key, or gist)")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() > 1 {
+		return fmt.Errorf("usage: aipipe history share [ID] [--public] [--to TARGET]")
+	}
+	if err := checkNotReadOnly("sharing conversations"); err != nil {
+		return err
+	}
+
+	target := *toFlag
+	if target == "" {
+		var err error
+		if target, err = util.GetShareTarget(); err != nil {
+			return err
+		}
+	}
+	if target == "" {
+		target = "gist"
+	}
+	if target != "gist" && !strings.HasPrefix(target, "http://") && !strings.HasPrefix(target, "https://") {
+		return fmt.Errorf("invalid share target %q: use \"gist\" or a paste service's http:// or https:// endpoint", target)
+	}
+	if *publicFlag && target != "gist" {
+		return fmt.Errorf("--public only applies to gists")
+	}
+
+	id, entry, err := loadHistoryEntry(flags.Args())
+	if err != nil {
+		return err
+	}
+	if id == 0 {
+		fmt.Println("The prompt history is empty.")
+		return nil
+	}
+	document := history.Markdown(entry)
+
+	var link string
+	if target == "gist" {
+		token := os.Getenv("GITHUB_TOKEN")
+		if token == "" {
+			token = os.Getenv("GH_TOKEN")
+		}
+		if token == "" {
+			return fmt.Errorf("sharing to a gist needs a GitHub token with the gist scope in $GITHUB_TOKEN or $GH_TOKEN")
+		}
+		title, _, _ := strings.Cut(strings.TrimSpace(entry.Prompt), "\n")
+		link, err = util.CreateGist(token, fmt.Sprintf("aipipe-conversation-%d.md", id), util.TruncateText(title, 200), document, *publicFlag)
+	} else {
+		link, err = util.UploadPaste(target, document)
+	}
+	if err != nil {
+		return err
+	}
+	fmt.Println(link)
+	return nil
+}
+
+// loadHistoryEntry returns the history entry with the id in args, or the
+// latest if args is empty, and its id. The id is 0 if the history is empty.
+func loadHistoryEntry(args []string) (int, history.Entry, error) {
+	log, err := history.DefaultLog()
+	if err != nil {
+		return 0, history.Entry{}, err
+	}
+	entries, err := log.List()
+	if err != nil || len(entries) == 0 {
+		return 0, history.Entry{}, err
+	}
+
+	id := len(entries)
+	if len(args) == 1 {
+		if id, err = strconv.Atoi(args[0]); err != nil {
+			return 0, history.Entry{}, fmt.Errorf("invalid id %q: ids are the numbers from 1, the oldest prompt, to %d", args[0], len(entries))
+		}
+	}
+	entry, err := log.Get(id)
+	return id, entry, err
+}
+
+// printEntryHeader prints the id of a history entry, when it was run, and the
+// model, tokens and cost of its answer where known
+func printEntryHeader(id int, entry history.Entry) {
+	fmt.Printf("#%d  %s", id, entry.Time.Local().Format("2006-01-02 15:04"))
+	if entry.Model != "" {
+		fmt.Printf("  %s", entry.Model)
+	}
+	if entry.Tokens > 0 {
+		fmt.Printf("  %d tokens", entry.Tokens)
+	}
+	if entry.Cost > 0 {
+		fmt.Printf("  $%.4f", entry.Cost)
+	}
+	fmt.Println()
+}
+
+// printBudget prints the estimated tokens of each message as a table, then a
+// bar showing how much of the model's context window they fill together
+func printBudget(messages []history.Message, model string, contextWindows map[string]int) {
+	rows := [][]string{{"#", "Role", "Tokens", "Message"}}
+	total := 0
+	for i, message := range messages {
+		tokens := tokenizer.Count(model, message.Content)
+		total += tokens
+		rows = append(rows, []string{strconv.Itoa(i + 1), message.Role, "~" + strconv.Itoa(tokens), message.Content})
+	}
+	fmt.Println()
+	fmt.Print(display.FormatTable(rows))
+	fmt.Printf("\nTotal: ~%d tokens\n", total)
+
+	window, ok := llm.ContextWindow(model, contextWindows)
+	if !ok {
+		fmt.Printf("The context window of %q isn't known, so the budget can't be charted; try --model, or set it under contextWindows in config.yaml.\n", model)
+		return
+	}
+	fraction := float64(total) / float64(window)
+	fmt.Printf("%s %.1f%% of %s's %d token context window\n", display.Bar(fraction, budgetBarWidth), fraction*100, model, window)
+	if fraction >= budgetWarning {
+		fmt.Fprintln(os.Stderr, i18n.T("Warning: the conversation fills most of the context window; a follow-up may be truncated or rejected"))
+	}
+}
+
+// printCounts prints the most used models or snippets as a table
+func printCounts(heading string, counts []history.Count) {
+	if len(counts) == 0 {
+		return
+	}
+
+	rows := [][]string{{heading, "Prompts"}}
+	for _, count := range counts[:min(len(counts), statsTop)] {
+		rows = append(rows, []string{count.Name, strconv.Itoa(count.Count)})
+	}
+	fmt.Println()
+	fmt.Print(display.FormatTable(rows))
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/rba100/aipipe/internal/display"
+	"github.com/rba100/aipipe/internal/i18n"
+	"github.com/rba100/aipipe/internal/llm"
+	"github.com/rba100/aipipe/internal/util"
+	"github.com/spf13/pflag"
+)
+
+// maxHTTPResponseBytes caps how much of a response is read, and
+// maxHTTPResponseChars how much of it is given to the model to summarise
+const (
+	maxHTTPResponseBytes = 5 << 20
+	maxHTTPResponseChars = 20000
+)
+
+// runHTTP implements `aipipe http [request]`, writing an HTTP request from a
+// description, sending it if the user confirms and summarising the response
+func runHTTP(args []string) error {
+	flags := pflag.NewFlagSet("http", pflag.ContinueOnError)
+	headerFlag := flags.StringArrayP("header", "H", nil, "Add a header, such as \"Authorization: Bearer $TOKEN\", without showing it to the model (can be repeated)")
+	rawFlag := flags.Bool("raw", false, "Print the response body instead of summarising it")
+	yesFlag := flags.BoolP("yes", "y", false, "Send the request without asking")
+	reasoningFlag := flags.BoolP("reasoning", "r", false, "Use reasoning model")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	description := strings.Join(flags.Args(), " ")
+	if description == "" {
+		return fmt.Errorf("usage: aipipe http [-H header] <request>")
+	}
+
+	apiConfig, err := loadAPIConfig(false)
+	if err != nil {
+		return err
+	}
+	config := newLLMConfig(apiConfig, llm.ModelTypeDefault, llm.GetHTTPRequestSystemPrompt())
+	if *reasoningFlag {
+		config.ModelType = llm.ModelTypeReasoning
+	}
+	client, err := llm.NewClient(config)
+	if err != nil {
+		return err
+	}
+
+	response, err := client.CreateCompletion(context.Background(), description)
+	if err != nil {
+		return err
+	}
+	request, err := util.ParseHTTPRequest(util.StripThinkTags(response))
+	if err != nil {
+		return err
+	}
+
+	printer := display.NewPrettyPrinter()
+	defer printer.Close()
+	printer.Print("```bash\n" + request.Curl() + "\n```\n")
+	printer.Flush()
+
+	if isReadOnly() {
+		fmt.Fprintln(os.Stderr, i18n.T("Not sending the request: aipipe is in read-only mode"))
+		return nil
+	}
+	if !*yesFlag {
+		confirmed, err := confirm("Send this request? [y/N] ")
+		if err != nil || !confirmed {
+			return nil
+		}
+	}
+
+	status, body, err := request.Send(*headerFlag, maxHTTPResponseBytes)
+	if err != nil {
+		return err
+	}
+	if *rawFlag {
+		fmt.Println(status)
+		fmt.Println(body)
+		return nil
+	}
+
+	// Summarise the response with the default system prompt, warning the
+	// model that the response could say anything
+	config.SystemPrompt = llm.GetSystemPrompt(false) + " " + llm.GetUntrustedContentPrompt()
+	summarizer, err := llm.NewClient(config)
+	if err != nil {
+		return err
+	}
+	summary, err := summarizer.CreateCompletion(context.Background(), "I asked for: "+description+"\n"+
+		"This request was sent:\n"+request.Curl()+"\n"+
+		"The response was "+status+":\n"+untrusted(request.URL, util.TruncateText(body, maxHTTPResponseChars))+"-----\n"+
+		"Give me what I asked for from this response, concisely. If the request failed, explain why.")
+	if err != nil {
+		return err
+	}
+	printer.Print("\n" + util.StripThinkTags(summary) + "\n")
+	printer.Flush()
+	return nil
+}
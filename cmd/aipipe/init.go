@@ -0,0 +1,207 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/rba100/aipipe/internal/llm"
+	"github.com/rba100/aipipe/internal/util"
+	"github.com/spf13/pflag"
+)
+
+// wizard asks the user questions on the terminal
+type wizard struct {
+	in  *bufio.Reader
+	out io.Writer
+}
+
+// ask asks a question, returning the trimmed answer or defaultAnswer if the
+// answer is blank
+func (w *wizard) ask(question string, defaultAnswer string) (string, error) {
+	if defaultAnswer != "" {
+		fmt.Fprintf(w.out, "%s [%s]: ", question, defaultAnswer)
+	} else {
+		fmt.Fprintf(w.out, "%s: ", question)
+	}
+
+	answer, err := w.in.ReadString('\n')
+	if err != nil && answer == "" {
+		return "", fmt.Errorf("no answer given")
+	}
+	if answer = strings.TrimSpace(answer); answer == "" {
+		return defaultAnswer, nil
+	}
+	return answer, nil
+}
+
+// askRequired asks a question until it gets a non-blank answer
+func (w *wizard) askRequired(question string) (string, error) {
+	for {
+		answer, err := w.ask(question, "")
+		if err != nil || answer != "" {
+			return answer, err
+		}
+	}
+}
+
+// askYesNo asks a yes or no question
+func (w *wizard) askYesNo(question string, defaultYes bool) (bool, error) {
+	defaultAnswer := "y/N"
+	if defaultYes {
+		defaultAnswer = "Y/n"
+	}
+	answer, err := w.ask(question, defaultAnswer)
+	if err != nil {
+		return false, err
+	}
+	if answer == defaultAnswer {
+		return defaultYes, nil
+	}
+	return strings.HasPrefix(strings.ToLower(answer), "y"), nil
+}
+
+// runInit implements `aipipe init`, which asks which provider to use, checks
+// the API key with a test request and writes the config file
+func runInit(args []string) error {
+	flags := pflag.NewFlagSet("init", pflag.ContinueOnError)
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if err := checkNotReadOnly("aipipe init"); err != nil {
+		return err
+	}
+
+	terminal, err := openTerminal()
+	if err != nil {
+		return fmt.Errorf("aipipe init asks questions, so it needs a terminal")
+	}
+	defer terminal.Close()
+	w := &wizard{in: bufio.NewReader(terminal), out: terminal}
+
+	path, err := util.ConfigPath("config.yaml")
+	if err != nil {
+		return err
+	}
+
+	// Suggest a provider whose key is already set
+	providers := []util.Provider{util.GroqProvider, util.OpenAIProvider, util.AnthropicProvider}
+	suggested := ""
+	for _, provider := range providers {
+		if os.Getenv(provider.KeyVariable) != "" {
+			fmt.Fprintf(w.out, "Found %s in the environment.\n", provider.KeyVariable)
+			if suggested == "" {
+				suggested = provider.Name
+			}
+		}
+	}
+	if suggested == "" {
+		suggested = util.GroqProvider.Name
+	}
+
+	name, err := w.ask("Provider (groq, openai, anthropic or other for any OpenAI compatible API)", suggested)
+	if err != nil {
+		return err
+	}
+
+	provider := util.Provider{Name: "other", KeyVariable: "AIPIPE_API_KEY"}
+	for _, known := range providers {
+		if strings.EqualFold(name, known.Name) {
+			provider = known
+		}
+	}
+	if provider.Name == "other" {
+		if provider.Endpoint, err = w.askRequired("API endpoint, such as https://openrouter.ai/api/v1"); err != nil {
+			return err
+		}
+		if provider.DefaultModel, err = w.askRequired("Default model"); err != nil {
+			return err
+		}
+		if provider.FastModel, err = w.ask("Fast model", provider.DefaultModel); err != nil {
+			return err
+		}
+		if provider.ReasoningModel, err = w.ask("Reasoning model", provider.DefaultModel); err != nil {
+			return err
+		}
+	}
+
+	// Prefer leaving the key in the environment to writing it to a file
+	apiKey := ""
+	useEnvironment := false
+	if envKey := os.Getenv(provider.KeyVariable); envKey != "" {
+		if useEnvironment, err = w.askYesNo("Use the key in "+provider.KeyVariable, true); err != nil {
+			return err
+		}
+		if useEnvironment {
+			apiKey = envKey
+		}
+	}
+	if apiKey == "" {
+		if apiKey, err = w.askRequired("API key (it will be saved in " + path + ")"); err != nil {
+			return err
+		}
+	}
+
+	fmt.Fprintf(w.out, "Checking the key with a test request to %s... ", provider.FastModel)
+	if err := testProvider(provider, apiKey); err != nil {
+		fmt.Fprintf(w.out, "failed:\n%v\n", err)
+		save, err := w.askYesNo("Save the settings anyway", false)
+		if err != nil || !save {
+			return err
+		}
+	} else {
+		fmt.Fprintln(w.out, "ok")
+	}
+
+	savedKey := apiKey
+	if useEnvironment {
+		savedKey = ""
+	}
+	err = util.WriteConfigSettings(path, []util.ConfigSetting{
+		{Key: "provider", Value: providerSetting(provider)},
+		{Key: "endpoint", Value: provider.Endpoint},
+		{Key: "apiKey", Value: savedKey},
+		{Key: "defaultModel", Value: provider.DefaultModel},
+		{Key: "fastModel", Value: provider.FastModel},
+		{Key: "reasoningModel", Value: provider.ReasoningModel},
+	})
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(w.out, "Wrote %s\n", path)
+	return nil
+}
+
+// providerSetting returns the value of the `provider` setting for a
+// provider, which is only needed for APIs that aren't OpenAI compatible
+func providerSetting(provider util.Provider) string {
+	if provider.Name == util.AnthropicProvider.Name {
+		return llm.ProviderAnthropic
+	}
+	return ""
+}
+
+// testProvider sends a short request to a provider's fast model, to check the
+// endpoint and key work
+func testProvider(provider util.Provider, apiKey string) error {
+	client, err := llm.NewClient(&llm.Config{
+		APIEndpoint:    provider.Endpoint,
+		APIToken:       apiKey,
+		Provider:       providerSetting(provider),
+		ModelType:      llm.ModelTypeFast,
+		DefaultModel:   provider.DefaultModel,
+		FastModel:      provider.FastModel,
+		ReasoningModel: provider.ReasoningModel,
+		SystemPrompt:   "Reply with the single word OK.",
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = client.CreateCompletion(context.Background(), "Are you there?")
+	return err
+}
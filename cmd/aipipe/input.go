@@ -0,0 +1,79 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/rba100/aipipe/internal/i18n"
+	"github.com/rba100/aipipe/internal/util"
+)
+
+// readInput reads the whole of the named file, or stdin if the path is empty
+// or "-", as UTF-8 text with "\n" line endings
+func readInput(path string) (string, error) {
+	if path == "" || path == "-" {
+		data, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			return "", fmt.Errorf("error reading from stdin: %v", err)
+		}
+		text, _ := decodeInput(data)
+		return text, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("error reading file: %v", err)
+	}
+	text, _ := decodeInput(data)
+	return text, nil
+}
+
+// attachFiles attaches the files named as @path in a prompt, saying on
+// stderr which were attached
+func attachFiles(prompt string) (string, error) {
+	expanded, attached, err := util.ExpandAttachments(prompt)
+	if err != nil {
+		return "", err
+	}
+	if len(attached) > 0 {
+		fmt.Fprintln(os.Stderr, i18n.T("Attached %s", strings.Join(attached, ", ")))
+	}
+	return expanded, nil
+}
+
+// decodeInput converts input to UTF-8 with "\n" line endings, returning the
+// name of the encoding it was converted from, if any. Text piped from
+// PowerShell or saved by Windows programs is often UTF-16, Latin-1 or has a
+// byte order mark.
+func decodeInput(data []byte) (string, string) {
+	text, encoding := util.DecodeText(data)
+	return util.NormalizeNewlines(text), encoding
+}
+
+// streamInput reads from r in chunks and sends them on the returned channel,
+// which is closed at EOF. Read errors are reported on stderr.
+func streamInput(r io.Reader) <-chan string {
+	stream := make(chan string)
+
+	go func() {
+		defer close(stream)
+
+		buf := make([]byte, 4096)
+		for {
+			n, err := r.Read(buf)
+			if n > 0 {
+				stream <- string(buf[:n])
+			}
+			if err != nil {
+				if err != io.EOF {
+					fmt.Fprintln(os.Stderr, i18n.T("Error reading input: %v", err))
+				}
+				return
+			}
+		}
+	}()
+
+	return stream
+}
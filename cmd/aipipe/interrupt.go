@@ -0,0 +1,31 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/signal"
+)
+
+// exitInterrupted is the exit status after Ctrl-C, as a shell would report
+// for SIGINT
+const exitInterrupted = 130
+
+// errInterrupted is returned when Ctrl-C cancels a request to the model
+var errInterrupted = &exitError{status: exitInterrupted, err: errors.New("interrupted")}
+
+// interruptContext returns a context that is cancelled by Ctrl-C. Until stop
+// is called, Ctrl-C cancels the request in flight instead of killing aipipe,
+// so whatever was printed is finished off cleanly and the terminal is reset.
+func interruptContext() (ctx context.Context, stop context.CancelFunc) {
+	return signal.NotifyContext(context.Background(), os.Interrupt)
+}
+
+// interrupted returns errInterrupted if ctx was cancelled by Ctrl-C, and err
+// otherwise
+func interrupted(ctx context.Context, err error) error {
+	if ctx.Err() != nil {
+		return errInterrupted
+	}
+	return err
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"time"
+
+	"github.com/rba100/aipipe/internal/llm"
+	"github.com/rba100/aipipe/internal/util"
+)
+
+// jsonEvent is a line of --json-stream output
+type jsonEvent struct {
+	// Type is delta, usage, done or error
+	Type         string     `json:"type"`
+	Content      string     `json:"content,omitempty"`
+	Usage        *llm.Usage `json:"usage,omitempty"`
+	Model        string     `json:"model,omitempty"`
+	FinishReason string     `json:"finish_reason,omitempty"`
+	DurationMS   int64      `json:"duration_ms,omitempty"`
+	Message      string     `json:"message,omitempty"`
+}
+
+// writeJSONEvent writes an event as a line of JSON
+func writeJSONEvent(out io.Writer, event jsonEvent) {
+	line, _ := json.Marshal(event)
+	out.Write(append(line, '\n'))
+}
+
+// writeJSONStream streams a completion to out as newline-delimited JSON
+// events: a delta for each part as the client receives it, then usage if the
+// API reported it, then done. If the stream fails, the error is returned
+// instead of done, for the caller to report.
+func writeJSONStream(ctx context.Context, out io.Writer, client llm.LLMClient, prompt string) error {
+	start := time.Now()
+
+	for event := range client.CreateCompletionStream(ctx, prompt) {
+		if event.Err != nil {
+			return event.Err
+		}
+		writeJSONEvent(out, jsonEvent{Type: "delta", Content: event.Content})
+	}
+
+	info := client.LastCompletionInfo()
+	if info.Usage != nil {
+		writeJSONEvent(out, jsonEvent{Type: "usage", Usage: info.Usage})
+	}
+	writeJSONEvent(out, jsonEvent{
+		Type:         "done",
+		Model:        info.Model,
+		FinishReason: info.FinishReason,
+		DurationMS:   time.Since(start).Milliseconds(),
+	})
+	return nil
+}
+
+// jsonCodeBlock is a code block in --json-out output
+type jsonCodeBlock struct {
+	Lang string `json:"lang"`
+	Text string `json:"text"`
+}
+
+// jsonResult is the --json-out output
+type jsonResult struct {
+	Content      string          `json:"content"`
+	CodeBlocks   []jsonCodeBlock `json:"code_blocks"`
+	Model        string          `json:"model"`
+	Usage        *llm.Usage      `json:"usage"`
+	FinishReason string          `json:"finish_reason"`
+	DurationMS   int64           `json:"duration_ms"`
+}
+
+// writeJSONResult writes a completed answer to out as a single JSON object,
+// with its code blocks and what the API reported about the completion
+func writeJSONResult(out io.Writer, info llm.CompletionInfo, response string, duration time.Duration) error {
+	result := jsonResult{
+		Content:      response,
+		CodeBlocks:   []jsonCodeBlock{},
+		Model:        info.Model,
+		Usage:        info.Usage,
+		FinishReason: info.FinishReason,
+		DurationMS:   duration.Milliseconds(),
+	}
+	for _, block := range util.ExtractAllCodeBlocks(response) {
+		result.CodeBlocks = append(result.CodeBlocks, jsonCodeBlock{Lang: block.Type, Text: block.Text})
+	}
+
+	encoder := json.NewEncoder(out)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(result)
+}
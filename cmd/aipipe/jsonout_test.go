@@ -0,0 +1,68 @@
+package main
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rba100/aipipe/internal/llm"
+)
+
+func TestWriteJSONResult(t *testing.T) {
+	tests := []struct {
+		name     string
+		info     llm.CompletionInfo
+		response string
+		expected map[string]interface{}
+	}{
+		{
+			name:     "Code blocks and usage",
+			info:     llm.CompletionInfo{Model: "gpt-4o-2024", FinishReason: "stop", Usage: &llm.Usage{PromptTokens: 5, CompletionTokens: 7, TotalTokens: 12}},
+			response: "Run this:\n```bash\nls -l\n```\nor this:\n```\ndir\n```",
+			expected: map[string]interface{}{
+				"content": "Run this:\n```bash\nls -l\n```\nor this:\n```\ndir\n```",
+				"code_blocks": []interface{}{
+					map[string]interface{}{"lang": "bash", "text": "ls -l"},
+					map[string]interface{}{"lang": "", "text": "dir"},
+				},
+				"model":         "gpt-4o-2024",
+				"usage":         map[string]interface{}{"prompt_tokens": 5.0, "completion_tokens": 7.0, "total_tokens": 12.0},
+				"finish_reason": "stop",
+				"duration_ms":   1500.0,
+			},
+		},
+		{
+			// Every field is written, so scripts needn't check for them
+			name:     "No code blocks or usage",
+			info:     llm.CompletionInfo{},
+			response: "Hello",
+			expected: map[string]interface{}{
+				"content":       "Hello",
+				"code_blocks":   []interface{}{},
+				"model":         "",
+				"usage":         nil,
+				"finish_reason": "",
+				"duration_ms":   1500.0,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out strings.Builder
+			if err := writeJSONResult(&out, tt.info, tt.response, 1500*time.Millisecond); err != nil {
+				t.Fatalf("writeJSONResult() error = %v", err)
+			}
+
+			var result map[string]interface{}
+			if err := json.Unmarshal([]byte(out.String()), &result); err != nil {
+				t.Fatalf("writeJSONResult() wrote invalid JSON %q: %v", out.String(), err)
+			}
+			if !reflect.DeepEqual(result, tt.expected) {
+				t.Errorf("writeJSONResult() wrote %v, want %v", result, tt.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,212 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/rba100/aipipe/internal/audit"
+	"github.com/rba100/aipipe/internal/display"
+	"github.com/rba100/aipipe/internal/i18n"
+	"github.com/rba100/aipipe/internal/llm"
+	"github.com/rba100/aipipe/internal/tokenizer"
+	"github.com/rba100/aipipe/internal/util"
+	"github.com/spf13/pflag"
+)
+
+// The default limits on the commands the model can ask for before it has to
+// answer: how many, how long since the question was asked, and how many
+// tokens its completions can use, so a model that keeps asking can't run
+// for ever or use up the API quota
+const (
+	maxKubectlSteps  = 8
+	maxKubectlTime   = 5 * time.Minute
+	maxKubectlTokens = 100000
+)
+
+// kubectlLimits are the limits on a question's commands, set by --max-steps,
+// --max-time and --max-tokens
+type kubectlLimits struct {
+	steps  int
+	time   time.Duration
+	tokens int
+}
+
+// reached returns the limit reached after running steps commands in elapsed
+// time using tokens, described for the user, or an empty string if there is
+// none
+func (l kubectlLimits) reached(steps int, elapsed time.Duration, tokens int) string {
+	switch {
+	case steps >= l.steps:
+		return i18n.T("%d commands", l.steps)
+	case elapsed >= l.time:
+		return l.time.String()
+	case tokens >= l.tokens:
+		return i18n.T("%d tokens", l.tokens)
+	}
+	return ""
+}
+
+// maxKubectlOutput caps the characters of each command's output added to the
+// conversation, and kubectlLogLines the lines of logs fetched by default
+const (
+	maxKubectlOutput = 20000
+	kubectlLogLines  = 200
+)
+
+// runK8s implements `aipipe k8s [question]`, answering a question about a
+// Kubernetes cluster by letting the model run read-only kubectl commands,
+// each confirmed on the terminal first
+func runK8s(args []string) error {
+	flags := pflag.NewFlagSet("k8s", pflag.ContinueOnError)
+	namespaceFlag := flags.StringP("namespace", "n", "", "Namespace to run commands in (default: kubectl's current namespace)")
+	contextFlag := flags.String("context", "", "kubectl context to use (default: kubectl's current context)")
+	reasoningFlag := flags.BoolP("reasoning", "r", false, "Use reasoning model")
+	maxStepsFlag := flags.Int("max-steps", maxKubectlSteps, "Most commands the model can ask for before it has to answer")
+	maxTimeFlag := flags.Duration("max-time", maxKubectlTime, "Longest time to spend on commands before the model has to answer")
+	maxTokensFlag := flags.Int("max-tokens", maxKubectlTokens, "Most tokens the model can use on commands before it has to answer")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	limits := kubectlLimits{steps: *maxStepsFlag, time: *maxTimeFlag, tokens: *maxTokensFlag}
+	if limits.steps < 0 || limits.time <= 0 || limits.tokens <= 0 {
+		return fmt.Errorf("the --max-steps, --max-time and --max-tokens limits must be positive")
+	}
+
+	question := strings.Join(flags.Args(), " ")
+	if question == "" {
+		return fmt.Errorf("usage: aipipe k8s [-n namespace] [--context context] <question>")
+	}
+	if _, err := exec.LookPath("kubectl"); err != nil {
+		return fmt.Errorf("kubectl is not installed")
+	}
+
+	apiConfig, err := loadAPIConfig(false)
+	if err != nil {
+		return err
+	}
+	model := llm.ModelTypeDefault
+	if *reasoningFlag {
+		model = llm.ModelTypeReasoning
+	}
+	config := newLLMConfig(apiConfig, model, llm.GetKubernetesSystemPrompt()+" "+llm.GetUntrustedContentPrompt())
+	if err := checkCapability(config, modelName(apiConfig, model), "aipipe k8s", llm.CapabilityTools); err != nil {
+		return err
+	}
+	client, err := llm.NewClient(config)
+	if err != nil {
+		return err
+	}
+
+	conversation := strings.Builder{}
+	conversation.WriteString("Question: " + question + "\n")
+	start := time.Now()
+	tokens := 0
+	var commands []string
+	limit := limits.reached(0, 0, 0)
+	for {
+		prompt := conversation.String()
+		if limit != "" {
+			prompt += "\nYou can't run any more commands. Answer the question with what you know.\n"
+		}
+
+		reply, err := client.CreateCompletion(context.Background(), prompt)
+		if err != nil {
+			return err
+		}
+		tokens += completionTokens(client.LastCompletionInfo(), modelName(apiConfig, model), prompt, reply)
+		reply = util.StripThinkTags(reply)
+
+		kubectlArgs, isRequest := util.ParseKubectlRequest(reply)
+		if !isRequest || limit != "" {
+			printer := display.NewPrettyPrinter()
+			defer printer.Close()
+			printer.Print(reply)
+			printer.Flush()
+			if limit != "" {
+				printLimitSummary(limit, commands)
+			}
+			return nil
+		}
+
+		command := "kubectl " + strings.Join(kubectlArgs, " ")
+		fmt.Fprintf(&conversation, "\nRUN: %s\n", command)
+		output, outcome, err := runKubectl(kubectlArgs, *namespaceFlag, *contextFlag)
+		if err != nil {
+			return err
+		}
+		recordToolCall("kubectl", util.KubectlCommandLine(kubectlArgs, *namespaceFlag, *contextFlag, kubectlLogLines), outcome)
+		conversation.WriteString(output)
+		commands = append(commands, command+" ("+i18n.T(outcome)+")")
+		limit = limits.reached(len(commands), time.Since(start), tokens)
+	}
+}
+
+// recordToolCall adds a command the model asked for to the audit log, with
+// what happened to it, unless aipipe is in read-only mode
+func recordToolCall(tool string, command []string, outcome string) {
+	if isReadOnly() {
+		return
+	}
+	log, err := audit.DefaultLog()
+	if err == nil {
+		err = log.Add(audit.Entry{Tool: tool, Command: command, Outcome: outcome})
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, i18n.T("Warning: Failed to record the command in the audit log: %v", err))
+	}
+}
+
+// completionTokens returns the tokens a completion used, as the API reported
+// them, or else estimated from its prompt and reply
+func completionTokens(info llm.CompletionInfo, model string, prompt string, reply string) int {
+	if tokens := usageTokens(info); tokens > 0 {
+		return tokens
+	}
+	return tokenizer.Count(model, prompt) + tokenizer.Count(model, reply)
+}
+
+// printLimitSummary tells the user which limit stopped the model asking for
+// commands, so its answer may be incomplete, and what it asked for before
+func printLimitSummary(limit string, commands []string) {
+	fmt.Fprintln(os.Stderr, i18n.T("Reached the limit of %s, so the model answered with what it had found. It asked for:", limit))
+	for _, command := range commands {
+		fmt.Fprintln(os.Stderr, "  "+command)
+	}
+}
+
+// runKubectl checks a command the model asked for, asks the user whether to
+// run it and runs it, returning what to tell the model and a short outcome,
+// such as "declined", for the user. It only returns an error if the user
+// can't be asked.
+func runKubectl(args []string, namespace string, context string) (string, string, error) {
+	if err := util.CheckKubectlArgs(args); err != nil {
+		return fmt.Sprintf("Not run: %v.\n", err), "refused", nil
+	}
+
+	if isReadOnly() {
+		return "Not run: aipipe is in read-only mode and can't run commands.\n", "not run in read-only mode", nil
+	}
+
+	commandLine := util.KubectlCommandLine(args, namespace, context, kubectlLogLines)
+	confirmed, err := confirm(fmt.Sprintf("Run kubectl %s? [y/N] ", strings.Join(commandLine, " ")))
+	if err != nil {
+		return "", "", fmt.Errorf("kubectl commands must be confirmed, and there is no terminal to ask on")
+	}
+	if !confirmed {
+		return "Not run: the user declined to run this command.\n", "declined", nil
+	}
+
+	// Logs and resource descriptions hold text anyone with access to the
+	// cluster could have written
+	output, err := exec.Command("kubectl", commandLine...).CombinedOutput()
+	result := untrusted("kubectl "+strings.Join(args, " "), util.TruncateText(string(output), maxKubectlOutput))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, i18n.T("kubectl failed: %v", err))
+		return fmt.Sprintf("The command failed (%v):\n%s", err, result), "failed", nil
+	}
+	return "Output:\n" + result, "ran", nil
+}
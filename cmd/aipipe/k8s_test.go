@@ -0,0 +1,30 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestKubectlLimitsReached(t *testing.T) {
+	limits := kubectlLimits{steps: 3, time: time.Minute, tokens: 1000}
+	tests := []struct {
+		name     string
+		steps    int
+		elapsed  time.Duration
+		tokens   int
+		expected string
+	}{
+		{name: "Within the limits", steps: 2, elapsed: 30 * time.Second, tokens: 999, expected: ""},
+		{name: "Steps", steps: 3, elapsed: 30 * time.Second, tokens: 10, expected: "3 commands"},
+		{name: "Time", steps: 1, elapsed: time.Minute, tokens: 10, expected: "1m0s"},
+		{name: "Tokens", steps: 1, elapsed: time.Second, tokens: 1500, expected: "1000 tokens"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if result := limits.reached(tt.steps, tt.elapsed, tt.tokens); result != tt.expected {
+				t.Errorf("reached(%d, %v, %d) = %q, want %q", tt.steps, tt.elapsed, tt.tokens, result, tt.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+
+	"github.com/rba100/aipipe/internal/i18n"
+	"github.com/rba100/aipipe/internal/llm"
+	"github.com/rba100/aipipe/internal/util"
+	"github.com/spf13/pflag"
+)
+
+// loadAPIConfig returns the API configuration, or the configuration for a
+// local Ollama server if local is true. If no API key is set, a local Ollama
+// server is used instead if one is running.
+func loadAPIConfig(local bool) (*util.APIConfig, error) {
+	if local {
+		return localAPIConfig()
+	}
+
+	apiConfig, err := util.GetAPIConfig()
+	if errors.Is(err, util.ErrNoAPIKey) {
+		if localConfig, localErr := localAPIConfig(); localErr == nil {
+			fmt.Fprintln(os.Stderr, i18n.T("Warning: No API key is set, so using the local Ollama server at %s", localConfig.APIEndpoint))
+			return localConfig, nil
+		}
+	}
+	return apiConfig, err
+}
+
+// localAPIConfig returns the configuration for the local Ollama server,
+// checking it is running. Without a localModel setting, the first model that
+// has been pulled is used.
+func localAPIConfig() (*util.APIConfig, error) {
+	apiConfig, err := util.GetLocalAPIConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	models, err := listModels(apiConfig)
+	if err != nil {
+		return nil, err
+	}
+	if apiConfig.LocalModel == "" {
+		if len(models) == 0 {
+			return nil, fmt.Errorf("no models have been pulled into Ollama at %s; pull one with `ollama pull llama3.2`", apiConfig.APIEndpoint)
+		}
+		fmt.Fprintln(os.Stderr, i18n.T("Using the local model %s; set localModel in config.yaml to choose another", models[0]))
+		apiConfig.DefaultModel = models[0]
+		apiConfig.FastModel = models[0]
+		apiConfig.ReasoningModel = models[0]
+	}
+	return apiConfig, nil
+}
+
+// listModels returns the models offered by the configured API
+func listModels(apiConfig *util.APIConfig) ([]string, error) {
+	client, err := llm.NewClient(newLLMConfig(apiConfig, llm.ModelTypeDefault, ""))
+	if err != nil {
+		return nil, err
+	}
+	lister, ok := client.(llm.ModelLister)
+	if !ok {
+		return nil, fmt.Errorf("the API at %s can't list its models", apiConfig.APIEndpoint)
+	}
+	return lister.ListModels(context.Background())
+}
+
+// runModels implements `aipipe models`, listing the models offered by the
+// configured API, or with --local those pulled into the local Ollama server
+func runModels(args []string) error {
+	flags := pflag.NewFlagSet("models", pflag.ContinueOnError)
+	localFlag := flags.Bool("local", false, "List the models pulled into the local Ollama server")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() > 0 {
+		return fmt.Errorf("usage: aipipe models [--local]")
+	}
+
+	apiConfig, err := util.GetAPIConfig()
+	if *localFlag || errors.Is(err, util.ErrNoAPIKey) {
+		apiConfig, err = util.GetLocalAPIConfig()
+	}
+	if err != nil {
+		return err
+	}
+
+	models, err := listModels(apiConfig)
+	if err != nil {
+		return err
+	}
+	sort.Strings(models)
+	for _, model := range models {
+		fmt.Println(model)
+	}
+	return nil
+}
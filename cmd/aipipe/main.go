@@ -2,61 +2,606 @@ package main
 
 import (
 	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"os"
 	"strings"
+	"time"
 
 	"github.com/rba100/aipipe/internal/display"
+	"github.com/rba100/aipipe/internal/i18n"
 	"github.com/rba100/aipipe/internal/llm"
+	"github.com/rba100/aipipe/internal/stream"
+	"github.com/rba100/aipipe/internal/tokenizer"
 	"github.com/rba100/aipipe/internal/util"
 	"github.com/spf13/pflag"
 )
 
+// subcommands maps subcommand names to their entry points. Each receives the
+// arguments following the subcommand name and parses its own flags.
+var subcommands = map[string]func(args []string) error{
+	"config":      runConfig,
+	"chat":        runChat,
+	"cron":        runCron,
+	"explain":     runExplain,
+	"extract":     runExtract,
+	"highlight":   runHighlight,
+	"history":     runHistory,
+	"http":        runHTTP,
+	"init":        runInit,
+	"k8s":         runK8s,
+	"memory":      runMemory,
+	"models":      runModels,
+	"oneliner":    runOneLiner,
+	"prices":      runPrices,
+	"prompts":     runPrompts,
+	"regex":       runRegex,
+	"remember":    runRemember,
+	"render":      runRender,
+	"snippet":     runSnippet,
+	"sql":         runSQL,
+	"strip-think": runStripThink,
+}
+
 func main() {
 	// Initialize console for proper UTF-8 output (Windows-specific)
 	initConsole()
-	
+	i18n.SetLocale(i18n.DetectLocale(os.Getenv))
+	if !isReadOnly() {
+		migrateLegacyDir()
+	}
+	if len(os.Args) < 2 || os.Args[1] != "config" {
+		warnConfigProblems()
+	}
+	configureHighlighter()
+	registerLanguageAliases()
+	registerParserPlugins()
+
+	if run, args, ok := subcommandFor(os.Args[1:]); ok {
+		if err := run(args); err != nil {
+			fmt.Fprintln(os.Stderr, i18n.T("Error: %v", err))
+			os.Exit(exitStatus(err))
+		}
+		return
+	}
+
+	if err := runQuery(os.Args[1:], "", ""); err != nil {
+		fmt.Fprintln(os.Stderr, i18n.T("Error: %v", err))
+		os.Exit(exitStatus(err))
+	}
+}
+
+// subcommandFor returns the subcommand named by the first argument, if any,
+// and the arguments that follow it. Any other arguments are a query. A prompt
+// that starts with a subcommand's name is asked by quoting it or putting --
+// before it, as in aipipe -- history of rome: -- isn't a subcommand, and the
+// query's flag parsing drops it.
+func subcommandFor(args []string) (func(args []string) error, []string, bool) {
+	if len(args) == 0 {
+		return nil, nil, false
+	}
+	run, ok := subcommands[args[0]]
+	return run, args[1:], ok
+}
+
+// migrateLegacyDir moves files kept in ~/.aipipe by older versions into the
+// XDG base directories, saying what moved
+func migrateLegacyDir() {
+	moved, err := util.MigrateLegacyDir()
+	for _, move := range moved {
+		fmt.Fprintln(os.Stderr, i18n.T("Moved %s", move))
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, i18n.T("Warning: Failed to move files out of ~/.aipipe, they will still be used there: %v", err))
+	}
+}
+
+// runQuery implements the default command, asking the model about the prompt
+// given as arguments and/or piped in. savedPrompt, if not empty, is the saved
+// prompt of the named snippet, and comes before any prompt in the arguments.
+func runQuery(args []string, snippet string, savedPrompt string) error {
+	flags := pflag.NewFlagSet("aipipe", pflag.ExitOnError)
+
 	// Define command line flags
-	codeBlockFlag := pflag.BoolP("codeblock", "c", false, "Extract code block from response")
-	streamFlag := pflag.BoolP("stream", "s", false, "Stream completions from the AI model")
-	prettyFlag := pflag.BoolP("pretty", "p", false, "Enable pretty printing with colors and formatting")
-	reasoningFlag := pflag.BoolP("reasoning", "r", false, "Use reasoning model")
-	fastFlag := pflag.BoolP("fast", "f", false, "Use fast model")
-	thinkingFlag := pflag.BoolP("thinking", "t", false, "Show thinking process")
+	codeBlockFlag := flags.IntP("codeblock", "c", 0, i18n.T("Extract a code block from the response: the first with -c, or the Nth with --codeblock=N"))
+	flags.Lookup("codeblock").NoOptDefVal = "1"
+	allBlocksFlag := flags.Bool("all-blocks", false, i18n.T("Extract every code block from the response, or with --output write each to a numbered file"))
+	blockSeparatorFlag := flags.String("block-separator", "", i18n.T("With --all-blocks, put a line of this text between code blocks instead of a blank line"))
+	streamFlag := flags.BoolP("stream", "s", false, i18n.T("Stream completions from the AI model"))
+	prettyFlag := flags.BoolP("pretty", "p", false, i18n.T("Enable pretty printing with colors and formatting"))
+	plainFlag := flags.Bool("plain", false, i18n.T("Remove markdown formatting from the answer, for plain text such as emails and commit messages"))
+	reasoningFlag := flags.BoolP("reasoning", "r", false, i18n.T("Use reasoning model"))
+	fastFlag := flags.BoolP("fast", "f", false, i18n.T("Use fast model"))
+	localFlag := flags.Bool("local", false, i18n.T("Use a model on the local Ollama server"))
+	thinkingFlag := flags.BoolP("thinking", "t", false, i18n.T("Show thinking process"))
+	collapseFlag := flags.Int("collapse", 0, i18n.T("In pretty mode, show at most this many lines of each code block"))
+	wrapFlag := flags.Int("wrap", 0, i18n.T("Reflow prose in the answer to this many columns, leaving code blocks alone"))
+	diffAgainstFlag := flags.String("diff-against", "", i18n.T("Show the answer as a word diff against this file, such as the current version of a regenerated doc"))
+	sectionFlag := flags.String("section", "", i18n.T("Print only the section of the answer under this markdown heading, such as \"## Usage\""))
+	oneLineFlag := flags.Bool("oneline", false, i18n.T("Answer with a single line, for use in command substitution"))
+	confidenceFlag := flags.Bool("confidence", false, i18n.T("With --oneline, append the model's confidence in its answer"))
+	minConfidenceFlag := flags.Float64("min-confidence", 0, i18n.T("Ask the model to score its confidence from 0 to 1, and exit with status 3 if the score is lower than this"))
+	commandFlag := flags.Bool("cmd", false, i18n.T("Generate a shell command for this platform (implies -c -f)"))
+	languageFlag := flags.StringP("lang", "l", "", i18n.T("With -c, the language of the code block; its syntax is checked and fixed once if broken"))
+	scaffoldFlag := flags.String("scaffold", "", i18n.T("Generate several files and write them into this directory"))
+	yesFlag := flags.BoolP("yes", "y", false, i18n.T("Don't ask for confirmation before writing files"))
+	statusFlag := flags.Bool("status", false, i18n.T("While streaming, show a status line with the model, elapsed time and token rate"))
+	previewFlag := flags.Bool("preview", false, i18n.T("With -r, show a greyed out answer from the fast model until the reasoning model's answer arrives"))
+	notifyFlag := flags.Bool("notify", false, i18n.T("Ring the terminal bell and show a desktop notification when the answer is complete"))
+	lastPromptFlag := flags.Bool("last-prompt", false, i18n.T("Run the previous prompt again, for example with a different model"))
+	jsonStreamFlag := flags.Bool("json-stream", false, i18n.T("Write the answer as newline-delimited JSON events, for programs to read"))
+	jsonOutFlag := flags.Bool("json-out", false, i18n.T("Write the answer, its code blocks and details of the model's response as one JSON object"))
+	jsonFlag := flags.Bool("json", false, i18n.T("Same as --json-out"))
+	ghaFlag := flags.Bool("gha", false, i18n.T("Review the input and report problems as GitHub Actions annotations"))
+	sourceFlag := flags.Bool("source", false, i18n.T("Include the code around the lines of local files named in a piped stack trace"))
+	logsFlag := flags.Bool("logs", false, i18n.T("Treat piped input as a log: fold repeated lines and replace timestamps and ids, keeping the latest errors"))
+	compressFlag := flags.Bool("compress", false, i18n.T("Shorten piped input, such as long logs, before sending it, to use fewer tokens"))
+	keepANSIFlag := flags.Bool("keep-ansi", false, i18n.T("Keep terminal escape sequences, such as colours, in piped input"))
+	urlFlag := flags.StringArray("url", nil, i18n.T("Include the text of a web page in the prompt (can be repeated)"))
+	outputFlag := flags.StringP("output", "o", "", i18n.T("Write the answer, or with -c its code block, to this file once it is complete, instead of to stdout"))
+	appendFlag := flags.Bool("append", false, i18n.T("With --output, add the answer to the end of the file instead of replacing it"))
+	profileFlag := flags.String("profile", "", i18n.T("Record a CPU (cpu) or memory allocation (mem) profile of the run, for go tool pprof"))
+	profileFileFlag := flags.String("profile-file", "", i18n.T("With --profile, write the profile to this file instead of aipipe.KIND.pprof"))
+	logRenderFlag := flags.String("log-render", "", i18n.T("With --pretty, also write a plain text snapshot of how the answer was rendered to this file, for reporting display problems"))
+	accessibleFlag := flags.Bool("accessible", false, i18n.T("Label code blocks, headings and quotes in text rather than by colour, and avoid box-drawing characters and redrawn status lines, for screen readers and braille terminals"))
+	inputRoleFlag := flags.String("input-role", "", i18n.T("Send piped input in the prompt (prompt), or apart from the instruction as a user or system message (user, system)"))
+
+	flags.Usage = func() {
+		fmt.Fprintln(os.Stderr, i18n.T("Usage of %s:", "aipipe"))
+		flags.PrintDefaults()
+	}
 
 	// Parse command line flags - pflag allows flags to be placed anywhere
-	pflag.Parse()
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
 
 	// Combine short and long flags
-	isCodeBlock := *codeBlockFlag
-	isStream := *streamFlag
-	isPretty := *prettyFlag
-	isReasoning := *reasoningFlag
-	isFast := *fastFlag
-	showThinking := *thinkingFlag
+	opts := queryOptions{
+		isCodeBlock:    *codeBlockFlag > 0 || *allBlocksFlag,
+		codeBlock:      *codeBlockFlag,
+		allBlocks:      *allBlocksFlag,
+		blockSeparator: *blockSeparatorFlag,
+		isStream:       *streamFlag,
+		isPretty:       *prettyFlag,
+		isPlain:        *plainFlag,
+		isReasoning:    *reasoningFlag,
+		isFast:         *fastFlag,
+		isLocal:        *localFlag,
+		showThinking:   *thinkingFlag,
+		collapseLines:  *collapseFlag,
+		wrapWidth:      *wrapFlag,
+		section:        *sectionFlag,
+		diffAgainst:    *diffAgainstFlag,
+		isOneLine:      *oneLineFlag,
+		showConfidence: *confidenceFlag,
+		minConfidence:  *minConfidenceFlag,
+		isCommand:      *commandFlag,
+		urls:           *urlFlag,
+		language:       *languageFlag,
+		scaffoldDir:    *scaffoldFlag,
+		assumeYes:      *yesFlag,
+		showStatus:     *statusFlag,
+		showPreview:    *previewFlag,
+		jsonStream:     *jsonStreamFlag,
+		jsonOut:        *jsonOutFlag || *jsonFlag,
+		jsonShort:      *jsonFlag && !*jsonOutFlag,
+		isAnnotations:  *ghaFlag,
+		compressInput:  *compressFlag,
+		isLogs:         *logsFlag,
+		withSource:     *sourceFlag,
+		keepANSI:       *keepANSIFlag,
+		outputPath:     *outputFlag,
+		appendOutput:   *appendFlag,
+		accessible:     isAccessible(flags, *accessibleFlag),
+		logRender:      *logRenderFlag,
+	}
+	opts.inputRole = *inputRoleFlag
+	if opts.inputRole == "" {
+		role, err := util.GetInputRole()
+		if err != nil {
+			fmt.Fprintln(os.Stderr, i18n.T("Warning: Failed to load the input role: %v", err))
+		}
+		opts.inputRole = role
+	}
+
+	if flags.Changed("codeblock") && *codeBlockFlag < 1 {
+		return errors.New(i18n.T("code blocks are numbered from 1, as in --codeblock=2"))
+	}
+	if flags.Changed("block-separator") && !opts.allBlocks {
+		return errors.New(i18n.T("the --block-separator option requires --all-blocks"))
+	}
+
+	// --cmd implies --codeblock, and --fast unless another model was chosen
+	if opts.isCommand {
+		opts.isCodeBlock = true
+		opts.codeBlock = max(opts.codeBlock, 1)
+		opts.isFast = !opts.isReasoning
+	}
+
 	// Get prompt from command line arguments
-	var argPrompt string
-	if pflag.NArg() > 0 {
-		argPrompt = strings.Join(pflag.Args(), " ")
+	argPrompt := savedPrompt
+	if flags.NArg() > 0 {
+		if argPrompt != "" {
+			argPrompt += "\n"
+		}
+		argPrompt += strings.Join(flags.Args(), " ")
+	}
+	if *lastPromptFlag {
+		if argPrompt != "" {
+			return errors.New(i18n.T("the --last-prompt option cannot be used with a prompt"))
+		}
+		prompt, err := lastPrompt()
+		if err != nil {
+			return err
+		}
+		argPrompt = prompt
+	}
+	if opts.scaffoldDir != "" {
+		if err := checkNotReadOnly("writing files with --scaffold"); err != nil {
+			return err
+		}
+	}
+	if opts.outputPath != "" {
+		if err := checkNotReadOnly("writing the answer with --output"); err != nil {
+			return err
+		}
+	}
+	if opts.logRender != "" {
+		if err := checkNotReadOnly("writing a render log with --log-render"); err != nil {
+			return err
+		}
+	}
+	if *profileFileFlag != "" && *profileFlag == "" {
+		return errors.New(i18n.T("the --profile-file option requires --profile"))
+	}
+	if *profileFlag != "" {
+		if err := checkNotReadOnly("writing a profile with --profile"); err != nil {
+			return err
+		}
+		stopProfile, err := startProfile(*profileFlag, *profileFileFlag)
+		if err != nil {
+			return err
+		}
+		defer stopProfile()
 	}
 
 	// Run the AI query
-	err := runAIQuery(isCodeBlock, isStream, isPretty, isReasoning, isFast, showThinking, argPrompt)
+	var result queryResult
+	err := runAIQuery(opts, argPrompt, &result)
+	recordPrompt(argPrompt, snippet, result, errors.Is(err, errInterrupted))
+	if err != nil && !opts.jsonStream && !opts.jsonOut {
+		suggestModels(err, opts.isLocal)
+	}
+	if err != nil && opts.jsonStream {
+		writeJSONEvent(os.Stdout, jsonEvent{Type: "error", Message: err.Error()})
+	}
+	if err != nil && opts.jsonOut {
+		json.NewEncoder(os.Stdout).Encode(map[string]string{"error": err.Error()})
+	}
+	if *notifyFlag {
+		notifyFinished(argPrompt, err)
+	}
+	return err
+}
+
+// queryOptions holds the command line options for a query
+type queryOptions struct {
+	isCodeBlock    bool
+	codeBlock      int
+	allBlocks      bool
+	blockSeparator string
+	isStream       bool
+	isPretty       bool
+	isPlain        bool
+	isReasoning    bool
+	isFast         bool
+	isLocal        bool
+	showThinking   bool
+	collapseLines  int
+	wrapWidth      int
+	section        string
+	diffAgainst    string
+	isOneLine      bool
+	showConfidence bool
+	minConfidence  float64
+	isCommand      bool
+	isExplain      bool
+	isChat         bool
+	urls           []string
+	language       string
+	scaffoldDir    string
+	assumeYes      bool
+	showStatus     bool
+	showPreview    bool
+	jsonStream     bool
+	jsonOut        bool
+	jsonShort      bool
+	isAnnotations  bool
+	compressInput  bool
+	isLogs         bool
+	withSource     bool
+	keepANSI       bool
+	outputPath     string
+	appendOutput   bool
+	accessible     bool
+	logRender      string
+	inputRole      string
+}
+
+// maxNotificationPrompt caps how much of the prompt is quoted in a
+// notification
+const maxNotificationPrompt = 60
+
+// notifyFinished rings the terminal bell and shows a desktop notification
+// saying the query for prompt has finished, or failed with err
+func notifyFinished(prompt string, err error) {
+	os.Stderr.WriteString("\a")
+
+	message := "Answer ready"
+	if prompt != "" {
+		message = strings.Join(strings.Fields(prompt), " ")
+		if runes := []rune(message); len(runes) > maxNotificationPrompt {
+			message = string(runes[:maxNotificationPrompt]) + "…"
+		}
+	}
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
-		os.Exit(1)
+		message = "Failed: " + err.Error()
 	}
+
+	// Desktop notifications run a command
+	if isReadOnly() {
+		return
+	}
+	if err := util.Notify("aipipe", message); err != nil {
+		fmt.Fprintln(os.Stderr, i18n.T("Warning: Failed to show notification: %v", err))
+	}
+}
+
+// maxURLChars caps the text included in the prompt for each --url page
+const maxURLChars = 20000
+
+// sourceContextLines is how many lines either side of each line named in a
+// stack trace --source includes, and maxSourceFiles how many files
+const (
+	sourceContextLines = 5
+	maxSourceFiles     = 10
+)
+
+// maxLogLines caps the distinct lines of a log kept by --logs
+const maxLogLines = 400
+
+// newPrinter creates a pretty printer configured from the query options
+func newPrinter(opts queryOptions) *display.PrettyPrinter {
+	printer := display.NewPrettyPrinter()
+	printer.SetMaxCodeBlockLines(opts.collapseLines)
+	printer.SetAccessible(opts.accessible)
+	if opts.logRender != "" {
+		file, err := os.Create(opts.logRender)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, i18n.T("Warning: Failed to create the render log: %v", err))
+		} else {
+			printer.SetRenderLog(file)
+		}
+	}
+	return printer
+}
+
+// jsonOutFlag returns the option that asked for JSON output as it was typed,
+// --json or --json-out, for error messages; jsonShort is set for --json
+func (opts queryOptions) jsonOutFlag() string {
+	if opts.jsonShort {
+		return "--json"
+	}
+	return "--json-out"
+}
+
+// isAccessible reports whether output should be accessible: as set by the
+// --accessible flag if it was given, or else by the accessible config key
+func isAccessible(flags *pflag.FlagSet, flag bool) bool {
+	if flags.Changed("accessible") {
+		return flag
+	}
+	accessible, err := util.GetAccessible()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, i18n.T("Warning: Failed to load the accessible setting: %v", err))
+	}
+	return accessible
+}
+
+// systemPrompt returns the system prompt for the query options, followed by
+// the environment details configured under contextVars and any remembered
+// facts
+func systemPrompt(opts queryOptions) string {
+	prompt := llm.GetSystemPrompt(opts.isCodeBlock)
+	if opts.isOneLine {
+		prompt = llm.GetOneLineSystemPrompt(opts.showConfidence)
+	}
+	if opts.isCommand {
+		prompt = llm.GetCommandSystemPrompt(util.PlatformHints())
+	}
+	if opts.isExplain {
+		prompt = llm.GetExplainSystemPrompt()
+	}
+	if opts.isChat {
+		prompt = llm.GetChatSystemPrompt()
+	}
+	if opts.scaffoldDir != "" {
+		prompt = llm.GetScaffoldSystemPrompt()
+	}
+	if opts.isAnnotations {
+		prompt = llm.GetAnnotationSystemPrompt()
+	}
+	if opts.language != "" {
+		prompt += " Write any code in " + opts.language + "."
+	}
+	if opts.minConfidence > 0 {
+		prompt += " " + llm.GetConfidenceScorePrompt()
+	}
+	if len(opts.urls) > 0 {
+		prompt += " " + llm.GetUntrustedContentPrompt()
+	}
+
+	names, err := util.GetContextVars()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, i18n.T("Warning: Failed to load context variables: %v", err))
+	}
+	context, err := util.BuildContext(names, time.Now())
+	if err != nil {
+		fmt.Fprintln(os.Stderr, i18n.T("Warning: %v", err))
+	}
+	if context != "" {
+		prompt += "\n\n" + context
+	}
+
+	if facts := rememberedFacts(); facts != "" {
+		prompt += "\n\n" + facts
+	}
+
+	return prompt
+}
+
+// queryResult describes the answer to a query
+type queryResult struct {
+	info llm.CompletionInfo
+	// reply is the text received from the model, as far as it got
+	reply string
 }
 
-func runAIQuery(isCodeBlock, isStream, isPretty, isReasoning, isFast, showThinking bool, argPrompt string) error {
+// runAIQuery asks the model about the prompt. result, if not nil, is set to
+// the description of its answer.
+func runAIQuery(opts queryOptions, argPrompt string, result *queryResult) error {
+	isCodeBlock := opts.isCodeBlock
+	isStream := opts.isStream
+	isPretty := opts.isPretty
+	isReasoning := opts.isReasoning
+	isFast := opts.isFast
+	showThinking := opts.showThinking
+
 	// Check for mutually exclusive options
 
 	if isReasoning && isFast {
-		return fmt.Errorf("the --reasoning and --fast options cannot be used together")
+		return errors.New(i18n.T("the --reasoning and --fast options cannot be used together"))
+	}
+	if opts.isOneLine && (isCodeBlock || isPretty) {
+		return errors.New(i18n.T("the --oneline option cannot be used with --codeblock or --pretty"))
+	}
+	if opts.scaffoldDir != "" && (isCodeBlock || opts.isOneLine) {
+		return errors.New(i18n.T("the --scaffold option cannot be used with --codeblock or --oneline"))
+	}
+	if opts.showConfidence && !opts.isOneLine {
+		return errors.New(i18n.T("the --confidence option requires --oneline"))
+	}
+	if opts.minConfidence < 0 || opts.minConfidence > 1 {
+		return errors.New(i18n.T("the --min-confidence score must be between 0 and 1"))
+	}
+	if opts.minConfidence > 0 && (opts.showConfidence || opts.jsonStream || opts.jsonOut) {
+		return errors.New(i18n.T("the --min-confidence option cannot be used with --confidence, --json-stream or %s", opts.jsonOutFlag()))
+	}
+	if opts.showPreview && !isReasoning {
+		return errors.New(i18n.T("the --preview option requires --reasoning"))
+	}
+	if opts.jsonStream && (isCodeBlock || isPretty || opts.isOneLine || opts.scaffoldDir != "" || opts.showPreview) {
+		return errors.New(i18n.T("the --json-stream option cannot be used with --codeblock, --pretty, --oneline, --scaffold or --preview"))
+	}
+	if opts.jsonOut && (isCodeBlock || isPretty || opts.isOneLine || opts.scaffoldDir != "" || opts.jsonStream) {
+		// The JSON object already lists the code blocks
+		return errors.New(i18n.T("the %s option cannot be used with --codeblock, --all-blocks, --pretty, --oneline, --scaffold or --json-stream", opts.jsonOutFlag()))
+	}
+	if opts.isPlain && (isCodeBlock || isPretty || opts.isOneLine || opts.scaffoldDir != "" || opts.jsonStream || opts.jsonOut || opts.isAnnotations) {
+		return errors.New(i18n.T("the --plain option cannot be used with --codeblock, --pretty, --oneline, --scaffold, --json-stream, %s or --gha", opts.jsonOutFlag()))
+	}
+	if opts.wrapWidth < 0 {
+		return errors.New(i18n.T("the --wrap width must be a positive number of columns"))
+	}
+	if opts.wrapWidth > 0 && (isCodeBlock || opts.isOneLine || opts.scaffoldDir != "" || opts.jsonStream || opts.jsonOut || opts.isAnnotations) {
+		return errors.New(i18n.T("the --wrap option cannot be used with --codeblock, --oneline, --scaffold, --json-stream, %s or --gha", opts.jsonOutFlag()))
+	}
+	if opts.section != "" && (opts.isOneLine || opts.scaffoldDir != "" || opts.jsonStream || opts.jsonOut || opts.isAnnotations) {
+		return errors.New(i18n.T("the --section option cannot be used with --oneline, --scaffold, --json-stream, %s or --gha", opts.jsonOutFlag()))
+	}
+	if opts.diffAgainst != "" && (isPretty || opts.isPlain || opts.isOneLine || opts.scaffoldDir != "" || opts.jsonStream || opts.jsonOut || opts.isAnnotations) {
+		return errors.New(i18n.T("the --diff-against option cannot be used with --pretty, --plain, --oneline, --scaffold, --json-stream, %s or --gha", opts.jsonOutFlag()))
+	}
+	if opts.isAnnotations && (isCodeBlock || isPretty || opts.isOneLine || opts.scaffoldDir != "" || opts.jsonStream || opts.jsonOut) {
+		return errors.New(i18n.T("the --gha option cannot be used with --codeblock, --pretty, --oneline, --scaffold, --json-stream or %s", opts.jsonOutFlag()))
+	}
+	if opts.outputPath != "" && (isPretty || opts.scaffoldDir != "" || opts.jsonStream || opts.jsonOut || opts.isAnnotations || opts.diffAgainst != "") {
+		return errors.New(i18n.T("the --output option cannot be used with --pretty, --scaffold, --json-stream, %s, --gha or --diff-against", opts.jsonOutFlag()))
+	}
+	if opts.appendOutput && opts.outputPath == "" {
+		return errors.New(i18n.T("the --append option requires --output"))
+	}
+	if opts.logRender != "" && !isPretty {
+		return errors.New(i18n.T("the --log-render option requires --pretty"))
+	}
+	if opts.accessible && (opts.showStatus || opts.showPreview) {
+		// Both redraw the screen as the answer arrives, which a screen
+		// reader would read out over and over
+		return errors.New(i18n.T("the --status and --preview options cannot be used with --accessible"))
+	}
+	if opts.allBlocks && opts.codeBlock > 1 {
+		return errors.New(i18n.T("the --all-blocks option cannot be used with --codeblock=N"))
+	}
+	if opts.allBlocks && (isPretty || opts.language != "" || opts.diffAgainst != "") {
+		return errors.New(i18n.T("the --all-blocks option cannot be used with --pretty, --lang or --diff-against"))
+	}
+	switch opts.inputRole {
+	case "", "prompt", llm.InputRoleUser, llm.InputRoleSystem:
+	default:
+		return errors.New(i18n.T("unknown --input-role %q (expected prompt, user or system)", opts.inputRole))
+	}
+	formatters, err := util.GetFormatters()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, i18n.T("Warning: Failed to load formatters: %v", err))
+	}
+	fileHeader := ""
+	if opts.outputPath != "" || opts.scaffoldDir != "" {
+		fileHeader, err = util.GetFileHeader()
+		if err != nil {
+			fmt.Fprintln(os.Stderr, i18n.T("Warning: Failed to load the file header: %v", err))
+		}
+	}
+	if opts.showPreview || opts.jsonOut || opts.isAnnotations {
+		// The preview is replaced by the whole answer at once, and JSON
+		// output describes the whole answer
+		isStream = false
+	}
+	if opts.isOneLine || opts.scaffoldDir != "" || opts.wrapWidth > 0 || opts.section != "" || opts.diffAgainst != "" || opts.minConfidence > 0 || (isCodeBlock && (opts.language != "" || len(formatters) > 0)) {
+		// The answer is post-processed, validated or formatted as a whole
+		isStream = false
+	}
+	if opts.codeBlock > 1 || opts.allBlocks {
+		// Later code blocks are found in the whole answer
+		isStream = false
+	}
+	if opts.outputPath != "" {
+		// The file is only written once the whole answer has arrived, so
+		// an error part way through leaves it as it was
+		isStream = false
+	}
+
+	// Read the reference before waiting for the model
+	reference := ""
+	if opts.diffAgainst != "" {
+		data, err := os.ReadFile(opts.diffAgainst)
+		if err != nil {
+			return fmt.Errorf("error reading the --diff-against file: %v", err)
+		}
+		reference, _ = decodeInput(data)
+	}
+
+	// Attach the files named as @path in the instruction
+	argPrompt, err = attachFiles(argPrompt)
+	if err != nil {
+		return err
 	}
 
 	// Get API configuration from environment variables
-	apiConfig, err := util.GetAPIConfig()
+	apiConfig, err := loadAPIConfig(opts.isLocal)
 	if err != nil {
 		return err
 	}
@@ -69,22 +614,10 @@ func runAIQuery(isCodeBlock, isStream, isPretty, isReasoning, isFast, showThinki
 		model = llm.ModelTypeFast
 	}
 
-	// Create LLM client
-	config := &llm.Config{
-		APIEndpoint:    apiConfig.APIEndpoint,
-		APIToken:       apiConfig.APIToken,
-		IsCodeBlock:    isCodeBlock,
-		IsStream:       isStream,
-		ModelType:      model,
-		DefaultModel:   apiConfig.DefaultModel,
-		FastModel:      apiConfig.FastModel,
-		ReasoningModel: apiConfig.ReasoningModel,
-	}
-
-	client, err := llm.NewClient(config)
-	if err != nil {
-		return err
-	}
+	config := newLLMConfig(apiConfig, model, systemPrompt(opts))
+	config.IsCodeBlock = isCodeBlock
+	config.IsStream = isStream
+	config.IncludeUsage = opts.jsonStream
 
 	// Build prompt from stdin and/or command line argument
 	promptBuilder := strings.Builder{}
@@ -92,18 +625,66 @@ func runAIQuery(isCodeBlock, isStream, isPretty, isReasoning, isFast, showThinki
 	// Check if there's input from stdin
 	stat, _ := os.Stdin.Stat()
 	if (stat.Mode() & os.ModeCharDevice) == 0 {
-		// Read from stdin
-		scanner := bufio.NewScanner(os.Stdin)
-		for scanner.Scan() {
-			promptBuilder.WriteString(scanner.Text())
-			promptBuilder.WriteString("\n")
+		// Refuse binary input, such as when aipipe is put in a pipeline by
+		// mistake, before reading it all
+		reader := bufio.NewReaderSize(os.Stdin, util.BinarySniffLength)
+		if sample, _ := reader.Peek(util.BinarySniffLength); util.LooksBinary(sample) {
+			return errors.New(i18n.T("the piped input looks like binary data, not text; aipipe only sends text to the model"))
 		}
 
-		if err := scanner.Err(); err != nil {
+		// Read from stdin
+		data, err := io.ReadAll(reader)
+		if err != nil {
 			return fmt.Errorf("error reading from stdin: %v", err)
 		}
+		text, encoding := decodeInput(data)
+		if encoding != "" {
+			fmt.Fprintln(os.Stderr, i18n.T("Converted the piped input from %s to UTF-8", encoding))
+		}
+		if !opts.keepANSI {
+			text = util.StripANSI(text)
+		}
+		if text != "" && !strings.HasSuffix(text, "\n") {
+			text += "\n"
+		}
+		source := ""
+		if opts.withSource {
+			source = util.SourceContext(util.FindSourceReferences(text), sourceContextLines, maxSourceFiles)
+			if source == "" {
+				fmt.Fprintln(os.Stderr, i18n.T("Warning: No files named in the piped input were found under the current directory"))
+			}
+		}
+		if opts.isLogs {
+			lines := strings.Count(text, "\n")
+			var distinct int
+			text, distinct = util.PreprocessLogs(text, maxLogLines)
+			fmt.Fprintln(os.Stderr, i18n.T("Condensed %d log lines to %d distinct lines", lines, distinct))
+		}
+		if opts.compressInput {
+			text = compressInput(text, modelName(apiConfig, model))
+		}
+		promptBuilder.WriteString(text)
+		if source != "" {
+			promptBuilder.WriteString("-----\nSource code around the lines named in the stack trace, which are marked with >:\n")
+			promptBuilder.WriteString(source)
+		}
 	}
 
+	// Add the text of any web pages, which anyone could have written
+	for _, url := range opts.urls {
+		text, err := util.FetchURLText(url, maxURLChars)
+		if err != nil {
+			return err
+		}
+		if promptBuilder.Len() > 0 {
+			promptBuilder.WriteString("-----\n")
+		}
+		promptBuilder.WriteString(untrusted(url, text))
+	}
+
+	// Piped input and web pages may be sent apart from the instruction
+	input := promptBuilder.String()
+
 	// Add command line argument if provided
 	if argPrompt != "" {
 		if promptBuilder.Len() > 0 {
@@ -114,55 +695,114 @@ func runAIQuery(isCodeBlock, isStream, isPretty, isReasoning, isFast, showThinki
 
 	// Check if we have any input
 	if promptBuilder.Len() == 0 {
-		return fmt.Errorf("no input provided")
+		return errors.New(i18n.T("no input provided"))
 	}
 
 	prompt := promptBuilder.String()
 
-	// Process the prompt with the LLM
-	if isStream {
-		stream := client.CreateCompletionStream(prompt)
+	// request is what is sent as the prompt, which is only the instruction
+	// if the input is sent as a message of its own
+	request := prompt
+	if (opts.inputRole == llm.InputRoleUser || opts.inputRole == llm.InputRoleSystem) && input != "" && argPrompt != "" {
+		config.Input = input
+		config.InputRole = opts.inputRole
+		request = argPrompt
+	}
+
+	// Create LLM client
+	client, err := llm.NewClient(config)
+	if err != nil {
+		return err
+	}
+	var reply strings.Builder
+	if result != nil {
+		defer func() { *result = queryResult{info: client.LastCompletionInfo(), reply: reply.String()} }()
+	}
+
+	ctx, stop := interruptContext()
+	defer stop()
+
+	// Once the reply has been printed, offer to remember what it taught us
+	// about the user. Deferred first so it runs after the printers close, and
+	// skipped if the reply was interrupted.
+	if !opts.isOneLine && !opts.jsonStream && !opts.jsonOut && !opts.isAnnotations {
+		defer func() {
+			if ctx.Err() == nil {
+				stop()
+				offerMemories(apiConfig, prompt, reply.String())
+			}
+		}()
+	}
+
+	// Process the prompt with the LLM. A stream is printed as far as it gets
+	// before any error that ends it is returned.
+	var streamErr error
+	if opts.jsonStream {
+		streamErr = writeJSONStream(ctx, os.Stdout, client, request)
+	} else if isStream {
+		var status *display.StatusLine
+		if opts.showStatus {
+			status = newStatusLine(modelName(apiConfig, model))
+		}
+		if status != nil {
+			status.Start()
+			defer status.Clear()
+		}
+
+		parts := bufferReply(ctx, stream.Tee(ctx, llm.Contents(client.CreateCompletionStream(ctx, request), &streamErr), func(part string) {
+			reply.WriteString(part)
+			if status != nil {
+				status.Count(part)
+			}
+		}))
 		if !showThinking {
-			stream = util.StripThinkTagsStream(stream)
+			parts = util.StripThinkTagsStream(ctx, parts)
 		}
 		if isCodeBlock {
-			codeBlockStream := util.ExtractCodeBlockStream(stream)
+			codeBlockStream := util.SelectCodeBlockStream(ctx, parts, max(opts.codeBlock, 1))
 
 			if isPretty {
-				printer := display.NewPrettyPrinter()
+				printer := newPrinter(opts)
 				defer printer.Close()
 
-				for result := range codeBlockStream {
+				forEachPart(codeBlockStream, status, func(result util.CodeBlockResult) {
 					if result.Type != "" {
 						printer.SetCodeBlockState(result.Type)
 					}
 					printer.Print(result.Text)
-				}
+				})
 
 				// Make sure to flush any remaining content before closing
 				printer.Flush()
 			} else {
-				for result := range codeBlockStream {
+				forEachPart(codeBlockStream, status, func(result util.CodeBlockResult) {
 					os.Stdout.WriteString(result.Text)
-				}
+				})
 				// Add a newline if the last part doesn't end with one
 				os.Stdout.WriteString("\n")
 			}
 		} else {
 			if isPretty {
-				printer := display.NewPrettyPrinter()
+				printer := newPrinter(opts)
 				defer printer.Close()
 
-				for part := range stream {
+				forEachPart(parts, status, func(part string) {
 					printer.Print(part)
-				}
+				})
 
 				// Make sure to flush any remaining content before closing
 				printer.Flush()
+			} else if opts.isPlain {
+				printer := display.NewPlainPrinter(os.Stdout)
+				forEachPart(parts, status, func(part string) {
+					printer.Print(part)
+				})
+				printer.Print("\n")
+				printer.Flush()
 			} else {
-				for part := range stream {
+				forEachPart(parts, status, func(part string) {
 					os.Stdout.WriteString(part)
-				}
+				})
 				// Add a newline if the last part doesn't end with one
 				os.Stdout.WriteString("\n")
 			}
@@ -171,42 +811,238 @@ func runAIQuery(isCodeBlock, isStream, isPretty, isReasoning, isFast, showThinki
 		var response string
 		var err error
 
-		response, err = client.CreateCompletion(prompt)
+		start := time.Now()
+		if opts.showPreview {
+			previewConfig := *config
+			previewConfig.ModelType = llm.ModelTypeFast
+			previewConfig.IsStream = true
+			response, err = completeWithPreview(ctx, client, &previewConfig, request)
+		} else {
+			response, err = client.CreateCompletion(ctx, request)
+		}
 		if err != nil {
-			return err
+			return interrupted(ctx, err)
+		}
+
+		reply.WriteString(response)
+
+		// With --output, what would be printed is collected and written
+		// to the file at the end
+		stdout := io.Writer(os.Stdout)
+		var output strings.Builder
+		if opts.outputPath != "" {
+			stdout = &output
 		}
 
 		if !showThinking {
 			response = util.StripThinkTags(response)
 		}
+		var confidenceErr error
+		if opts.minConfidence > 0 {
+			response, confidenceErr = checkConfidence(response, opts.minConfidence)
+		}
+		if opts.section != "" {
+			response, err = util.ExtractSection(response, opts.section)
+			if err != nil {
+				return err
+			}
+		}
 
-		if isCodeBlock {
-			result := util.ExtractCodeBlock(response)
+		// Code written to files starts with the configured header
+		header := ""
+		if fileHeader != "" {
+			name := client.LastCompletionInfo().Model
+			if name == "" {
+				name = modelName(apiConfig, model)
+			}
+			header = util.ExpandHeader(fileHeader, name, time.Now())
+		}
+
+		if opts.jsonOut {
+			return writeJSONResult(os.Stdout, client.LastCompletionInfo(), response, time.Since(start))
+		} else if opts.isAnnotations {
+			if err := writeAnnotations(response); err != nil {
+				return err
+			}
+		} else if opts.scaffoldDir != "" {
+			if err := writeScaffold(opts.scaffoldDir, response, opts.assumeYes, header); err != nil {
+				return err
+			}
+		} else if opts.isOneLine {
+			io.WriteString(stdout, util.FormatOneLine(response, opts.showConfidence))
+			io.WriteString(stdout, "\n")
+		} else if opts.allBlocks {
+			if err := writeAllBlocks(stdout, response, opts, formatters, header); err != nil {
+				return err
+			}
+		} else if isCodeBlock {
+			result, err := util.SelectCodeBlock(response, max(opts.codeBlock, 1))
+			if err != nil {
+				return err
+			}
+			if opts.language != "" {
+				result = validateCodeBlock(ctx, client, request, result, opts.language)
+			}
+			result = formatCodeBlock(result, formatters)
+			if opts.outputPath != "" {
+				result.Text = withHeader(result.Text, result.Type, opts.outputPath, header)
+			}
 
 			if isPretty {
-				printer := display.NewPrettyPrinter()
+				printer := newPrinter(opts)
 				defer printer.Close()
 				if result.Type != "" {
 					printer.SetCodeBlockState(result.Type)
 				}
 				printer.Print(result.Text)
 				printer.Flush()
+			} else if opts.diffAgainst != "" {
+				printDiff(opts.diffAgainst, reference, result.Text, opts.accessible)
 			} else {
-				os.Stdout.WriteString(result.Text)
-				os.Stdout.WriteString("\n")
+				io.WriteString(stdout, result.Text)
+				io.WriteString(stdout, "\n")
 			}
 		} else {
-			if isPretty {
-				printer := display.NewPrettyPrinter()
+			response = util.WrapText(response, opts.wrapWidth)
+			if opts.diffAgainst != "" {
+				printDiff(opts.diffAgainst, reference, response, opts.accessible)
+			} else if isPretty {
+				printer := newPrinter(opts)
 				defer printer.Close()
 				printer.Print(response)
 				printer.Flush()
+			} else if opts.isPlain {
+				printer := display.NewPlainPrinter(stdout)
+				printer.Print(response + "\n")
+				printer.Flush()
 			} else {
-				os.Stdout.WriteString(response)
-				os.Stdout.WriteString("\n")
+				io.WriteString(stdout, response)
+				io.WriteString(stdout, "\n")
 			}
 		}
+
+		if opts.outputPath != "" && !opts.allBlocks {
+			if err := util.WriteFileAtomic(opts.outputPath, output.String(), opts.appendOutput); err != nil {
+				return err
+			}
+		}
+
+		// The answer is printed even if the model is unsure of it, for the
+		// caller to check
+		return confidenceErr
 	}
 
-	return nil
+	return interrupted(ctx, streamErr)
+}
+
+// statusInterval is how often the status line is redrawn while waiting for
+// the model
+const statusInterval = 250 * time.Millisecond
+
+// forEachPart calls fn with each part of a stream. If there is a status line,
+// it is redrawn after each part and while waiting for the next, so the
+// elapsed time keeps moving.
+func forEachPart[T any](stream <-chan T, status *display.StatusLine, fn func(T)) {
+	if status == nil {
+		for part := range stream {
+			fn(part)
+		}
+		return
+	}
+
+	ticker := time.NewTicker(statusInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case part, ok := <-stream:
+			if !ok {
+				return
+			}
+			fn(part)
+			status.Draw()
+		case <-ticker.C:
+			status.Draw()
+		}
+	}
+}
+
+// bufferReply keeps reading a streamed reply while the terminal or pager
+// catches up, holding up to the streamBuffer setting, so a slow terminal
+// doesn't stall the HTTP read and trip the provider's idle timeout
+func bufferReply(ctx context.Context, parts <-chan string) <-chan string {
+	size, err := util.GetStreamBuffer()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, i18n.T("Warning: Failed to load the stream buffer setting: %v", err))
+	}
+	if size == 0 {
+		return parts
+	}
+	return stream.Queue(ctx, parts, size, func(part string) int { return len(part) })
+}
+
+// newStatusLine returns a status line at the bottom of the terminal, or nil if
+// stdout and stderr aren't both a terminal
+func newStatusLine(model string) *display.StatusLine {
+	if !isTerminal(os.Stdout) || !isTerminal(os.Stderr) {
+		return nil
+	}
+
+	rows, _ := terminalSize()
+	if rows <= 2 {
+		return nil
+	}
+	return display.NewStatusLine(os.Stderr, rows, model)
+}
+
+// isTerminal reports whether file is a terminal rather than a pipe or file
+func isTerminal(file *os.File) bool {
+	stat, err := file.Stat()
+	return err == nil && (stat.Mode()&os.ModeCharDevice) != 0
+}
+
+// modelName returns the name of the configured model of the given type
+func modelName(apiConfig *util.APIConfig, model llm.ModelType) string {
+	switch model {
+	case llm.ModelTypeFast:
+		return apiConfig.FastModel
+	case llm.ModelTypeReasoning:
+		return apiConfig.ReasoningModel
+	default:
+		return apiConfig.DefaultModel
+	}
+}
+
+// newLLMConfig returns the client configuration for a request to the model of
+// the given type, including the configured attribution for the request
+func newLLMConfig(apiConfig *util.APIConfig, model llm.ModelType, systemPrompt string) *llm.Config {
+	return &llm.Config{
+		APIEndpoint:    apiConfig.APIEndpoint,
+		APIToken:       apiConfig.APIToken,
+		Provider:       apiConfig.Provider,
+		ModelType:      model,
+		DefaultModel:   apiConfig.DefaultModel,
+		FastModel:      apiConfig.FastModel,
+		ReasoningModel: apiConfig.ReasoningModel,
+		SystemPrompt:   systemPrompt,
+		User:           apiConfig.User,
+		Metadata:       apiConfig.Metadata,
+		Headers:        apiConfig.Headers,
+		ContextWindows: apiConfig.ContextWindows,
+		Organization:   apiConfig.Organization,
+		Project:        apiConfig.Project,
+	}
+}
+
+// compressInput shortens piped input with util.CompressInput and reports on
+// stderr how many tokens were saved, as counted for the model
+func compressInput(input string, model string) string {
+	compressed := util.CompressInput(input)
+
+	before := tokenizer.Count(model, input)
+	after := tokenizer.Count(model, compressed)
+	if before > 0 {
+		fmt.Fprintln(os.Stderr, i18n.T("Compressed the input from ~%d to ~%d tokens (%d%% smaller)", before, after, 100*(before-after)/before))
+	}
+	return compressed
 }
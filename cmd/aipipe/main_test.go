@@ -0,0 +1,107 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+// TestRunAIQueryOptionConflicts tests that options which can't be used
+// together are rejected before anything is sent to the model
+func TestRunAIQueryOptionConflicts(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_CONFIG_HOME", home)
+
+	tests := []struct {
+		name     string
+		opts     queryOptions
+		expected string
+	}{
+		{
+			name:     "Reasoning and fast",
+			opts:     queryOptions{isReasoning: true, isFast: true},
+			expected: "the --reasoning and --fast options cannot be used together",
+		},
+		{
+			name:     "One line code block",
+			opts:     queryOptions{isOneLine: true, isCodeBlock: true, codeBlock: 1},
+			expected: "the --oneline option cannot be used with --codeblock or --pretty",
+		},
+		{
+			name:     "JSON and a code block",
+			opts:     queryOptions{jsonOut: true, isCodeBlock: true, codeBlock: 1},
+			expected: "the --json-out option cannot be used with --codeblock, --all-blocks, --pretty, --oneline, --scaffold or --json-stream",
+		},
+		{
+			name:     "JSON as --json and every block",
+			opts:     queryOptions{jsonOut: true, jsonShort: true, isCodeBlock: true, allBlocks: true},
+			expected: "the --json option cannot be used with --codeblock, --all-blocks, --pretty, --oneline, --scaffold or --json-stream",
+		},
+		{
+			name:     "Plain JSON as --json",
+			opts:     queryOptions{isPlain: true, jsonOut: true, jsonShort: true},
+			expected: "the --plain option cannot be used with --codeblock, --pretty, --oneline, --scaffold, --json-stream, --json or --gha",
+		},
+		{
+			name:     "Output and pretty",
+			opts:     queryOptions{outputPath: "out.txt", isPretty: true},
+			expected: "the --output option cannot be used with --pretty, --scaffold, --json-stream, --json-out, --gha or --diff-against",
+		},
+		{
+			name:     "Append without output",
+			opts:     queryOptions{appendOutput: true},
+			expected: "the --append option requires --output",
+		},
+		{
+			name:     "Every block and the Nth",
+			opts:     queryOptions{isCodeBlock: true, allBlocks: true, codeBlock: 2},
+			expected: "the --all-blocks option cannot be used with --codeblock=N",
+		},
+		{
+			name:     "Every block pretty printed",
+			opts:     queryOptions{isCodeBlock: true, allBlocks: true, isPretty: true},
+			expected: "the --all-blocks option cannot be used with --pretty, --lang or --diff-against",
+		},
+		{
+			name:     "Accessible status line",
+			opts:     queryOptions{accessible: true, showStatus: true},
+			expected: "the --status and --preview options cannot be used with --accessible",
+		},
+		{
+			name:     "Unknown input role",
+			opts:     queryOptions{inputRole: "assistant"},
+			expected: "unknown --input-role \"assistant\" (expected prompt, user or system)",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := runAIQuery(tt.opts, "hello", nil)
+			if err == nil || err.Error() != tt.expected {
+				t.Errorf("runAIQuery() error = %v, want %q", err, tt.expected)
+			}
+		})
+	}
+}
+
+func TestSubcommandFor(t *testing.T) {
+	tests := []struct {
+		args       []string
+		subcommand bool
+		rest       []string
+	}{
+		{args: []string{"history", "show", "3"}, subcommand: true, rest: []string{"show", "3"}},
+		{args: []string{"history", "of", "rome"}, subcommand: true, rest: []string{"of", "rome"}},
+		{args: []string{"--", "history", "of", "rome"}, subcommand: false},
+		{args: []string{"history of rome"}, subcommand: false},
+		{args: []string{"-s", "chat", "about", "x"}, subcommand: false},
+		{args: nil, subcommand: false},
+	}
+
+	for _, tt := range tests {
+		_, rest, ok := subcommandFor(tt.args)
+		if ok != tt.subcommand || (ok && !reflect.DeepEqual(rest, tt.rest)) {
+			t.Errorf("subcommandFor(%q) = %q, %v; want %q, %v", tt.args, rest, ok, tt.rest, tt.subcommand)
+		}
+	}
+}
@@ -0,0 +1,175 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/rba100/aipipe/internal/i18n"
+	"github.com/rba100/aipipe/internal/llm"
+	"github.com/rba100/aipipe/internal/memory"
+	"github.com/rba100/aipipe/internal/util"
+	"github.com/spf13/pflag"
+)
+
+// runRemember implements `aipipe remember <fact>`, storing a fact that is
+// included in the system prompt of later queries
+func runRemember(args []string) error {
+	flags := pflag.NewFlagSet("remember", pflag.ContinueOnError)
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if err := checkNotReadOnly("remembering facts"); err != nil {
+		return err
+	}
+
+	store, err := memory.DefaultStore()
+	if err != nil {
+		return err
+	}
+
+	fact, err := store.Add(strings.Join(flags.Args(), " "))
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Remembered [%d] %s\n", fact.ID, fact.Text)
+	return nil
+}
+
+// runMemory implements `aipipe memory list` and `aipipe memory forget`
+func runMemory(args []string) error {
+	flags := pflag.NewFlagSet("memory", pflag.ContinueOnError)
+	allFlag := flags.BoolP("all", "a", false, "With forget, remove every fact")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	store, err := memory.DefaultStore()
+	if err != nil {
+		return err
+	}
+
+	switch flags.Arg(0) {
+	case "", "list":
+		facts, err := store.List()
+		if err != nil {
+			return err
+		}
+		if len(facts) == 0 {
+			fmt.Println("Nothing remembered yet. Add facts with `aipipe remember`.")
+		}
+		for _, fact := range facts {
+			fmt.Printf("[%d] %s\n", fact.ID, fact.Text)
+		}
+		return nil
+
+	case "forget":
+		if err := checkNotReadOnly("forgetting facts"); err != nil {
+			return err
+		}
+		if *allFlag {
+			return store.Clear()
+		}
+		if flags.NArg() < 2 {
+			return fmt.Errorf("usage: aipipe memory forget <id>... or --all")
+		}
+
+		var ids []int
+		for _, arg := range flags.Args()[1:] {
+			id, err := strconv.Atoi(arg)
+			if err != nil {
+				return fmt.Errorf("invalid fact id %q", arg)
+			}
+			ids = append(ids, id)
+		}
+		return store.Forget(ids...)
+
+	default:
+		return fmt.Errorf("unknown memory command %q (expected list or forget)", flags.Arg(0))
+	}
+}
+
+// rememberedFacts returns the remembered facts formatted for the system
+// prompt, or an empty string if there are none or memory is turned off
+func rememberedFacts() string {
+	enabled, err := util.GetMemoryEnabled()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, i18n.T("Warning: Failed to load memory setting: %v", err))
+	}
+	if !enabled {
+		return ""
+	}
+
+	store, err := memory.DefaultStore()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, i18n.T("Warning: %v", err))
+		return ""
+	}
+
+	facts, err := store.List()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, i18n.T("Warning: Failed to load remembered facts: %v", err))
+		return ""
+	}
+
+	return memory.FormatForPrompt(facts)
+}
+
+// offerMemories asks a fast model for durable facts about the user in a
+// conversation and offers to remember each one, if memoryExtraction is on.
+// The user is asked on the terminal, so nothing happens without one.
+func offerMemories(apiConfig *util.APIConfig, prompt string, reply string) {
+	if strings.TrimSpace(reply) == "" {
+		return
+	}
+	if enabled, err := util.GetMemoryExtraction(); err != nil || !enabled {
+		return
+	}
+
+	terminal, err := openTerminal()
+	if err != nil {
+		return
+	}
+	defer terminal.Close()
+
+	store, err := memory.DefaultStore()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, i18n.T("Warning: %v", err))
+		return
+	}
+	known, err := store.List()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, i18n.T("Warning: Failed to load remembered facts: %v", err))
+		return
+	}
+
+	client, err := llm.NewClient(newLLMConfig(apiConfig, llm.ModelTypeFast, llm.GetMemoryExtractionSystemPrompt()))
+	if err != nil {
+		return
+	}
+
+	response, err := client.CreateCompletion(context.Background(), memory.BuildExtractionPrompt(prompt, reply, known))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, i18n.T("Warning: Failed to extract memories: %v", err))
+		return
+	}
+
+	answers := bufio.NewReader(terminal)
+	for _, text := range memory.ParseExtractedFacts(util.StripThinkTags(response), known) {
+		fmt.Fprintf(terminal, "Remember %q? [y/N] ", text)
+		answer, err := answers.ReadString('\n')
+		if err != nil {
+			return
+		}
+		if !strings.EqualFold(strings.TrimSpace(answer), "y") {
+			continue
+		}
+		if _, err := store.Add(text); err != nil {
+			fmt.Fprintln(os.Stderr, i18n.T("Warning: %v", err))
+		}
+	}
+}
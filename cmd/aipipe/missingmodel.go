@@ -0,0 +1,100 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/rba100/aipipe/internal/i18n"
+	"github.com/rba100/aipipe/internal/llm"
+	"github.com/rba100/aipipe/internal/util"
+)
+
+// maxModelSuggestions is how many similar models are suggested in place of
+// one the API doesn't offer
+const maxModelSuggestions = 3
+
+// suggestModels follows an error for a model the API doesn't offer, which
+// happens when providers rename or retire models, by suggesting similar
+// models it does offer. If there is a terminal, it offers to put one in the
+// config file in place of the missing model.
+func suggestModels(err error, local bool) {
+	var notFound *llm.ModelNotFoundError
+	if !errors.As(err, &notFound) {
+		return
+	}
+
+	// Without an API key, aipipe falls back to Ollama
+	apiConfig, err := util.GetAPIConfig()
+	if local || errors.Is(err, util.ErrNoAPIKey) {
+		local = true
+		apiConfig, err = util.GetLocalAPIConfig()
+	}
+	if err != nil {
+		return
+	}
+	models, err := listModels(apiConfig)
+	if err != nil {
+		return
+	}
+
+	suggestions := util.ClosestMatches(notFound.Model, models, maxModelSuggestions)
+	if len(suggestions) == 0 {
+		fmt.Fprintln(os.Stderr, i18n.T("No similar models are offered; run `aipipe models` to list them"))
+		return
+	}
+	fmt.Fprintln(os.Stderr, i18n.T("Similar models offered: %s", strings.Join(suggestions, ", ")))
+
+	key := modelConfigKey(apiConfig, notFound.Model, local)
+	if key == "" || isReadOnly() {
+		return
+	}
+	terminal, err := openTerminal()
+	if err != nil {
+		return
+	}
+	defer terminal.Close()
+
+	for i, suggestion := range suggestions {
+		fmt.Fprintf(terminal, "  %d. %s\n", i+1, suggestion)
+	}
+	fmt.Fprintf(terminal, "Set %s to one of these in config.yaml? [1-%d, or Enter to leave it] ", key, len(suggestions))
+	answer, err := bufio.NewReader(terminal).ReadString('\n')
+	if err != nil {
+		return
+	}
+	choice, err := strconv.Atoi(strings.TrimSpace(answer))
+	if err != nil || choice < 1 || choice > len(suggestions) {
+		return
+	}
+
+	path, err := util.ConfigPath("config.yaml")
+	if err == nil {
+		err = util.WriteConfigSettings(path, []util.ConfigSetting{{Key: key, Value: suggestions[choice-1]}})
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, i18n.T("Warning: Failed to update the config file: %v", err))
+		return
+	}
+	fmt.Fprintf(terminal, "Set %s to %s in %s\n", key, suggestions[choice-1], path)
+}
+
+// modelConfigKey returns the config key that selects a model, or an empty
+// string if the model isn't one of those configured
+func modelConfigKey(apiConfig *util.APIConfig, model string, local bool) string {
+	switch {
+	case local:
+		return "localModel"
+	case model == apiConfig.DefaultModel:
+		return "defaultModel"
+	case model == apiConfig.FastModel:
+		return "fastModel"
+	case model == apiConfig.ReasoningModel:
+		return "reasoningModel"
+	default:
+		return ""
+	}
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/rba100/aipipe/internal/i18n"
+	"github.com/rba100/aipipe/internal/llm"
+	"github.com/rba100/aipipe/internal/util"
+	"github.com/spf13/pflag"
+)
+
+// maxSampleLines is how many lines of the piped input a one-liner is written
+// for and tested against
+const maxSampleLines = 50
+
+// runOneLiner implements `aipipe oneliner [description]`, writing a jq, awk or
+// sed one-liner for input piped in, running it on a sample of the input and
+// asking again until its output is what the model said it would be
+func runOneLiner(args []string) error {
+	flags := pflag.NewFlagSet("oneliner", pflag.ContinueOnError)
+	toolFlag := flags.String("tool", "", "Program to use: jq, awk or sed (default: the model chooses)")
+	retriesFlag := flags.Int("retries", 3, "How many times to ask again when the output isn't as expected")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if err := checkNotReadOnly("testing one-liners"); err != nil {
+		return err
+	}
+
+	description := strings.Join(flags.Args(), " ")
+	if description == "" {
+		return fmt.Errorf("usage: some-command | aipipe oneliner [--tool jq|awk|sed] <transformation>")
+	}
+	stat, _ := os.Stdin.Stat()
+	if (stat.Mode() & os.ModeCharDevice) != 0 {
+		return fmt.Errorf("pipe in some sample input for the one-liner to transform")
+	}
+	input, err := readInput("")
+	if err != nil {
+		return err
+	}
+	lines := strings.SplitAfter(input, "\n")
+	sample := strings.Join(lines[:min(len(lines), maxSampleLines)], "")
+
+	apiConfig, err := loadAPIConfig(false)
+	if err != nil {
+		return err
+	}
+	client, err := llm.NewClient(newLLMConfig(apiConfig, llm.ModelTypeDefault, llm.GetOneLinerSystemPrompt()))
+	if err != nil {
+		return err
+	}
+
+	prompt := strings.Builder{}
+	prompt.WriteString("Sample input:\n```\n" + sample + "\n```\n")
+	prompt.WriteString("Write a one-liner to " + description + "\n")
+	if *toolFlag != "" {
+		prompt.WriteString("Use " + *toolFlag + ".\n")
+	}
+
+	for attempt := 0; ; attempt++ {
+		response, err := client.CreateCompletion(context.Background(), prompt.String())
+		if err != nil {
+			return err
+		}
+		blocks := util.ExtractAllCodeBlocks(util.StripThinkTags(response))
+		if len(blocks) < 2 {
+			return fmt.Errorf("the model didn't write a command and its expected output:\n%s", response)
+		}
+		command := strings.TrimSpace(blocks[0].Text)
+		expected := strings.TrimRight(blocks[1].Text, "\n")
+
+		output, err := checkOneLiner(command, sample)
+		output = strings.TrimRight(output, "\n")
+		if err == nil && output == expected {
+			fmt.Println(command)
+			fmt.Fprintln(os.Stderr, i18n.T("Checked against the first %d lines of the input", min(len(lines), maxSampleLines)))
+			return nil
+		}
+
+		var problem string
+		if err != nil {
+			problem = fmt.Sprintf("running it failed: %v", err)
+		} else {
+			problem = "running it on the sample gave this instead:\n```\n" + output + "\n```"
+		}
+		if attempt == *retriesFlag {
+			fmt.Println(command)
+			if err != nil {
+				return fmt.Errorf("the one-liner still fails after %d retries: %v", *retriesFlag, err)
+			}
+			fmt.Fprint(os.Stderr, util.DiffLines(expected, output))
+			return fmt.Errorf("the one-liner's output still isn't what was expected after %d retries", *retriesFlag)
+		}
+		fmt.Fprintln(os.Stderr, i18n.T("%s didn't give the expected output, asking again", command))
+		fmt.Fprintf(&prompt, "\nYou wrote:\n```bash\n%s\n```\nand expected:\n```text\n%s\n```\nbut %s\nWrite a corrected command, and the output it gives.\n", command, expected, problem)
+	}
+}
+
+// checkOneLiner runs a generated one-liner on the sample input, if it is a
+// jq, awk or sed command that can safely be run
+func checkOneLiner(command string, sample string) (string, error) {
+	words, err := util.SplitShellWords(command)
+	if err != nil {
+		return "", err
+	}
+	return util.RunOneLiner(words, sample)
+}
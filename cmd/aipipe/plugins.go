@@ -0,0 +1,56 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/rba100/aipipe/internal/i18n"
+	"github.com/rba100/aipipe/internal/parsing"
+	"github.com/rba100/aipipe/internal/util"
+)
+
+// configureHighlighter selects the syntax highlighting backend named in the
+// config file, falling back to the built-in regex parsers on any problem
+func configureHighlighter() {
+	backend, err := util.GetHighlighterBackend()
+	if err != nil || backend == "" {
+		return
+	}
+
+	if err := parsing.SetBackend(parsing.Backend(backend)); err != nil {
+		fmt.Fprintln(os.Stderr, i18n.T("Warning: %v, using built-in highlighting", err))
+	}
+}
+
+// registerLanguageAliases adds the code fence aliases listed in the config file
+func registerLanguageAliases() {
+	aliases, err := util.GetLanguageAliases()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, i18n.T("Warning: Failed to load language aliases: %v", err))
+		return
+	}
+
+	for alias, language := range aliases {
+		parsing.RegisterAlias(alias, language)
+	}
+}
+
+// registerParserPlugins makes the external parsers listed in the config file
+// available for syntax highlighting. Problems are reported as warnings since
+// highlighting is never essential.
+func registerParserPlugins() {
+	plugins, err := util.GetParserPlugins()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, i18n.T("Warning: Failed to load parser plugins: %v", err))
+		return
+	}
+
+	for language, command := range plugins {
+		parser, err := parsing.NewExternalParser(command)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, i18n.T("Warning: Invalid parser plugin for %s: %v", language, err))
+			continue
+		}
+		parsing.RegisterParser(language, parser)
+	}
+}
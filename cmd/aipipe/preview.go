@@ -0,0 +1,63 @@
+package main
+
+import (
+	"context"
+	"os"
+
+	"github.com/rba100/aipipe/internal/display"
+	"github.com/rba100/aipipe/internal/llm"
+	"github.com/rba100/aipipe/internal/util"
+)
+
+// completion is the outcome of a CreateCompletion call
+type completion struct {
+	response string
+	err      error
+}
+
+// completeWithPreview gets a completion from client while streaming a
+// provisional answer from the fast model, greyed out, to the terminal. The
+// preview is erased once the real answer arrives. Without a terminal to show
+// it on there is no preview.
+func completeWithPreview(ctx context.Context, client llm.LLMClient, previewConfig *llm.Config, prompt string) (string, error) {
+	rows, columns := terminalSize()
+	if !isTerminal(os.Stdout) || rows <= 1 || columns <= 0 {
+		return client.CreateCompletion(ctx, prompt)
+	}
+
+	previewClient, err := llm.NewClient(previewConfig)
+	if err != nil {
+		return client.CreateCompletion(ctx, prompt)
+	}
+
+	done := make(chan completion, 1)
+	go func() {
+		response, err := client.CreateCompletion(ctx, prompt)
+		done <- completion{response, err}
+	}()
+
+	preview := display.NewPreview(os.Stdout, rows, columns)
+	defer preview.Clear()
+
+	// The preview is abandoned once the real answer arrives, which cancelling
+	// its context stops
+	previewCtx, cancelPreview := context.WithCancel(ctx)
+	defer cancelPreview()
+
+	// The preview is only a courtesy, so if it fails it just stops
+	var previewErr error
+	parts := util.StripThinkTagsStream(previewCtx, llm.Contents(previewClient.CreateCompletionStream(previewCtx, prompt), &previewErr))
+	for {
+		select {
+		case part, ok := <-parts:
+			if !ok {
+				// The preview is complete; wait for the real answer
+				parts = nil
+				continue
+			}
+			preview.Write(part)
+		case result := <-done:
+			return result.response, result.err
+		}
+	}
+}
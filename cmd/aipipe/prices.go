@@ -0,0 +1,102 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+
+	"github.com/rba100/aipipe/internal/display"
+	"github.com/rba100/aipipe/internal/i18n"
+	"github.com/rba100/aipipe/internal/llm"
+	"github.com/rba100/aipipe/internal/pricing"
+	"github.com/rba100/aipipe/internal/util"
+	"github.com/spf13/pflag"
+)
+
+// runPrices implements `aipipe prices list` and `aipipe prices update`, which
+// show and update the price table used to estimate what prompts cost
+func runPrices(args []string) error {
+	flags := pflag.NewFlagSet("prices", pflag.ContinueOnError)
+	urlFlag := flags.String("url", "", "With update, fetch the price table from this URL")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	switch flags.Arg(0) {
+	case "", "list":
+		table, err := pricing.Load()
+		if err != nil {
+			fmt.Fprintln(os.Stderr, i18n.T("Warning: %v; using the bundled prices", err))
+		}
+		printPrices(table)
+		return nil
+
+	case "update":
+		if err := checkNotReadOnly("updating prices"); err != nil {
+			return err
+		}
+		url := *urlFlag
+		if url == "" {
+			configured, err := util.GetPricesURL()
+			if err != nil {
+				return err
+			}
+			url = configured
+		}
+		if url == "" {
+			url = pricing.DefaultURL
+		}
+
+		table, updated, err := pricing.Update(url)
+		if err != nil {
+			return err
+		}
+		if !updated {
+			fmt.Printf("The price table is up to date (version %d)\n", table.Version)
+			return nil
+		}
+		fmt.Printf("Updated the price table to version %d, with %d models\n", table.Version, len(table.Models))
+		return nil
+
+	default:
+		return fmt.Errorf("unknown prices command %q (expected list or update)", flags.Arg(0))
+	}
+}
+
+// printPrices prints a price table, by model name
+func printPrices(table *pricing.Table) {
+	models := make([]string, 0, len(table.Models))
+	for model := range table.Models {
+		models = append(models, model)
+	}
+	sort.Strings(models)
+
+	rows := [][]string{{"Model", "Input $/M", "Output $/M"}}
+	for _, model := range models {
+		price := table.Models[model]
+		rows = append(rows, []string{model, formatPrice(price.Input), formatPrice(price.Output)})
+	}
+	fmt.Printf("Prices in US dollars per million tokens (version %d)\n\n", table.Version)
+	fmt.Print(display.FormatTable(rows))
+}
+
+// formatPrice formats a price without trailing zeros
+func formatPrice(price float64) string {
+	return strconv.FormatFloat(price, 'f', -1, 64)
+}
+
+// usageCost estimates what a completion cost in US dollars from the price
+// table, or returns 0 if the API didn't report usage or the model's price
+// isn't known
+func usageCost(info llm.CompletionInfo) float64 {
+	if info.Usage == nil {
+		return 0
+	}
+	table, err := pricing.Load()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, i18n.T("Warning: %v; using the bundled prices", err))
+	}
+	cost, _ := table.Cost(info.Model, info.Usage.PromptTokens, info.Usage.CompletionTokens)
+	return cost
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"runtime"
+	"runtime/pprof"
+
+	"github.com/rba100/aipipe/internal/i18n"
+)
+
+// startProfile starts recording the profile named by --profile, cpu or mem,
+// to be written to path, which defaults to aipipe.KIND.pprof. It returns a
+// function that stops recording and writes the profile, for `go tool pprof`.
+func startProfile(kind string, path string) (func(), error) {
+	if path == "" {
+		path = fmt.Sprintf("aipipe.%s.pprof", kind)
+	}
+
+	switch kind {
+	case "cpu":
+		file, err := os.Create(path)
+		if err != nil {
+			return nil, fmt.Errorf("error creating the CPU profile: %v", err)
+		}
+		if err := pprof.StartCPUProfile(file); err != nil {
+			file.Close()
+			return nil, fmt.Errorf("error starting the CPU profile: %v", err)
+		}
+		return func() {
+			pprof.StopCPUProfile()
+			if err := file.Close(); err != nil {
+				fmt.Fprintln(os.Stderr, i18n.T("Warning: Failed to write the CPU profile: %v", err))
+				return
+			}
+			fmt.Fprintln(os.Stderr, i18n.T("Wrote the CPU profile to %s", path))
+		}, nil
+
+	case "mem":
+		// Allocations are sampled from the start, so the profile covers
+		// the whole run
+		file, err := os.Create(path)
+		if err != nil {
+			return nil, fmt.Errorf("error creating the memory profile: %v", err)
+		}
+		return func() {
+			runtime.GC()
+			err := pprof.Lookup("allocs").WriteTo(file, 0)
+			if closeErr := file.Close(); err == nil {
+				err = closeErr
+			}
+			if err != nil {
+				fmt.Fprintln(os.Stderr, i18n.T("Warning: Failed to write the memory profile: %v", err))
+				return
+			}
+			fmt.Fprintln(os.Stderr, i18n.T("Wrote the memory profile to %s", path))
+		}, nil
+	}
+
+	return nil, fmt.Errorf("unknown --profile %q (expected cpu or mem)", kind)
+}
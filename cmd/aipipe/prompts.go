@@ -0,0 +1,112 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/rba100/aipipe/internal/history"
+	"github.com/rba100/aipipe/internal/i18n"
+	"github.com/rba100/aipipe/internal/llm"
+	"github.com/rba100/aipipe/internal/util"
+	"github.com/spf13/pflag"
+)
+
+// runPrompts implements `aipipe prompts [search]`, listing previously typed
+// prompts, each once, oldest first
+func runPrompts(args []string) error {
+	flags := pflag.NewFlagSet("prompts", pflag.ContinueOnError)
+	limitFlag := flags.IntP("limit", "n", 0, "Show only the most recent N prompts")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	log, err := history.DefaultLog()
+	if err != nil {
+		return err
+	}
+	entries, err := log.List()
+	if err != nil {
+		return err
+	}
+
+	entries = history.Search(history.Unique(entries), strings.Join(flags.Args(), " "))
+	if *limitFlag > 0 && len(entries) > *limitFlag {
+		entries = entries[len(entries)-*limitFlag:]
+	}
+
+	for _, entry := range entries {
+		// Keep each prompt on one line
+		prompt := strings.Join(strings.Fields(entry.Prompt), " ")
+		fmt.Printf("%s  %s\n", entry.Time.Local().Format("2006-01-02 15:04"), prompt)
+	}
+	return nil
+}
+
+// recordPrompt adds a prompt typed on the command line to the prompt history,
+// with the snippet it came from, the model that answered it and whether the
+// answer was interrupted, unless promptHistory is off. An interrupted answer
+// is kept as far as it got, since it can't be run again for the same text.
+func recordPrompt(prompt string, snippet string, result queryResult, interrupted bool) {
+	if prompt == "" {
+		return
+	}
+
+	info := result.info
+	entry := history.Entry{Prompt: prompt, Snippet: snippet, Model: info.Model, Tokens: usageTokens(info), Cost: usageCost(info), Interrupted: interrupted}
+	if interrupted && result.reply != "" {
+		entry.Messages = []history.Message{
+			{Role: "user", Content: prompt},
+			{Role: "assistant", Content: result.reply},
+		}
+	}
+	recordEntry(entry)
+}
+
+// usageTokens returns the tokens used by a completion, or 0 if the API didn't
+// say
+func usageTokens(info llm.CompletionInfo) int {
+	if info.Usage == nil {
+		return 0
+	}
+	if info.Usage.TotalTokens == 0 {
+		return info.Usage.PromptTokens + info.Usage.CompletionTokens
+	}
+	return info.Usage.TotalTokens
+}
+
+// recordEntry adds an entry to the prompt history, unless promptHistory is off
+func recordEntry(entry history.Entry) {
+	enabled, err := util.GetPromptHistoryEnabled()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, i18n.T("Warning: Failed to load prompt history setting: %v", err))
+	}
+	if !enabled {
+		return
+	}
+
+	log, err := history.DefaultLog()
+	if err == nil {
+		err = log.AddEntry(entry)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, i18n.T("Warning: Failed to record prompt: %v", err))
+	}
+}
+
+// lastPrompt returns the most recent prompt in the prompt history
+func lastPrompt() (string, error) {
+	log, err := history.DefaultLog()
+	if err != nil {
+		return "", err
+	}
+
+	entry, ok, err := log.Last()
+	if err != nil {
+		return "", err
+	}
+	if !ok {
+		return "", fmt.Errorf("the prompt history is empty")
+	}
+	return entry.Prompt, nil
+}
@@ -0,0 +1,30 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/rba100/aipipe/internal/i18n"
+	"github.com/rba100/aipipe/internal/util"
+)
+
+// isReadOnly reports whether the readOnly policy is on, in which case aipipe
+// doesn't run commands, write files or record history. Failing to read the
+// config is treated as read-only, since the policy can't be checked.
+func isReadOnly() bool {
+	readOnly, err := util.GetReadOnly()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, i18n.T("Warning: Failed to load the readOnly setting, assuming it is on: %v", err))
+		return true
+	}
+	return readOnly
+}
+
+// checkNotReadOnly returns an error saying action is disabled if the readOnly
+// policy is on
+func checkNotReadOnly(action string) error {
+	if isReadOnly() {
+		return fmt.Errorf("%s is disabled in read-only mode", action)
+	}
+	return nil
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/rba100/aipipe/internal/i18n"
+	"github.com/rba100/aipipe/internal/llm"
+	"github.com/rba100/aipipe/internal/util"
+	"github.com/spf13/pflag"
+)
+
+// runRegex implements `aipipe regex [description]`, asking for a regular
+// expression and checking it against test cases locally, asking again with
+// the failures until it passes or the retries run out
+func runRegex(args []string) error {
+	flags := pflag.NewFlagSet("regex", pflag.ContinueOnError)
+	testFlag := flags.String("test", "", "File of test cases, one per line: lines starting with \"- \" must not match, others must")
+	retriesFlag := flags.Int("retries", 3, "How many times to ask again when the regex fails to compile or fails a test")
+	reasoningFlag := flags.BoolP("reasoning", "r", false, "Use reasoning model")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	description := strings.Join(flags.Args(), " ")
+	if description == "" {
+		return fmt.Errorf("usage: aipipe regex [--test file] <description>")
+	}
+
+	var tests []util.RegexTestCase
+	if *testFlag != "" {
+		content, err := os.ReadFile(*testFlag)
+		if err != nil {
+			return fmt.Errorf("error reading test cases: %v", err)
+		}
+		tests = util.ParseRegexTests(string(content))
+	}
+
+	apiConfig, err := loadAPIConfig(false)
+	if err != nil {
+		return err
+	}
+	model := llm.ModelTypeDefault
+	if *reasoningFlag {
+		model = llm.ModelTypeReasoning
+	}
+	client, err := llm.NewClient(newLLMConfig(apiConfig, model, llm.GetRegexSystemPrompt()))
+	if err != nil {
+		return err
+	}
+
+	prompt := strings.Builder{}
+	prompt.WriteString("Write a regular expression to " + description + "\n")
+	for _, test := range tests {
+		if test.ShouldMatch {
+			fmt.Fprintf(&prompt, "It must match: %s\n", test.Text)
+		} else {
+			fmt.Fprintf(&prompt, "It must not match: %s\n", test.Text)
+		}
+	}
+
+	for attempt := 0; ; attempt++ {
+		response, err := client.CreateCompletion(context.Background(), prompt.String())
+		if err != nil {
+			return err
+		}
+		response = util.StripThinkTags(response)
+		pattern, _, _ := strings.Cut(strings.TrimSpace(util.ExtractCodeBlock(response).Text), "\n")
+
+		failures, err := util.CheckRegex(pattern, tests)
+		if err != nil {
+			failures = []string{fmt.Sprintf("it doesn't compile: %v", err)}
+		}
+		if len(failures) == 0 {
+			fmt.Println(pattern)
+			// The explanation follows the code block
+			if end := strings.LastIndex(response, "```"); end >= 0 {
+				if explanation := strings.TrimSpace(response[end+3:]); explanation != "" {
+					fmt.Fprintln(os.Stderr, explanation)
+				}
+			}
+			if len(tests) > 0 {
+				fmt.Fprintln(os.Stderr, i18n.T("Passes all %d test cases", len(tests)))
+			}
+			return nil
+		}
+
+		if attempt == *retriesFlag {
+			fmt.Println(pattern)
+			return fmt.Errorf("the regex still fails after %d retries:\n  %s", *retriesFlag, strings.Join(failures, "\n  "))
+		}
+		fmt.Fprintln(os.Stderr, i18n.T("%s failed %d check(s), asking again", pattern, len(failures)))
+		fmt.Fprintf(&prompt, "\nYou wrote %s, but %s.\nWrite a corrected regular expression.\n", pattern, strings.Join(failures, ", and "))
+	}
+}
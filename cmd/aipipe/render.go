@@ -0,0 +1,35 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/rba100/aipipe/internal/display"
+	"github.com/spf13/pflag"
+)
+
+// runRender implements `aipipe render [file]`, printing a markdown file (or
+// stdin) with the same formatting used by --pretty
+func runRender(args []string) error {
+	flags := pflag.NewFlagSet("render", pflag.ContinueOnError)
+	collapseFlag := flags.Int("collapse", 0, "Show at most this many lines of each code block")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	if flags.NArg() > 1 {
+		return fmt.Errorf("render accepts at most one file")
+	}
+
+	markdown, err := readInput(flags.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	printer := display.NewPrettyPrinter()
+	defer printer.Close()
+	printer.SetMaxCodeBlockLines(*collapseFlag)
+	printer.Print(markdown)
+	printer.Flush()
+
+	return nil
+}
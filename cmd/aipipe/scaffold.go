@@ -0,0 +1,78 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/rba100/aipipe/internal/util"
+)
+
+// writeScaffold writes the files in a response into dir, with the file
+// header, after showing a summary and asking for confirmation unless
+// assumeYes is set
+func writeScaffold(dir string, response string, assumeYes bool, header string) error {
+	files := util.ExtractFiles(response)
+	if len(files) == 0 {
+		os.Stdout.WriteString(response)
+		os.Stdout.WriteString("\n")
+		return fmt.Errorf("the answer didn't contain any files to write")
+	}
+
+	targets := make([]string, len(files))
+	fmt.Printf("Files to write in %s:\n", dir)
+	for i, file := range files {
+		target, err := util.ScaffoldPath(dir, file.Path)
+		if err != nil {
+			return err
+		}
+		targets[i] = target
+
+		status := "new"
+		if _, err := os.Stat(target); err == nil {
+			status = "overwrite"
+		}
+		fmt.Printf("  %-9s  %s (%d lines)\n", status, file.Path, strings.Count(file.Content, "\n"))
+	}
+
+	if !assumeYes {
+		confirmed, err := confirm(fmt.Sprintf("Write %d files? [y/N] ", len(files)))
+		if err != nil {
+			return err
+		}
+		if !confirmed {
+			return fmt.Errorf("cancelled, nothing was written")
+		}
+	}
+
+	for i, file := range files {
+		if err := os.MkdirAll(filepath.Dir(targets[i]), 0755); err != nil {
+			return fmt.Errorf("error creating directory for %s: %v", file.Path, err)
+		}
+		content := withHeader(file.Content, file.Language, file.Path, header)
+		if err := os.WriteFile(targets[i], []byte(content), 0644); err != nil {
+			return fmt.Errorf("error writing %s: %v", file.Path, err)
+		}
+	}
+
+	fmt.Printf("Wrote %d files to %s\n", len(files), dir)
+	return nil
+}
+
+// confirm asks a yes/no question on the terminal, since stdin may be a pipe
+func confirm(question string) (bool, error) {
+	terminal, err := openTerminal()
+	if err != nil {
+		return false, fmt.Errorf("no terminal to ask for confirmation on (use --yes to skip it)")
+	}
+	defer terminal.Close()
+
+	fmt.Fprint(terminal, question)
+	answer, err := bufio.NewReader(terminal).ReadString('\n')
+	if err != nil {
+		return false, nil
+	}
+	return strings.EqualFold(strings.TrimSpace(answer), "y"), nil
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/rba100/aipipe/internal/snippets"
+	"github.com/rba100/aipipe/internal/util"
+	"github.com/spf13/pflag"
+)
+
+// runSnippet implements `aipipe snippet save|run|list|show|delete|sync`, for
+// prompts saved under a name
+func runSnippet(args []string) error {
+	store, err := snippets.DefaultStore()
+	if err != nil {
+		return err
+	}
+
+	// The arguments after a snippet's name are a query's, flags included
+	if len(args) > 0 && args[0] == "run" {
+		if len(args) < 2 {
+			return fmt.Errorf("usage: aipipe snippet run <name> [options] [prompt]")
+		}
+		prompt, err := store.Get(args[1])
+		if err != nil {
+			return err
+		}
+		return runQuery(args[2:], args[1], prompt)
+	}
+
+	flags := pflag.NewFlagSet("snippet", pflag.ContinueOnError)
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	switch flags.Arg(0) {
+	case "", "list":
+		list, err := store.List()
+		if err != nil {
+			return err
+		}
+		if len(list) == 0 {
+			fmt.Println("No snippets yet. Save one with `aipipe snippet save <name> <prompt>`.")
+		}
+		for _, snippet := range list {
+			// Keep each prompt on one line
+			name := snippet.Name
+			if snippet.Shared {
+				name += " (shared)"
+			}
+			fmt.Printf("%s: %s\n", name, strings.Join(strings.Fields(snippet.Prompt), " "))
+		}
+		return nil
+
+	case "save":
+		if err := checkNotReadOnly("saving snippets"); err != nil {
+			return err
+		}
+		if flags.NArg() < 2 {
+			return fmt.Errorf("usage: aipipe snippet save <name> [prompt]")
+		}
+		prompt := strings.Join(flags.Args()[2:], " ")
+		if prompt == "" {
+			// Read the prompt from stdin, for prompts too long to type
+			stat, _ := os.Stdin.Stat()
+			if (stat.Mode() & os.ModeCharDevice) == 0 {
+				prompt, err = readInput("")
+				if err != nil {
+					return err
+				}
+			}
+		}
+		if err := store.Save(flags.Arg(1), prompt); err != nil {
+			return err
+		}
+		fmt.Printf("Saved snippet %s\n", flags.Arg(1))
+		return nil
+
+	case "show":
+		if flags.NArg() != 2 {
+			return fmt.Errorf("usage: aipipe snippet show <name>")
+		}
+		prompt, err := store.Get(flags.Arg(1))
+		if err != nil {
+			return err
+		}
+		fmt.Println(prompt)
+		return nil
+
+	case "delete":
+		if err := checkNotReadOnly("deleting snippets"); err != nil {
+			return err
+		}
+		if flags.NArg() < 2 {
+			return fmt.Errorf("usage: aipipe snippet delete <name>...")
+		}
+		for _, name := range flags.Args()[1:] {
+			if err := store.Delete(name); err != nil {
+				return err
+			}
+		}
+		return nil
+
+	case "sync":
+		if err := checkNotReadOnly("syncing snippets"); err != nil {
+			return err
+		}
+		source, err := util.GetSnippetSource()
+		if err != nil {
+			return err
+		}
+		if source == "" {
+			return fmt.Errorf("set snippetSource in the config file to a git repository or the URL of a YAML file of snippets")
+		}
+		count, err := snippets.Sync(source)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Synced %d shared snippets from %s\n", count, source)
+		return nil
+
+	default:
+		return fmt.Errorf("unknown snippet command %q (expected save, run, list, show, delete or sync)", flags.Arg(0))
+	}
+}
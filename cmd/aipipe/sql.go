@@ -0,0 +1,93 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/rba100/aipipe/internal/database"
+	"github.com/rba100/aipipe/internal/display"
+	"github.com/rba100/aipipe/internal/i18n"
+	"github.com/rba100/aipipe/internal/llm"
+	"github.com/rba100/aipipe/internal/util"
+	"github.com/spf13/pflag"
+)
+
+// maxSchemaChars caps the schema description included in the prompt
+const maxSchemaChars = 50000
+
+// runSQL implements `aipipe sql [question]`, writing a query for a question
+// about a database from its schema, then running it read-only if the user
+// confirms and printing the result as a table
+func runSQL(args []string) error {
+	flags := pflag.NewFlagSet("sql", pflag.ContinueOnError)
+	dsnFlag := flags.String("dsn", os.Getenv("DATABASE_URL"), "Database to query: a postgres:// or mysql:// URL, or sqlite:path (default: $DATABASE_URL)")
+	reasoningFlag := flags.BoolP("reasoning", "r", false, "Use reasoning model")
+	yesFlag := flags.BoolP("yes", "y", false, "Run the query without asking")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if err := checkNotReadOnly("querying databases"); err != nil {
+		return err
+	}
+
+	question := strings.Join(flags.Args(), " ")
+	if question == "" {
+		return fmt.Errorf("usage: aipipe sql --dsn DSN <question>")
+	}
+	if *dsnFlag == "" {
+		return fmt.Errorf("no database given; use --dsn or set DATABASE_URL")
+	}
+
+	db, err := database.Open(*dsnFlag)
+	if err != nil {
+		return err
+	}
+	schema, err := db.Schema()
+	if err != nil {
+		return err
+	}
+
+	apiConfig, err := loadAPIConfig(false)
+	if err != nil {
+		return err
+	}
+	model := llm.ModelTypeDefault
+	if *reasoningFlag {
+		model = llm.ModelTypeReasoning
+	}
+	client, err := llm.NewClient(newLLMConfig(apiConfig, model, llm.GetSQLSystemPrompt(db.Dialect())))
+	if err != nil {
+		return err
+	}
+
+	prompt := "Schema:\n" + util.TruncateText(schema, maxSchemaChars) + "\n-----\n" + question
+	response, err := client.CreateCompletion(context.Background(), prompt)
+	if err != nil {
+		return err
+	}
+	query := strings.TrimSpace(util.ExtractCodeBlock(util.StripThinkTags(response)).Text)
+
+	printer := display.NewPrettyPrinter()
+	printer.Print("```sql\n" + query + "\n```\n")
+	printer.Flush()
+	printer.Close()
+
+	if !*yesFlag {
+		confirmed, err := confirm("Run this query? [y/N] ")
+		if err != nil || !confirmed {
+			return nil
+		}
+	}
+
+	rows, err := db.Query(query)
+	if err != nil {
+		return fmt.Errorf("the query failed: %v", err)
+	}
+	fmt.Print(display.FormatTable(rows))
+	if len(rows) > 0 {
+		fmt.Fprintln(os.Stderr, i18n.T("(%d rows)", len(rows)-1))
+	}
+	return nil
+}
@@ -0,0 +1,29 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/rba100/aipipe/internal/util"
+	"github.com/spf13/pflag"
+)
+
+// runStripThink implements `aipipe strip-think`, copying stdin to stdout with
+// any leading <think>...</think> section removed
+func runStripThink(args []string) error {
+	flags := pflag.NewFlagSet("strip-think", pflag.ContinueOnError)
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	if flags.NArg() > 0 {
+		return fmt.Errorf("strip-think reads from stdin and takes no arguments")
+	}
+
+	for part := range util.StripThinkTagsStream(context.Background(), streamInput(os.Stdin)) {
+		os.Stdout.WriteString(part)
+	}
+
+	return nil
+}
@@ -0,0 +1,25 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/rba100/aipipe/internal/i18n"
+	"github.com/rba100/aipipe/internal/util"
+)
+
+// untrusted wraps text from a web page, API response or command output for
+// the prompt, warning on stderr if it seems to contain instructions for the
+// model. The system prompt must include llm.GetUntrustedContentPrompt.
+func untrusted(source string, text string) string {
+	if injections := util.FindInjections(text); len(injections) > 0 {
+		quoted := make([]string, len(injections))
+		for i, injection := range injections {
+			quoted[i] = fmt.Sprintf("%q", injection)
+		}
+		fmt.Fprintln(os.Stderr, i18n.T("Warning: %s contains text that looks like instructions to the model (%s); it has been marked as untrusted",
+			source, strings.Join(quoted, ", ")))
+	}
+	return util.WrapUntrusted(source, text)
+}
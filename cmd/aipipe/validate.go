@@ -0,0 +1,67 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/rba100/aipipe/internal/i18n"
+	"github.com/rba100/aipipe/internal/llm"
+	"github.com/rba100/aipipe/internal/parsing"
+	"github.com/rba100/aipipe/internal/util"
+)
+
+// validateCodeBlock checks that a code block parses as the expected language.
+// If it doesn't, the model is asked once to fix it. Remaining problems are
+// reported as warnings, since broken code is still better than nothing.
+func validateCodeBlock(ctx context.Context, client llm.LLMClient, prompt string, result util.CodeBlockResult, language string) util.CodeBlockResult {
+	if result.Type == "" {
+		result.Type = language
+	}
+
+	problem := util.ValidateCode(result.Text, language)
+	if problem == nil {
+		return result
+	}
+
+	fmt.Fprintln(os.Stderr, i18n.T("Warning: the %s code has syntax errors, asking for a fix:\n%v", language, problem))
+
+	response, err := client.CreateCompletion(ctx, buildFixPrompt(prompt, result.Text, language, problem))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, i18n.T("Warning: failed to fix the code: %v", err))
+		return result
+	}
+
+	fixed, _ := util.SelectCodeBlock(util.StripThinkTags(response), 1)
+	fixed.Type = result.Type
+	if problem := util.ValidateCode(fixed.Text, language); problem != nil {
+		fmt.Fprintln(os.Stderr, i18n.T("Warning: the fixed %s code still has syntax errors:\n%v", language, problem))
+	}
+	return fixed
+}
+
+// formatCodeBlock pipes a code block through the formatter configured for
+// its language, if any. If the formatter fails the code is left as it is.
+func formatCodeBlock(result util.CodeBlockResult, formatters map[string]string) util.CodeBlockResult {
+	language := parsing.CanonicalLanguage(result.Type)
+	for name, command := range formatters {
+		if parsing.CanonicalLanguage(name) != language {
+			continue
+		}
+
+		formatted, err := util.FormatCode(result.Text, command)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, i18n.T("Warning: %v", err))
+			return result
+		}
+		result.Text = formatted
+		return result
+	}
+	return result
+}
+
+// buildFixPrompt asks the model to correct the syntax errors in its answer
+func buildFixPrompt(prompt string, code string, language string, problem error) string {
+	return fmt.Sprintf("%s\n-----\nYour previous answer was:\n```%s\n%s\n```\nIt doesn't parse as %s:\n%v\n-----\nReply with the corrected code in a single code block.",
+		prompt, language, code, language, problem)
+}
@@ -3,6 +3,10 @@ module github.com/rba100/aipipe
 go 1.21
 
 require (
-	github.com/spf13/pflag v1.0.6 // indirect
-	gopkg.in/yaml.v3 v3.0.1 // indirect
+	github.com/alecthomas/chroma/v2 v2.14.0
+	github.com/smacker/go-tree-sitter v0.0.0-20240827094217-dd81d9e9be82
+	github.com/spf13/pflag v1.0.6
+	gopkg.in/yaml.v3 v3.0.1
 )
+
+require github.com/dlclark/regexp2 v1.11.0 // indirect
@@ -0,0 +1,100 @@
+// Package audit records the commands aipipe runs on the model's behalf, such
+// as the kubectl commands of aipipe k8s, so what was run against a system can
+// be checked afterwards
+package audit
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/rba100/aipipe/internal/util"
+)
+
+// Entry is a command the model asked for
+type Entry struct {
+	// Time is when the command was run or turned down
+	Time time.Time `json:"time"`
+	// Tool is the program the command is for, such as kubectl
+	Tool string `json:"tool"`
+	// Command holds the arguments the tool was, or would have been, run with
+	Command []string `json:"command"`
+	// Outcome is what happened: ran, failed, declined by the user or refused
+	// because the command isn't allowed
+	Outcome string `json:"outcome"`
+}
+
+// Log keeps entries in a JSON lines file, one command per line, oldest first
+type Log struct {
+	path string
+}
+
+// NewLog creates a log that keeps its entries in the given file
+func NewLog(path string) *Log {
+	return &Log{path: path}
+}
+
+// DefaultLog returns the log in tool_audit in aipipe's state directory
+func DefaultLog() (*Log, error) {
+	path, err := util.StatePath("tool_audit")
+	if err != nil {
+		return nil, err
+	}
+	return NewLog(path), nil
+}
+
+// List returns the logged commands, oldest first
+func (l *Log) List() ([]Entry, error) {
+	file, err := os.Open(l.path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open audit log: %w", err)
+	}
+	defer file.Close()
+
+	var entries []Entry
+	decoder := json.NewDecoder(bufio.NewReader(file))
+	for {
+		var entry Entry
+		err := decoder.Decode(&entry)
+		if err == io.EOF {
+			return entries, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse audit log: %w", err)
+		}
+		entries = append(entries, entry)
+	}
+}
+
+// Add appends an entry to the log. The time is set to now if it is zero.
+func (l *Log) Add(entry Entry) error {
+	if entry.Time.IsZero() {
+		entry.Time = time.Now()
+	}
+
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("failed to encode audit entry: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(l.path), 0700); err != nil {
+		return fmt.Errorf("failed to create audit log directory: %w", err)
+	}
+	file, err := os.OpenFile(l.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to open audit log: %w", err)
+	}
+	defer file.Close()
+
+	if _, err := file.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("failed to write audit log: %w", err)
+	}
+	return nil
+}
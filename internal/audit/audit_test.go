@@ -0,0 +1,39 @@
+package audit
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestLog(t *testing.T) {
+	log := NewLog(filepath.Join(t.TempDir(), "state", "tool_audit"))
+
+	if entries, err := log.List(); err != nil || len(entries) != 0 {
+		t.Fatalf("List() of a new log = %+v, %v; want no entries", entries, err)
+	}
+
+	added := []Entry{
+		{Tool: "kubectl", Command: []string{"--namespace", "shop", "get", "pods"}, Outcome: "ran"},
+		{Tool: "kubectl", Command: []string{"get", "secrets"}, Outcome: "refused"},
+	}
+	for _, entry := range added {
+		if err := log.Add(entry); err != nil {
+			t.Fatalf("Add() error: %v", err)
+		}
+	}
+
+	entries, err := log.List()
+	if err != nil || len(entries) != 2 {
+		t.Fatalf("List() = %+v, %v; want 2 entries", entries, err)
+	}
+	for i, entry := range entries {
+		if entry.Time.IsZero() {
+			t.Errorf("entry %d has no time", i)
+		}
+		entry.Time = added[i].Time
+		if !reflect.DeepEqual(entry, added[i]) {
+			t.Errorf("entry %d = %+v, want %+v", i, entry, added[i])
+		}
+	}
+}
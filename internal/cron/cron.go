@@ -0,0 +1,314 @@
+// Package cron parses standard five-field cron expressions, works out when
+// they next run and converts them to systemd calendar events.
+package cron
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// field describes one of the five fields of a cron expression
+type field struct {
+	name  string
+	min   int
+	max   int
+	names []string
+}
+
+var (
+	minuteField = field{name: "minute", min: 0, max: 59}
+	hourField   = field{name: "hour", min: 0, max: 23}
+	dayField    = field{name: "day of month", min: 1, max: 31}
+	monthField  = field{name: "month", min: 1, max: 12,
+		names: []string{"", "jan", "feb", "mar", "apr", "may", "jun", "jul", "aug", "sep", "oct", "nov", "dec"}}
+	weekdayField = field{name: "day of week", min: 0, max: 7,
+		names: []string{"sun", "mon", "tue", "wed", "thu", "fri", "sat"}}
+)
+
+// macros are the @ shorthands for common schedules
+var macros = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+// Schedule is a parsed cron expression
+type Schedule struct {
+	minutes, hours, days, months, weekdays uint64
+	// dayIsStar and weekdayIsStar record unrestricted day fields. When both
+	// day fields are restricted, a day matches if either does.
+	dayIsStar, weekdayIsStar bool
+	// fields are the expression's fields as written, after expanding macros
+	fields []string
+}
+
+// Parse parses a five-field cron expression, or one of the @yearly, @monthly,
+// @weekly, @daily and @hourly macros. Month and weekday names are accepted,
+// and 7 is Sunday as well as 0.
+func Parse(expression string) (*Schedule, error) {
+	expression = strings.TrimSpace(expression)
+	if strings.HasPrefix(expression, "@") {
+		expanded, ok := macros[strings.ToLower(expression)]
+		if !ok {
+			return nil, fmt.Errorf("%s has no schedule that can be checked", expression)
+		}
+		expression = expanded
+	}
+
+	fields := strings.Fields(expression)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("expected 5 fields (minute hour day-of-month month day-of-week), found %d", len(fields))
+	}
+
+	schedule := &Schedule{fields: fields}
+	var err error
+	if schedule.minutes, err = parseField(fields[0], minuteField); err != nil {
+		return nil, err
+	}
+	if schedule.hours, err = parseField(fields[1], hourField); err != nil {
+		return nil, err
+	}
+	if schedule.days, err = parseField(fields[2], dayField); err != nil {
+		return nil, err
+	}
+	if schedule.months, err = parseField(fields[3], monthField); err != nil {
+		return nil, err
+	}
+	if schedule.weekdays, err = parseField(fields[4], weekdayField); err != nil {
+		return nil, err
+	}
+	// Sunday can be written as 7
+	if schedule.weekdays&(1<<7) != 0 {
+		schedule.weekdays |= 1
+	}
+	schedule.dayIsStar = strings.HasPrefix(fields[2], "*")
+	schedule.weekdayIsStar = strings.HasPrefix(fields[4], "*")
+	return schedule, nil
+}
+
+// parseField parses a comma-separated list of values, ranges and steps into a
+// bit set of the values it matches
+func parseField(text string, f field) (uint64, error) {
+	var bits uint64
+	for _, item := range strings.Split(text, ",") {
+		rangeText, stepText, hasStep := strings.Cut(item, "/")
+		step := 1
+		if hasStep {
+			var err error
+			step, err = strconv.Atoi(stepText)
+			if err != nil || step < 1 {
+				return 0, fmt.Errorf("invalid step %q in the %s field", stepText, f.name)
+			}
+		}
+
+		start, end := f.min, f.max
+		if rangeText != "*" {
+			startText, endText, isRange := strings.Cut(rangeText, "-")
+			var err error
+			if start, err = parseValue(startText, f); err != nil {
+				return 0, err
+			}
+			end = start
+			if isRange {
+				if end, err = parseValue(endText, f); err != nil {
+					return 0, err
+				}
+				if end < start {
+					return 0, fmt.Errorf("range %q in the %s field goes backwards", rangeText, f.name)
+				}
+			} else if hasStep {
+				end = f.max
+			}
+		}
+
+		for value := start; value <= end; value += step {
+			bits |= 1 << value
+		}
+	}
+	return bits, nil
+}
+
+// parseValue parses a single number or name in a field
+func parseValue(text string, f field) (int, error) {
+	for i, name := range f.names {
+		if name != "" && strings.EqualFold(text, name) {
+			return i, nil
+		}
+	}
+
+	value, err := strconv.Atoi(text)
+	if err != nil {
+		return 0, fmt.Errorf("invalid value %q in the %s field", text, f.name)
+	}
+	if value < f.min || value > f.max {
+		return 0, fmt.Errorf("%d is out of range for the %s field (%d-%d)", value, f.name, f.min, f.max)
+	}
+	return value, nil
+}
+
+// matchesDay reports whether the schedule runs on the day of t
+func (s *Schedule) matchesDay(t time.Time) bool {
+	day := s.days&(1<<t.Day()) != 0
+	weekday := s.weekdays&(1<<int(t.Weekday())) != 0
+	if s.dayIsStar || s.weekdayIsStar {
+		return day && weekday
+	}
+	return day || weekday
+}
+
+// Next returns the first time after t that the schedule runs, or the zero
+// time if it doesn't run in the next five years, as for "0 0 30 2 *"
+func (s *Schedule) Next(t time.Time) time.Time {
+	t = t.Truncate(time.Minute).Add(time.Minute)
+	limit := t.AddDate(5, 0, 0)
+
+	for t.Before(limit) {
+		switch {
+		case s.months&(1<<int(t.Month())) == 0:
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, t.Location())
+		case !s.matchesDay(t):
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, t.Location())
+		case s.hours&(1<<t.Hour()) == 0:
+			t = t.Truncate(time.Hour).Add(time.Hour)
+		case s.minutes&(1<<t.Minute()) == 0:
+			t = t.Add(time.Minute)
+		default:
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+// NextRuns returns the next n times after t that the schedule runs
+func (s *Schedule) NextRuns(t time.Time, n int) []time.Time {
+	var runs []time.Time
+	for len(runs) < n {
+		t = s.Next(t)
+		if t.IsZero() {
+			break
+		}
+		runs = append(runs, t)
+	}
+	return runs
+}
+
+// systemdWeekdays are the weekday names used in systemd calendar events
+var systemdWeekdays = []string{"Sun", "Mon", "Tue", "Wed", "Thu", "Fri", "Sat", "Sun"}
+
+// Systemd converts the schedule to a systemd calendar event, for OnCalendar=
+// in a timer unit. Schedules that restrict both the day of the month and the
+// day of the week can't be converted, since cron runs them when either
+// matches and systemd only when both do.
+func (s *Schedule) Systemd() (string, error) {
+	if !s.dayIsStar && !s.weekdayIsStar {
+		return "", fmt.Errorf("systemd can't run on either a day of the month or a day of the week, as cron does when both are given")
+	}
+
+	minute, err := systemdField(s.fields[0], minuteField, 2)
+	if err != nil {
+		return "", err
+	}
+	hour, err := systemdField(s.fields[1], hourField, 2)
+	if err != nil {
+		return "", err
+	}
+	day, err := systemdField(s.fields[2], dayField, 2)
+	if err != nil {
+		return "", err
+	}
+	month, err := systemdField(s.fields[3], monthField, 2)
+	if err != nil {
+		return "", err
+	}
+
+	event := fmt.Sprintf("*-%s-%s %s:%s:00", month, day, hour, minute)
+	if s.fields[4] != "*" {
+		weekdays, err := systemdWeekdayField(s.fields[4])
+		if err != nil {
+			return "", err
+		}
+		event = weekdays + " " + event
+	}
+	return event, nil
+}
+
+// systemdField converts a cron field to systemd's syntax, where ranges are
+// written "a..b" and steps "start/step"
+func systemdField(text string, f field, width int) (string, error) {
+	var items []string
+	for _, item := range strings.Split(text, ",") {
+		rangeText, stepText, hasStep := strings.Cut(item, "/")
+		startText, endText, isRange := strings.Cut(rangeText, "-")
+
+		if rangeText == "*" {
+			if hasStep {
+				items = append(items, fmt.Sprintf("%0*d/%s", width, f.min, stepText))
+			} else {
+				items = append(items, "*")
+			}
+			continue
+		}
+
+		start, err := parseValue(startText, f)
+		if err != nil {
+			return "", err
+		}
+		switch {
+		case isRange && hasStep:
+			return "", fmt.Errorf("systemd can't express the stepped range %q in the %s field", item, f.name)
+		case isRange:
+			end, err := parseValue(endText, f)
+			if err != nil {
+				return "", err
+			}
+			items = append(items, fmt.Sprintf("%0*d..%0*d", width, start, width, end))
+		case hasStep:
+			items = append(items, fmt.Sprintf("%0*d/%s", width, start, stepText))
+		default:
+			items = append(items, fmt.Sprintf("%0*d", width, start))
+		}
+	}
+	return strings.Join(items, ","), nil
+}
+
+// systemdWeekdayField converts a cron day of week field to systemd's weekday
+// names
+func systemdWeekdayField(text string) (string, error) {
+	var items []string
+	for _, item := range strings.Split(text, ",") {
+		if strings.Contains(item, "/") {
+			return "", fmt.Errorf("systemd can't express the step %q in the day of week field", item)
+		}
+		startText, endText, isRange := strings.Cut(item, "-")
+		start, err := parseValue(startText, weekdayField)
+		if err != nil {
+			return "", err
+		}
+		if !isRange {
+			items = append(items, systemdWeekdays[start])
+			continue
+		}
+		end, err := parseValue(endText, weekdayField)
+		if err != nil {
+			return "", err
+		}
+		// systemd weeks start on Monday, so a range starting on Sunday is
+		// split in two
+		if start == 0 {
+			items = append(items, "Sun")
+			start = 1
+		}
+		if end > start {
+			items = append(items, systemdWeekdays[start]+".."+systemdWeekdays[end])
+		} else if end == start {
+			items = append(items, systemdWeekdays[start])
+		}
+	}
+	return strings.Join(items, ","), nil
+}
@@ -0,0 +1,115 @@
+package cron
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseErrors(t *testing.T) {
+	for _, expression := range []string{
+		"* * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * * 13 *",
+		"* * * * 8",
+		"*/0 * * * *",
+		"5-1 * * * *",
+		"* * * foo *",
+		"@reboot",
+	} {
+		if _, err := Parse(expression); err == nil {
+			t.Errorf("Parse(%q) accepted an invalid expression", expression)
+		}
+	}
+}
+
+func TestNextRuns(t *testing.T) {
+	// A Wednesday
+	now := time.Date(2024, time.May, 1, 10, 30, 0, 0, time.UTC)
+	tests := []struct {
+		expression string
+		expected   []time.Time
+	}{
+		{
+			expression: "*/15 * * * *",
+			expected: []time.Time{
+				time.Date(2024, time.May, 1, 10, 45, 0, 0, time.UTC),
+				time.Date(2024, time.May, 1, 11, 0, 0, 0, time.UTC),
+				time.Date(2024, time.May, 1, 11, 15, 0, 0, time.UTC),
+			},
+		},
+		{
+			expression: "0 9 * * mon-fri",
+			expected: []time.Time{
+				time.Date(2024, time.May, 2, 9, 0, 0, 0, time.UTC),
+				time.Date(2024, time.May, 3, 9, 0, 0, 0, time.UTC),
+				time.Date(2024, time.May, 6, 9, 0, 0, 0, time.UTC),
+			},
+		},
+		{
+			// Either the 1st of the month or a Sunday
+			expression: "0 0 1 * 7",
+			expected: []time.Time{
+				time.Date(2024, time.May, 5, 0, 0, 0, 0, time.UTC),
+				time.Date(2024, time.May, 12, 0, 0, 0, 0, time.UTC),
+				time.Date(2024, time.May, 19, 0, 0, 0, 0, time.UTC),
+			},
+		},
+		{
+			expression: "@yearly",
+			expected: []time.Time{
+				time.Date(2025, time.January, 1, 0, 0, 0, 0, time.UTC),
+				time.Date(2026, time.January, 1, 0, 0, 0, 0, time.UTC),
+				time.Date(2027, time.January, 1, 0, 0, 0, 0, time.UTC),
+			},
+		},
+		{
+			expression: "0 0 30 2 *",
+			expected:   nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expression, func(t *testing.T) {
+			schedule, err := Parse(tt.expression)
+			if err != nil {
+				t.Fatalf("Parse() error = %v", err)
+			}
+			if got := schedule.NextRuns(now, 3); !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("NextRuns() = %v, expected %v", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestSystemd(t *testing.T) {
+	tests := []struct {
+		expression string
+		expected   string
+	}{
+		{"*/15 * * * *", "*-*-* *:00/15:00"},
+		{"0 9 * * 1-5", "Mon..Fri *-*-* 09:00:00"},
+		{"30 2 1,15 * *", "*-*-01,15 02:30:00"},
+		{"0 0 * * 0-3", "Sun,Mon..Wed *-*-* 00:00:00"},
+		{"0 8-17 * jan *", "*-01-* 08..17:00:00"},
+		{"@weekly", "Sun *-*-* 00:00:00"},
+	}
+	for _, tt := range tests {
+		schedule, err := Parse(tt.expression)
+		if err != nil {
+			t.Fatalf("Parse(%q) error = %v", tt.expression, err)
+		}
+		if got, err := schedule.Systemd(); err != nil || got != tt.expected {
+			t.Errorf("Systemd() for %q = %q, %v, expected %q", tt.expression, got, err, tt.expected)
+		}
+	}
+
+	for _, expression := range []string{"0 0 1 * 1", "0 0-12/2 * * *"} {
+		schedule, _ := Parse(expression)
+		if _, err := schedule.Systemd(); err == nil {
+			t.Errorf("Systemd() converted %q, which systemd can't express", expression)
+		}
+	}
+}
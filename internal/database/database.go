@@ -0,0 +1,224 @@
+// Package database reads schemas from and runs read-only queries against
+// databases through their command line clients: sqlite3, psql and mysql.
+package database
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"net/url"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// Kind identifies the database engine behind a DSN
+type Kind string
+
+const (
+	KindSQLite   Kind = "sqlite"
+	KindPostgres Kind = "postgres"
+	KindMySQL    Kind = "mysql"
+)
+
+// Database is a database reached through its command line client
+type Database struct {
+	Kind Kind
+	// DSN is the connection string for PostgreSQL, or the file for SQLite
+	DSN string
+	// url is the parsed DSN for MySQL, whose client takes separate options
+	url *url.URL
+}
+
+// Open returns the database for a DSN: a postgres://, postgresql:// or
+// mysql:// URL, or a SQLite file given as sqlite:path or a path ending in
+// .db, .sqlite or .sqlite3. It checks that the client is installed but
+// doesn't connect.
+func Open(dsn string) (*Database, error) {
+	var db *Database
+	switch {
+	case strings.HasPrefix(dsn, "postgres://"), strings.HasPrefix(dsn, "postgresql://"):
+		db = &Database{Kind: KindPostgres, DSN: dsn}
+	case strings.HasPrefix(dsn, "mysql://"):
+		parsed, err := url.Parse(dsn)
+		if err != nil {
+			return nil, fmt.Errorf("invalid MySQL DSN: %v", err)
+		}
+		db = &Database{Kind: KindMySQL, DSN: dsn, url: parsed}
+	case strings.HasPrefix(dsn, "sqlite:"):
+		path := strings.TrimPrefix(strings.TrimPrefix(dsn, "sqlite:"), "//")
+		db = &Database{Kind: KindSQLite, DSN: path}
+	case strings.HasSuffix(dsn, ".db"), strings.HasSuffix(dsn, ".sqlite"), strings.HasSuffix(dsn, ".sqlite3"):
+		db = &Database{Kind: KindSQLite, DSN: dsn}
+	default:
+		return nil, fmt.Errorf("unrecognised DSN %q; use a postgres:// or mysql:// URL, or sqlite:path", dsn)
+	}
+
+	if db.Kind == KindSQLite {
+		if _, err := os.Stat(db.DSN); err != nil {
+			return nil, fmt.Errorf("cannot open SQLite database: %v", err)
+		}
+	}
+	if _, err := exec.LookPath(db.client()); err != nil {
+		return nil, fmt.Errorf("%s is needed to query %s databases, and is not installed", db.client(), db.Dialect())
+	}
+	return db, nil
+}
+
+// Dialect returns the name of the database's SQL dialect, for the model
+func (db *Database) Dialect() string {
+	switch db.Kind {
+	case KindPostgres:
+		return "PostgreSQL"
+	case KindMySQL:
+		return "MySQL"
+	default:
+		return "SQLite"
+	}
+}
+
+// client returns the name of the database's command line client
+func (db *Database) client() string {
+	switch db.Kind {
+	case KindPostgres:
+		return "psql"
+	case KindMySQL:
+		return "mysql"
+	default:
+		return "sqlite3"
+	}
+}
+
+// command returns the command that runs query read-only, writing the result
+// as CSV (tab-separated for MySQL) with a header row
+func (db *Database) command(query string) *exec.Cmd {
+	switch db.Kind {
+	case KindPostgres:
+		cmd := exec.Command("psql", db.DSN, "--csv", "--no-psqlrc", "-v", "ON_ERROR_STOP=1", "-c", query)
+		cmd.Env = append(os.Environ(), "PGOPTIONS=-c default_transaction_read_only=on")
+		return cmd
+	case KindMySQL:
+		args := []string{"--batch", "--init-command=SET SESSION TRANSACTION READ ONLY"}
+		if db.url.Hostname() != "" {
+			args = append(args, "--host", db.url.Hostname())
+		}
+		if db.url.Port() != "" {
+			args = append(args, "--port", db.url.Port())
+		}
+		if db.url.User != nil && db.url.User.Username() != "" {
+			args = append(args, "--user", db.url.User.Username())
+		}
+		if name := strings.TrimPrefix(db.url.Path, "/"); name != "" {
+			args = append(args, "--database", name)
+		}
+		cmd := exec.Command("mysql", append(args, "--execute", query)...)
+		// The password is passed in the environment so it isn't visible in
+		// the process list
+		cmd.Env = os.Environ()
+		if password, ok := db.url.User.Password(); ok {
+			cmd.Env = append(cmd.Env, "MYSQL_PWD="+password)
+		}
+		return cmd
+	default:
+		return exec.Command("sqlite3", "-readonly", "-csv", "-header", db.DSN, query)
+	}
+}
+
+// Query runs a query read-only and returns its result, starting with a row of
+// column names. Statements that write to the database fail.
+func (db *Database) Query(query string) ([][]string, error) {
+	var stdout, stderr bytes.Buffer
+	cmd := db.command(query)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if message := strings.TrimSpace(stderr.String()); message != "" {
+			return nil, fmt.Errorf("%s", message)
+		}
+		return nil, fmt.Errorf("%s failed: %v", db.client(), err)
+	}
+
+	if db.Kind == KindMySQL {
+		return parseTabSeparated(stdout.String()), nil
+	}
+	reader := csv.NewReader(&stdout)
+	reader.FieldsPerRecord = -1
+	rows, err := reader.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("error reading %s output: %v", db.client(), err)
+	}
+	return rows, nil
+}
+
+// schemaQueries list the columns of every user table, as schema, table,
+// column and type
+var schemaQueries = map[Kind]string{
+	KindPostgres: "SELECT table_schema, table_name, column_name, data_type FROM information_schema.columns " +
+		"WHERE table_schema NOT IN ('pg_catalog', 'information_schema') ORDER BY table_schema, table_name, ordinal_position",
+	KindMySQL: "SELECT table_schema, table_name, column_name, column_type FROM information_schema.columns " +
+		"WHERE table_schema = DATABASE() ORDER BY table_name, ordinal_position",
+	KindSQLite: "SELECT '', m.name, p.name, p.type FROM sqlite_master m JOIN pragma_table_info(m.name) p " +
+		"WHERE m.type IN ('table', 'view') AND m.name NOT LIKE 'sqlite_%' ORDER BY m.name, p.cid",
+}
+
+// Schema describes the database's tables and their columns, one table per
+// line, for including in a prompt
+func (db *Database) Schema() (string, error) {
+	rows, err := db.Query(schemaQueries[db.Kind])
+	if err != nil {
+		return "", fmt.Errorf("error reading the schema: %v", err)
+	}
+	if len(rows) > 0 {
+		rows = rows[1:]
+	}
+	return FormatSchema(rows), nil
+}
+
+// FormatSchema formats rows of schema, table, column and type as one line per
+// table, like "public.users(id integer, email text)". The schema is left out
+// when it is empty.
+func FormatSchema(rows [][]string) string {
+	var builder strings.Builder
+	table := ""
+	for _, row := range rows {
+		if len(row) < 4 {
+			continue
+		}
+		name := row[1]
+		if row[0] != "" {
+			name = row[0] + "." + row[1]
+		}
+		if name != table {
+			if table != "" {
+				builder.WriteString(")\n")
+			}
+			builder.WriteString(name + "(")
+			table = name
+		} else {
+			builder.WriteString(", ")
+		}
+		builder.WriteString(row[2] + " " + row[3])
+	}
+	if table != "" {
+		builder.WriteString(")\n")
+	}
+	return builder.String()
+}
+
+// parseTabSeparated parses the output of mysql --batch: tab-separated
+// columns, with tabs, newlines and backslashes in values escaped
+func parseTabSeparated(output string) [][]string {
+	unescape := strings.NewReplacer(`\t`, "\t", `\n`, "\n", `\\`, `\`, `\0`, "\x00")
+	var rows [][]string
+	for _, line := range strings.Split(strings.TrimRight(output, "\n"), "\n") {
+		if line == "" {
+			continue
+		}
+		fields := strings.Split(line, "\t")
+		for i, field := range fields {
+			fields[i] = unescape.Replace(field)
+		}
+		rows = append(rows, fields)
+	}
+	return rows
+}
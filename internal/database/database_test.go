@@ -0,0 +1,70 @@
+package database
+
+import (
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestFormatSchema(t *testing.T) {
+	rows := [][]string{
+		{"public", "users", "id", "integer"},
+		{"public", "users", "email", "text"},
+		{"public", "orders", "id", "integer"},
+	}
+	expected := "public.users(id integer, email text)\npublic.orders(id integer)\n"
+	if got := FormatSchema(rows); got != expected {
+		t.Errorf("FormatSchema() = %q, expected %q", got, expected)
+	}
+
+	if got := FormatSchema([][]string{{"", "notes", "body", "TEXT"}}); got != "notes(body TEXT)\n" {
+		t.Errorf("FormatSchema() without a schema = %q", got)
+	}
+}
+
+func TestParseTabSeparated(t *testing.T) {
+	got := parseTabSeparated("id\tnote\n1\tfirst\\tline\\nsecond\n2\tNULL\n")
+	expected := [][]string{{"id", "note"}, {"1", "first\tline\nsecond"}, {"2", "NULL"}}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("parseTabSeparated() = %q, expected %q", got, expected)
+	}
+}
+
+func TestOpenUnrecognisedDSN(t *testing.T) {
+	if _, err := Open("redis://localhost"); err == nil {
+		t.Errorf("Open() accepted a redis DSN")
+	}
+}
+
+func TestSQLite(t *testing.T) {
+	if _, err := exec.LookPath("sqlite3"); err != nil {
+		t.Skip("sqlite3 is not installed")
+	}
+
+	path := filepath.Join(t.TempDir(), "test.db")
+	setup := "CREATE TABLE users (id INTEGER PRIMARY KEY, name TEXT); INSERT INTO users (name) VALUES ('Ada'), ('Grace');"
+	if output, err := exec.Command("sqlite3", path, setup).CombinedOutput(); err != nil {
+		t.Fatalf("creating the database failed: %v: %s", err, output)
+	}
+
+	db, err := Open("sqlite:" + path)
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+
+	schema, err := db.Schema()
+	if err != nil || schema != "users(id INTEGER, name TEXT)\n" {
+		t.Errorf("Schema() = %q, %v", schema, err)
+	}
+
+	rows, err := db.Query("SELECT id, name FROM users ORDER BY id")
+	expected := [][]string{{"id", "name"}, {"1", "Ada"}, {"2", "Grace"}}
+	if err != nil || !reflect.DeepEqual(rows, expected) {
+		t.Errorf("Query() = %q, %v", rows, err)
+	}
+
+	if _, err := db.Query("DELETE FROM users"); err == nil {
+		t.Errorf("Query() ran a DELETE on a read-only database")
+	}
+}
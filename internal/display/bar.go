@@ -0,0 +1,25 @@
+package display
+
+import "strings"
+
+// barEighths are the partial blocks used for the end of a bar, one eighth of
+// a cell wide and up
+var barEighths = []rune("▏▎▍▌▋▊▉")
+
+// Bar returns a horizontal bar width cells wide, filled in proportion to
+// fraction, which is clamped to between 0 and 1. The unfilled part is shaded
+// so the bar's length is visible.
+func Bar(fraction float64, width int) string {
+	fraction = min(max(fraction, 0), 1)
+	eighths := int(fraction * float64(width*8))
+
+	var builder strings.Builder
+	builder.WriteString(strings.Repeat("█", eighths/8))
+	cells := eighths / 8
+	if eighths%8 > 0 {
+		builder.WriteRune(barEighths[eighths%8-1])
+		cells++
+	}
+	builder.WriteString(strings.Repeat("░", width-cells))
+	return builder.String()
+}
@@ -0,0 +1,26 @@
+package display
+
+import "testing"
+
+func TestBar(t *testing.T) {
+	tests := []struct {
+		name     string
+		fraction float64
+		expected string
+	}{
+		{"Empty", 0, "░░░░"},
+		{"Half", 0.5, "██░░"},
+		{"Partial cell", 0.3, "█▏░░"},
+		{"Full", 1, "████"},
+		{"Over", 1.5, "████"},
+		{"Negative", -1, "░░░░"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Bar(tt.fraction, 4); got != tt.expected {
+				t.Errorf("Bar(%v, 4) = %q, expected %q", tt.fraction, got, tt.expected)
+			}
+		})
+	}
+}
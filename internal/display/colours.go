@@ -75,6 +75,11 @@ var (
 	TokenCommentColor    string
 	TokenOtherColor      string
 
+	// Diff colors for added and removed lines and hunk headers
+	TokenInsertedColor string
+	TokenDeletedColor  string
+	TokenHeadingColor  string
+
 	// Markdown formatting colors (will be initialized in InitializeColors)
 	MdHeaderColor     string
 	MdCodeBlockColor  string
@@ -83,6 +88,12 @@ var (
 	MdListMarkerColor string
 	MdEmphasisColor   string
 	MdNormalTextColor string
+
+	// Colours of the headers and gutters of each role's messages in a
+	// conversation (will be initialized in InitializeColors)
+	RoleUserColor      string
+	RoleAssistantColor string
+	RoleOtherColor     string
 )
 
 // GetColorMode detects the terminal's color capabilities
@@ -139,6 +150,9 @@ func InitializeColors() {
 	TokenLiteralColor = GreenFg
 	TokenCommentColor = BrightBlackFg
 	TokenOtherColor = CyanFg
+	TokenInsertedColor = GreenFg
+	TokenDeletedColor = RedFg
+	TokenHeadingColor = CyanFg
 
 	MdHeaderColor = BoldFormat + YellowFg
 	MdCodeBlockColor = CyanFg
@@ -148,6 +162,10 @@ func InitializeColors() {
 	MdEmphasisColor = YellowFg + DimFormat
 	MdNormalTextColor = WhiteFg
 
+	RoleUserColor = BlueFg
+	RoleAssistantColor = GreenFg
+	RoleOtherColor = YellowFg
+
 	// Windows Terminal has good color support even if TERM doesn't indicate it
 	if IsWindowsTerminal() {
 		mode = Color256Mode
@@ -160,6 +178,9 @@ func InitializeColors() {
 		TokenLiteralColor = "\033[38;5;114m"    // Light green
 		TokenCommentColor = "\033[38;5;245m"    // Medium gray
 		TokenOtherColor = "\033[38;5;81m"       // Light cyan
+		TokenInsertedColor = "\033[38;5;114m"   // Light green
+		TokenDeletedColor = "\033[38;5;203m"    // Soft red
+		TokenHeadingColor = "\033[38;5;81m"     // Light cyan
 
 		MdHeaderColor = BoldFormat + "\033[38;5;220m" // Gold
 		MdCodeBlockColor = "\033[38;5;81m"            // Light cyan
@@ -168,6 +189,10 @@ func InitializeColors() {
 		MdListMarkerColor = "\033[38;5;75m"           // Medium blue
 		MdEmphasisColor = "\033[38;5;222m"            // Light gold
 		MdNormalTextColor = "\033[38;5;252m"          // Light gray
+
+		RoleUserColor = "\033[38;5;75m"       // Medium blue
+		RoleAssistantColor = "\033[38;5;114m" // Light green
+		RoleOtherColor = "\033[38;5;222m"     // Light gold
 	}
 
 	// If we have true color support, use RGB colors for even better representation
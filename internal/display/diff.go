@@ -0,0 +1,48 @@
+package display
+
+import (
+	"strings"
+
+	"github.com/rba100/aipipe/internal/util"
+)
+
+// FormatWordDiff returns a word diff for display. With color, deleted text is
+// red and inserted text green. Without, they are marked as [-deleted-] and
+// {+inserted+}, as by git diff --word-diff.
+func FormatWordDiff(parts []util.DiffPart, color bool) string {
+	var builder strings.Builder
+	for _, part := range parts {
+		start, end := "", ""
+		switch {
+		case part.Op == util.DiffDelete && color:
+			start, end = RedFg, ResetFormat
+		case part.Op == util.DiffInsert && color:
+			start, end = GreenFg, ResetFormat
+		case part.Op == util.DiffDelete:
+			start, end = "[-", "-]"
+		case part.Op == util.DiffInsert:
+			start, end = "{+", "+}"
+		}
+		if start == "" {
+			builder.WriteString(part.Text)
+			continue
+		}
+
+		// Each line is marked separately, so colors don't run on into the
+		// next line when the output is paged or cut
+		lines := strings.SplitAfter(part.Text, "\n")
+		for _, line := range lines {
+			text := strings.TrimSuffix(line, "\n")
+			if text != "" {
+				builder.WriteString(start + text + end)
+			} else if line == "\n" {
+				// Make a deleted or inserted blank line visible
+				builder.WriteString(start + "⏎" + end)
+			}
+			if text != line {
+				builder.WriteString("\n")
+			}
+		}
+	}
+	return builder.String()
+}
@@ -0,0 +1,40 @@
+package display
+
+import (
+	"testing"
+
+	"github.com/rba100/aipipe/internal/util"
+)
+
+func TestFormatWordDiff(t *testing.T) {
+	parts := []util.DiffPart{
+		{Op: util.DiffEqual, Text: "port: "},
+		{Op: util.DiffDelete, Text: "8080"},
+		{Op: util.DiffInsert, Text: "9090\nhost: b"},
+		{Op: util.DiffEqual, Text: "\n"},
+	}
+
+	tests := []struct {
+		name     string
+		color    bool
+		expected string
+	}{
+		{
+			name:     "Without color",
+			expected: "port: [-8080-]{+9090+}\n{+host: b+}\n",
+		},
+		{
+			name:     "With color",
+			color:    true,
+			expected: "port: " + RedFg + "8080" + ResetFormat + GreenFg + "9090" + ResetFormat + "\n" + GreenFg + "host: b" + ResetFormat + "\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := FormatWordDiff(parts, tt.color); got != tt.expected {
+				t.Errorf("FormatWordDiff() = %q, expected %q", got, tt.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,164 @@
+package display
+
+import (
+	"bufio"
+	"io"
+	"regexp"
+	"strings"
+)
+
+var (
+	plainCodeFenceRegex  = regexp.MustCompile("^\\s*(\x60\x60\x60|~~~)")
+	plainHeaderRegex     = regexp.MustCompile(`^\s{0,3}#{1,6}\s+(.*?)(\s+#+)?\s*$`)
+	plainRuleRegex       = regexp.MustCompile(`^\s*([-*_])(\s*[-*_]){2,}\s*$`)
+	plainBlockQuoteRegex = regexp.MustCompile(`^(\s*)(>\s?)+`)
+	plainBulletRegex     = regexp.MustCompile(`^(\s*)[*+]\s+`)
+	plainLiteralRegex    = regexp.MustCompile("\x60+[^\x60\n]+\x60+|\\\\[\\\\\x60*_{}\\[\\]()#+\\-.!~>|]")
+	plainImageRegex      = regexp.MustCompile(`!\[([^\]]*)\]\([^)]*\)`)
+	plainLinkRegex       = regexp.MustCompile(`\[([^\]]+)\]\(([^)\s]+)(?:\s+"[^"]*")?\)`)
+	plainAutoLinkRegex   = regexp.MustCompile(`<((?:https?|mailto):[^>\s]+)>`)
+
+	// plainEmphasisRegexes match emphasis markers around text, strongest
+	// first
+	plainEmphasisRegexes = []*regexp.Regexp{
+		regexp.MustCompile(`\*\*\*(\S(?:.*?\S)?)\*\*\*`),
+		regexp.MustCompile(`\*\*(\S(?:.*?\S)?)\*\*`),
+		regexp.MustCompile(`~~(\S(?:.*?\S)?)~~`),
+		regexp.MustCompile(`\*(\S(?:[^*]*?\S)?)\*`),
+	}
+
+	// plainUnderscoreRegexes match emphasis with underscores, which only
+	// count at word boundaries so snake_case names are left alone. They also
+	// match the characters either side.
+	plainUnderscoreRegexes = []*regexp.Regexp{
+		regexp.MustCompile(`(^|[^\w])__(\S(?:.*?\S)?)__($|[^\w])`),
+		regexp.MustCompile(`(^|[^\w])_(\S(?:[^_]*?\S)?)_($|[^\w])`),
+	}
+)
+
+// PlainPrinter converts markdown to plain text as it streams in, removing
+// code fences, heading hashes, emphasis markers and link syntax without adding
+// any colours, for text that goes into emails, commit messages and the like.
+// Code inside code blocks is written unchanged.
+type PlainPrinter struct {
+	out         *bufio.Writer
+	lineBuffer  strings.Builder
+	inCodeBlock bool
+	fence       string
+}
+
+// NewPlainPrinter creates a plain text printer writing to out
+func NewPlainPrinter(out io.Writer) *PlainPrinter {
+	return &PlainPrinter{out: bufio.NewWriter(out)}
+}
+
+// Print converts and writes the complete lines in text, keeping any partial
+// line until the rest of it arrives
+func (p *PlainPrinter) Print(text string) {
+	defer p.out.Flush()
+
+	for {
+		newline := strings.IndexByte(text, '\n')
+		if newline < 0 {
+			p.lineBuffer.WriteString(text)
+			return
+		}
+
+		p.lineBuffer.WriteString(text[:newline])
+		p.printLine(p.lineBuffer.String(), true)
+		p.lineBuffer.Reset()
+		text = text[newline+1:]
+	}
+}
+
+// Flush writes any partial line still buffered
+func (p *PlainPrinter) Flush() {
+	if p.lineBuffer.Len() > 0 {
+		p.printLine(p.lineBuffer.String(), false)
+		p.lineBuffer.Reset()
+	}
+	p.out.Flush()
+}
+
+// printLine converts and writes a line, followed by a newline if it had one.
+// Code fences are dropped altogether.
+func (p *PlainPrinter) printLine(line string, terminated bool) {
+	line = strings.TrimSuffix(line, "\r")
+
+	if match := plainCodeFenceRegex.FindStringSubmatch(line); match != nil {
+		if !p.inCodeBlock {
+			p.inCodeBlock = true
+			p.fence = match[1]
+			return
+		}
+		if strings.TrimSpace(line) == p.fence {
+			p.inCodeBlock = false
+			return
+		}
+	}
+
+	if !p.inCodeBlock {
+		line = plainLine(line)
+	}
+	p.out.WriteString(line)
+	if terminated {
+		p.out.WriteString("\n")
+	}
+}
+
+// plainLine converts a line of markdown outside code blocks to plain text
+func plainLine(line string) string {
+	if match := plainHeaderRegex.FindStringSubmatch(line); match != nil {
+		return plainInline(match[1])
+	}
+	if plainRuleRegex.MatchString(line) {
+		return ""
+	}
+
+	line = plainBlockQuoteRegex.ReplaceAllString(line, "$1")
+	line = plainBulletRegex.ReplaceAllString(line, "$1- ")
+	return plainInline(line)
+}
+
+// plainInline removes inline markdown from text. Inline code loses its
+// backticks but is otherwise left as it is, and escaped characters lose their
+// backslash.
+func plainInline(text string) string {
+	var result strings.Builder
+	last := 0
+	for _, span := range plainLiteralRegex.FindAllStringIndex(text, -1) {
+		result.WriteString(plainFormatting(text[last:span[0]]))
+		literal := text[span[0]:span[1]]
+		if strings.HasPrefix(literal, "\\") {
+			result.WriteString(literal[1:])
+		} else {
+			result.WriteString(strings.TrimSpace(strings.Trim(literal, "\x60")))
+		}
+		last = span[1]
+	}
+	result.WriteString(plainFormatting(text[last:]))
+	return result.String()
+}
+
+// plainFormatting removes emphasis and links from text without inline code or
+// escaped characters
+func plainFormatting(text string) string {
+	text = plainImageRegex.ReplaceAllString(text, "$1")
+	text = plainLinkRegex.ReplaceAllStringFunc(text, func(link string) string {
+		match := plainLinkRegex.FindStringSubmatch(link)
+		if match[1] == match[2] {
+			return match[2]
+		}
+		return match[1] + " (" + match[2] + ")"
+	})
+	text = plainAutoLinkRegex.ReplaceAllString(text, "$1")
+
+	for _, regex := range plainEmphasisRegexes {
+		text = regex.ReplaceAllString(text, "$1")
+	}
+	for _, regex := range plainUnderscoreRegexes {
+		text = regex.ReplaceAllString(text, "$1$2$3")
+	}
+
+	return text
+}
@@ -0,0 +1,71 @@
+package display
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestPlainLine(t *testing.T) {
+	tests := []struct {
+		name string
+		line string
+		want string
+	}{
+		{"plain text", "Nothing to change.", "Nothing to change."},
+		{"heading", "## Usage", "Usage"},
+		{"closed heading", "# Title #", "Title"},
+		{"bold and italic", "This is **bold**, *italic* and ***both***.", "This is bold, italic and both."},
+		{"underscores", "Use __strong__ or _em_ but keep snake_case_names.", "Use strong or em but keep snake_case_names."},
+		{"strikethrough", "~~old~~ new", "old new"},
+		{"inline code", "Run `go test ./...` to check `**this**`.", "Run go test ./... to check **this**."},
+		{"link", "See [the docs](https://example.com/docs).", "See the docs (https://example.com/docs)."},
+		{"bare link", "[https://example.com](https://example.com)", "https://example.com"},
+		{"autolink", "<https://example.com>", "https://example.com"},
+		{"image", "![a diagram](diagram.png)", "a diagram"},
+		{"bullet", "* item with **bold**", "- item with bold"},
+		{"nested bullet", "  + nested", "  - nested"},
+		{"numbered list", "1. first", "1. first"},
+		{"block quote", "> quoted *text*", "quoted text"},
+		{"horizontal rule", "---", ""},
+		{"escapes", `2 \* 3 \_not\_ emphasis`, "2 * 3 _not_ emphasis"},
+		{"multiplication", "2 * 3 * 4", "2 * 3 * 4"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := plainLine(tt.line); got != tt.want {
+				t.Errorf("plainLine(%q) = %q, want %q", tt.line, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPlainPrinterStreams(t *testing.T) {
+	markdown := "# Fix the build\n\nThe **config** loader:\n\n```go\nx := *y // **not** emphasis\n```\n\n- done\n"
+	want := "Fix the build\n\nThe config loader:\n\nx := *y // **not** emphasis\n\n- done\n"
+
+	// Feed the markdown in small pieces, as a stream would
+	var out strings.Builder
+	printer := NewPlainPrinter(&out)
+	for i := 0; i < len(markdown); i += 3 {
+		printer.Print(markdown[i:min(i+3, len(markdown))])
+	}
+	printer.Flush()
+
+	if out.String() != want {
+		t.Errorf("output = %q, want %q", out.String(), want)
+	}
+}
+
+func TestPlainPrinterFlushesPartialLine(t *testing.T) {
+	var out strings.Builder
+	printer := NewPlainPrinter(&out)
+	printer.Print("no **newline**")
+	if out.Len() != 0 {
+		t.Errorf("Print() wrote %q before the line was complete", out.String())
+	}
+	printer.Flush()
+	if out.String() != "no newline" {
+		t.Errorf("Flush() wrote %q, want %q", out.String(), "no newline")
+	}
+}
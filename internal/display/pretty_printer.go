@@ -1,7 +1,10 @@
 package display
 
 import (
+	"bufio"
 	"fmt"
+	"io"
+	"os"
 	"regexp"
 	"strings"
 )
@@ -16,6 +19,7 @@ const (
 
 // PrettyPrinter handles pretty printing of markdown text
 type PrettyPrinter struct {
+	out                 *bufio.Writer
 	originalColor       int
 	isBoldSupported     bool
 	reformattedMarkdown bool
@@ -32,6 +36,21 @@ type PrettyPrinter struct {
 	horizontalRuleRegex *regexp.Regexp
 	syntaxHighlighter   *SyntaxHighlighter
 	currentLanguage     string
+	maxCodeBlockLines   int
+	codeBlockLines      int
+	hiddenLines         int
+	// wholeBlock is set for code blocks whose parser takes whole blocks,
+	// such as a plugin; their lines are held back until the block closes
+	wholeBlock bool
+	heldLines  []string
+	accessible bool
+	// snapshot replaces colours with markers of the structure they show
+	snapshot bool
+	// dest is where out writes to, through a gutter if one is set
+	dest io.Writer
+	// renderLog, if set, is sent everything printed, to record a snapshot
+	renderLog       *PrettyPrinter
+	renderLogCloser io.Closer
 }
 
 // NewPrettyPrinter creates a new pretty printer
@@ -40,6 +59,8 @@ func NewPrettyPrinter() *PrettyPrinter {
 	InitializeColors()
 
 	p := &PrettyPrinter{
+		out:             bufio.NewWriter(os.Stdout),
+		dest:            os.Stdout,
 		originalColor:   0, // Not used in Go implementation
 		isBoldSupported: IsBoldSupported(),
 		currentState:    Normal,
@@ -61,28 +82,125 @@ func NewPrettyPrinter() *PrettyPrinter {
 	return p
 }
 
+// NewSnapshotPrinter creates a pretty printer that writes a stable, plain
+// text snapshot of what it would show to out, for golden tests and logs.
+// Colours are left out, and the structure they show is marked instead, as
+// <h2>, <code lang=go>, <em> and so on. Code is written as it is.
+func NewSnapshotPrinter(out io.Writer) *PrettyPrinter {
+	p := NewPrettyPrinter()
+	p.out = bufio.NewWriter(out)
+	p.dest = out
+	p.snapshot = true
+	return p
+}
+
+// SetRenderLog also writes a snapshot of everything printed to w, as
+// NewSnapshotPrinter would, to debug how an answer was rendered. If w is an
+// io.Closer, Close closes it.
+func (p *PrettyPrinter) SetRenderLog(w io.Writer) {
+	p.renderLog = NewSnapshotPrinter(w)
+	p.renderLog.SetMaxCodeBlockLines(p.maxCodeBlockLines)
+	p.renderLogCloser, _ = w.(io.Closer)
+}
+
 // Close cleans up the pretty printer
 func (p *PrettyPrinter) Close() {
-	fmt.Print(ResetFormat)
+	p.style(ResetFormat)
+	p.out.Flush()
+
+	if p.renderLog != nil {
+		p.renderLog.Close()
+		if p.renderLogCloser != nil {
+			p.renderLogCloser.Close()
+		}
+		p.renderLog = nil
+	}
+}
+
+// style writes a colour or format, which a snapshot leaves out
+func (p *PrettyPrinter) style(format string) {
+	if !p.snapshot {
+		fmt.Fprint(p.out, format)
+	}
+}
+
+// mark writes a structure marker, which only a snapshot shows
+func (p *PrettyPrinter) mark(marker string) {
+	if p.snapshot {
+		fmt.Fprint(p.out, marker)
+	}
+}
+
+// SetMaxCodeBlockLines limits how many lines of each code block are shown.
+// Lines beyond the limit are replaced with a footer noting how many were hidden.
+// A value of zero or less disables collapsing.
+func (p *PrettyPrinter) SetMaxCodeBlockLines(n int) {
+	p.maxCodeBlockLines = n
+	if p.renderLog != nil {
+		p.renderLog.SetMaxCodeBlockLines(n)
+	}
+}
+
+// SetGutter starts each line printed from now on that has text with prefix,
+// such as a RoleGutter. An empty prefix prints lines as they are.
+func (p *PrettyPrinter) SetGutter(prefix string) {
+	p.out.Flush()
+	if prefix == "" {
+		p.out = bufio.NewWriter(p.dest)
+		return
+	}
+	p.out = bufio.NewWriter(&gutterWriter{w: p.dest, prefix: prefix})
+}
+
+// SetAccessible turns accessible output on or off. Code blocks, headings and
+// quotes are labelled, as [CODE go], [HEADING 2] and [QUOTE], rather than
+// shown by colour alone; markdown such as **bold** is left as written; and no
+// box-drawing characters are used. This suits screen readers and braille
+// terminals.
+func (p *PrettyPrinter) SetAccessible(accessible bool) {
+	p.accessible = accessible
+	p.reformattedMarkdown = !accessible
 }
 
 // Flush prints any remaining content in the line buffer
 func (p *PrettyPrinter) Flush() {
+	if p.renderLog != nil {
+		p.renderLog.Flush()
+	}
+
 	if p.lineBuffer.Len() > 0 {
 		var line string = p.lineBuffer.String()
-		p.processLine(line)
+		printed := p.processLine(line)
 		p.lineBuffer.Reset()
-		if !strings.HasSuffix(line, "\n") {
-			fmt.Println()
+		if printed && !strings.HasSuffix(line, "\n") {
+			fmt.Fprintln(p.out)
+		}
+	}
+
+	// A code block that never closed (e.g. extracted with -c) still needs its footer
+	if p.currentState == InCodeBlock {
+		p.printHeldLines()
+		p.printCollapsedFooter()
+		if p.snapshot {
+			// Close the block in the snapshot, as its fence would have
+			fmt.Fprintln(p.out, "</code>")
+			p.currentState = Normal
 		}
 	}
+
+	p.out.Flush()
 }
 
-// Print prints the text with pretty formatting
+// Print prints the text with pretty formatting. Output is buffered and
+// written once per call, so that streamed text still appears promptly.
 func (p *PrettyPrinter) Print(text string) {
 	if len(text) == 0 {
 		return
 	}
+	if p.renderLog != nil {
+		p.renderLog.Print(text)
+	}
+	defer p.out.Flush()
 
 	if !strings.Contains(text, "\n") {
 		p.lineBuffer.WriteString(text)
@@ -108,19 +226,15 @@ func (p *PrettyPrinter) Print(text string) {
 			return
 		}
 
-		p.processLine(line)
-		if !isLastLine {
-			fmt.Println()
+		if p.processLine(line) {
+			fmt.Fprintln(p.out)
 		}
 	}
-
-	if isTerminated {
-		fmt.Println()
-	}
 }
 
-// processLine processes a single line of text
-func (p *PrettyPrinter) processLine(line string) {
+// processLine processes a single line of text, returning false if the line
+// was hidden rather than printed
+func (p *PrettyPrinter) processLine(line string) bool {
 	if strings.Contains(line, "\r") {
 		line = strings.ReplaceAll(line, "\r", "")
 	}
@@ -131,37 +245,115 @@ func (p *PrettyPrinter) processLine(line string) {
 			language := p.syntaxHighlighter.ExtractLanguage(line)
 			p.currentLanguage = language
 
-			fmt.Print(MdCodeBlockColor)
-			fmt.Print(line)
-			p.currentState = InCodeBlock
-			return
+			p.style(MdCodeBlockColor)
+			if p.snapshot {
+				fmt.Fprint(p.out, codeMarker(language))
+			} else if p.accessible {
+				fmt.Fprint(p.out, strings.TrimRight("[CODE "+language, " ")+"]")
+			} else {
+				fmt.Fprint(p.out, line)
+			}
+			p.startCodeBlock(language)
+			return true
 		}
 
 		p.processNormalLine(line)
 	} else { // InCodeBlock
 		if p.codeBlockEndRegex.MatchString(line) {
-			fmt.Print(MdCodeBlockColor)
-			fmt.Print(line)
+			p.printHeldLines()
+			p.printCollapsedFooter()
+			p.style(MdCodeBlockColor)
+			if p.snapshot {
+				fmt.Fprint(p.out, "</code>")
+			} else if p.accessible {
+				fmt.Fprint(p.out, "[END CODE]")
+			} else {
+				fmt.Fprint(p.out, line)
+			}
 			p.currentState = Normal
 			p.currentLanguage = ""
-			return
+			return true
+		}
+
+		p.codeBlockLines++
+		if p.maxCodeBlockLines > 0 && p.codeBlockLines > p.maxCodeBlockLines {
+			p.hiddenLines++
+			return false
 		}
 
 		// Apply syntax highlighting if we have a language
-		if p.currentLanguage != "" {
-			highlightedLine := p.syntaxHighlighter.HighlightCode(line, p.currentLanguage)
-			fmt.Print(highlightedLine)
+		if p.snapshot {
+			fmt.Fprint(p.out, line)
+		} else if p.wholeBlock {
+			p.heldLines = append(p.heldLines, line)
+			return false
+		} else if p.currentLanguage != "" {
+			highlightedLine := p.syntaxHighlighter.HighlightLine(line, p.currentLanguage)
+			fmt.Fprint(p.out, highlightedLine)
 		} else {
 			// Default to cyan for code blocks without a language
-			fmt.Print(MdCodeBlockColor)
-			fmt.Print(line)
+			fmt.Fprint(p.out, MdCodeBlockColor)
+			fmt.Fprint(p.out, line)
 		}
 	}
+
+	return true
+}
+
+// startCodeBlock resets the state kept for each code block
+func (p *PrettyPrinter) startCodeBlock(language string) {
+	p.currentState = InCodeBlock
+	p.codeBlockLines = 0
+	p.hiddenLines = 0
+	p.heldLines = nil
+	p.wholeBlock = language != "" && !p.snapshot && p.syntaxHighlighter.HighlightsWholeBlocks(language)
+	p.syntaxHighlighter.ResetLineState()
+}
+
+// printHeldLines highlights and prints the lines of a code block held back
+// for a parser that takes whole blocks
+func (p *PrettyPrinter) printHeldLines() {
+	if len(p.heldLines) == 0 {
+		return
+	}
+	fmt.Fprintln(p.out, p.syntaxHighlighter.HighlightCode(strings.Join(p.heldLines, "\n"), p.currentLanguage))
+	p.heldLines = nil
+}
+
+// printCollapsedFooter prints a note about code block lines hidden by
+// SetMaxCodeBlockLines, if there were any
+func (p *PrettyPrinter) printCollapsedFooter() {
+	if p.hiddenLines == 0 {
+		return
+	}
+
+	noun := "lines"
+	if p.hiddenLines == 1 {
+		noun = "line"
+	}
+	ellipsis := "…"
+	if p.accessible {
+		ellipsis = "..."
+	}
+	p.style(TokenCommentColor)
+	p.mark("<collapsed>")
+	fmt.Fprintf(p.out, "%s %d more %s (rerun without --collapse to print)", ellipsis, p.hiddenLines, noun)
+	p.mark("</collapsed>")
+	p.style(ResetFormat)
+	fmt.Fprintln(p.out)
+	p.hiddenLines = 0
 }
 
 func (p *PrettyPrinter) SetCodeBlockState(language string) {
+	if p.renderLog != nil {
+		p.renderLog.SetCodeBlockState(language)
+	}
+	if p.snapshot && p.currentState == Normal {
+		fmt.Fprintln(p.out, codeMarker(language))
+	}
+
 	p.currentLanguage = language
-	p.currentState = InCodeBlock
+	p.startCodeBlock(language)
 }
 
 // processNormalLine processes a line in normal (non-code-block) state
@@ -196,20 +388,36 @@ func (p *PrettyPrinter) processNormalLine(line string) {
 
 // printHeader prints a header line
 func (p *PrettyPrinter) printHeader(line string) {
-	fmt.Print(MdHeaderColor)
-	fmt.Print(line)
-	fmt.Print(ResetFormat)
+	if p.snapshot {
+		text := strings.TrimLeft(line, "#")
+		level := len(line) - len(text)
+		fmt.Fprintf(p.out, "<h%d>%s</h%d>", level, strings.TrimSpace(text), level)
+		return
+	}
+
+	fmt.Fprint(p.out, MdHeaderColor)
+	if p.accessible {
+		text := strings.TrimLeft(line, "#")
+		line = fmt.Sprintf("[HEADING %d] %s", len(line)-len(text), strings.TrimSpace(text))
+	}
+	fmt.Fprint(p.out, line)
+	fmt.Fprint(p.out, ResetFormat)
 }
 
 // printHorizontalRule prints a horizontal rule
 func (p *PrettyPrinter) printHorizontalRule(line string) {
-	fmt.Print(MdHeaderColor)
+	if p.snapshot {
+		fmt.Fprint(p.out, "<hr>")
+		return
+	}
+
+	fmt.Fprint(p.out, MdHeaderColor)
 	if p.reformattedMarkdown {
-		fmt.Print(strings.Repeat("─", 20))
+		fmt.Fprint(p.out, strings.Repeat("─", 20))
 	} else {
-		fmt.Print(line)
+		fmt.Fprint(p.out, line)
 	}
-	fmt.Print(ResetFormat)
+	fmt.Fprint(p.out, ResetFormat)
 }
 
 // printBlockQuote prints a block quote
@@ -220,11 +428,21 @@ func (p *PrettyPrinter) printBlockQuote(line string) {
 		quote := matches[2]
 		content := matches[3]
 
-		fmt.Print(indentation)
-		fmt.Print(MdBlockQuoteColor)
-		fmt.Print(quote)
-		fmt.Print(ResetFormat)
+		levels := strings.Count(quote, ">")
+		if p.accessible {
+			// Nested quotes are labelled once for each level
+			quote = strings.Repeat("[QUOTE] ", levels)
+		}
+		if p.snapshot {
+			quote = strings.Repeat("<quote>", levels)
+		}
+
+		fmt.Fprint(p.out, indentation)
+		p.style(MdBlockQuoteColor)
+		fmt.Fprint(p.out, quote)
+		p.style(ResetFormat)
 		p.printFormattedText(content)
+		p.mark(strings.Repeat("</quote>", levels))
 	}
 }
 
@@ -236,12 +454,14 @@ func (p *PrettyPrinter) printNumberedList(line string) {
 		number := matches[2]
 		content := matches[3]
 
-		fmt.Print(indentation)
-		fmt.Print(MdListMarkerColor)
-		fmt.Print(number)
-		fmt.Print(ResetFormat)
-		fmt.Print(" ")
+		fmt.Fprint(p.out, indentation)
+		p.mark("<li>")
+		p.style(MdListMarkerColor)
+		fmt.Fprint(p.out, number)
+		p.style(ResetFormat)
+		fmt.Fprint(p.out, " ")
 		p.printFormattedText(content)
+		p.mark("</li>")
 	}
 }
 
@@ -253,12 +473,14 @@ func (p *PrettyPrinter) printUnorderedList(line string) {
 		bullet := matches[2]
 		content := matches[3]
 
-		fmt.Print(indentation)
-		fmt.Print(MdListMarkerColor)
-		fmt.Print(bullet)
-		fmt.Print(ResetFormat)
-		fmt.Print(" ")
+		fmt.Fprint(p.out, indentation)
+		p.mark("<li>")
+		p.style(MdListMarkerColor)
+		fmt.Fprint(p.out, bullet)
+		p.style(ResetFormat)
+		fmt.Fprint(p.out, " ")
 		p.printFormattedText(content)
+		p.mark("</li>")
 	}
 }
 
@@ -306,22 +528,24 @@ func (p *PrettyPrinter) printFormattedText(line string) {
 		matchText := line[m.index : m.index+m.length]
 		// Print text before the match
 		if m.index > lastIndex {
-			fmt.Print(MdNormalTextColor)
-			fmt.Print(line[lastIndex:m.index])
+			p.style(MdNormalTextColor)
+			fmt.Fprint(p.out, line[lastIndex:m.index])
 		}
 
 		// Print the match with appropriate formatting
 		if m.typ == "code" {
-			fmt.Print(MdInlineCodeColor)
+			p.style(MdInlineCodeColor)
 			if p.reformattedMarkdown {
 				// Skip the first and last backtick characters
 				if len(matchText) >= 2 {
 					matchText = matchText[1 : len(matchText)-1]
 				}
 			}
-			fmt.Print(matchText)
+			p.mark("<code>")
+			fmt.Fprint(p.out, matchText)
+			p.mark("</code>")
 		} else if m.typ == "emphasis" {
-			fmt.Print(MdEmphasisColor)
+			p.style(MdEmphasisColor)
 			numberOfAsterisks := strings.Count(matchText, "*")
 			isItalic := numberOfAsterisks != 4
 			isBold := numberOfAsterisks > 2
@@ -329,17 +553,21 @@ func (p *PrettyPrinter) printFormattedText(line string) {
 				// remove asterisks from the match text
 				matchText = strings.ReplaceAll(matchText, "*", "")
 			}
-			if p.isBoldSupported {
+			if p.snapshot {
+				fmt.Fprint(p.out, emphasisMarker(isBold, isItalic, false))
+				fmt.Fprint(p.out, matchText)
+				fmt.Fprint(p.out, emphasisMarker(isBold, isItalic, true))
+			} else if p.isBoldSupported {
 				if isBold {
-					fmt.Print(BoldFormat)
+					fmt.Fprint(p.out, BoldFormat)
 				}
 				if isItalic {
-					fmt.Print(ItalicFormat)
+					fmt.Fprint(p.out, ItalicFormat)
 				}
-				fmt.Print(matchText)
-				fmt.Print(ResetFormat + MdNormalTextColor) // Reset bold but keep color
+				fmt.Fprint(p.out, matchText)
+				fmt.Fprint(p.out, ResetFormat+MdNormalTextColor) // Reset bold but keep color
 			} else {
-				fmt.Print(matchText)
+				fmt.Fprint(p.out, matchText)
 			}
 		}
 
@@ -348,9 +576,38 @@ func (p *PrettyPrinter) printFormattedText(line string) {
 
 	// Print remaining text
 	if lastIndex < len(line) {
-		fmt.Print(MdNormalTextColor)
-		fmt.Print(line[lastIndex:])
+		p.style(MdNormalTextColor)
+		fmt.Fprint(p.out, line[lastIndex:])
 	}
 
-	fmt.Print(ResetFormat)
+	p.style(ResetFormat)
+}
+
+// codeMarker returns the snapshot marker for the start of a code block
+func codeMarker(language string) string {
+	if language == "" {
+		return "<code>"
+	}
+	return "<code lang=" + language + ">"
+}
+
+// emphasisMarker returns the snapshot marker that opens, or with end closes,
+// emphasised text
+func emphasisMarker(isBold bool, isItalic bool, end bool) string {
+	slash := ""
+	if end {
+		slash = "/"
+	}
+	marker := ""
+	if isBold {
+		marker += "<" + slash + "strong>"
+	}
+	if isItalic {
+		if end {
+			marker = "<" + slash + "em>" + marker
+		} else {
+			marker += "<em>"
+		}
+	}
+	return marker
 }
@@ -0,0 +1,217 @@
+package display
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"flag"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/rba100/aipipe/internal/parsing"
+	"github.com/rba100/aipipe/internal/stream"
+	"github.com/rba100/aipipe/internal/util"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files in testdata")
+
+// TestSnapshots renders each markdown file in testdata/snapshots, a few bytes
+// at a time as a model would stream it, and compares the snapshot with the
+// .golden file beside it. Run with -update to accept changes.
+func TestSnapshots(t *testing.T) {
+	inputs, err := filepath.Glob(filepath.Join("testdata", "snapshots", "*.md"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(inputs) == 0 {
+		t.Fatal("no snapshots in testdata/snapshots")
+	}
+
+	for _, input := range inputs {
+		t.Run(filepath.Base(input), func(t *testing.T) {
+			markdown, err := os.ReadFile(input)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var buf bytes.Buffer
+			printer := NewSnapshotPrinter(&buf)
+			for rest := string(markdown); len(rest) > 0; {
+				n := min(5, len(rest))
+				printer.Print(rest[:n])
+				rest = rest[n:]
+			}
+			printer.Flush()
+			printer.Close()
+
+			golden := strings.TrimSuffix(input, ".md") + ".golden"
+			if *update {
+				if err := os.WriteFile(golden, buf.Bytes(), 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			expected, err := os.ReadFile(golden)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if buf.String() != string(expected) {
+				t.Errorf("snapshot of %s differs from %s:\n%s", input, golden, buf.String())
+			}
+		})
+	}
+}
+
+func TestRenderLog(t *testing.T) {
+	var shown, logged bytes.Buffer
+	printer := NewPrettyPrinter()
+	printer.out = bufio.NewWriter(&shown)
+	printer.SetRenderLog(&logged)
+	printer.SetCodeBlockState("go")
+	printer.Print("x := 1\n")
+	printer.Flush()
+	printer.Close()
+
+	if expected := "<code lang=go>\nx := 1\n</code>\n"; logged.String() != expected {
+		t.Errorf("render log = %q, want %q", logged.String(), expected)
+	}
+	if !strings.Contains(util.StripANSI(shown.String()), "x := 1") {
+		t.Errorf("printed %q, want the code", shown.String())
+	}
+}
+
+// wholeBlockParser is a parser that takes whole blocks, like a plugin,
+// recording the code it is given
+type wholeBlockParser struct {
+	parsed []string
+}
+
+func (p *wholeBlockParser) Parse(code string) (parsing.TokenSequence, error) {
+	p.parsed = append(p.parsed, code)
+	return parsing.TokenSequence{{Type: parsing.TokenKeyword, Text: code}}, nil
+}
+
+func (p *wholeBlockParser) ParsesWholeBlocks() bool {
+	return true
+}
+
+func TestPrettyPrinterWholeBlockParser(t *testing.T) {
+	parser := &wholeBlockParser{}
+	parsing.RegisterParser("wholeblocktest", parser)
+
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{
+			name:     "Closed block",
+			input:    "```wholeblocktest\nfirst line\nsecond line\n```\nafter\n",
+			expected: "```wholeblocktest\nfirst line\nsecond line\n```\nafter\n",
+		},
+		{
+			name:     "Block left open",
+			input:    "```wholeblocktest\nfirst line\nsecond line",
+			expected: "```wholeblocktest\nfirst line\nsecond line\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parser.parsed = nil
+			var buf bytes.Buffer
+			printer := NewPrettyPrinter()
+			printer.out = bufio.NewWriter(&buf)
+			for rest := tt.input; len(rest) > 0; {
+				n := min(3, len(rest))
+				printer.Print(rest[:n])
+				rest = rest[n:]
+			}
+			printer.Flush()
+
+			if result := util.StripANSI(buf.String()); result != tt.expected {
+				t.Errorf("Print(%q) = %q, want %q", tt.input, result, tt.expected)
+			}
+			if len(parser.parsed) != 1 || parser.parsed[0] != "first line\nsecond line" {
+				t.Errorf("The parser was given %q, want the whole block once", parser.parsed)
+			}
+		})
+	}
+}
+
+func TestPrettyPrinterAccessible(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{
+			name:     "Heading",
+			input:    "## Usage\n",
+			expected: "[HEADING 2] Usage\n",
+		},
+		{
+			name:     "Code block",
+			input:    "```go\nx := 1\n```\n",
+			expected: "[CODE go]\nx := 1\n[END CODE]\n",
+		},
+		{
+			name:     "Code block without a language",
+			input:    "```\nls\n```\n",
+			expected: "[CODE]\nls\n[END CODE]\n",
+		},
+		{
+			name:     "Nested quote",
+			input:    "> > quoted\n",
+			expected: "[QUOTE] [QUOTE] quoted\n",
+		},
+		{
+			name:     "Markdown left as written",
+			input:    "use **bold** and `code`\n---\n",
+			expected: "use **bold** and `code`\n---\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			printer := NewPrettyPrinter()
+			printer.out = bufio.NewWriter(&buf)
+			printer.SetAccessible(true)
+			printer.Print(tt.input)
+			printer.Flush()
+
+			if result := util.StripANSI(buf.String()); result != tt.expected {
+				t.Errorf("Print(%q) = %q, want %q", tt.input, result, tt.expected)
+			}
+		})
+	}
+}
+
+// BenchmarkStreamedReply measures the path a streamed reply takes with -p:
+// small parts, as a model streams them, through the think tag stripper to the
+// pretty printer
+func BenchmarkStreamedReply(b *testing.B) {
+	reply := "<think>The user wants a refund check.</think>\n" + strings.Repeat(
+		"## Refunds\n\nOrders over **100** are refunded by `refund()`:\n\n```python\nif order.amount > 100 and not order.cancelled:  # skip refunds\n    refund(order)\n```\n\n- one\n- two\n\n", 100)
+	var parts []string
+	for rest := reply; len(rest) > 0; {
+		n := min(4, len(rest))
+		parts = append(parts, rest[:n])
+		rest = rest[n:]
+	}
+	ctx := context.Background()
+	b.SetBytes(int64(len(reply)))
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		printer := NewPrettyPrinter()
+		printer.out = bufio.NewWriter(io.Discard)
+		for part := range util.StripThinkTagsStream(ctx, stream.Of(parts...)) {
+			printer.Print(part)
+		}
+		printer.Flush()
+	}
+}
@@ -0,0 +1,99 @@
+package display
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// previewTabWidth is the tab stop spacing assumed when counting columns
+const previewTabWidth = 8
+
+// Preview shows provisional text, greyed out, until the real answer replaces
+// it. Lines are wrapped by the preview itself so it knows how many rows to
+// erase, and text stops being shown once the screen is full, since rows that
+// have scrolled off the top can't be erased.
+type Preview struct {
+	out     io.Writer
+	maxRows int
+	columns int
+	// row and column are the cursor position relative to where the preview
+	// started
+	row    int
+	column int
+	full   bool
+	// written is true once anything has been shown
+	written bool
+}
+
+// NewPreview creates a preview for a terminal of the given size, written to
+// out. The cursor must be at the start of a line.
+func NewPreview(out io.Writer, rows int, columns int) *Preview {
+	InitializeColors()
+	return &Preview{out: out, maxRows: rows - 1, columns: columns}
+}
+
+// Write shows text as part of the preview
+func (p *Preview) Write(text string) {
+	if p.full || p.maxRows < 1 || p.columns < 1 {
+		return
+	}
+
+	var shown strings.Builder
+	newLine := func() bool {
+		if p.row+1 >= p.maxRows {
+			p.full = true
+			return false
+		}
+		p.row++
+		p.column = 0
+		shown.WriteString("\r\n")
+		return true
+	}
+
+	for _, r := range text {
+		switch {
+		case r == '\n':
+			newLine()
+		case r == '\t':
+			spaces := previewTabWidth - p.column%previewTabWidth
+			if p.column+spaces > p.columns {
+				spaces = p.columns - p.column
+			}
+			shown.WriteString(strings.Repeat(" ", spaces))
+			p.column += spaces
+		case r < ' ' || r == 0x7f:
+			// Control characters would move the cursor unpredictably
+			continue
+		default:
+			if p.column >= p.columns && !newLine() {
+				break
+			}
+			shown.WriteRune(r)
+			p.column++
+		}
+		if p.full {
+			break
+		}
+	}
+
+	if shown.Len() > 0 {
+		fmt.Fprint(p.out, TokenCommentColor+shown.String()+ResetFormat)
+		p.written = true
+	}
+}
+
+// Clear erases the preview, leaving the cursor where it started
+func (p *Preview) Clear() {
+	if !p.written {
+		return
+	}
+
+	fmt.Fprint(p.out, "\r")
+	if p.row > 0 {
+		fmt.Fprintf(p.out, "\033[%dA", p.row)
+	}
+	fmt.Fprint(p.out, "\033[J")
+
+	p.row, p.column, p.full, p.written = 0, 0, false, false
+}
@@ -0,0 +1,53 @@
+package display
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestPreviewWrapsAndClears(t *testing.T) {
+	var out strings.Builder
+	preview := NewPreview(&out, 10, 5)
+
+	preview.Write("abcdefg\nhi")
+	shown := out.String()
+	if !strings.Contains(shown, "abcde\r\nfg\r\nhi") {
+		t.Errorf("Write() output = %q, want lines wrapped at 5 columns", shown)
+	}
+
+	out.Reset()
+	preview.Clear()
+	if out.String() != "\r\033[2A\033[J" {
+		t.Errorf("Clear() output = %q, want the cursor moved up 2 rows and the screen cleared", out.String())
+	}
+}
+
+func TestPreviewStopsWhenScreenIsFull(t *testing.T) {
+	var out strings.Builder
+	preview := NewPreview(&out, 3, 80)
+
+	preview.Write("one\ntwo\nthree\nfour")
+	if strings.Contains(out.String(), "three") {
+		t.Errorf("Write() output = %q, want it to stop before scrolling the screen", out.String())
+	}
+
+	out.Reset()
+	preview.Write("more")
+	if out.Len() != 0 {
+		t.Errorf("Write() after the screen is full wrote %q", out.String())
+	}
+
+	preview.Clear()
+	if !strings.Contains(out.String(), "\033[1A") {
+		t.Errorf("Clear() output = %q, want the cursor moved up 1 row", out.String())
+	}
+}
+
+func TestPreviewClearWithoutText(t *testing.T) {
+	var out strings.Builder
+	preview := NewPreview(&out, 10, 80)
+	preview.Clear()
+	if out.Len() != 0 {
+		t.Errorf("Clear() with nothing shown wrote %q", out.String())
+	}
+}
@@ -0,0 +1,25 @@
+package display
+
+import "strings"
+
+// sparkBars are the bars of a sparkline, lowest first
+var sparkBars = []rune("▁▂▃▄▅▆▇█")
+
+// Sparkline returns a bar for each value, scaled so the largest value is the
+// tallest bar. Zero values are a space, so gaps stand out.
+func Sparkline(values []int) string {
+	highest := 0
+	for _, value := range values {
+		highest = max(highest, value)
+	}
+
+	var builder strings.Builder
+	for _, value := range values {
+		if value <= 0 {
+			builder.WriteRune(' ')
+			continue
+		}
+		builder.WriteRune(sparkBars[(value*len(sparkBars)-1)/highest])
+	}
+	return builder.String()
+}
@@ -0,0 +1,24 @@
+package display
+
+import "testing"
+
+func TestSparkline(t *testing.T) {
+	tests := []struct {
+		name     string
+		values   []int
+		expected string
+	}{
+		{"Empty", nil, ""},
+		{"Rising", []int{1, 2, 3, 4, 5, 6, 7, 8}, "▁▂▃▄▅▆▇█"},
+		{"Gaps", []int{0, 10, 0, 5}, " █ ▄"},
+		{"Small counts", []int{1, 1, 2}, "▄▄█"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Sparkline(tt.values); got != tt.expected {
+				t.Errorf("Sparkline(%v) = %q, expected %q", tt.values, got, tt.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,72 @@
+package display
+
+import (
+	"fmt"
+	"io"
+	"sync/atomic"
+	"time"
+
+	"github.com/rba100/aipipe/internal/i18n"
+	"github.com/rba100/aipipe/internal/tokenizer"
+)
+
+// StatusLine shows the model, elapsed time and an estimate of the tokens
+// received on the bottom row of the terminal while a response streams. The
+// rest of the terminal scrolls above it.
+type StatusLine struct {
+	out   io.Writer
+	rows  int
+	model string
+	start time.Time
+	// tokenizer estimates the tokens in each part of the response
+	tokenizer tokenizer.Tokenizer
+	// tokens counts the tokens received, written by Count from any goroutine
+	tokens atomic.Int64
+}
+
+// NewStatusLine creates a status line for a terminal with the given number of
+// rows, written to out
+func NewStatusLine(out io.Writer, rows int, model string) *StatusLine {
+	InitializeColors()
+	return &StatusLine{out: out, rows: rows, model: model, tokenizer: tokenizer.ForModel(model)}
+}
+
+// Start reserves the bottom row of the terminal and draws the status line
+func (s *StatusLine) Start() {
+	s.start = time.Now()
+
+	// Make sure the cursor isn't on the bottom row, then limit scrolling to
+	// the rows above it. Setting the scroll region moves the cursor, so it is
+	// saved and restored around it.
+	fmt.Fprint(s.out, "\n\033[1A")
+	fmt.Fprintf(s.out, "\0337\033[1;%dr\0338", s.rows-1)
+	s.Draw()
+}
+
+// Count records streamed text towards the token estimate. It is safe to call
+// from any goroutine.
+func (s *StatusLine) Count(text string) {
+	s.tokens.Add(int64(s.tokenizer.Count(text)))
+}
+
+// Draw redraws the status line
+func (s *StatusLine) Draw() {
+	fmt.Fprintf(s.out, "\0337\033[%d;1H\033[2K%s%s%s\0338", s.rows, TokenCommentColor, s.text(time.Now()), ResetFormat)
+}
+
+// Clear removes the status line and gives the bottom row back to the output
+func (s *StatusLine) Clear() {
+	fmt.Fprintf(s.out, "\0337\033[%d;1H\033[2K\033[r\0338", s.rows)
+}
+
+// text formats the status line as it should appear at the given time
+func (s *StatusLine) text(now time.Time) string {
+	elapsed := now.Sub(s.start)
+	tokens := s.tokens.Load()
+
+	text := i18n.T("%s · %.1fs · ~%d tokens", s.model, elapsed.Seconds(), tokens)
+	if seconds := elapsed.Seconds(); seconds >= 1 {
+		text += i18n.T(" · %.1f tok/s", float64(tokens)/seconds)
+	}
+	return text
+}
@@ -0,0 +1,38 @@
+package display
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestStatusLineText(t *testing.T) {
+	status := NewStatusLine(&bytes.Buffer{}, 24, "gpt-4o")
+	status.start = time.Date(2024, time.January, 1, 12, 0, 0, 0, time.UTC)
+
+	if got := status.text(status.start.Add(500 * time.Millisecond)); got != "gpt-4o · 0.5s · ~0 tokens" {
+		t.Errorf("text() = %q", got)
+	}
+
+	status.Count(strings.Repeat("The quick brown fox jumps over the lazy dog. ", 10))
+	if got := status.text(status.start.Add(4 * time.Second)); got != "gpt-4o · 4.0s · ~96 tokens · 24.0 tok/s" {
+		t.Errorf("text() = %q", got)
+	}
+}
+
+func TestStatusLineEscapes(t *testing.T) {
+	var out bytes.Buffer
+	status := NewStatusLine(&out, 24, "model")
+
+	status.Start()
+	if !strings.Contains(out.String(), "\033[1;23r") {
+		t.Errorf("Start() output %q doesn't reserve the bottom row", out.String())
+	}
+
+	out.Reset()
+	status.Clear()
+	if !strings.Contains(out.String(), "\033[24;1H\033[2K") || !strings.Contains(out.String(), "\033[r") {
+		t.Errorf("Clear() output %q doesn't clear the row and reset the scroll region", out.String())
+	}
+}
@@ -23,6 +23,12 @@ const (
 	TokenComment
 	// TokenWhitespace represents spaces, tabs, newlines
 	TokenWhitespace
+	// TokenInserted represents lines added by a diff
+	TokenInserted
+	// TokenDeleted represents lines removed by a diff
+	TokenDeleted
+	// TokenHeading represents section headings, such as a diff's hunk headers
+	TokenHeading
 )
 
 // Token represents a single token in the parsed code
@@ -37,6 +43,9 @@ type Token struct {
 type SyntaxHighlighter struct {
 	languageRegex   *regexp.Regexp
 	currentLanguage string
+	// lineState is the state returned by a LineParser for the last line
+	// passed to HighlightLine
+	lineState parsing.LineState
 }
 
 // NewSyntaxHighlighter creates a new syntax highlighter
@@ -48,10 +57,20 @@ func NewSyntaxHighlighter() *SyntaxHighlighter {
 	return h
 }
 
+// getParser returns the parser used to highlight the language, or nil
+func getParser(language string) parsing.Parser {
+	parser := parsing.GetParser(language)
+	if parser == nil {
+		// Fall back to the chroma lexers for languages we don't have a parser for
+		parser = parsing.GetChromaParser(language)
+	}
+	return parser
+}
+
 // HighlightCode highlights code based on the language identifier
 func (h *SyntaxHighlighter) HighlightCode(code string, language string) string {
 	// Get the parser for the specified language
-	parser := parsing.GetParser(language)
+	parser := getParser(language)
 	if parser == nil {
 		// For unsupported languages, just return the code as is
 		return code
@@ -64,32 +83,82 @@ func (h *SyntaxHighlighter) HighlightCode(code string, language string) string {
 		return code
 	}
 
-	// Convert parsing.Token to display.Token
-	var tokens []Token
-	for _, token := range parsingTokens {
-		tokens = append(tokens, Token{
-			Type: TokenType(token.Type), // TokenType enums match between packages
-			Text: token.Text,
-		})
+	return renderTokens(parsingTokens, len(code))
+}
+
+// HighlightLine highlights one line of a code block. Unlike HighlightCode it
+// carries parser state from the previous line, so constructs spanning lines
+// such as Bash here-documents are highlighted correctly. Call ResetLineState
+// at the start of each code block.
+func (h *SyntaxHighlighter) HighlightLine(line string, language string) string {
+	parser := getParser(language)
+	if parser == nil {
+		return line
+	}
+
+	lineParser, ok := parser.(parsing.LineParser)
+	if !ok {
+		return h.HighlightCode(line, language)
+	}
+
+	parsingTokens, state, err := lineParser.ParseLine(line, h.lineState)
+	if err != nil {
+		h.lineState = nil
+		return line
 	}
+	h.lineState = state
+
+	return renderTokens(parsingTokens, len(line))
+}
 
-	// Build the highlighted code
+// HighlightsWholeBlocks reports whether code in the language must be
+// highlighted a whole block at a time with HighlightCode, because its parser
+// is too slow to run for each line
+func (h *SyntaxHighlighter) HighlightsWholeBlocks(language string) bool {
+	return parsing.ParsesWholeBlocks(getParser(language))
+}
+
+// ResetLineState discards the parser state kept by HighlightLine
+func (h *SyntaxHighlighter) ResetLineState() {
+	h.lineState = nil
+}
+
+// renderTokens colors a token sequence for the terminal. size is the length
+// of the source text, used to size the output buffer.
+func renderTokens(parsingTokens parsing.TokenSequence, size int) string {
+	// Build the highlighted code. TokenType enums match between packages, so
+	// the parsing tokens can be used directly.
 	var highlighted strings.Builder
-	for _, token := range tokens {
-		switch token.Type {
+	highlighted.Grow(size * 2)
+	for _, token := range parsingTokens {
+		color := ""
+		switch TokenType(token.Type) {
 		case TokenKeyword:
-			highlighted.WriteString(TokenKeywordColor + token.Text + ResetFormat)
+			color = TokenKeywordColor
 		case TokenIdentifier:
-			highlighted.WriteString(TokenIdentifierColor + token.Text + ResetFormat)
+			color = TokenIdentifierColor
 		case TokenLiteral:
-			highlighted.WriteString(TokenLiteralColor + token.Text + ResetFormat)
+			color = TokenLiteralColor
 		case TokenComment:
-			highlighted.WriteString(TokenCommentColor + token.Text + ResetFormat)
+			color = TokenCommentColor
+		case TokenInserted:
+			color = TokenInsertedColor
+		case TokenDeleted:
+			color = TokenDeletedColor
+		case TokenHeading:
+			color = TokenHeadingColor
 		case TokenWhitespace:
-			highlighted.WriteString(token.Text)
 		default:
-			highlighted.WriteString(TokenOtherColor + token.Text + ResetFormat)
+			color = TokenOtherColor
+		}
+
+		if color == "" {
+			highlighted.WriteString(token.Text)
+			continue
 		}
+		highlighted.WriteString(color)
+		highlighted.WriteString(token.Text)
+		highlighted.WriteString(ResetFormat)
 	}
 
 	return highlighted.String()
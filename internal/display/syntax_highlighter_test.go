@@ -0,0 +1,21 @@
+package display
+
+import (
+	"strings"
+	"testing"
+)
+
+func BenchmarkHighlightCode(b *testing.B) {
+	InitializeColors()
+	highlighter := NewSyntaxHighlighter()
+	line := `    if order.amount > 100 and not order.cancelled:  # skip refunds`
+	code := strings.Repeat(line+"\n", 2000)
+	b.SetBytes(int64(len(code)))
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		for _, l := range strings.Split(code, "\n") {
+			highlighter.HighlightCode(l, "python")
+		}
+	}
+}
@@ -0,0 +1,62 @@
+package display
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// maxCellWidth is the widest a table column is allowed to be; longer values
+// are cut short
+const maxCellWidth = 40
+
+// FormatTable formats rows as a plain text table with aligned columns. The
+// first row is the header, and is underlined. Newlines in values are shown as
+// spaces.
+func FormatTable(rows [][]string) string {
+	if len(rows) == 0 {
+		return ""
+	}
+
+	cells := make([][]string, len(rows))
+	var widths []int
+	for i, row := range rows {
+		cells[i] = make([]string, len(row))
+		for j, value := range row {
+			value = strings.Join(strings.Fields(value), " ")
+			if runes := []rune(value); len(runes) > maxCellWidth {
+				value = string(runes[:maxCellWidth-1]) + "…"
+			}
+			cells[i][j] = value
+
+			if j == len(widths) {
+				widths = append(widths, 0)
+			}
+			widths[j] = max(widths[j], utf8.RuneCountInString(value))
+		}
+	}
+
+	var builder strings.Builder
+	writeRow := func(row []string) {
+		line := strings.Builder{}
+		for j, value := range row {
+			if j > 0 {
+				line.WriteString("  ")
+			}
+			line.WriteString(value)
+			line.WriteString(strings.Repeat(" ", widths[j]-utf8.RuneCountInString(value)))
+		}
+		builder.WriteString(strings.TrimRight(line.String(), " "))
+		builder.WriteString("\n")
+	}
+
+	writeRow(cells[0])
+	underline := make([]string, len(widths))
+	for j, width := range widths {
+		underline[j] = strings.Repeat("-", width)
+	}
+	writeRow(underline)
+	for _, row := range cells[1:] {
+		writeRow(row)
+	}
+	return builder.String()
+}
@@ -0,0 +1,29 @@
+package display
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestFormatTable(t *testing.T) {
+	rows := [][]string{
+		{"id", "name", "city"},
+		{"1", "Ada Lovelace", "London"},
+		{"22", "Grace", "New\nYork"},
+	}
+	expected := "id  name          city\n" +
+		"--  ------------  --------\n" +
+		"1   Ada Lovelace  London\n" +
+		"22  Grace         New York\n"
+	if got := FormatTable(rows); got != expected {
+		t.Errorf("FormatTable() = %q, expected %q", got, expected)
+	}
+}
+
+func TestFormatTableCutsLongValues(t *testing.T) {
+	got := FormatTable([][]string{{"text"}, {strings.Repeat("x", 50)}})
+	expected := "text\n" + strings.Repeat("-", 40) + "\n" + strings.Repeat("x", 39) + "…\n"
+	if got != expected {
+		t.Errorf("FormatTable() = %q, expected %q", got, expected)
+	}
+}
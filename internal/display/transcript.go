@@ -0,0 +1,123 @@
+package display
+
+import (
+	"io"
+	"time"
+
+	"github.com/rba100/aipipe/internal/i18n"
+)
+
+// roleLabel names who sent a message of a conversation
+func roleLabel(role string) string {
+	switch role {
+	case "user":
+		return i18n.T("You")
+	case "assistant":
+		return i18n.T("Assistant")
+	default:
+		return role
+	}
+}
+
+// roleColor returns the colour of a role's messages
+func roleColor(role string) string {
+	switch role {
+	case "user":
+		return RoleUserColor
+	case "assistant":
+		return RoleAssistantColor
+	default:
+		return RoleOtherColor
+	}
+}
+
+// FormatMessageHeader returns the line shown above a message of a
+// conversation, naming who sent it in the role's colour, and when, if at
+// isn't zero. Accessible headers are written as [You, 15:04] instead.
+func FormatMessageHeader(role string, at time.Time, accessible bool) string {
+	label := roleLabel(role)
+	if accessible {
+		if at.IsZero() {
+			return "[" + label + "]"
+		}
+		return "[" + label + ", " + at.Local().Format("15:04") + "]"
+	}
+
+	header := BoldFormat + roleColor(role) + label + ResetFormat
+	if !at.IsZero() {
+		header += DimFormat + " · " + at.Local().Format("15:04") + ResetFormat
+	}
+	return header
+}
+
+// RoleGutter returns the gutter drawn down the left of a role's messages, a
+// bar in the role's colour, or an empty string for accessible output, which
+// avoids box-drawing characters
+func RoleGutter(role string, accessible bool) string {
+	if accessible {
+		return ""
+	}
+	return roleColor(role) + "┃" + ResetFormat + " "
+}
+
+// gutterWriter writes to w, starting each line that has text with prefix.
+// Colours set before the prefix are set again after it, since the prefix
+// resets them.
+type gutterWriter struct {
+	w      io.Writer
+	prefix string
+	// midLine is set once the prefix of the current line is written
+	midLine bool
+	// escape holds an escape sequence being written, and style the colour
+	// and format sequences in effect
+	escape []byte
+	style  []byte
+}
+
+// Write writes data, adding the prefix at the start of each line
+func (g *gutterWriter) Write(data []byte) (int, error) {
+	var out []byte
+	for _, b := range data {
+		switch {
+		case len(g.escape) > 0:
+			g.escape = append(g.escape, b)
+			if len(g.escape) > 2 && b >= 0x40 && b <= 0x7e {
+				g.endEscape()
+			}
+			out = append(out, b)
+		case b == '\033':
+			g.escape = append(g.escape, b)
+			out = append(out, b)
+		case b == '\n':
+			g.midLine = false
+			out = append(out, b)
+		default:
+			if !g.midLine {
+				out = append(out, g.prefix...)
+				out = append(out, g.style...)
+				g.midLine = true
+			}
+			out = append(out, b)
+		}
+	}
+
+	if _, err := g.w.Write(out); err != nil {
+		return 0, err
+	}
+	return len(data), nil
+}
+
+// endEscape records a finished escape sequence that sets colours or formats
+// as in effect, or clears them for a reset
+func (g *gutterWriter) endEscape() {
+	escape := string(g.escape)
+	g.escape = g.escape[:0]
+	if escape[len(escape)-1] != 'm' {
+		return
+	}
+	if escape == ResetFormat || escape == "\033[m" {
+		g.style = g.style[:0]
+		return
+	}
+	g.style = append(g.style, escape...)
+}
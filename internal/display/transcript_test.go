@@ -0,0 +1,81 @@
+package display
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFormatMessageHeader(t *testing.T) {
+	InitializeColors()
+	at := time.Date(2026, 3, 4, 15, 7, 0, 0, time.Local)
+	tests := []struct {
+		name       string
+		role       string
+		at         time.Time
+		accessible bool
+		expected   string
+	}{
+		{"User", "user", at, false, BoldFormat + RoleUserColor + "You" + ResetFormat + DimFormat + " · 15:07" + ResetFormat},
+		{"Assistant without a time", "assistant", time.Time{}, false, BoldFormat + RoleAssistantColor + "Assistant" + ResetFormat},
+		{"Accessible", "assistant", at, true, "[Assistant, 15:07]"},
+		{"Accessible without a time", "system", time.Time{}, true, "[system]"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := FormatMessageHeader(tt.role, tt.at, tt.accessible); got != tt.expected {
+				t.Errorf("FormatMessageHeader() = %q, expected %q", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestGutterWriter(t *testing.T) {
+	tests := []struct {
+		name     string
+		writes   []string
+		expected string
+	}{
+		{
+			name:     "Lines",
+			writes:   []string{"one\ntwo\n"},
+			expected: "| one\n| two\n",
+		},
+		{
+			name:     "Split writes",
+			writes:   []string{"on", "e\n", "\ntw", "o"},
+			expected: "| one\n\n| two",
+		},
+		{
+			name:     "Colour carried over the prefix",
+			writes:   []string{"\033[32mgreen\nstill", " green" + ResetFormat + "\nplain\n"},
+			expected: "\033[32m| \033[32mgreen\n| \033[32mstill green" + ResetFormat + "\n| plain\n",
+		},
+		{
+			name:     "Escape sequence split between writes",
+			writes:   []string{"\033[3", "2mx\ny\n"},
+			expected: "\033[32m| \033[32mx\n| \033[32my\n",
+		},
+		{
+			name:     "No prefix for a trailing reset",
+			writes:   []string{"text\n" + ResetFormat},
+			expected: "| text\n" + ResetFormat,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out strings.Builder
+			writer := &gutterWriter{w: &out, prefix: "| "}
+			for _, data := range tt.writes {
+				if n, err := writer.Write([]byte(data)); err != nil || n != len(data) {
+					t.Fatalf("Write(%q) = %d, %v", data, n, err)
+				}
+			}
+			if out.String() != tt.expected {
+				t.Errorf("Wrote %q, expected %q", out.String(), tt.expected)
+			}
+		})
+	}
+}
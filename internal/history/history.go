@@ -0,0 +1,204 @@
+package history
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/rba100/aipipe/internal/util"
+)
+
+// Entry is a prompt typed on the command line
+type Entry struct {
+	// Prompt is the prompt as typed, without any piped input
+	Prompt string `json:"prompt"`
+	// Time is when the prompt was run
+	Time time.Time `json:"time"`
+
+	// Snippet is the name of the snippet run, if any
+	Snippet string `json:"snippet,omitempty"`
+	// Model is the model that answered, and Tokens the tokens used if the API
+	// reported them. Both are empty if the prompt wasn't answered.
+	Model  string `json:"model,omitempty"`
+	Tokens int    `json:"tokens,omitempty"`
+	// Cost is what the answer cost in US dollars, estimated from the price
+	// table when it was run, or 0 if the price wasn't known
+	Cost float64 `json:"cost,omitempty"`
+	// Turns is how many requests were made to the model, if more than one
+	Turns int `json:"turns,omitempty"`
+	// Interrupted is set if Ctrl-C stopped the answer part way through
+	Interrupted bool `json:"interrupted,omitempty"`
+	// Messages holds the messages of a conversation, for `aipipe chat`
+	Messages []Message `json:"messages,omitempty"`
+}
+
+// Message is a message of a conversation
+type Message struct {
+	// Role is "user" or "assistant"
+	Role    string `json:"role"`
+	Content string `json:"content"`
+	// Time is when the message was sent, or nil if it wasn't recorded
+	Time *time.Time `json:"time,omitempty"`
+}
+
+// SentAt returns when the message was sent, or the zero time if it wasn't
+// recorded
+func (m Message) SentAt() time.Time {
+	if m.Time == nil {
+		return time.Time{}
+	}
+	return *m.Time
+}
+
+// Log keeps prompts in a JSON lines file, one prompt per line, oldest first,
+// like a shell history file
+type Log struct {
+	path string
+}
+
+// NewLog creates a log that keeps its prompts in the given file
+func NewLog(path string) *Log {
+	return &Log{path: path}
+}
+
+// DefaultLog returns the log in prompt_history in aipipe's state directory
+func DefaultLog() (*Log, error) {
+	path, err := util.StatePath("prompt_history")
+	if err != nil {
+		return nil, err
+	}
+	return NewLog(path), nil
+}
+
+// List returns the logged prompts, oldest first
+func (l *Log) List() ([]Entry, error) {
+	file, err := os.Open(l.path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open prompt history: %w", err)
+	}
+	defer file.Close()
+
+	// A decoder rather than a line scanner, since a saved conversation can
+	// make a line of any length
+	var entries []Entry
+	decoder := json.NewDecoder(bufio.NewReader(file))
+	for {
+		var entry Entry
+		err := decoder.Decode(&entry)
+		if err == io.EOF {
+			return entries, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse prompt history: %w", err)
+		}
+		entries = append(entries, entry)
+	}
+}
+
+// Last returns the most recent prompt, or false if the log is empty
+func (l *Log) Last() (Entry, bool, error) {
+	entries, err := l.List()
+	if err != nil || len(entries) == 0 {
+		return Entry{}, false, err
+	}
+	return entries[len(entries)-1], true, nil
+}
+
+// Get returns the prompt with the given id: its position in the log, counting
+// from 1 for the oldest
+func (l *Log) Get(id int) (Entry, error) {
+	entries, err := l.List()
+	if err != nil {
+		return Entry{}, err
+	}
+	if id < 1 || id > len(entries) {
+		return Entry{}, fmt.Errorf("there is no prompt %d in the history, which has %d", id, len(entries))
+	}
+	return entries[id-1], nil
+}
+
+// Add appends a prompt to the log
+func (l *Log) Add(prompt string) error {
+	return l.AddEntry(Entry{Prompt: prompt})
+}
+
+// AddEntry appends an entry to the log. Every run is kept, even of the same
+// prompt, so its model and usage count towards the stats; use Unique to list
+// each prompt once. The time is set to now if it is zero.
+func (l *Log) AddEntry(entry Entry) error {
+	entry.Prompt = strings.TrimSpace(entry.Prompt)
+	if entry.Prompt == "" {
+		return nil
+	}
+	if entry.Time.IsZero() {
+		entry.Time = time.Now()
+	}
+
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("failed to encode prompt: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(l.path), 0700); err != nil {
+		return fmt.Errorf("failed to create prompt history directory: %w", err)
+	}
+	file, err := os.OpenFile(l.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to open prompt history: %w", err)
+	}
+	defer file.Close()
+
+	if _, err := file.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("failed to write prompt history: %w", err)
+	}
+	return nil
+}
+
+// Unique removes repeated prompts from entries, keeping the most recent run of
+// each, oldest first
+func Unique(entries []Entry) []Entry {
+	seen := make(map[string]bool)
+	var unique []Entry
+	for i := len(entries) - 1; i >= 0; i-- {
+		if seen[entries[i].Prompt] {
+			continue
+		}
+		seen[entries[i].Prompt] = true
+		unique = append(unique, entries[i])
+	}
+
+	for i, j := 0, len(unique)-1; i < j; i, j = i+1, j-1 {
+		unique[i], unique[j] = unique[j], unique[i]
+	}
+	return unique
+}
+
+// Search returns the entries whose prompt contains every word of query,
+// ignoring case
+func Search(entries []Entry, query string) []Entry {
+	words := strings.Fields(strings.ToLower(query))
+
+	var matches []Entry
+	for _, entry := range entries {
+		prompt := strings.ToLower(entry.Prompt)
+		matched := true
+		for _, word := range words {
+			if !strings.Contains(prompt, word) {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			matches = append(matches, entry)
+		}
+	}
+	return matches
+}
@@ -0,0 +1,102 @@
+package history
+
+import (
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLog(t *testing.T) {
+	log := NewLog(filepath.Join(t.TempDir(), "prompt_history"))
+
+	if _, ok, err := log.Last(); ok || err != nil {
+		t.Fatalf("Last() on an empty log = %v, %v; want nothing", ok, err)
+	}
+
+	for _, prompt := range []string{"to JSON", "  to JSON  ", "summarise", "to JSON", ""} {
+		if err := log.Add(prompt); err != nil {
+			t.Fatalf("Add(%q) error: %v", prompt, err)
+		}
+	}
+
+	entries, err := log.List()
+	if err != nil {
+		t.Fatalf("List() error: %v", err)
+	}
+	var prompts []string
+	for _, entry := range entries {
+		prompts = append(prompts, entry.Prompt)
+	}
+	if !reflect.DeepEqual(prompts, []string{"to JSON", "to JSON", "summarise", "to JSON"}) {
+		t.Errorf("List() prompts = %q, want every run kept and blanks skipped", prompts)
+	}
+
+	last, ok, err := log.Last()
+	if err != nil || !ok || last.Prompt != "to JSON" {
+		t.Errorf("Last() = %+v, %v, %v; want the most recent prompt", last, ok, err)
+	}
+
+	sent := time.Date(2026, 3, 4, 15, 7, 0, 0, time.UTC)
+	messages := []Message{{Role: "user", Content: "hello"}, {Role: "assistant", Content: "hi", Time: &sent}}
+	if err := log.AddEntry(Entry{Prompt: "hello", Messages: messages}); err != nil {
+		t.Fatalf("AddEntry() error: %v", err)
+	}
+	entry, err := log.Get(5)
+	if err != nil || !reflect.DeepEqual(entry.Messages, messages) {
+		t.Errorf("Get(5) = %+v, %v; want the conversation with its messages", entry, err)
+	}
+	if entry, err := log.Get(3); err != nil || entry.Prompt != "summarise" {
+		t.Errorf("Get(3) = %+v, %v; want the third prompt", entry, err)
+	}
+	for _, id := range []int{0, 6} {
+		if _, err := log.Get(id); err == nil {
+			t.Errorf("Get(%d) succeeded, want an error", id)
+		}
+	}
+}
+
+func TestLogLongConversation(t *testing.T) {
+	log := NewLog(filepath.Join(t.TempDir(), "prompt_history"))
+
+	// Longer than a line scanner's default limit
+	reply := strings.Repeat("x", 2<<20)
+	entry := Entry{Prompt: "write a lot", Messages: []Message{{Role: "user", Content: "write a lot"}, {Role: "assistant", Content: reply}}}
+	if err := log.AddEntry(entry); err != nil {
+		t.Fatalf("AddEntry() error: %v", err)
+	}
+	if err := log.Add("next"); err != nil {
+		t.Fatalf("Add() error: %v", err)
+	}
+
+	entries, err := log.List()
+	if err != nil {
+		t.Fatalf("List() error: %v", err)
+	}
+	if len(entries) != 2 || entries[0].Messages[1].Content != reply || entries[1].Prompt != "next" {
+		t.Errorf("List() returned %d entries, want the long conversation and the next prompt", len(entries))
+	}
+}
+
+func TestUniqueAndSearch(t *testing.T) {
+	now := time.Now()
+	entries := []Entry{
+		{Prompt: "to JSON", Time: now},
+		{Prompt: "explain this error", Time: now.Add(time.Second)},
+		{Prompt: "to JSON", Time: now.Add(2 * time.Second)},
+	}
+
+	unique := Unique(entries)
+	if len(unique) != 2 || unique[0].Prompt != "explain this error" || !unique[1].Time.Equal(entries[2].Time) {
+		t.Errorf("Unique() = %+v, want the latest run of each prompt, oldest first", unique)
+	}
+
+	matches := Search(entries, "ERROR explain")
+	if len(matches) != 1 || matches[0].Prompt != "explain this error" {
+		t.Errorf("Search() = %+v, want the prompt containing both words", matches)
+	}
+	if matches := Search(entries, ""); len(matches) != len(entries) {
+		t.Errorf("Search() with no query returned %d entries, want all %d", len(matches), len(entries))
+	}
+}
@@ -0,0 +1,60 @@
+package history
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// maxTitleChars caps the length of the title Markdown takes from the prompt
+const maxTitleChars = 72
+
+// Conversation returns the messages of an entry, or its prompt alone as a
+// message from the user if it isn't a conversation
+func (e Entry) Conversation() []Message {
+	if len(e.Messages) == 0 {
+		return []Message{{Role: "user", Content: e.Prompt, Time: &e.Time}}
+	}
+	return e.Messages
+}
+
+// Markdown renders an entry as a markdown document to share: a title taken
+// from the first line of the prompt, when it was run and by which model, then
+// each message under a heading naming who sent it and when
+func Markdown(entry Entry) string {
+	var document strings.Builder
+
+	title, _, _ := strings.Cut(strings.TrimSpace(entry.Prompt), "\n")
+	if utf8.RuneCountInString(title) > maxTitleChars {
+		title = string([]rune(title)[:maxTitleChars-1]) + "…"
+	}
+	document.WriteString("# " + title + "\n\n")
+
+	details := []string{entry.Time.Local().Format("2006-01-02 15:04 MST")}
+	if entry.Model != "" {
+		details = append(details, entry.Model)
+	}
+	document.WriteString("*" + strings.Join(details, " · ") + "*\n")
+
+	for _, message := range entry.Conversation() {
+		heading := roleName(message.Role)
+		if at := message.SentAt(); !at.IsZero() {
+			heading += " · " + at.Local().Format("15:04")
+		}
+		document.WriteString("\n## " + heading + "\n\n" + strings.TrimSpace(message.Content) + "\n")
+	}
+	return document.String()
+}
+
+// roleName names the sender of a message with a given role for a heading
+func roleName(role string) string {
+	switch role {
+	case "user":
+		return "User"
+	case "assistant":
+		return "Assistant"
+	case "":
+		return "Unknown"
+	default:
+		return strings.ToUpper(role[:1]) + role[1:]
+	}
+}
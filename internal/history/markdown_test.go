@@ -0,0 +1,58 @@
+package history
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMarkdown(t *testing.T) {
+	local := time.Local
+	time.Local = time.UTC
+	defer func() { time.Local = local }()
+
+	sent := time.Date(2026, 3, 4, 15, 7, 0, 0, time.UTC)
+	replied := sent.Add(time.Minute)
+
+	tests := []struct {
+		name     string
+		entry    Entry
+		expected string
+	}{
+		{
+			name: "Conversation",
+			entry: Entry{
+				Prompt: "why does the build fail?\nIt worked yesterday.",
+				Time:   sent,
+				Model:  "gpt-4o",
+				Messages: []Message{
+					{Role: "user", Content: "why does the build fail?\nIt worked yesterday.", Time: &sent},
+					{Role: "assistant", Content: "The `go.sum` entry is missing.\n\n```sh\ngo mod tidy\n```\n", Time: &replied},
+				},
+			},
+			expected: "# why does the build fail?\n\n*2026-03-04 15:07 UTC · gpt-4o*\n\n" +
+				"## User · 15:07\n\nwhy does the build fail?\nIt worked yesterday.\n\n" +
+				"## Assistant · 15:08\n\nThe `go.sum` entry is missing.\n\n```sh\ngo mod tidy\n```\n",
+		},
+		{
+			name:  "Prompt without an answer",
+			entry: Entry{Prompt: "summarise", Time: sent},
+			expected: "# summarise\n\n*2026-03-04 15:07 UTC*\n\n" +
+				"## User · 15:07\n\nsummarise\n",
+		},
+		{
+			name:  "Long title",
+			entry: Entry{Prompt: "explain " + strings.Repeat("x", 80), Time: sent, Messages: []Message{{Role: "user", Content: "hi"}}},
+			expected: "# explain " + strings.Repeat("x", 63) + "…\n\n*2026-03-04 15:07 UTC*\n\n" +
+				"## User\n\nhi\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if result := Markdown(tt.entry); result != tt.expected {
+				t.Errorf("Markdown() = %q, want %q", result, tt.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,92 @@
+package history
+
+import (
+	"math"
+	"sort"
+	"time"
+)
+
+// Count is how many prompts used a model or snippet
+type Count struct {
+	Name  string
+	Count int
+}
+
+// Stats summarises the prompt history
+type Stats struct {
+	Prompts int
+	// Weekly counts the prompts run in each recent week, oldest first, ending
+	// with the current week
+	Weekly []int
+	// Tokens is the total tokens used by the prompts whose usage the API
+	// reported
+	Tokens int
+	// Cost is the estimated cost of the prompts whose cost was known, in US
+	// dollars
+	Cost float64
+	// AverageTurns is the average number of requests made to the model for
+	// each prompt answered
+	AverageTurns float64
+	// Models counts the models that answered, and Snippets the snippets run,
+	// most used first
+	Models   []Count
+	Snippets []Count
+}
+
+// ComputeStats summarises entries, counting the prompts run in each of the
+// given number of weeks up to now. Weeks start on Monday.
+func ComputeStats(entries []Entry, now time.Time, weeks int) Stats {
+	stats := Stats{Prompts: len(entries), Weekly: make([]int, weeks)}
+	models := make(map[string]int)
+	snippets := make(map[string]int)
+	answered, turns := 0, 0
+
+	thisWeek := weekStart(now)
+	for _, entry := range entries {
+		// Whole weeks, rounded as a week across a clock change isn't 168 hours
+		ago := int(math.Round(thisWeek.Sub(weekStart(entry.Time)).Hours() / (7 * 24)))
+		if ago >= 0 && ago < weeks {
+			stats.Weekly[weeks-1-ago]++
+		}
+
+		stats.Tokens += entry.Tokens
+		stats.Cost += entry.Cost
+		if entry.Snippet != "" {
+			snippets[entry.Snippet]++
+		}
+		if entry.Model != "" {
+			models[entry.Model]++
+			answered++
+			turns += max(entry.Turns, 1)
+		}
+	}
+
+	if answered > 0 {
+		stats.AverageTurns = float64(turns) / float64(answered)
+	}
+	stats.Models = sortCounts(models)
+	stats.Snippets = sortCounts(snippets)
+	return stats
+}
+
+// weekStart returns midnight on the Monday of the week containing t, in t's
+// location
+func weekStart(t time.Time) time.Time {
+	daysSinceMonday := (int(t.Weekday()) + 6) % 7
+	return time.Date(t.Year(), t.Month(), t.Day()-daysSinceMonday, 0, 0, 0, 0, t.Location())
+}
+
+// sortCounts returns counts most first, then by name
+func sortCounts(counts map[string]int) []Count {
+	var sorted []Count
+	for name, count := range counts {
+		sorted = append(sorted, Count{Name: name, Count: count})
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		if sorted[i].Count != sorted[j].Count {
+			return sorted[i].Count > sorted[j].Count
+		}
+		return sorted[i].Name < sorted[j].Name
+	})
+	return sorted
+}
@@ -0,0 +1,53 @@
+package history
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestComputeStats(t *testing.T) {
+	// A Wednesday
+	now := time.Date(2025, 3, 12, 15, 0, 0, 0, time.UTC)
+	entries := []Entry{
+		{Prompt: "too old", Time: now.AddDate(0, 0, -30)},
+		{Prompt: "two weeks ago", Time: now.AddDate(0, 0, -14), Model: "gpt-4o", Tokens: 100, Cost: 0.5},
+		{Prompt: "last Sunday", Time: time.Date(2025, 3, 9, 23, 0, 0, 0, time.UTC), Model: "gpt-4o-mini", Tokens: 50, Cost: 0.25, Snippet: "summarize-pr"},
+		{Prompt: "this Monday", Time: time.Date(2025, 3, 10, 1, 0, 0, 0, time.UTC), Model: "gpt-4o", Turns: 4},
+		{Prompt: "failed", Time: now, Snippet: "summarize-pr"},
+		{Prompt: "today", Time: now, Model: "llama3.2", Tokens: 25, Snippet: "fix-typos"},
+	}
+
+	stats := ComputeStats(entries, now, 3)
+
+	if stats.Prompts != 6 {
+		t.Errorf("Prompts = %d, want 6", stats.Prompts)
+	}
+	if want := []int{1, 1, 3}; !reflect.DeepEqual(stats.Weekly, want) {
+		t.Errorf("Weekly = %v, want %v", stats.Weekly, want)
+	}
+	if stats.Tokens != 175 {
+		t.Errorf("Tokens = %d, want 175", stats.Tokens)
+	}
+	if stats.Cost != 0.75 {
+		t.Errorf("Cost = %v, want 0.75", stats.Cost)
+	}
+	if stats.AverageTurns != 7.0/4 {
+		t.Errorf("AverageTurns = %v, want %v", stats.AverageTurns, 7.0/4)
+	}
+	wantModels := []Count{{"gpt-4o", 2}, {"gpt-4o-mini", 1}, {"llama3.2", 1}}
+	if !reflect.DeepEqual(stats.Models, wantModels) {
+		t.Errorf("Models = %v, want %v", stats.Models, wantModels)
+	}
+	wantSnippets := []Count{{"summarize-pr", 2}, {"fix-typos", 1}}
+	if !reflect.DeepEqual(stats.Snippets, wantSnippets) {
+		t.Errorf("Snippets = %v, want %v", stats.Snippets, wantSnippets)
+	}
+}
+
+func TestComputeStatsEmpty(t *testing.T) {
+	stats := ComputeStats(nil, time.Now(), 4)
+	if stats.Prompts != 0 || stats.AverageTurns != 0 || len(stats.Weekly) != 4 || stats.Models != nil {
+		t.Errorf("ComputeStats(nil) = %+v, want nothing counted", stats)
+	}
+}
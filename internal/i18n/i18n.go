@@ -0,0 +1,103 @@
+// Package i18n translates the messages aipipe shows on the terminal, such as
+// errors, flag descriptions and the status line, into the user's language.
+// Translations are kept in YAML catalogs embedded in the binary, one per
+// language, mapping each English message to its translation. Messages
+// without a translation are shown in English.
+package i18n
+
+import (
+	"embed"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+//go:embed locales/*.yaml
+var catalogFiles embed.FS
+
+// catalog holds the translations for the selected locale, or nil for English
+var catalog map[string]string
+
+// DetectLocale returns the locale for messages from the environment, read
+// with getenv: LC_ALL, then LC_MESSAGES, then LANG, as POSIX programs do. It
+// returns an empty string if none is set, or if the locale is C or POSIX.
+func DetectLocale(getenv func(string) string) string {
+	for _, name := range []string{"LC_ALL", "LC_MESSAGES", "LANG"} {
+		if locale := getenv(name); locale != "" {
+			if locale == "C" || locale == "POSIX" || strings.HasPrefix(locale, "C.") {
+				return ""
+			}
+			return locale
+		}
+	}
+	return ""
+}
+
+// SetLocale selects the catalog for a locale such as de_DE.UTF-8, pt-BR or
+// es. A catalog for the language and region is preferred, then one for the
+// language. It reports whether a catalog was found; if not, messages are
+// shown in English.
+func SetLocale(locale string) bool {
+	catalog = nil
+
+	// Drop the encoding and modifier, as in de_DE.UTF-8@euro
+	if i := strings.IndexAny(locale, ".@"); i >= 0 {
+		locale = locale[:i]
+	}
+	locale = strings.ToLower(strings.ReplaceAll(locale, "-", "_"))
+	if locale == "" {
+		return false
+	}
+
+	candidates := []string{locale}
+	if language, _, ok := strings.Cut(locale, "_"); ok {
+		candidates = append(candidates, language)
+	}
+	for _, candidate := range candidates {
+		if messages, err := loadCatalog(candidate); err == nil {
+			catalog = messages
+			return true
+		}
+	}
+	return false
+}
+
+// Languages returns the names of the embedded catalogs, such as "de"
+func Languages() []string {
+	entries, _ := catalogFiles.ReadDir("locales")
+	var languages []string
+	for _, entry := range entries {
+		languages = append(languages, strings.TrimSuffix(entry.Name(), ".yaml"))
+	}
+	sort.Strings(languages)
+	return languages
+}
+
+// loadCatalog reads the embedded catalog for a language
+func loadCatalog(language string) (map[string]string, error) {
+	data, err := catalogFiles.ReadFile(path.Join("locales", language+".yaml"))
+	if err != nil {
+		return nil, err
+	}
+	var messages map[string]string
+	if err := yaml.Unmarshal(data, &messages); err != nil {
+		return nil, fmt.Errorf("error reading the %s catalog: %w", language, err)
+	}
+	return messages, nil
+}
+
+// T returns message translated into the selected language, or message itself
+// if it has no translation. With args, message is a format for fmt.Sprintf,
+// and so is its translation.
+func T(message string, args ...interface{}) string {
+	if translation, ok := catalog[message]; ok && translation != "" {
+		message = translation
+	}
+	if len(args) == 0 {
+		return message
+	}
+	return fmt.Sprintf(message, args...)
+}
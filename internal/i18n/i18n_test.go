@@ -0,0 +1,82 @@
+package i18n
+
+import (
+	"reflect"
+	"regexp"
+	"testing"
+)
+
+func TestDetectLocale(t *testing.T) {
+	tests := []struct {
+		name     string
+		env      map[string]string
+		expected string
+	}{
+		{"LANG", map[string]string{"LANG": "de_DE.UTF-8"}, "de_DE.UTF-8"},
+		{"LC_ALL wins", map[string]string{"LC_ALL": "es_ES.UTF-8", "LC_MESSAGES": "fr_FR", "LANG": "de_DE"}, "es_ES.UTF-8"},
+		{"LC_MESSAGES before LANG", map[string]string{"LC_MESSAGES": "fr_FR", "LANG": "de_DE"}, "fr_FR"},
+		{"C locale", map[string]string{"LC_ALL": "C.UTF-8", "LANG": "de_DE"}, ""},
+		{"Unset", map[string]string{}, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			getenv := func(name string) string { return tt.env[name] }
+			if got := DetectLocale(getenv); got != tt.expected {
+				t.Errorf("DetectLocale() = %q, want %q", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestSetLocale(t *testing.T) {
+	defer SetLocale("")
+
+	tests := []struct {
+		locale   string
+		found    bool
+		expected string
+	}{
+		{"de_DE.UTF-8@euro", true, "unbekannte --input-role \"x\" (erwartet: prompt, user oder system)"},
+		{"es-MX", true, "--input-role \"x\" desconocido (se esperaba prompt, user o system)"},
+		{"pt_BR", false, "unknown --input-role \"x\" (expected prompt, user or system)"},
+		{"", false, "unknown --input-role \"x\" (expected prompt, user or system)"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.locale, func(t *testing.T) {
+			if found := SetLocale(tt.locale); found != tt.found {
+				t.Errorf("SetLocale(%q) = %t, want %t", tt.locale, found, tt.found)
+			}
+			if got := T("unknown --input-role %q (expected prompt, user or system)", "x"); got != tt.expected {
+				t.Errorf("T() = %q, want %q", got, tt.expected)
+			}
+		})
+	}
+
+	// Messages without a translation are shown as they are
+	SetLocale("de")
+	if got := T("not translated %d", 1); got != "not translated 1" {
+		t.Errorf("T() = %q for a message without a translation", got)
+	}
+}
+
+// formatVerbRegex matches the verbs of a fmt format string
+var formatVerbRegex = regexp.MustCompile(`%[-+# 0-9.]*[a-zA-Z%]`)
+
+func TestCatalogs(t *testing.T) {
+	for _, language := range Languages() {
+		messages, err := loadCatalog(language)
+		if err != nil {
+			t.Errorf("%s: %v", language, err)
+			continue
+		}
+		for message, translation := range messages {
+			want := formatVerbRegex.FindAllString(message, -1)
+			got := formatVerbRegex.FindAllString(translation, -1)
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("%s: the translation of %q has format verbs %q, want %q", language, message, got, want)
+			}
+		}
+	}
+}
@@ -0,0 +1,325 @@
+package llm
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+)
+
+const (
+	// anthropicVersion is the version of the Messages API requested
+	anthropicVersion = "2023-06-01"
+
+	// anthropicMaxTokens limits the length of answers from models whose
+	// limits aren't known. The Messages API requires a limit, and this one is
+	// accepted by every current model.
+	anthropicMaxTokens = 8192
+)
+
+// AnthropicClient implements the LLMClient interface for Anthropic's
+// Messages API
+type AnthropicClient struct {
+	config     *Config
+	httpClient *http.Client
+	baseURL    *url.URL
+	apiKey     string
+
+	infoMutex sync.Mutex
+	lastInfo  CompletionInfo
+}
+
+// anthropicUsage counts the tokens used by a message
+type anthropicUsage struct {
+	InputTokens  int `json:"input_tokens"`
+	OutputTokens int `json:"output_tokens"`
+}
+
+// anthropicMessage is a response from the Messages API, or the message_start
+// event of a stream
+type anthropicMessage struct {
+	Model   string `json:"model"`
+	Content []struct {
+		Type string `json:"type"`
+		Text string `json:"text"`
+	} `json:"content"`
+	StopReason string          `json:"stop_reason"`
+	Usage      *anthropicUsage `json:"usage"`
+}
+
+// anthropicEvent is the data of an event in a streamed response
+type anthropicEvent struct {
+	Type    string           `json:"type"`
+	Message anthropicMessage `json:"message"`
+	Delta   struct {
+		Type       string `json:"type"`
+		Text       string `json:"text"`
+		StopReason string `json:"stop_reason"`
+	} `json:"delta"`
+	Usage *anthropicUsage `json:"usage"`
+	Error struct {
+		Type    string `json:"type"`
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+// GetModel returns the appropriate model based on the config
+func (c *AnthropicClient) GetModel() string {
+	return c.config.model()
+}
+
+// LastCompletionInfo implements the LLMClient interface
+func (c *AnthropicClient) LastCompletionInfo() CompletionInfo {
+	c.infoMutex.Lock()
+	defer c.infoMutex.Unlock()
+	return c.lastInfo
+}
+
+// setCompletionInfo records the description of the latest completion
+func (c *AnthropicClient) setCompletionInfo(info CompletionInfo) {
+	c.infoMutex.Lock()
+	defer c.infoMutex.Unlock()
+	c.lastInfo = info
+}
+
+// anthropicFinishReason converts a stop reason to the finish reason the
+// OpenAI API would give, so callers can check for "stop" and "length"
+func anthropicFinishReason(stopReason string) string {
+	switch stopReason {
+	case "end_turn", "stop_sequence":
+		return "stop"
+	case "max_tokens":
+		return "length"
+	default:
+		return stopReason
+	}
+}
+
+// setUsage records the token counts reported so far. A stream reports the
+// input tokens when it starts and the output tokens when it ends.
+func setUsage(info *CompletionInfo, usage *anthropicUsage) {
+	if usage == nil {
+		return
+	}
+	if info.Usage == nil {
+		info.Usage = &Usage{}
+	}
+	if usage.InputTokens > 0 {
+		info.Usage.PromptTokens = usage.InputTokens
+	}
+	if usage.OutputTokens > 0 {
+		info.Usage.CompletionTokens = usage.OutputTokens
+	}
+	info.Usage.TotalTokens = info.Usage.PromptTokens + info.Usage.CompletionTokens
+}
+
+// newRequest creates a request to the Messages API. The Messages API has no
+// equivalent of the OpenAI API's metadata, only a user ID, so Config.Metadata
+// isn't sent.
+func (c *AnthropicClient) newRequest(ctx context.Context, prompt string, stream bool) (*http.Request, error) {
+	// The Messages API takes the system prompt apart from the messages, so
+	// input in the system role is added to it
+	var system []string
+	var messages []map[string]string
+	all := c.config.messages(prompt)
+	for _, message := range all {
+		if message["role"] == "system" {
+			system = append(system, message["content"])
+		} else {
+			messages = append(messages, message)
+		}
+	}
+
+	model := c.GetModel()
+	maxTokens, ok := c.config.maxTokens(model, all)
+	if !ok {
+		maxTokens = anthropicMaxTokens
+	}
+
+	requestBody := map[string]interface{}{
+		"model":      model,
+		"max_tokens": maxTokens,
+		"system":     strings.Join(system, "\n\n"),
+		"messages":   messages,
+	}
+	if stream {
+		requestBody["stream"] = true
+	}
+	if c.config.User != "" {
+		requestBody["metadata"] = map[string]string{"user_id": c.config.User}
+	}
+
+	jsonBody, err := json.Marshal(requestBody)
+	if err != nil {
+		return nil, fmt.Errorf("error marshaling request: %v", err)
+	}
+
+	endpoint := c.baseURL.String()
+	if !strings.HasSuffix(endpoint, "/") {
+		endpoint += "/"
+	}
+	req, err := http.NewRequestWithContext(ctx, "POST", endpoint+"messages", bytes.NewBuffer(jsonBody))
+	if err != nil {
+		return nil, fmt.Errorf("error creating request: %v", err)
+	}
+
+	c.setHeaders(req)
+	return req, nil
+}
+
+// setHeaders sets the headers of a request to the API, including any extra
+// headers configured. The content type, key and version can't be replaced.
+func (c *AnthropicClient) setHeaders(req *http.Request) {
+	for name, value := range c.config.Headers {
+		req.Header.Set(name, value)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Api-Key", c.apiKey)
+	req.Header.Set("Anthropic-Version", anthropicVersion)
+}
+
+// CreateCompletion sends a prompt to the API and returns the completion
+func (c *AnthropicClient) CreateCompletion(ctx context.Context, prompt string) (string, error) {
+	req, err := c.newRequest(ctx, prompt, false)
+	if err != nil {
+		return "", err
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("error sending request: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		bodyBytes, _ := io.ReadAll(resp.Body)
+		return "", apiError(resp.StatusCode, bodyBytes, c.GetModel())
+	}
+
+	var message anthropicMessage
+	if err := json.NewDecoder(resp.Body).Decode(&message); err != nil {
+		return "", fmt.Errorf("error decoding response: %v", err)
+	}
+
+	info := CompletionInfo{Model: c.GetModel()}
+	if message.Model != "" {
+		info.Model = message.Model
+	}
+	info.FinishReason = anthropicFinishReason(message.StopReason)
+	setUsage(&info, message.Usage)
+	c.setCompletionInfo(info)
+
+	if message.Content == nil {
+		return "", fmt.Errorf("invalid response format: missing content")
+	}
+
+	// Thinking and other blocks are left out
+	var content strings.Builder
+	for _, block := range message.Content {
+		if block.Type == "text" {
+			content.WriteString(block.Text)
+		}
+	}
+	return content.String(), nil
+}
+
+// CreateCompletionStream sends a prompt to the API and returns a stream of
+// the completion's parts
+func (c *AnthropicClient) CreateCompletionStream(ctx context.Context, prompt string) <-chan StreamEvent {
+	events := make(chan StreamEvent)
+
+	go func() {
+		defer close(events)
+
+		info := CompletionInfo{Model: c.GetModel()}
+		c.setCompletionInfo(info)
+		defer func() { c.setCompletionInfo(info) }()
+
+		req, err := c.newRequest(ctx, prompt, true)
+		if err != nil {
+			sendEvent(ctx, events, StreamEvent{Err: err})
+			return
+		}
+
+		resp, err := c.httpClient.Do(req)
+		if err != nil {
+			sendEvent(ctx, events, StreamEvent{Err: fmt.Errorf("error sending request: %v", err)})
+			return
+		}
+		defer resp.Body.Close()
+
+		if resp.StatusCode != http.StatusOK {
+			bodyBytes, _ := io.ReadAll(resp.Body)
+			sendEvent(ctx, events, StreamEvent{Err: apiError(resp.StatusCode, bodyBytes, c.GetModel())})
+			return
+		}
+
+		// Each event has an event line naming its type, which is repeated in
+		// the data, so only the data lines are read
+		reader := bufio.NewReader(resp.Body)
+		for {
+			line, err := reader.ReadString('\n')
+			if err != nil {
+				if err != io.EOF {
+					sendEvent(ctx, events, StreamEvent{Err: fmt.Errorf("error reading stream: %v", err)})
+				}
+				break
+			}
+
+			data, ok := strings.CutPrefix(strings.TrimSpace(line), "data:")
+			if !ok {
+				continue
+			}
+
+			var event anthropicEvent
+			if err := json.Unmarshal([]byte(strings.TrimSpace(data)), &event); err != nil {
+				sendEvent(ctx, events, StreamEvent{Err: fmt.Errorf("error parsing stream data: %v", err)})
+				return
+			}
+
+			switch event.Type {
+			case "message_start":
+				if event.Message.Model != "" {
+					info.Model = event.Message.Model
+				}
+				setUsage(&info, event.Message.Usage)
+			case "content_block_delta":
+				if event.Delta.Type == "text_delta" && event.Delta.Text != "" {
+					if !sendEvent(ctx, events, StreamEvent{Content: event.Delta.Text}) {
+						return
+					}
+				}
+			case "message_delta":
+				if event.Delta.StopReason != "" {
+					info.FinishReason = anthropicFinishReason(event.Delta.StopReason)
+				}
+				setUsage(&info, event.Usage)
+			case "error":
+				sendEvent(ctx, events, StreamEvent{Err: fmt.Errorf("API error (%s): %s", event.Error.Type, event.Error.Message)})
+				return
+			case "message_stop":
+				return
+			}
+		}
+	}()
+
+	return events
+}
+
+// ListModels implements the ModelLister interface, returning the models the
+// API offers
+func (c *AnthropicClient) ListModels(ctx context.Context) ([]string, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", strings.TrimSuffix(c.baseURL.String(), "/")+"/models", nil)
+	if err != nil {
+		return nil, fmt.Errorf("error creating request: %v", err)
+	}
+	c.setHeaders(req)
+
+	return listModels(c.httpClient, req)
+}
@@ -0,0 +1,191 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"testing"
+)
+
+// newTestAnthropicClient creates a client for the Messages API of a test
+// server
+func newTestAnthropicClient(server *httptest.Server, config *Config) *AnthropicClient {
+	baseURL, _ := url.Parse(server.URL)
+	return &AnthropicClient{
+		config:     config,
+		httpClient: server.Client(),
+		baseURL:    baseURL,
+		apiKey:     "test-key",
+	}
+}
+
+// TestAnthropicInput tests that input in the system role is added to the
+// system prompt, which the Messages API takes apart from the messages
+func TestAnthropicInput(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var requestBody map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&requestBody)
+		if want := GetSystemPrompt(false) + "\n\nA long document"; requestBody["system"] != want {
+			t.Errorf("system = %q, want %q", requestBody["system"], want)
+		}
+		wantMessages := []interface{}{map[string]interface{}{"role": "user", "content": "Summarise this"}}
+		if !reflect.DeepEqual(requestBody["messages"], wantMessages) {
+			t.Errorf("messages = %v, want %v", requestBody["messages"], wantMessages)
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"content":[{"type":"text","text":"Done"}]}`))
+	}))
+	defer server.Close()
+
+	client := newTestAnthropicClient(server, &Config{
+		DefaultModel: "claude-test",
+		ModelType:    ModelTypeDefault,
+		Input:        "A long document",
+		InputRole:    InputRoleSystem,
+	})
+	if _, err := client.CreateCompletion(context.Background(), "Summarise this"); err != nil {
+		t.Fatalf("CreateCompletion() error: %v", err)
+	}
+}
+
+// TestNewClientProvider tests that the Anthropic provider selects its client
+func TestNewClientProvider(t *testing.T) {
+	client, err := NewClient(&Config{APIToken: "test-key", Provider: ProviderAnthropic})
+	if err != nil {
+		t.Fatalf("NewClient() error: %v", err)
+	}
+	anthropic, ok := client.(*AnthropicClient)
+	if !ok {
+		t.Fatalf("NewClient() = %T, want *AnthropicClient", client)
+	}
+	if anthropic.baseURL.String() != "https://api.anthropic.com/v1" {
+		t.Errorf("baseURL = %v, want Anthropic's API", anthropic.baseURL)
+	}
+
+	client, _ = NewClient(&Config{APIToken: "test-key"})
+	if _, ok := client.(*OpenAIClient); !ok {
+		t.Errorf("NewClient() without a provider = %T, want *OpenAIClient", client)
+	}
+}
+
+func TestAnthropicCreateCompletion(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/messages" {
+			t.Errorf("path = %s, want /messages", r.URL.Path)
+		}
+		if r.Header.Get("X-Api-Key") != "test-key" {
+			t.Errorf("x-api-key header = %q, want test-key", r.Header.Get("X-Api-Key"))
+		}
+		if r.Header.Get("Anthropic-Version") != anthropicVersion {
+			t.Errorf("anthropic-version header = %q, want %s", r.Header.Get("Anthropic-Version"), anthropicVersion)
+		}
+		if r.Header.Get("X-Team") != "platform" {
+			t.Errorf("X-Team header = %q, want the configured header", r.Header.Get("X-Team"))
+		}
+
+		var requestBody map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&requestBody)
+		if requestBody["model"] != "claude-test" {
+			t.Errorf("model = %v, want claude-test", requestBody["model"])
+		}
+		if requestBody["system"] != GetSystemPrompt(false) {
+			t.Errorf("system = %v, want the default system prompt", requestBody["system"])
+		}
+		if requestBody["max_tokens"] == nil {
+			t.Errorf("max_tokens is missing")
+		}
+		wantMessages := []interface{}{map[string]interface{}{"role": "user", "content": "Test prompt"}}
+		if !reflect.DeepEqual(requestBody["messages"], wantMessages) {
+			t.Errorf("messages = %v, want %v", requestBody["messages"], wantMessages)
+		}
+		if metadata, _ := requestBody["metadata"].(map[string]interface{}); metadata["user_id"] != "alice" {
+			t.Errorf("metadata = %v, want user_id: alice", requestBody["metadata"])
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"model":"claude-test-20250101","content":[{"type":"thinking","thinking":"Hmm"},{"type":"text","text":"Hello"},{"type":"text","text":" there"}],"stop_reason":"end_turn","usage":{"input_tokens":10,"output_tokens":2}}`))
+	}))
+	defer server.Close()
+
+	client := newTestAnthropicClient(server, &Config{
+		DefaultModel: "claude-test",
+		ModelType:    ModelTypeDefault,
+		User:         "alice",
+		Headers:      map[string]string{"X-Team": "platform", "X-Api-Key": "other"},
+	})
+
+	result, err := client.CreateCompletion(context.Background(), "Test prompt")
+	if err != nil {
+		t.Fatalf("CreateCompletion() error: %v", err)
+	}
+	if result != "Hello there" {
+		t.Errorf("CreateCompletion() = %q, want %q", result, "Hello there")
+	}
+
+	info := client.LastCompletionInfo()
+	want := CompletionInfo{Model: "claude-test-20250101", FinishReason: "stop", Usage: &Usage{PromptTokens: 10, CompletionTokens: 2, TotalTokens: 12}}
+	if !reflect.DeepEqual(info, want) {
+		t.Errorf("LastCompletionInfo() = %+v, want %+v", info, want)
+	}
+}
+
+func TestAnthropicCreateCompletionError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+		w.Write([]byte(`{"type":"error","error":{"type":"authentication_error","message":"invalid x-api-key"}}`))
+	}))
+	defer server.Close()
+
+	client := newTestAnthropicClient(server, &Config{DefaultModel: "claude-test"})
+	if _, err := client.CreateCompletion(context.Background(), "Test prompt"); err == nil {
+		t.Errorf("CreateCompletion() error = nil, expected an error")
+	}
+}
+
+func TestAnthropicCreateCompletionStream(t *testing.T) {
+	events := []string{
+		"event: message_start\ndata: {\"type\":\"message_start\",\"message\":{\"model\":\"claude-test-20250101\",\"content\":[],\"usage\":{\"input_tokens\":10,\"output_tokens\":1}}}\n\n",
+		"event: content_block_start\ndata: {\"type\":\"content_block_start\",\"index\":0,\"content_block\":{\"type\":\"text\",\"text\":\"\"}}\n\n",
+		"event: ping\ndata: {\"type\": \"ping\"}\n\n",
+		"event: content_block_delta\ndata: {\"type\":\"content_block_delta\",\"index\":0,\"delta\":{\"type\":\"text_delta\",\"text\":\"Part 1\"}}\n\n",
+		"event: content_block_delta\ndata: {\"type\":\"content_block_delta\",\"index\":0,\"delta\":{\"type\":\"text_delta\",\"text\":\"Part 2\"}}\n\n",
+		"event: content_block_stop\ndata: {\"type\":\"content_block_stop\",\"index\":0}\n\n",
+		"event: message_delta\ndata: {\"type\":\"message_delta\",\"delta\":{\"stop_reason\":\"max_tokens\"},\"usage\":{\"output_tokens\":7}}\n\n",
+		"event: message_stop\ndata: {\"type\":\"message_stop\"}\n\n",
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var requestBody map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&requestBody)
+		if requestBody["stream"] != true {
+			t.Errorf("stream = %v, want true", requestBody["stream"])
+		}
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		for _, event := range events {
+			w.Write([]byte(event))
+			w.(http.Flusher).Flush()
+		}
+	}))
+	defer server.Close()
+
+	client := newTestAnthropicClient(server, &Config{DefaultModel: "claude-test"})
+
+	results, err := collectStream(client.CreateCompletionStream(context.Background(), "Test prompt"))
+	if err != nil {
+		t.Fatalf("CreateCompletionStream() error: %v", err)
+	}
+	if want := []string{"Part 1", "Part 2"}; !reflect.DeepEqual(results, want) {
+		t.Errorf("CreateCompletionStream() = %v, want %v", results, want)
+	}
+
+	info := client.LastCompletionInfo()
+	want := CompletionInfo{Model: "claude-test-20250101", FinishReason: "length", Usage: &Usage{PromptTokens: 10, CompletionTokens: 7, TotalTokens: 17}}
+	if !reflect.DeepEqual(info, want) {
+		t.Errorf("LastCompletionInfo() after a stream = %+v, want %+v", info, want)
+	}
+}
@@ -0,0 +1,103 @@
+package llm
+
+import "strings"
+
+// Capability is an optional feature of a request that not every API or model
+// supports
+type Capability int
+
+const (
+	// CapabilityTools is needed to run commands on the model's behalf and
+	// return their output, as aipipe k8s does
+	CapabilityTools Capability = iota
+)
+
+// String describes a capability for error messages
+func (c Capability) String() string {
+	switch c {
+	case CapabilityTools:
+		return "tools"
+	default:
+		return "unknown capability"
+	}
+}
+
+// providerCapabilities lists what each API supports, for models that aren't
+// in modelCapabilities. The OpenAI compatible APIs vary too much to say, so
+// they are only described model by model.
+var providerCapabilities = map[string][]Capability{
+	ProviderAnthropic: {CapabilityTools},
+	ProviderOllama:    {CapabilityTools},
+}
+
+// modelCapabilities lists what well known models support, by name. A model
+// also matches a longer name made by adding a version, such as
+// gpt-4o-2024-08-06, or an Ollama tag, such as llava:13b.
+var modelCapabilities = map[string][]Capability{
+	// OpenAI
+	"gpt-4o":       {CapabilityTools},
+	"gpt-4o-mini":  {CapabilityTools},
+	"gpt-4.1":      {CapabilityTools},
+	"gpt-4.1-mini": {CapabilityTools},
+	"gpt-4.1-nano": {CapabilityTools},
+	"gpt-5":        {CapabilityTools},
+	"o3":           {CapabilityTools},
+	"o3-mini":      {CapabilityTools},
+	"o4-mini":      {CapabilityTools},
+
+	// Groq
+	"llama-3.3-70b-versatile": {CapabilityTools},
+	"llama-3.1-8b-instant":    {CapabilityTools},
+	"qwen-qwq-32b":            {CapabilityTools},
+
+	// Ollama vision models, most of which weren't trained to use tools
+	"llava":           {},
+	"llama3.2-vision": {},
+	"gemma3":          {},
+	"qwen2.5vl":       {CapabilityTools},
+}
+
+// lookupModel returns the entry of a table of models for a model, matching
+// the longest name in the table that the model is, or starts with followed by
+// a dash or colon
+func lookupModel[V any](table map[string]V, model string) (V, bool) {
+	name, ok := matchModel(table, model)
+	return table[name], ok
+}
+
+// matchModel returns the name in a table of models that lookupModel matches
+// for a model
+func matchModel[V any](table map[string]V, model string) (string, bool) {
+	if _, ok := table[model]; ok {
+		return model, true
+	}
+
+	best := ""
+	for name := range table {
+		if (strings.HasPrefix(model, name+"-") || strings.HasPrefix(model, name+":")) && len(name) > len(best) {
+			best = name
+		}
+	}
+	return best, best != ""
+}
+
+// Supports reports whether the model in use supports a capability, so that
+// options can be refused with a clear error rather than a 400 response.
+// Models and APIs that aren't known are assumed to support everything,
+// leaving the API to decide.
+func (c *Config) Supports(capability Capability) bool {
+	capabilities, ok := lookupModel(modelCapabilities, c.model())
+	if !ok {
+		capabilities, ok = providerCapabilities[c.Provider]
+	}
+	if !ok {
+		return true
+	}
+
+	for _, supported := range capabilities {
+		if supported == capability {
+			return true
+		}
+	}
+	return false
+}
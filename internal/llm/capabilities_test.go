@@ -0,0 +1,30 @@
+package llm
+
+import "testing"
+
+func TestSupports(t *testing.T) {
+	tests := []struct {
+		name       string
+		provider   string
+		model      string
+		capability Capability
+		want       bool
+	}{
+		{"known model", "", "gpt-4o", CapabilityTools, true},
+		{"dated version of a known model", "", "gpt-4o-2024-08-06", CapabilityTools, true},
+		{"longest match", "", "llama3.2-vision-11b", CapabilityTools, false},
+		{"unknown model", "", "my-gateway-model", CapabilityTools, true},
+		{"similar name isn't a match", "", "llavaa", CapabilityTools, true},
+		{"provider with the capability", ProviderAnthropic, "claude-sonnet-4-5", CapabilityTools, true},
+		{"Ollama model with the capability", ProviderOllama, "llama3.2:latest", CapabilityTools, true},
+		{"Ollama tag of a model without the capability", ProviderOllama, "llava:13b", CapabilityTools, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := &Config{Provider: tt.provider, ModelType: ModelTypeDefault, DefaultModel: tt.model}
+			if got := config.Supports(tt.capability); got != tt.want {
+				t.Errorf("Supports(%v) for %s = %v, want %v", tt.capability, tt.model, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,108 @@
+package llm
+
+import (
+	"strings"
+
+	"github.com/rba100/aipipe/internal/tokenizer"
+)
+
+// modelLimit is how many tokens a model reads and writes
+type modelLimit struct {
+	// context is the size of the context window: the tokens read, prompt
+	// and answer together
+	context int
+	// output is the most tokens written in an answer
+	output int
+}
+
+// modelLimits lists the limits of well known models, matched by name like
+// modelCapabilities. Ollama models are left out, since the server chooses how
+// much context to give them.
+var modelLimits = map[string]modelLimit{
+	// OpenAI
+	"gpt-4o":       {context: 128000, output: 16384},
+	"gpt-4o-mini":  {context: 128000, output: 16384},
+	"gpt-4.1":      {context: 1047576, output: 32768},
+	"gpt-4.1-mini": {context: 1047576, output: 32768},
+	"gpt-4.1-nano": {context: 1047576, output: 32768},
+	"gpt-5":        {context: 400000, output: 128000},
+	"o3":           {context: 200000, output: 100000},
+	"o3-mini":      {context: 200000, output: 100000},
+	"o4-mini":      {context: 200000, output: 100000},
+
+	// Groq
+	"llama-3.3-70b-versatile": {context: 131072, output: 32768},
+	"llama-3.1-8b-instant":    {context: 131072, output: 131072},
+	"qwen-qwq-32b":            {context: 131072, output: 131072},
+
+	// Anthropic
+	"claude-sonnet-4-5": {context: 200000, output: 64000},
+	"claude-haiku-4-5":  {context: 200000, output: 64000},
+	"claude-opus-4-1":   {context: 200000, output: 32000},
+}
+
+const (
+	// contextMargin is the fraction of the context window left unused when
+	// working out max_tokens, since prompt tokens are estimated rather than
+	// counted by the model's own tokenizer
+	contextMargin = 0.05
+
+	// messageOverhead is the tokens each message costs beyond its content,
+	// for its role and delimiters
+	messageOverhead = 4
+)
+
+// ContextWindow returns how many tokens a model reads, prompt and answer
+// together, or false if the model isn't known. overrides, from the
+// `contextWindows` key of the config file, take precedence over the built-in
+// table and are matched by name the same way.
+func ContextWindow(model string, overrides map[string]int) (int, bool) {
+	limit, ok := lookupLimit(model, overrides)
+	return limit.context, ok
+}
+
+// lookupLimit returns the limits of a model, from overrides or the built-in
+// table, whichever names the model more closely. Overrides are matched
+// ignoring case, and a model only in overrides may answer with the whole
+// window.
+func lookupLimit(model string, overrides map[string]int) (modelLimit, bool) {
+	name, known := matchModel(modelLimits, model)
+	limit := modelLimits[name]
+
+	lowered := make(map[string]int, len(overrides))
+	for override, tokens := range overrides {
+		lowered[strings.ToLower(override)] = tokens
+	}
+	override, ok := matchModel(lowered, strings.ToLower(model))
+	if !ok || lowered[override] <= 0 || (known && len(name) > len(override)) {
+		return limit, known
+	}
+
+	limit.context = lowered[override]
+	if !known || limit.output > limit.context {
+		limit.output = limit.context
+	}
+	return limit, true
+}
+
+// maxTokens returns the most tokens the model can answer a request made of
+// messages with: what the prompt leaves of the context window, less a margin
+// for estimation, up to the model's output limit. It returns false if the
+// model's limits aren't known, or the prompt already fills the window, in
+// which case the provider's default is left to apply.
+func (config *Config) maxTokens(model string, messages []map[string]string) (int, bool) {
+	limit, ok := lookupLimit(model, config.ContextWindows)
+	if !ok {
+		return 0, false
+	}
+
+	prompt := 0
+	for _, message := range messages {
+		prompt += tokenizer.Count(model, message["content"]) + messageOverhead
+	}
+	available := limit.context - prompt - int(float64(limit.context)*contextMargin)
+	if available <= 0 {
+		return 0, false
+	}
+	return min(available, limit.output), true
+}
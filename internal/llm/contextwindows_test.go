@@ -0,0 +1,63 @@
+package llm
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/rba100/aipipe/internal/tokenizer"
+)
+
+func TestContextWindow(t *testing.T) {
+	overrides := map[string]int{"Llama3.2": 32768, "gpt-4o": 64000}
+	tests := []struct {
+		model     string
+		overrides map[string]int
+		want      int
+		wantOK    bool
+	}{
+		{"gpt-4o", nil, 128000, true},
+		{"gpt-4.1-mini-2025-04-14", nil, 1047576, true},
+		{"claude-sonnet-4-5-20250929", nil, 200000, true},
+		{"llama3.2:latest", nil, 0, false},
+		{"llama3.2:latest", overrides, 32768, true},
+		{"gpt-4o-2024-08-06", overrides, 64000, true},
+		{"gpt-4o-mini", overrides, 128000, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.model, func(t *testing.T) {
+			got, ok := ContextWindow(tt.model, tt.overrides)
+			if got != tt.want || ok != tt.wantOK {
+				t.Errorf("ContextWindow(%q) = %d, %v, want %d, %v", tt.model, got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestMaxTokens(t *testing.T) {
+	short := []map[string]string{{"role": "user", "content": "hello"}}
+	long := []map[string]string{{"role": "user", "content": strings.Repeat("word ", 120000)}}
+	longTokens := tokenizer.Count("gpt-4o", long[0]["content"]) + messageOverhead
+	tests := []struct {
+		name     string
+		model    string
+		windows  map[string]int
+		messages []map[string]string
+		want     int
+		wantOK   bool
+	}{
+		{"Output limit", "gpt-4o", nil, short, 16384, true},
+		{"Long prompt", "gpt-4o", nil, long, 128000 - longTokens - 6400, true},
+		{"Prompt fills the window", "gpt-4o-mini", map[string]int{"gpt-4o-mini": 100000}, long, 0, false},
+		{"Unknown model", "mistral", nil, short, 0, false},
+		{"Configured window", "mistral", map[string]int{"mistral": 8192}, short, 8192 - tokenizer.Count("mistral", "hello") - messageOverhead - 409, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := &Config{ContextWindows: tt.windows}
+			got, ok := config.maxTokens(tt.model, tt.messages)
+			if got != tt.want || ok != tt.wantOK {
+				t.Errorf("maxTokens(%q) = %d, %v, want %d, %v", tt.model, got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
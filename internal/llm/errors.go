@@ -0,0 +1,62 @@
+package llm
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// ModelNotFoundError is returned when the API doesn't offer the model
+// requested, usually because it has been renamed or retired
+type ModelNotFoundError struct {
+	Model string
+	// Message is the API's explanation
+	Message string
+}
+
+func (e *ModelNotFoundError) Error() string {
+	return fmt.Sprintf("the API doesn't offer the model %s: %s", e.Model, e.Message)
+}
+
+// apiError returns the error for an unsuccessful response to a request for
+// a model, recognising the ways each API says the model doesn't exist
+func apiError(statusCode int, body []byte, model string) error {
+	if message, ok := modelNotFoundMessage(body); ok {
+		return &ModelNotFoundError{Model: model, Message: message}
+	}
+	return fmt.Errorf("API error (status %d): %s", statusCode, string(body))
+}
+
+// modelNotFoundMessage returns the message of an error response saying the
+// model requested doesn't exist. OpenAI and Groq give a code, Anthropic a
+// not_found_error about the model and Ollama only a message.
+func modelNotFoundMessage(body []byte) (string, bool) {
+	var response struct {
+		Error json.RawMessage `json:"error"`
+	}
+	if err := json.Unmarshal(body, &response); err != nil || response.Error == nil {
+		return "", false
+	}
+
+	var message string
+	if err := json.Unmarshal(response.Error, &message); err == nil {
+		return message, strings.HasPrefix(message, "model ") && strings.Contains(message, "not found")
+	}
+
+	var details struct {
+		Code    string `json:"code"`
+		Type    string `json:"type"`
+		Message string `json:"message"`
+	}
+	if err := json.Unmarshal(response.Error, &details); err != nil {
+		return "", false
+	}
+	switch {
+	case details.Code == "model_not_found" || details.Code == "model_decommissioned":
+		return details.Message, true
+	case details.Type == "not_found_error" && strings.HasPrefix(details.Message, "model:"):
+		return details.Message, true
+	default:
+		return "", false
+	}
+}
@@ -0,0 +1,34 @@
+package llm
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestAPIError(t *testing.T) {
+	tests := []struct {
+		name         string
+		body         string
+		wantNotFound bool
+	}{
+		{"OpenAI", `{"error":{"message":"The model ` + "`gpt-5o`" + ` does not exist or you do not have access to it.","type":"invalid_request_error","code":"model_not_found"}}`, true},
+		{"Groq retired model", `{"error":{"message":"The model ` + "`qwen-qwq-32b`" + ` has been decommissioned.","type":"invalid_request_error","code":"model_decommissioned"}}`, true},
+		{"Anthropic", `{"type":"error","error":{"type":"not_found_error","message":"model: claude-old"}}`, true},
+		{"Ollama", `{"error":"model \"llama9\" not found, try pulling it first"}`, true},
+		{"other error", `{"error":{"message":"Rate limit reached","type":"requests","code":"rate_limit_exceeded"}}`, false},
+		{"other Anthropic not found", `{"type":"error","error":{"type":"not_found_error","message":"Not found"}}`, false},
+		{"not JSON", `Bad Gateway`, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := apiError(404, []byte(tt.body), "the-model")
+			var notFound *ModelNotFoundError
+			if errors.As(err, &notFound) != tt.wantNotFound {
+				t.Fatalf("apiError() = %v, want a ModelNotFoundError: %v", err, tt.wantNotFound)
+			}
+			if tt.wantNotFound && (notFound.Model != "the-model" || notFound.Message == "") {
+				t.Errorf("apiError() = %+v, want the model and the API's message", notFound)
+			}
+		})
+	}
+}
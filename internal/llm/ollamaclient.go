@@ -0,0 +1,257 @@
+package llm
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+)
+
+// OllamaClient implements the LLMClient interface for a local Ollama server,
+// using its native chat API
+type OllamaClient struct {
+	config     *Config
+	httpClient *http.Client
+	baseURL    *url.URL
+
+	infoMutex sync.Mutex
+	lastInfo  CompletionInfo
+}
+
+// ollamaResponse is a response from the chat API, or a line of a streamed
+// response
+type ollamaResponse struct {
+	Model   string `json:"model"`
+	Message struct {
+		Content string `json:"content"`
+	} `json:"message"`
+	Done            bool   `json:"done"`
+	DoneReason      string `json:"done_reason"`
+	PromptEvalCount int    `json:"prompt_eval_count"`
+	EvalCount       int    `json:"eval_count"`
+	Error           string `json:"error"`
+}
+
+// GetModel returns the appropriate model based on the config
+func (c *OllamaClient) GetModel() string {
+	return c.config.model()
+}
+
+// LastCompletionInfo implements the LLMClient interface
+func (c *OllamaClient) LastCompletionInfo() CompletionInfo {
+	c.infoMutex.Lock()
+	defer c.infoMutex.Unlock()
+	return c.lastInfo
+}
+
+// setCompletionInfo records the description of the latest completion
+func (c *OllamaClient) setCompletionInfo(info CompletionInfo) {
+	c.infoMutex.Lock()
+	defer c.infoMutex.Unlock()
+	c.lastInfo = info
+}
+
+// update records what the final response, or line of a stream, says about
+// the completion
+func (r *ollamaResponse) update(info *CompletionInfo) {
+	if r.Model != "" {
+		info.Model = r.Model
+	}
+	if r.DoneReason != "" {
+		info.FinishReason = r.DoneReason
+	}
+	if r.Done {
+		info.Usage = &Usage{
+			PromptTokens:     r.PromptEvalCount,
+			CompletionTokens: r.EvalCount,
+			TotalTokens:      r.PromptEvalCount + r.EvalCount,
+		}
+	}
+}
+
+// url returns the URL of an API path on the server
+func (c *OllamaClient) url(path string) string {
+	return strings.TrimSuffix(c.baseURL.String(), "/") + path
+}
+
+// newRequest creates a request to the chat API
+func (c *OllamaClient) newRequest(ctx context.Context, prompt string, stream bool) (*http.Request, error) {
+	model := c.GetModel()
+	messages := c.config.messages(prompt)
+	requestBody := map[string]interface{}{
+		"model":    model,
+		"messages": messages,
+		// Ollama streams unless told not to
+		"stream": stream,
+	}
+
+	// The server gives models a small context window unless asked for more,
+	// so a configured window is requested along with an answer to fit it
+	options := map[string]interface{}{}
+	if window, ok := ContextWindow(model, c.config.ContextWindows); ok {
+		options["num_ctx"] = window
+	}
+	if maxTokens, ok := c.config.maxTokens(model, messages); ok {
+		options["num_predict"] = maxTokens
+	}
+	if len(options) > 0 {
+		requestBody["options"] = options
+	}
+
+	jsonBody, err := json.Marshal(requestBody)
+	if err != nil {
+		return nil, fmt.Errorf("error marshaling request: %v", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", c.url("/api/chat"), bytes.NewBuffer(jsonBody))
+	if err != nil {
+		return nil, fmt.Errorf("error creating request: %v", err)
+	}
+	for name, value := range c.config.Headers {
+		req.Header.Set(name, value)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	return req, nil
+}
+
+// do sends a request to the server, returning an error if it fails or isn't
+// running
+func (c *OllamaClient) do(req *http.Request) (*http.Response, error) {
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("error sending request to Ollama at %s (is it running?): %v", c.baseURL, err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		bodyBytes, _ := io.ReadAll(resp.Body)
+		return nil, apiError(resp.StatusCode, bodyBytes, c.GetModel())
+	}
+	return resp, nil
+}
+
+// CreateCompletion sends a prompt to the API and returns the completion
+func (c *OllamaClient) CreateCompletion(ctx context.Context, prompt string) (string, error) {
+	req, err := c.newRequest(ctx, prompt, false)
+	if err != nil {
+		return "", err
+	}
+
+	resp, err := c.do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	var response ollamaResponse
+	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+		return "", fmt.Errorf("error decoding response: %v", err)
+	}
+	if response.Error != "" {
+		return "", fmt.Errorf("API error: %s", response.Error)
+	}
+
+	info := CompletionInfo{Model: c.GetModel()}
+	response.update(&info)
+	c.setCompletionInfo(info)
+
+	return response.Message.Content, nil
+}
+
+// CreateCompletionStream sends a prompt to the API and returns a stream of
+// the completion's parts
+func (c *OllamaClient) CreateCompletionStream(ctx context.Context, prompt string) <-chan StreamEvent {
+	events := make(chan StreamEvent)
+
+	go func() {
+		defer close(events)
+
+		info := CompletionInfo{Model: c.GetModel()}
+		c.setCompletionInfo(info)
+		defer func() { c.setCompletionInfo(info) }()
+
+		req, err := c.newRequest(ctx, prompt, true)
+		if err != nil {
+			sendEvent(ctx, events, StreamEvent{Err: err})
+			return
+		}
+
+		resp, err := c.do(req)
+		if err != nil {
+			sendEvent(ctx, events, StreamEvent{Err: err})
+			return
+		}
+		defer resp.Body.Close()
+
+		// The response is a JSON object on each line
+		reader := bufio.NewReader(resp.Body)
+		for {
+			line, err := reader.ReadString('\n')
+			if strings.TrimSpace(line) != "" {
+				var response ollamaResponse
+				if err := json.Unmarshal([]byte(line), &response); err != nil {
+					sendEvent(ctx, events, StreamEvent{Err: fmt.Errorf("error parsing stream data: %v", err)})
+					return
+				}
+				if response.Error != "" {
+					sendEvent(ctx, events, StreamEvent{Err: fmt.Errorf("API error: %s", response.Error)})
+					return
+				}
+
+				response.update(&info)
+				if response.Message.Content != "" {
+					if !sendEvent(ctx, events, StreamEvent{Content: response.Message.Content}) {
+						return
+					}
+				}
+				if response.Done {
+					return
+				}
+			}
+
+			if err != nil {
+				if err != io.EOF {
+					sendEvent(ctx, events, StreamEvent{Err: fmt.Errorf("error reading stream: %v", err)})
+				}
+				return
+			}
+		}
+	}()
+
+	return events
+}
+
+// ListModels implements the ModelLister interface, returning the models that
+// have been pulled
+func (c *OllamaClient) ListModels(ctx context.Context) ([]string, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", c.url("/api/tags"), nil)
+	if err != nil {
+		return nil, fmt.Errorf("error creating request: %v", err)
+	}
+
+	resp, err := c.do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var response struct {
+		Models []struct {
+			Name string `json:"name"`
+		} `json:"models"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+		return nil, fmt.Errorf("error decoding response: %v", err)
+	}
+
+	var models []string
+	for _, model := range response.Models {
+		models = append(models, model.Name)
+	}
+	return models, nil
+}
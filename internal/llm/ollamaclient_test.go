@@ -0,0 +1,127 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+// newTestOllamaServer serves the chat and tags APIs of an Ollama server
+func newTestOllamaServer(t *testing.T) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/tags":
+			w.Write([]byte(`{"models":[{"name":"llama3.2:latest","size":2019393189},{"name":"qwen2.5-coder:7b"}]}`))
+		case "/api/chat":
+			var requestBody map[string]interface{}
+			json.NewDecoder(r.Body).Decode(&requestBody)
+			if requestBody["model"] != "llama3.2" {
+				t.Errorf("model = %v, want llama3.2", requestBody["model"])
+			}
+			if r.Header.Get("Authorization") != "" {
+				t.Errorf("Authorization header = %q, want none", r.Header.Get("Authorization"))
+			}
+			if options, _ := requestBody["options"].(map[string]interface{}); options["num_ctx"] != 8192.0 || options["num_predict"] == nil {
+				t.Errorf("options = %v, want the configured context window and an answer to fit it", requestBody["options"])
+			}
+
+			if requestBody["stream"] != true {
+				w.Write([]byte(`{"model":"llama3.2","message":{"role":"assistant","content":"Hello"},"done":true,"done_reason":"stop","prompt_eval_count":20,"eval_count":3}`))
+				return
+			}
+			w.Write([]byte(`{"model":"llama3.2","message":{"role":"assistant","content":"Part 1"},"done":false}` + "\n"))
+			w.(http.Flusher).Flush()
+			w.Write([]byte(`{"model":"llama3.2","message":{"role":"assistant","content":"Part 2"},"done":false}` + "\n"))
+			w.Write([]byte(`{"model":"llama3.2","message":{"role":"assistant","content":""},"done":true,"done_reason":"length","prompt_eval_count":20,"eval_count":9}` + "\n"))
+		default:
+			t.Errorf("unexpected request for %s", r.URL.Path)
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+}
+
+func TestOllamaClient(t *testing.T) {
+	server := newTestOllamaServer(t)
+	defer server.Close()
+
+	client, err := NewClient(&Config{
+		APIEndpoint:    server.URL,
+		Provider:       ProviderOllama,
+		ModelType:      ModelTypeDefault,
+		DefaultModel:   "llama3.2",
+		ContextWindows: map[string]int{"llama3.2": 8192},
+	})
+	if err != nil {
+		t.Fatalf("NewClient() error: %v", err)
+	}
+
+	result, err := client.CreateCompletion(context.Background(), "Test prompt")
+	if err != nil {
+		t.Fatalf("CreateCompletion() error: %v", err)
+	}
+	if result != "Hello" {
+		t.Errorf("CreateCompletion() = %q, want Hello", result)
+	}
+	want := CompletionInfo{Model: "llama3.2", FinishReason: "stop", Usage: &Usage{PromptTokens: 20, CompletionTokens: 3, TotalTokens: 23}}
+	if info := client.LastCompletionInfo(); !reflect.DeepEqual(info, want) {
+		t.Errorf("LastCompletionInfo() = %+v, want %+v", info, want)
+	}
+
+	results, err := collectStream(client.CreateCompletionStream(context.Background(), "Test prompt"))
+	if err != nil {
+		t.Fatalf("CreateCompletionStream() error: %v", err)
+	}
+	if want := []string{"Part 1", "Part 2"}; !reflect.DeepEqual(results, want) {
+		t.Errorf("CreateCompletionStream() = %v, want %v", results, want)
+	}
+	want = CompletionInfo{Model: "llama3.2", FinishReason: "length", Usage: &Usage{PromptTokens: 20, CompletionTokens: 9, TotalTokens: 29}}
+	if info := client.LastCompletionInfo(); !reflect.DeepEqual(info, want) {
+		t.Errorf("LastCompletionInfo() after a stream = %+v, want %+v", info, want)
+	}
+
+	models, err := client.(ModelLister).ListModels(context.Background())
+	if err != nil {
+		t.Fatalf("ListModels() error: %v", err)
+	}
+	if want := []string{"llama3.2:latest", "qwen2.5-coder:7b"}; !reflect.DeepEqual(models, want) {
+		t.Errorf("ListModels() = %v, want %v", models, want)
+	}
+}
+
+func TestOllamaClientNotRunning(t *testing.T) {
+	server := newTestOllamaServer(t)
+	server.Close()
+
+	client, _ := NewClient(&Config{APIEndpoint: server.URL, Provider: ProviderOllama, ModelType: ModelTypeDefault, DefaultModel: "llama3.2"})
+	if _, err := client.CreateCompletion(context.Background(), "Test prompt"); err == nil {
+		t.Errorf("CreateCompletion() error = nil, expected an error")
+	}
+	if _, err := client.(ModelLister).ListModels(context.Background()); err == nil {
+		t.Errorf("ListModels() error = nil, expected an error")
+	}
+}
+
+func TestOpenAIListModels(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/models" {
+			t.Errorf("path = %s, want /v1/models", r.URL.Path)
+		}
+		if r.Header.Get("Authorization") != "Bearer test-token" {
+			t.Errorf("Authorization header = %q, want Bearer test-token", r.Header.Get("Authorization"))
+		}
+		w.Write([]byte(`{"object":"list","data":[{"id":"gpt-4o","object":"model"},{"id":"gpt-4o-mini","object":"model"}]}`))
+	}))
+	defer server.Close()
+
+	client, _ := NewClient(&Config{APIEndpoint: server.URL + "/v1", APIToken: "test-token"})
+	models, err := client.(ModelLister).ListModels(context.Background())
+	if err != nil {
+		t.Fatalf("ListModels() error: %v", err)
+	}
+	if want := []string{"gpt-4o", "gpt-4o-mini"}; !reflect.DeepEqual(models, want) {
+		t.Errorf("ListModels() = %v, want %v", models, want)
+	}
+}
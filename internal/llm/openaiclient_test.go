@@ -1,11 +1,13 @@
 package llm
 
 import (
+	"context"
 	"encoding/json"
 	"io"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
+	"reflect"
 	"testing"
 )
 
@@ -221,7 +223,7 @@ func TestCreateCompletion(t *testing.T) {
 		}
 
 		// Call CreateCompletion
-		response, err := client.CreateCompletion("Test prompt")
+		response, err := client.CreateCompletion(context.Background(), "Test prompt")
 		if err != nil {
 			t.Errorf("CreateCompletion() error = %v, expected no error", err)
 		}
@@ -252,7 +254,7 @@ func TestCreateCompletion(t *testing.T) {
 		}
 
 		// Call CreateCompletion
-		_, err := client.CreateCompletion("Test prompt")
+		_, err := client.CreateCompletion(context.Background(), "Test prompt")
 		if err == nil {
 			t.Errorf("CreateCompletion() error = nil, expected an error")
 		}
@@ -281,7 +283,7 @@ func TestCreateCompletion(t *testing.T) {
 		}
 
 		// Call CreateCompletion
-		_, err := client.CreateCompletion("Test prompt")
+		_, err := client.CreateCompletion(context.Background(), "Test prompt")
 		if err == nil {
 			t.Errorf("CreateCompletion() error = nil, expected an error")
 		}
@@ -370,12 +372,12 @@ func TestCreateCompletionStream(t *testing.T) {
 		}
 
 		// Call CreateCompletionStream
-		stream := client.CreateCompletionStream("Test prompt")
+		stream := client.CreateCompletionStream(context.Background(), "Test prompt")
 
 		// Collect stream results
-		var results []string
-		for part := range stream {
-			results = append(results, part)
+		results, err := collectStream(stream)
+		if err != nil {
+			t.Errorf("CreateCompletionStream() error = %v, expected no error", err)
 		}
 
 		// Check results
@@ -413,12 +415,12 @@ func TestCreateCompletionStream(t *testing.T) {
 		}
 
 		// Call CreateCompletionStream
-		stream := client.CreateCompletionStream("Test prompt")
+		stream := client.CreateCompletionStream(context.Background(), "Test prompt")
 
 		// Collect stream results
-		var results []string
-		for part := range stream {
-			results = append(results, part)
+		results, err := collectStream(stream)
+		if err == nil {
+			t.Errorf("CreateCompletionStream() error = nil, expected an error")
 		}
 
 		// Check results - should be empty since there was an error
@@ -426,4 +428,235 @@ func TestCreateCompletionStream(t *testing.T) {
 			t.Errorf("CreateCompletionStream() returned %d parts, expected 0", len(results))
 		}
 	})
+
+	// Test cancelling the stream part way through
+	t.Run("Cancelled stream", func(t *testing.T) {
+		done := make(chan struct{})
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "text/event-stream")
+			w.Write([]byte("data: {\"choices\":[{\"delta\":{\"content\":\"Part 1\"}}]}\n\n"))
+			w.(http.Flusher).Flush()
+
+			// Never finish, until the client goes away
+			select {
+			case <-r.Context().Done():
+			case <-done:
+			}
+		}))
+		defer server.Close()
+		defer close(done)
+
+		baseURL, _ := url.Parse(server.URL)
+		client := &OpenAIClient{
+			config: &Config{
+				DefaultModel: "test-model",
+				ModelType:    ModelTypeDefault,
+			},
+			httpClient: server.Client(),
+			baseURL:    baseURL,
+			apiKey:     "test-token",
+		}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		var results []string
+		for event := range client.CreateCompletionStream(ctx, "Test prompt") {
+			if event.Err != nil {
+				t.Errorf("CreateCompletionStream() error after cancelling = %v, want none", event.Err)
+			}
+			results = append(results, event.Content)
+			cancel()
+		}
+
+		// The stream is closed with the parts received before cancelling
+		if len(results) != 1 || results[0] != "Part 1" {
+			t.Errorf("CreateCompletionStream() after cancelling = %v, want [Part 1]", results)
+		}
+	})
+}
+
+// TestSystemPromptOverride tests that Config.SystemPrompt replaces the default prompt
+func TestSystemPromptOverride(t *testing.T) {
+	client := &OpenAIClient{config: &Config{IsCodeBlock: true}}
+	if got := client.getSystemPrompt(); got != GetSystemPrompt(true) {
+		t.Errorf("getSystemPrompt() = %q, want the code block prompt", got)
+	}
+
+	client.config.SystemPrompt = GetOneLineSystemPrompt(false)
+	if got := client.getSystemPrompt(); got != GetOneLineSystemPrompt(false) {
+		t.Errorf("getSystemPrompt() = %q, want the configured prompt", got)
+	}
+}
+
+// TestMessages tests that input is sent as a message of its own, before the
+// conversation and the prompt, in the role configured
+func TestMessages(t *testing.T) {
+	system := GetSystemPrompt(false)
+	conversation := []Message{
+		{Role: "user", Content: "What is a monad?"},
+		{Role: "assistant", Content: "A monoid in the category of endofunctors."},
+	}
+	tests := []struct {
+		name         string
+		input        string
+		inputRole    string
+		conversation []Message
+		want         []map[string]string
+	}{
+		{"no input", "", InputRoleUser, nil, []map[string]string{
+			{"role": "system", "content": system},
+			{"role": "user", "content": "Summarise this"},
+		}},
+		{"user input", "A long document", InputRoleUser, nil, []map[string]string{
+			{"role": "system", "content": system},
+			{"role": "user", "content": "A long document"},
+			{"role": "user", "content": "Summarise this"},
+		}},
+		{"system input", "A long document", InputRoleSystem, nil, []map[string]string{
+			{"role": "system", "content": system},
+			{"role": "system", "content": "A long document"},
+			{"role": "user", "content": "Summarise this"},
+		}},
+		{"conversation", "", InputRoleUser, conversation, []map[string]string{
+			{"role": "system", "content": system},
+			{"role": "user", "content": "What is a monad?"},
+			{"role": "assistant", "content": "A monoid in the category of endofunctors."},
+			{"role": "user", "content": "Summarise this"},
+		}},
+		{"input and conversation", "A long document", InputRoleUser, conversation, []map[string]string{
+			{"role": "system", "content": system},
+			{"role": "user", "content": "A long document"},
+			{"role": "user", "content": "What is a monad?"},
+			{"role": "assistant", "content": "A monoid in the category of endofunctors."},
+			{"role": "user", "content": "Summarise this"},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := &Config{Input: tt.input, InputRole: tt.inputRole, Conversation: tt.conversation}
+			if got := config.messages("Summarise this"); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("messages() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestLastCompletionInfo tests that the model, finish reason and usage are
+// recorded from plain and streamed responses
+func TestLastCompletionInfo(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var requestBody map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&requestBody)
+
+		if requestBody["stream"] != true {
+			w.Header().Set("Content-Type", "application/json")
+			w.Write([]byte(`{"model":"test-model-2024","choices":[{"message":{"content":"Hi"},"finish_reason":"stop"}],"usage":{"prompt_tokens":5,"completion_tokens":1,"total_tokens":6}}`))
+			return
+		}
+
+		if _, ok := requestBody["stream_options"]; !ok {
+			t.Errorf("Expected stream_options in a streamed request with IncludeUsage")
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Write([]byte("data: {\"model\":\"test-model-2024\",\"choices\":[{\"delta\":{\"content\":\"Hi\"}}]}\n\n"))
+		w.Write([]byte("data: {\"choices\":[{\"delta\":{},\"finish_reason\":\"length\"}]}\n\n"))
+		w.Write([]byte("data: {\"choices\":[],\"usage\":{\"prompt_tokens\":5,\"completion_tokens\":7,\"total_tokens\":12}}\n\n"))
+		w.Write([]byte("data: [DONE]\n\n"))
+	}))
+	defer server.Close()
+
+	baseURL, _ := url.Parse(server.URL)
+	client := &OpenAIClient{
+		config: &Config{
+			DefaultModel: "test-model",
+			ModelType:    ModelTypeDefault,
+			IncludeUsage: true,
+		},
+		httpClient: server.Client(),
+		baseURL:    baseURL,
+		apiKey:     "test-token",
+	}
+
+	if _, err := client.CreateCompletion(context.Background(), "Test prompt"); err != nil {
+		t.Fatalf("CreateCompletion() error: %v", err)
+	}
+	info := client.LastCompletionInfo()
+	if info.Model != "test-model-2024" || info.FinishReason != "stop" || info.Usage == nil || info.Usage.TotalTokens != 6 {
+		t.Errorf("LastCompletionInfo() after CreateCompletion() = %+v, want the reported model, finish reason and usage", info)
+	}
+
+	for range client.CreateCompletionStream(context.Background(), "Test prompt") {
+	}
+	info = client.LastCompletionInfo()
+	if info.Model != "test-model-2024" || info.FinishReason != "length" || info.Usage == nil || info.Usage.CompletionTokens != 7 {
+		t.Errorf("LastCompletionInfo() after a stream = %+v, want the reported model, finish reason and usage", info)
+	}
+}
+
+func TestAttribution(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var requestBody map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&requestBody)
+
+		if requestBody["user"] != "alice" {
+			t.Errorf("user = %v, want alice", requestBody["user"])
+		}
+		if metadata, _ := requestBody["metadata"].(map[string]interface{}); metadata["team"] != "platform" {
+			t.Errorf("metadata = %v, want team: platform", requestBody["metadata"])
+		}
+		if r.Header.Get("X-Cost-Center") != "42" {
+			t.Errorf("X-Cost-Center header = %q, want 42", r.Header.Get("X-Cost-Center"))
+		}
+		if r.Header.Get("Authorization") != "Bearer test-token" {
+			t.Errorf("Authorization header = %q, the configured headers must not replace it", r.Header.Get("Authorization"))
+		}
+		if r.Header.Get("OpenAI-Organization") != "org-123" || r.Header.Get("OpenAI-Project") != "proj_456" {
+			t.Errorf("organization and project headers = %q, %q, want org-123, proj_456", r.Header.Get("OpenAI-Organization"), r.Header.Get("OpenAI-Project"))
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"choices":[{"message":{"content":"Hi"}}]}`))
+	}))
+	defer server.Close()
+
+	baseURL, _ := url.Parse(server.URL)
+	client := &OpenAIClient{
+		config: &Config{
+			DefaultModel: "test-model",
+			ModelType:    ModelTypeDefault,
+			User:         "alice",
+			Metadata:     map[string]string{"team": "platform"},
+			Headers:      map[string]string{"X-Cost-Center": "42", "Authorization": "Bearer other"},
+			Organization: "org-123",
+			Project:      "proj_456",
+		},
+		httpClient: server.Client(),
+		baseURL:    baseURL,
+		apiKey:     "test-token",
+	}
+
+	if _, err := client.CreateCompletion(context.Background(), "Test prompt"); err != nil {
+		t.Fatalf("CreateCompletion() error: %v", err)
+	}
+}
+
+func TestMaxTokensField(t *testing.T) {
+	tests := []struct {
+		endpoint string
+		model    string
+		expected string
+	}{
+		{"https://api.openai.com/v1", "gpt-4o", "max_completion_tokens"},
+		{"https://api.groq.com/openai/v1", "llama-3.3-70b-versatile", "max_tokens"},
+		{"https://gateway.example.com/v1", "o3-mini", "max_completion_tokens"},
+		{"https://gateway.example.com/v1", "openchat", "max_tokens"},
+	}
+
+	for _, tt := range tests {
+		baseURL, _ := url.Parse(tt.endpoint)
+		client := &OpenAIClient{config: &Config{}, baseURL: baseURL}
+		if field := client.maxTokensField(tt.model); field != tt.expected {
+			t.Errorf("maxTokensField(%q) at %s = %q, want %q", tt.model, tt.endpoint, field, tt.expected)
+		}
+	}
 }
@@ -0,0 +1,43 @@
+package llm
+
+import (
+	"context"
+
+	"github.com/rba100/aipipe/internal/stream"
+)
+
+// StreamEvent is an event of a streamed completion: a part of the
+// completion, or the error that ended the stream
+type StreamEvent struct {
+	Content string
+	Err     error
+}
+
+// sendEvent sends an event on a stream, returning false if ctx is cancelled
+// first, when the stream should end. Errors after ctx is cancelled are only
+// the result of cancelling, so they aren't sent.
+func sendEvent(ctx context.Context, events chan<- StreamEvent, event StreamEvent) bool {
+	if event.Err != nil && ctx.Err() != nil {
+		return false
+	}
+	select {
+	case events <- event:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// Contents returns the parts of a stream of events. Once the returned
+// channel is closed, *err is the error that ended the stream, if any. The
+// stream ends when the request's context is cancelled, so this stage ends
+// with it rather than having a context of its own.
+func Contents(events <-chan StreamEvent, err *error) <-chan string {
+	return stream.Transform(context.Background(), events, func(event StreamEvent, emit stream.Emit[string]) bool {
+		if event.Err != nil {
+			*err = event.Err
+			return true
+		}
+		return emit(event.Content)
+	}, nil)
+}
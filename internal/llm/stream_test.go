@@ -0,0 +1,46 @@
+package llm
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+// collectStream returns the parts of a stream and the error that ended it
+func collectStream(events <-chan StreamEvent) ([]string, error) {
+	var err error
+	var parts []string
+	for part := range Contents(events, &err) {
+		parts = append(parts, part)
+	}
+	return parts, err
+}
+
+func TestContents(t *testing.T) {
+	failure := errors.New("API error (status 500)")
+	tests := []struct {
+		name      string
+		events    []StreamEvent
+		wantParts []string
+		wantErr   error
+	}{
+		{"complete", []StreamEvent{{Content: "Part 1"}, {Content: "Part 2"}}, []string{"Part 1", "Part 2"}, nil},
+		{"failed part way", []StreamEvent{{Content: "Part 1"}, {Err: failure}}, []string{"Part 1"}, failure},
+		{"failed", []StreamEvent{{Err: failure}}, nil, failure},
+		{"empty", nil, nil, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			events := make(chan StreamEvent, len(tt.events))
+			for _, event := range tt.events {
+				events <- event
+			}
+			close(events)
+
+			parts, err := collectStream(events)
+			if !reflect.DeepEqual(parts, tt.wantParts) || err != tt.wantErr {
+				t.Errorf("Contents() = %v, %v, want %v, %v", parts, err, tt.wantParts, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,56 @@
+package memory
+
+import (
+	"strings"
+)
+
+// maxExtractedFacts limits how many facts are offered from one conversation
+const maxExtractedFacts = 3
+
+// BuildExtractionPrompt builds the user message asking a model to pick out
+// facts from a conversation, listing the facts already known so they aren't
+// suggested again
+func BuildExtractionPrompt(prompt string, reply string, known []Fact) string {
+	var builder strings.Builder
+	if len(known) > 0 {
+		builder.WriteString("Already known:\n")
+		for _, fact := range known {
+			builder.WriteString("- " + fact.Text + "\n")
+		}
+		builder.WriteString("\n")
+	}
+	builder.WriteString("User:\n" + strings.TrimSpace(prompt) + "\n\n")
+	builder.WriteString("Assistant:\n" + strings.TrimSpace(reply) + "\n")
+	return builder.String()
+}
+
+// ParseExtractedFacts returns the new facts in a model's reply to an
+// extraction prompt, dropping list markers, "NONE" and facts already known
+func ParseExtractedFacts(response string, known []Fact) []string {
+	seen := make(map[string]bool)
+	for _, fact := range known {
+		seen[strings.ToLower(fact.Text)] = true
+	}
+
+	var facts []string
+	for _, line := range strings.Split(response, "\n") {
+		line = strings.TrimSpace(line)
+		line = strings.TrimLeft(line, "-*•0123456789.) ")
+		line = strings.TrimSpace(line)
+		if line == "" || strings.EqualFold(strings.Trim(line, "."), "none") {
+			continue
+		}
+
+		key := strings.ToLower(line)
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+
+		facts = append(facts, line)
+		if len(facts) == maxExtractedFacts {
+			break
+		}
+	}
+	return facts
+}
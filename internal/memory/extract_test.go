@@ -0,0 +1,56 @@
+package memory
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseExtractedFacts(t *testing.T) {
+	known := []Fact{{ID: 1, Text: "uses zsh"}}
+
+	tests := []struct {
+		name     string
+		response string
+		expected []string
+	}{
+		{
+			name:     "None",
+			response: "NONE",
+			expected: nil,
+		},
+		{
+			name:     "List markers",
+			response: "- prefers ripgrep over grep\n2. deploys with Nomad\n",
+			expected: []string{"prefers ripgrep over grep", "deploys with Nomad"},
+		},
+		{
+			name:     "Known and repeated facts",
+			response: "Uses zsh\nprefers vim\nprefers vim",
+			expected: []string{"prefers vim"},
+		},
+		{
+			name:     "At most three",
+			response: "a\nb\nc\nd",
+			expected: []string{"a", "b", "c"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ParseExtractedFacts(tt.response, known); !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("ParseExtractedFacts() = %q, want %q", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestBuildExtractionPrompt(t *testing.T) {
+	prompt := BuildExtractionPrompt("find big files\n", "Use `fd -S +100m`", []Fact{{Text: "uses zsh"}})
+
+	for _, part := range []string{"Already known:\n- uses zsh", "User:\nfind big files\n", "Assistant:\nUse `fd -S +100m`"} {
+		if !strings.Contains(prompt, part) {
+			t.Errorf("BuildExtractionPrompt() = %q, missing %q", prompt, part)
+		}
+	}
+}
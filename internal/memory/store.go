@@ -0,0 +1,180 @@
+package memory
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/rba100/aipipe/internal/util"
+)
+
+// Fact is something the user has asked aipipe to remember
+type Fact struct {
+	// ID identifies the fact for `aipipe memory forget`
+	ID int `json:"id"`
+	// Text is the fact itself
+	Text string `json:"text"`
+	// Created is when the fact was remembered
+	Created time.Time `json:"created"`
+}
+
+// Store keeps facts in a JSON lines file, one fact per line
+type Store struct {
+	path string
+}
+
+// NewStore creates a store that keeps its facts in the given directory
+func NewStore(dir string) *Store {
+	return &Store{path: filepath.Join(dir, "facts.jsonl")}
+}
+
+// DefaultStore returns the store in the memory directory of aipipe's data
+// directory
+func DefaultStore() (*Store, error) {
+	dir, err := util.DataPath("memory")
+	if err != nil {
+		return nil, err
+	}
+	return NewStore(dir), nil
+}
+
+// List returns the stored facts, oldest first
+func (s *Store) List() ([]Fact, error) {
+	file, err := os.Open(s.path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open memory file: %w", err)
+	}
+	defer file.Close()
+
+	var facts []Fact
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+
+		var fact Fact
+		if err := json.Unmarshal([]byte(line), &fact); err != nil {
+			return nil, fmt.Errorf("failed to parse memory file: %w", err)
+		}
+		facts = append(facts, fact)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read memory file: %w", err)
+	}
+
+	return facts, nil
+}
+
+// Add stores a new fact and returns it
+func (s *Store) Add(text string) (Fact, error) {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return Fact{}, fmt.Errorf("nothing to remember")
+	}
+
+	facts, err := s.List()
+	if err != nil {
+		return Fact{}, err
+	}
+
+	fact := Fact{ID: 1, Text: text, Created: time.Now()}
+	for _, existing := range facts {
+		if strings.EqualFold(existing.Text, text) {
+			return existing, nil
+		}
+		if existing.ID >= fact.ID {
+			fact.ID = existing.ID + 1
+		}
+	}
+
+	return fact, s.write(append(facts, fact))
+}
+
+// Forget removes the facts with the given IDs. It fails without removing
+// anything if any of the IDs don't exist.
+func (s *Store) Forget(ids ...int) error {
+	facts, err := s.List()
+	if err != nil {
+		return err
+	}
+
+	exists := make(map[int]bool)
+	for _, fact := range facts {
+		exists[fact.ID] = true
+	}
+
+	remove := make(map[int]bool)
+	for _, id := range ids {
+		if !exists[id] {
+			return fmt.Errorf("no remembered fact with id %d", id)
+		}
+		remove[id] = true
+	}
+
+	var kept []Fact
+	for _, fact := range facts {
+		if !remove[fact.ID] {
+			kept = append(kept, fact)
+		}
+	}
+
+	return s.write(kept)
+}
+
+// Clear removes every fact
+func (s *Store) Clear() error {
+	if err := os.Remove(s.path); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove memory file: %w", err)
+	}
+	return nil
+}
+
+// write replaces the stored facts. The file is written alongside and renamed
+// into place so an interrupted write can't lose facts.
+func (s *Store) write(facts []Fact) error {
+	if err := os.MkdirAll(filepath.Dir(s.path), 0700); err != nil {
+		return fmt.Errorf("failed to create memory directory: %w", err)
+	}
+
+	var content strings.Builder
+	for _, fact := range facts {
+		line, err := json.Marshal(fact)
+		if err != nil {
+			return fmt.Errorf("failed to encode fact: %w", err)
+		}
+		content.Write(line)
+		content.WriteString("\n")
+	}
+
+	tempPath := s.path + ".tmp"
+	if err := os.WriteFile(tempPath, []byte(content.String()), 0600); err != nil {
+		return fmt.Errorf("failed to write memory file: %w", err)
+	}
+	if err := os.Rename(tempPath, s.path); err != nil {
+		return fmt.Errorf("failed to write memory file: %w", err)
+	}
+	return nil
+}
+
+// FormatForPrompt describes the facts for the system prompt, or returns an
+// empty string if there are none
+func FormatForPrompt(facts []Fact) string {
+	if len(facts) == 0 {
+		return ""
+	}
+
+	lines := []string{"Facts the user has asked you to remember:"}
+	for _, fact := range facts {
+		lines = append(lines, "- "+fact.Text)
+	}
+	return strings.Join(lines, "\n")
+}
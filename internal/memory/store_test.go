@@ -0,0 +1,67 @@
+package memory
+
+import (
+	"testing"
+)
+
+func TestStore(t *testing.T) {
+	store := NewStore(t.TempDir())
+
+	facts, err := store.List()
+	if err != nil || len(facts) != 0 {
+		t.Fatalf("List() on an empty store = %v, %v; want no facts", facts, err)
+	}
+
+	first, err := store.Add("we deploy with Nomad, not k8s")
+	if err != nil {
+		t.Fatalf("Add() error: %v", err)
+	}
+	second, err := store.Add("  prefers ripgrep  ")
+	if err != nil {
+		t.Fatalf("Add() error: %v", err)
+	}
+	if first.ID != 1 || second.ID != 2 || second.Text != "prefers ripgrep" {
+		t.Errorf("Add() returned %+v and %+v, want IDs 1 and 2 with trimmed text", first, second)
+	}
+
+	duplicate, err := store.Add("Prefers ripgrep")
+	if err != nil || duplicate.ID != second.ID {
+		t.Errorf("Add() of a duplicate = %+v, %v; want the existing fact", duplicate, err)
+	}
+
+	if _, err := store.Add("   "); err == nil {
+		t.Errorf("Add() of blank text succeeded, want an error")
+	}
+
+	if err := store.Forget(1); err != nil {
+		t.Fatalf("Forget() error: %v", err)
+	}
+	if err := store.Forget(1); err == nil {
+		t.Errorf("Forget() of a missing ID succeeded, want an error")
+	}
+
+	third, err := store.Add("uses zsh")
+	if err != nil || third.ID != 3 {
+		t.Errorf("Add() after Forget() = %+v, %v; want ID 3", third, err)
+	}
+
+	facts, err = store.List()
+	if err != nil {
+		t.Fatalf("List() error: %v", err)
+	}
+	if len(facts) != 2 || facts[0].Text != "prefers ripgrep" || facts[1].Text != "uses zsh" {
+		t.Errorf("List() = %+v, want the two remaining facts in order", facts)
+	}
+
+	expected := "Facts the user has asked you to remember:\n- prefers ripgrep\n- uses zsh"
+	if got := FormatForPrompt(facts); got != expected {
+		t.Errorf("FormatForPrompt() = %q, want %q", got, expected)
+	}
+
+	if err := store.Clear(); err != nil {
+		t.Fatalf("Clear() error: %v", err)
+	}
+	if facts, _ := store.List(); len(facts) != 0 {
+		t.Errorf("List() after Clear() = %+v, want no facts", facts)
+	}
+}
@@ -2,6 +2,7 @@ package parsing
 
 import (
 	"regexp"
+	"strings"
 )
 
 var (
@@ -55,17 +56,29 @@ var (
 
 	// Regular expressions for Bash tokens
 	bashVariableRegex     = regexp.MustCompile(`^\$([a-zA-Z_][a-zA-Z0-9_]*|\{[a-zA-Z_][a-zA-Z0-9_]*\}|[0-9])`)
-	bashIdentifierRegex   = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*`)
-	bashCommentRegex      = regexp.MustCompile(`^#.*`)
 	bashOperatorRegex     = regexp.MustCompile(`^(&&|\|\||>>|<<|>=|<=|==|!=|>|<|\+|-|\*|/|=|;|\||&)`)
 	bashRedirectionRegex  = regexp.MustCompile(`^([0-9]*>>?|[0-9]*<<?)`)
-	bashWhitespaceRegex   = regexp.MustCompile(`^[ \t\r\n]+`)
 	bashHeredocStartRegex = regexp.MustCompile(`^<<-?\s*([a-zA-Z_][a-zA-Z0-9_]*|'[^']*'|"[^"]*")`)
 	bashProcessSubRegex   = regexp.MustCompile(`^[<>]\(`)
 	bashNumberRegex       = regexp.MustCompile(`^[0-9]+(\.[0-9]+)?`)
 )
 
-// BashParser implements the Parser interface for Bash shell commands
+// bashHeredoc is a here-document whose body has not been consumed yet
+type bashHeredoc struct {
+	delimiter string
+	stripTabs bool
+}
+
+// bashState is the state carried between lines while parsing Bash
+type bashState struct {
+	// pending holds here-documents started on the current or earlier lines,
+	// in the order their bodies appear
+	pending []bashHeredoc
+	// inBody is true while consuming the body of pending[0]
+	inBody bool
+}
+
+// BashParser implements the Parser and LineParser interfaces for Bash shell commands
 type BashParser struct{}
 
 // Parse implements the Parser interface for Bash
@@ -73,6 +86,25 @@ func (p *BashParser) Parse(code string) (TokenSequence, error) {
 	return ParseBash(code)
 }
 
+// ParseLine implements the LineParser interface for Bash, so here-document
+// bodies are recognised when a block is highlighted a line at a time
+func (p *BashParser) ParseLine(line string, state LineState) (TokenSequence, LineState, error) {
+	current := bashState{}
+	if previous, ok := state.(bashState); ok {
+		current = previous
+		current.pending = append([]bashHeredoc(nil), previous.pending...)
+	}
+
+	tokens := parseBash(line, &current)
+
+	// Bodies of here-documents started on this line begin on the next one
+	if len(current.pending) > 0 {
+		current.inBody = true
+	}
+
+	return tokens, current, nil
+}
+
 // isBashStringStart checks if the code starts with a string delimiter
 func isBashStringStart(code string) (string, bool) {
 	if len(code) == 0 {
@@ -115,40 +147,182 @@ func findBashStringEnd(code string, delimiter string) int {
 	return len(code) - 1
 }
 
+// findBashSubstitutionEnd returns the index of the parenthesis closing the
+// command substitution at the start of code ("$(..."), or -1 if it is not
+// closed. Nested substitutions, parentheses and quoted strings are skipped.
+func findBashSubstitutionEnd(code string) int {
+	depth := 0
+	for i := 1; i < len(code); i++ {
+		switch code[i] {
+		case '\\':
+			i++
+		case '\'':
+			end := strings.IndexByte(code[i+1:], '\'')
+			if end < 0 {
+				return -1
+			}
+			i += end + 1
+		case '"':
+			_, length := parseBashDoubleQuoted(code[i:])
+			if length <= 1 || code[i+length-1] != '"' {
+				return -1
+			}
+			i += length - 1
+		case '(':
+			depth++
+		case ')':
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// parseBashSubstitution tokenizes a complete command substitution, parsing
+// the command inside it as Bash
+func parseBashSubstitution(substitution string) TokenSequence {
+	tokens := TokenSequence{{Type: TokenOther, Text: "$("}}
+	tokens = append(tokens, parseBash(substitution[2:len(substitution)-1], &bashState{})...)
+	return append(tokens, Token{Type: TokenOther, Text: ")"})
+}
+
+// parseBashDoubleQuoted tokenizes the double-quoted string at the start of
+// code, returning its tokens and length. Command substitutions inside the
+// string are tokenized as code; the rest of the string is a literal.
+func parseBashDoubleQuoted(code string) (TokenSequence, int) {
+	var tokens TokenSequence
+	segmentStart := 0
+
+	for i := 1; i < len(code); i++ {
+		switch code[i] {
+		case '\\':
+			i++
+		case '"':
+			tokens = append(tokens, Token{Type: TokenLiteral, Text: code[segmentStart : i+1]})
+			return tokens, i + 1
+		case '$':
+			if !strings.HasPrefix(code[i:], "$(") {
+				continue
+			}
+			end := findBashSubstitutionEnd(code[i:])
+			if end < 0 {
+				continue
+			}
+			if i > segmentStart {
+				tokens = append(tokens, Token{Type: TokenLiteral, Text: code[segmentStart:i]})
+			}
+			tokens = append(tokens, parseBashSubstitution(code[i:i+end+1])...)
+			segmentStart = i + end + 1
+			i += end
+		}
+	}
+
+	// Unterminated string
+	if segmentStart < len(code) {
+		tokens = append(tokens, Token{Type: TokenLiteral, Text: code[segmentStart:]})
+	}
+	return tokens, len(code)
+}
+
 // ParseBash parses Bash shell commands and returns a sequence of tokens
 func ParseBash(code string) (TokenSequence, error) {
+	return parseBash(code, &bashState{}), nil
+}
+
+// parseBash tokenizes Bash code, starting from and updating the given state
+func parseBash(code string, state *bashState) TokenSequence {
 	tokens := TokenSequence{}
 
 	for len(code) > 0 {
+		// Here-document bodies are literal text up to the delimiter line
+		if state.inBody {
+			n := scanToLineEnd(code)
+			line := code[:n]
+			heredoc := state.pending[0]
+
+			candidate := strings.TrimSuffix(line, "\r")
+			if heredoc.stripTabs {
+				candidate = strings.TrimLeft(candidate, "\t")
+			}
+
+			if candidate == heredoc.delimiter {
+				tokens = append(tokens, Token{Type: TokenOther, Text: line})
+				state.pending = state.pending[1:]
+				state.inBody = len(state.pending) > 0
+			} else if n > 0 {
+				tokens = append(tokens, Token{Type: TokenLiteral, Text: line})
+			}
+
+			code = code[n:]
+			if len(code) > 0 {
+				tokens = append(tokens, Token{Type: TokenWhitespace, Text: "\n"})
+				code = code[1:]
+			}
+			continue
+		}
+
 		// Check for whitespace
-		if match := bashWhitespaceRegex.FindString(code); match != "" {
-			tokens = append(tokens, Token{Type: TokenWhitespace, Text: match})
-			code = code[len(match):]
+		if n := scanWhitespace(code); n > 0 {
+			whitespace := code[:n]
+			if len(state.pending) > 0 {
+				// A newline ends the command; any here-document bodies follow it
+				if newline := strings.IndexByte(whitespace, '\n'); newline >= 0 {
+					whitespace = whitespace[:newline+1]
+					state.inBody = true
+				}
+			}
+			tokens = append(tokens, Token{Type: TokenWhitespace, Text: whitespace})
+			code = code[len(whitespace):]
 			continue
 		}
 
 		// Check for comments
-		if match := bashCommentRegex.FindString(code); match != "" {
-			tokens = append(tokens, Token{Type: TokenComment, Text: match})
-			code = code[len(match):]
+		if code[0] == '#' {
+			n := scanToLineEnd(code)
+			tokens = append(tokens, Token{Type: TokenComment, Text: code[:n]})
+			code = code[n:]
 			continue
 		}
 
 		// Check for here-documents
-		if match := bashHeredocStartRegex.FindString(code); match != "" {
+		if match := scanRegex(code, "<", bashHeredocStartRegex); match != "" {
+			delimiter := strings.TrimLeft(match[2:], "- \t")
+			state.pending = append(state.pending, bashHeredoc{
+				delimiter: strings.Trim(delimiter, "'\""),
+				stripTabs: strings.HasPrefix(match, "<<-"),
+			})
 			tokens = append(tokens, Token{Type: TokenOther, Text: match})
 			code = code[len(match):]
 			continue
 		}
 
+		// Check for command substitution
+		if strings.HasPrefix(code, "$(") {
+			if end := findBashSubstitutionEnd(code); end > 0 {
+				tokens = append(tokens, parseBashSubstitution(code[:end+1])...)
+				code = code[end+1:]
+				continue
+			}
+		}
+
 		// Check for process substitution
-		if match := bashProcessSubRegex.FindString(code); match != "" {
+		if match := scanRegex(code, "<>", bashProcessSubRegex); match != "" {
 			tokens = append(tokens, Token{Type: TokenOther, Text: match})
 			code = code[len(match):]
 			continue
 		}
 
-		// Check for strings
+		// Check for double-quoted strings, which may contain substitutions
+		if code[0] == '"' {
+			stringTokens, length := parseBashDoubleQuoted(code)
+			tokens = append(tokens, stringTokens...)
+			code = code[length:]
+			continue
+		}
+
+		// Check for other strings
 		if delimiter, isString := isBashStringStart(code); isString {
 			end := findBashStringEnd(code, delimiter)
 			if end < 0 {
@@ -160,35 +334,36 @@ func ParseBash(code string) (TokenSequence, error) {
 		}
 
 		// Check for variables
-		if match := bashVariableRegex.FindString(code); match != "" {
+		if match := scanRegex(code, "$", bashVariableRegex); match != "" {
 			tokens = append(tokens, Token{Type: TokenIdentifier, Text: match})
 			code = code[len(match):]
 			continue
 		}
 
 		// Check for numbers
-		if match := bashNumberRegex.FindString(code); match != "" {
+		if match := scanRegex(code, digits, bashNumberRegex); match != "" {
 			tokens = append(tokens, Token{Type: TokenLiteral, Text: match})
 			code = code[len(match):]
 			continue
 		}
 
 		// Check for redirections
-		if match := bashRedirectionRegex.FindString(code); match != "" {
+		if match := scanRegex(code, digits+"<>", bashRedirectionRegex); match != "" {
 			tokens = append(tokens, Token{Type: TokenOther, Text: match})
 			code = code[len(match):]
 			continue
 		}
 
 		// Check for operators
-		if match := bashOperatorRegex.FindString(code); match != "" {
+		if match := scanRegex(code, "&|<>=!+-*/;", bashOperatorRegex); match != "" {
 			tokens = append(tokens, Token{Type: TokenOther, Text: match})
 			code = code[len(match):]
 			continue
 		}
 
 		// Check for keywords and identifiers
-		if match := bashIdentifierRegex.FindString(code); match != "" {
+		if n := scanIdentifier(code, "", ""); n > 0 {
+			match := code[:n]
 			if bashKeywords[match] {
 				tokens = append(tokens, Token{Type: TokenKeyword, Text: match})
 			} else {
@@ -199,9 +374,10 @@ func ParseBash(code string) (TokenSequence, error) {
 		}
 
 		// Handle other characters
-		tokens = append(tokens, Token{Type: TokenOther, Text: string(code[0])})
-		code = code[1:]
+		n := scanChar(code)
+		tokens = append(tokens, Token{Type: TokenOther, Text: code[:n]})
+		code = code[n:]
 	}
 
-	return tokens, nil
+	return tokens
 }
@@ -106,3 +106,125 @@ func TestBashKeywordIdentification(t *testing.T) {
 		}
 	}
 }
+
+func TestBashHeredocBody(t *testing.T) {
+	testCases := []struct {
+		name     string
+		input    string
+		expected []Token
+	}{
+		{
+			name:  "Body is literal",
+			input: "cat <<EOF\nif $x\nEOF\necho ok",
+			expected: []Token{
+				{Type: TokenIdentifier, Text: "cat"},
+				{Type: TokenWhitespace, Text: " "},
+				{Type: TokenOther, Text: "<<EOF"},
+				{Type: TokenWhitespace, Text: "\n"},
+				{Type: TokenLiteral, Text: "if $x"},
+				{Type: TokenWhitespace, Text: "\n"},
+				{Type: TokenOther, Text: "EOF"},
+				{Type: TokenWhitespace, Text: "\n"},
+				{Type: TokenKeyword, Text: "echo"},
+				{Type: TokenWhitespace, Text: " "},
+				{Type: TokenIdentifier, Text: "ok"},
+			},
+		},
+		{
+			name:  "Quoted delimiter with tab stripping",
+			input: "cat <<-'END' > out\n\tbody\n\tEND",
+			expected: []Token{
+				{Type: TokenIdentifier, Text: "cat"},
+				{Type: TokenWhitespace, Text: " "},
+				{Type: TokenOther, Text: "<<-'END'"},
+				{Type: TokenWhitespace, Text: " "},
+				{Type: TokenOther, Text: ">"},
+				{Type: TokenWhitespace, Text: " "},
+				{Type: TokenIdentifier, Text: "out"},
+				{Type: TokenWhitespace, Text: "\n"},
+				{Type: TokenLiteral, Text: "\tbody"},
+				{Type: TokenWhitespace, Text: "\n"},
+				{Type: TokenOther, Text: "\tEND"},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			tokens, err := ParseBash(tc.input)
+			if err != nil {
+				t.Fatalf("Error parsing bash code: %v", err)
+			}
+			if len(tokens) != len(tc.expected) {
+				t.Fatalf("Expected %d tokens, got %d. Tokens: %v", len(tc.expected), len(tokens), tokens)
+			}
+			for i := range tokens {
+				if tokens[i] != tc.expected[i] {
+					t.Errorf("Token %d = %+v, want %+v", i, tokens[i], tc.expected[i])
+				}
+			}
+		})
+	}
+}
+
+func TestBashParseLineCarriesHeredocState(t *testing.T) {
+	parser := &BashParser{}
+	lines := []string{"cat <<EOF", "echo $HOME", "EOF", "echo $HOME"}
+	expectedTypes := [][]TokenType{
+		{TokenIdentifier, TokenWhitespace, TokenOther},
+		{TokenLiteral},
+		{TokenOther},
+		{TokenKeyword, TokenWhitespace, TokenIdentifier},
+	}
+
+	var state LineState
+	for i, line := range lines {
+		tokens, next, err := parser.ParseLine(line, state)
+		if err != nil {
+			t.Fatalf("Error parsing line %d: %v", i, err)
+		}
+		state = next
+
+		if len(tokens) != len(expectedTypes[i]) {
+			t.Fatalf("Line %d: expected %d tokens, got %d. Tokens: %v", i, len(expectedTypes[i]), len(tokens), tokens)
+		}
+		for j, token := range tokens {
+			if token.Type != expectedTypes[i][j] {
+				t.Errorf("Line %d token %d (%q): expected type %v, got %v", i, j, token.Text, expectedTypes[i][j], token.Type)
+			}
+		}
+	}
+}
+
+func TestBashCommandSubstitution(t *testing.T) {
+	tokens, err := ParseBash(`echo "today: $(date +"%Y $(whoami)")!"`)
+	if err != nil {
+		t.Fatalf("Error parsing bash code: %v", err)
+	}
+
+	expected := []Token{
+		{Type: TokenKeyword, Text: "echo"},
+		{Type: TokenWhitespace, Text: " "},
+		{Type: TokenLiteral, Text: `"today: `},
+		{Type: TokenOther, Text: "$("},
+		{Type: TokenIdentifier, Text: "date"},
+		{Type: TokenWhitespace, Text: " "},
+		{Type: TokenOther, Text: "+"},
+		{Type: TokenLiteral, Text: `"%Y `},
+		{Type: TokenOther, Text: "$("},
+		{Type: TokenIdentifier, Text: "whoami"},
+		{Type: TokenOther, Text: ")"},
+		{Type: TokenLiteral, Text: `"`},
+		{Type: TokenOther, Text: ")"},
+		{Type: TokenLiteral, Text: `!"`},
+	}
+
+	if len(tokens) != len(expected) {
+		t.Fatalf("Expected %d tokens, got %d. Tokens: %v", len(expected), len(tokens), tokens)
+	}
+	for i := range tokens {
+		if tokens[i] != expected[i] {
+			t.Errorf("Token %d = %+v, want %+v", i, tokens[i], expected[i])
+		}
+	}
+}
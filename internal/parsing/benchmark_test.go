@@ -0,0 +1,184 @@
+package parsing
+
+import (
+	"strings"
+	"testing"
+)
+
+// benchmarkLines is the size of the generated inputs, roughly matching a
+// large file emitted by a model in one code block
+const benchmarkLines = 2000
+
+// benchmarkSamples holds a few representative lines of each language, which
+// are repeated to build the benchmark inputs
+var benchmarkSamples = map[string]string{
+	"python": `# Compute totals for each customer
+def total(orders, discount=0.1):
+    result = {"count": 0, 'sum': 0.0}
+    for order in orders:
+        if order.amount > 100 and not order.cancelled:
+            result["sum"] += order.amount * (1 - discount)
+    return result
+`,
+	"typescript": `// Fetch the user and render their profile
+export async function loadUser(id: number): Promise<User | undefined> {
+    const response = await fetch(` + "`/api/users/${id}`" + `);
+    /* tolerate missing users */
+    if (response.status === 404) { return undefined; }
+    return { name: "unknown", age: 0x1F, ...(await response.json()) };
+}
+`,
+	"bash": `#!/bin/bash
+# Back up each home directory
+for dir in /home/*; do
+    if [ -d "$dir" ] && [[ $dir != *tmp* ]]; then
+        tar -czf "/backup/$(basename $dir).tar.gz" "$dir" 2>> /var/log/backup.log
+    fi
+done
+`,
+	"json": `{"id": 12345, "name": "Widget", "tags": ["a", "b"], "price": 9.99,
+ "stock": {"warehouse": 100, "retail": null, "active": true},
+ "description": "A \"quoted\" description with escapes\n"},
+`,
+	"csharp": `// Repository for orders
+public async Task<IEnumerable<Order>> GetOrdersAsync(int customerId)
+{
+    var path = @"C:\data\orders";
+    /* query the database */
+    return await _context.Orders.Where(o => o.CustomerId == customerId && o.Total > 10.5m).ToListAsync();
+}
+`,
+	"powershell": `# Archive old logs
+function Invoke-Archive([string]$Path, [int]$Days = 30) {
+    $cutoff = (Get-Date).AddDays(-$Days)
+    Get-ChildItem -Path $Path -Filter '*.log' | Where-Object { $_.LastWriteTime -lt $cutoff } | ForEach-Object {
+        Write-Host "Archiving $($_.Name) from $env:COMPUTERNAME ($(1MB / 1KB) KB blocks)"
+    }
+}
+`,
+	"rust": `/// Sums the orders above a threshold
+#[derive(Debug, Clone)]
+pub struct Totals<'a> { name: &'a str, sum: f64 }
+fn total<'a>(orders: &'a [Order], min: u32) -> Totals<'a> {
+    let pattern = r#"^order-\d+$"#; let sep = b'\n'; /* skip /* nested */ refunds */
+    orders.iter().filter(|o| o.amount > min && o.code != 'x').fold(Totals { name: "all", sum: 0.0_f64 }, |t, o| t.add(o.amount as f64 * 1_000u32 as f64))
+}
+`,
+	"yaml": `# Deployment for the web tier
+apiVersion: apps/v1
+kind: Deployment
+metadata: {name: web, labels: {app: "web", tier: 'front'}}
+spec:
+  replicas: 3
+  template: &template
+    containers:
+      - name: web
+        image: nginx:1.25 # pinned
+        command: ["/bin/sh", "-c"]
+        args:
+          - |
+            echo starting on port 8080
+            exec nginx -g 'daemon off;'
+        enabled: true
+  backup: *template
+`,
+	"html": `<!DOCTYPE html>
+<html lang="en">
+<head><meta charset="utf-8"><title>Orders &amp; refunds</title></head>
+<body class=wide data-count='3'>
+  <!-- the order table is filled in by the script below -->
+  <table id="orders"><tr><th>Order</th><th>Amount &#163;</th></tr></table>
+  <svg:rect x="0" y="0" width="10" height="10"/>
+  <script>for (const row of rows) { if (row.amount < 10 && row.code) show(row) }</script>
+</body>
+</html>
+`,
+	"dockerfile": `# syntax=docker/dockerfile:1
+FROM golang:1.21-alpine AS build
+ARG VERSION=dev
+WORKDIR /src
+COPY --chown=app:app go.mod go.sum ./
+RUN --mount=type=cache,target=/root/.cache/go-build \
+    go build -ldflags "-X main.version=${VERSION}" -o /out/app ./cmd/app
+FROM alpine:3.19
+ENV PORT=8080 LOG_LEVEL='info'
+EXPOSE 8080/tcp
+HEALTHCHECK --interval=30s CMD wget -qO- http://localhost:$PORT/health || exit 1
+ENTRYPOINT ["/app", "--port", "8080"]
+`,
+	"diff": `diff --git a/orders.py b/orders.py
+index 3b18e51..a2c4f09 100644
+--- a/orders.py
++++ b/orders.py
+@@ -10,7 +10,8 @@ def refund(order):
+     if order.amount > 100:
+-        raise ValueError("too large")
++        notify(order.owner)
++        return None
+     total = order.amount
+ 
+     return total
+\ No newline at end of file
+`,
+}
+
+// benchmarkInput builds an input of benchmarkLines lines for a language
+func benchmarkInput(language string) string {
+	sample := benchmarkSamples[language]
+	repeats := benchmarkLines / strings.Count(sample, "\n")
+	return strings.Repeat(sample, repeats)
+}
+
+func benchmarkParser(b *testing.B, language string) {
+	parser := GetParser(language)
+	code := benchmarkInput(language)
+	b.SetBytes(int64(len(code)))
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		if _, err := parser.Parse(code); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// benchmarkParserByLine parses the input a line at a time, as the pretty
+// printer does while streaming
+func benchmarkParserByLine(b *testing.B, language string) {
+	parser := GetParser(language)
+	code := benchmarkInput(language)
+	lines := strings.Split(code, "\n")
+	b.SetBytes(int64(len(code)))
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		for _, line := range lines {
+			if _, err := parser.Parse(line); err != nil {
+				b.Fatal(err)
+			}
+		}
+	}
+}
+
+func BenchmarkPythonParser(b *testing.B)           { benchmarkParser(b, "python") }
+func BenchmarkPythonParserByLine(b *testing.B)     { benchmarkParserByLine(b, "python") }
+func BenchmarkTypeScriptParser(b *testing.B)       { benchmarkParser(b, "typescript") }
+func BenchmarkTypeScriptParserByLine(b *testing.B) { benchmarkParserByLine(b, "typescript") }
+func BenchmarkBashParser(b *testing.B)             { benchmarkParser(b, "bash") }
+func BenchmarkBashParserByLine(b *testing.B)       { benchmarkParserByLine(b, "bash") }
+func BenchmarkJSONParser(b *testing.B)             { benchmarkParser(b, "json") }
+func BenchmarkJSONParserByLine(b *testing.B)       { benchmarkParserByLine(b, "json") }
+func BenchmarkCsharpParser(b *testing.B)           { benchmarkParser(b, "csharp") }
+func BenchmarkCsharpParserByLine(b *testing.B)     { benchmarkParserByLine(b, "csharp") }
+func BenchmarkPowerShellParser(b *testing.B)       { benchmarkParser(b, "powershell") }
+func BenchmarkPowerShellParserByLine(b *testing.B) { benchmarkParserByLine(b, "powershell") }
+func BenchmarkRustParser(b *testing.B)             { benchmarkParser(b, "rust") }
+func BenchmarkRustParserByLine(b *testing.B)       { benchmarkParserByLine(b, "rust") }
+func BenchmarkYAMLParser(b *testing.B)             { benchmarkParser(b, "yaml") }
+func BenchmarkYAMLParserByLine(b *testing.B)       { benchmarkParserByLine(b, "yaml") }
+func BenchmarkMarkupParser(b *testing.B)           { benchmarkParser(b, "html") }
+func BenchmarkMarkupParserByLine(b *testing.B)     { benchmarkParserByLine(b, "html") }
+func BenchmarkDockerfileParser(b *testing.B)       { benchmarkParser(b, "dockerfile") }
+func BenchmarkDockerfileParserByLine(b *testing.B) { benchmarkParserByLine(b, "dockerfile") }
+func BenchmarkDiffParser(b *testing.B)             { benchmarkParser(b, "diff") }
+func BenchmarkDiffParserByLine(b *testing.B)       { benchmarkParserByLine(b, "diff") }
@@ -0,0 +1,98 @@
+package parsing
+
+import (
+	"strings"
+	"sync"
+
+	"github.com/alecthomas/chroma/v2"
+	"github.com/alecthomas/chroma/v2/lexers"
+)
+
+var (
+	// chromaLexers caches lexer lookups, which are slow when nothing matches
+	chromaLexers      = map[string]chroma.Lexer{}
+	chromaLexersMutex sync.Mutex
+)
+
+// ChromaParser implements the Parser interface using a lexer from the chroma
+// library. It is used for languages without a dedicated parser.
+type ChromaParser struct {
+	lexer chroma.Lexer
+}
+
+// GetChromaParser returns a parser backed by the chroma lexer for the language,
+// or nil if chroma doesn't know the language either
+func GetChromaParser(language string) Parser {
+	language = CanonicalLanguage(language)
+	if language == "" {
+		return nil
+	}
+
+	chromaLexersMutex.Lock()
+	lexer, ok := chromaLexers[language]
+	if !ok {
+		lexer = lexers.Get(language)
+		if lexer != nil {
+			lexer = chroma.Coalesce(lexer)
+		}
+		chromaLexers[language] = lexer
+	}
+	chromaLexersMutex.Unlock()
+
+	if lexer == nil {
+		return nil
+	}
+	return &ChromaParser{lexer: lexer}
+}
+
+// Parse tokenises code with the chroma lexer and maps its token types onto ours
+func (p *ChromaParser) Parse(code string) (TokenSequence, error) {
+	iterator, err := p.lexer.Tokenise(nil, code)
+	if err != nil {
+		return nil, err
+	}
+
+	var tokens TokenSequence
+	for _, token := range iterator.Tokens() {
+		if token.Value == "" {
+			continue
+		}
+		tokens = append(tokens, Token{
+			Type: chromaTokenType(token),
+			Text: token.Value,
+		})
+	}
+
+	return tokens, nil
+}
+
+// ParseLine lexes a line together with the lines of the block before it, so
+// the lexer is in the right state for strings and comments that span lines
+func (p *ChromaParser) ParseLine(line string, state LineState) (TokenSequence, LineState, error) {
+	return parseLineInContext(p, line, state)
+}
+
+// chromaTokenType maps a chroma token onto the closest TokenType
+func chromaTokenType(token chroma.Token) TokenType {
+	switch {
+	case token.Type == chroma.GenericInserted:
+		return TokenInserted
+	case token.Type == chroma.GenericDeleted:
+		return TokenDeleted
+	case token.Type == chroma.GenericHeading, token.Type == chroma.GenericSubheading:
+		return TokenHeading
+	case token.Type.InCategory(chroma.Comment):
+		return TokenComment
+	case token.Type.InCategory(chroma.Keyword):
+		return TokenKeyword
+	case token.Type.InCategory(chroma.LiteralString), token.Type.InCategory(chroma.LiteralNumber),
+		token.Type.InCategory(chroma.Literal):
+		return TokenLiteral
+	case token.Type.InCategory(chroma.Name):
+		return TokenIdentifier
+	case strings.TrimSpace(token.Value) == "":
+		return TokenWhitespace
+	default:
+		return TokenOther
+	}
+}
@@ -0,0 +1,121 @@
+package parsing
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestChromaParser(t *testing.T) {
+	testCases := []struct {
+		name     string
+		language string
+		input    string
+		keywords []string
+		comments []string
+	}{
+		{
+			name:     "Go",
+			language: "go",
+			input:    "func main() { // entry point\n\treturn\n}",
+			keywords: []string{"func", "return"},
+			comments: []string{"// entry point\n"},
+		},
+		{
+			name:     "Ruby",
+			language: "ruby",
+			input:    "def greet # say hi\n  puts 'hi'\nend",
+			keywords: []string{"def", "end"},
+			comments: []string{"# say hi"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			parser := GetChromaParser(tc.language)
+			if parser == nil {
+				t.Fatalf("No chroma lexer for %s", tc.language)
+			}
+
+			tokens, err := parser.Parse(tc.input)
+			if err != nil {
+				t.Fatalf("Error parsing %s: %v", tc.language, err)
+			}
+
+			var rebuilt strings.Builder
+			found := map[TokenType][]string{}
+			for _, token := range tokens {
+				rebuilt.WriteString(token.Text)
+				found[token.Type] = append(found[token.Type], token.Text)
+			}
+			if rebuilt.String() != tc.input {
+				t.Errorf("Tokens rebuild to %q, want %q", rebuilt.String(), tc.input)
+			}
+
+			for _, keyword := range tc.keywords {
+				if !containsText(found[TokenKeyword], keyword) {
+					t.Errorf("Expected keyword %q, found %v", keyword, found[TokenKeyword])
+				}
+			}
+			for _, comment := range tc.comments {
+				if !containsText(found[TokenComment], comment) {
+					t.Errorf("Expected comment %q, found %v", comment, found[TokenComment])
+				}
+			}
+		})
+	}
+}
+
+func TestChromaParserUnknownLanguage(t *testing.T) {
+	if GetChromaParser("not-a-real-language") != nil {
+		t.Error("Expected no parser for an unknown language")
+	}
+	if GetChromaParser("") != nil {
+		t.Error("Expected no parser for an empty language")
+	}
+}
+
+// containsText reports whether texts includes text
+func TestChromaParseLineMultiLineString(t *testing.T) {
+	parser, ok := GetChromaParser("python").(LineParser)
+	if !ok {
+		t.Fatal("Expected the chroma parser to parse lines")
+	}
+
+	lines := []string{"doc = \"\"\"first", "if not a keyword", "last\"\"\"", "if x:"}
+	var state LineState
+	var results []TokenSequence
+	for _, line := range lines {
+		tokens, next, err := parser.ParseLine(line, state)
+		if err != nil {
+			t.Fatalf("ParseLine(%q) error = %v", line, err)
+		}
+
+		var rebuilt strings.Builder
+		for _, token := range tokens {
+			rebuilt.WriteString(token.Text)
+		}
+		if rebuilt.String() != line {
+			t.Errorf("Tokens rebuild to %q, want %q", rebuilt.String(), line)
+		}
+		results = append(results, tokens)
+		state = next
+	}
+
+	for _, token := range results[1] {
+		if token.Type != TokenLiteral {
+			t.Errorf("Expected the middle line of the string to be a literal, found %v %q", token.Type, token.Text)
+		}
+	}
+	if len(results[3]) == 0 || results[3][0].Type != TokenKeyword || results[3][0].Text != "if" {
+		t.Errorf("Expected \"if\" after the string to be a keyword, found %+v", results[3])
+	}
+}
+
+func containsText(texts []string, text string) bool {
+	for _, t := range texts {
+		if t == text {
+			return true
+		}
+	}
+	return false
+}
@@ -101,11 +101,9 @@ var (
 	}
 
 	// Regular expressions for C# tokens
-	csharpNumberRegex     = regexp.MustCompile(`^(0[xX][0-9a-fA-F]+[ULul]*|0[bB][01]+[ULul]*|[0-9]+(\.[0-9]+)?([eE][+-]?[0-9]+)?[fFdDmMULul]*)`)
-	csharpIdentifierRegex = regexp.MustCompile(`^[a-zA-Z_@][a-zA-Z0-9_]*`)
-	csharpCommentRegex    = regexp.MustCompile(`^(//.*|/\*[\s\S]*?\*/)`)
-	csharpWhitespaceRegex = regexp.MustCompile(`^[ \t\r\n]+`)
-	csharpVerbatimRegex   = regexp.MustCompile(`^@"(?:[^"]|"")*"`)
+	csharpNumberRegex   = regexp.MustCompile(`^(0[xX][0-9a-fA-F]+[ULul]*|0[bB][01]+[ULul]*|[0-9]+(\.[0-9]+)?([eE][+-]?[0-9]+)?[fFdDmMULul]*)`)
+	csharpCommentRegex  = regexp.MustCompile(`^(//.*|/\*[\s\S]*?\*/)`)
+	csharpVerbatimRegex = regexp.MustCompile(`^@"(?:[^"]|"")*"`)
 )
 
 // CsharpParser implements the Parser interface for C# code
@@ -151,21 +149,21 @@ func ParseCsharp(code string) (TokenSequence, error) {
 
 	for len(code) > 0 {
 		// Try to match whitespace first to preserve indentation
-		if match := csharpWhitespaceRegex.FindString(code); match != "" {
-			tokens = append(tokens, Token{Type: TokenWhitespace, Text: match})
-			code = code[len(match):]
+		if n := scanWhitespace(code); n > 0 {
+			tokens = append(tokens, Token{Type: TokenWhitespace, Text: code[:n]})
+			code = code[n:]
 			continue
 		}
 
 		// Try to match a comment
-		if match := csharpCommentRegex.FindString(code); match != "" {
+		if match := scanRegex(code, "/", csharpCommentRegex); match != "" {
 			tokens = append(tokens, Token{Type: TokenComment, Text: match})
 			code = code[len(match):]
 			continue
 		}
 
 		// Try to match a verbatim string (@"...")
-		if match := csharpVerbatimRegex.FindString(code); match != "" {
+		if match := scanRegex(code, "@", csharpVerbatimRegex); match != "" {
 			tokens = append(tokens, Token{Type: TokenLiteral, Text: match})
 			code = code[len(match):]
 			continue
@@ -182,14 +180,15 @@ func ParseCsharp(code string) (TokenSequence, error) {
 		}
 
 		// Try to match a number
-		if match := csharpNumberRegex.FindString(code); match != "" {
+		if match := scanRegex(code, digits, csharpNumberRegex); match != "" {
 			tokens = append(tokens, Token{Type: TokenLiteral, Text: match})
 			code = code[len(match):]
 			continue
 		}
 
 		// Try to match an identifier or keyword
-		if match := csharpIdentifierRegex.FindString(code); match != "" {
+		if n := scanIdentifier(code, "@", ""); n > 0 {
+			match := code[:n]
 			if csharpKeywords[match] {
 				tokens = append(tokens, Token{Type: TokenKeyword, Text: match})
 			} else {
@@ -200,8 +199,9 @@ func ParseCsharp(code string) (TokenSequence, error) {
 		}
 
 		// If none of the above matched, it's an "other" token (operator, punctuation, etc.)
-		tokens = append(tokens, Token{Type: TokenOther, Text: string(code[0])})
-		code = code[1:]
+		n := scanChar(code)
+		tokens = append(tokens, Token{Type: TokenOther, Text: code[:n]})
+		code = code[n:]
 	}
 
 	return tokens, nil
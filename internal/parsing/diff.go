@@ -0,0 +1,149 @@
+package parsing
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var (
+	// diffHunkRegex matches a hunk header such as @@ -12,7 +12,9 @@, whose
+	// line counts default to 1 when left out
+	diffHunkRegex = regexp.MustCompile(`^@@+ -[0-9]+(?:,([0-9]+))? \+[0-9]+(?:,([0-9]+))? @@+`)
+
+	// diffHeaderPrefixes start the lines describing the files a diff applies
+	// to, as written by diff -u and git diff
+	diffHeaderPrefixes = []string{
+		"diff ",
+		"index ",
+		"--- ",
+		"+++ ",
+		"new file mode ",
+		"deleted file mode ",
+		"old mode ",
+		"new mode ",
+		"similarity index ",
+		"dissimilarity index ",
+		"rename from ",
+		"rename to ",
+		"copy from ",
+		"copy to ",
+		"Binary files ",
+	}
+)
+
+// diffState is the state carried between lines while parsing a diff: the
+// number of old and new lines still to come in the current hunk, which tell
+// a removed line starting "--- " from a file header
+type diffState struct {
+	oldLines int
+	newLines int
+}
+
+// DiffParser implements the Parser and LineParser interfaces for unified
+// diffs and patches
+type DiffParser struct{}
+
+// Parse implements the Parser interface for diffs
+func (p *DiffParser) Parse(code string) (TokenSequence, error) {
+	return ParseDiff(code)
+}
+
+// ParseLine implements the LineParser interface for diffs, so lines are
+// classified by the hunk they belong to when a block is highlighted a line at
+// a time
+func (p *DiffParser) ParseLine(line string, state LineState) (TokenSequence, LineState, error) {
+	current, _ := state.(diffState)
+	tokens := parseDiffLine(line, &current)
+	return tokens, current, nil
+}
+
+// ParseDiff parses a unified diff and returns a sequence of tokens. Each line
+// is a single token: added lines are inserted, removed lines deleted, hunk
+// headers headings and file headers keywords. Context lines are comments, so
+// they are dimmed next to the changes.
+func ParseDiff(code string) (TokenSequence, error) {
+	tokens := TokenSequence{}
+	state := diffState{}
+	for i, line := range strings.Split(code, "\n") {
+		if i > 0 {
+			tokens = append(tokens, Token{Type: TokenWhitespace, Text: "\n"})
+		}
+		tokens = append(tokens, parseDiffLine(line, &state)...)
+	}
+	return tokens, nil
+}
+
+// parseDiffHunkCount converts a line count from a hunk header, which is 1
+// when left out
+func parseDiffHunkCount(count string) int {
+	if count == "" {
+		return 1
+	}
+	n, _ := strconv.Atoi(count)
+	return n
+}
+
+// parseDiffLine tokenizes a line of a diff, starting from and updating the
+// given state
+func parseDiffLine(line string, state *diffState) TokenSequence {
+	if line == "" {
+		// Blank context lines often lose their leading space
+		if state.oldLines > 0 && state.newLines > 0 {
+			state.oldLines--
+			state.newLines--
+		}
+		return TokenSequence{}
+	}
+
+	tokenType := TokenComment
+	inHunk := state.oldLines > 0 || state.newLines > 0
+	switch {
+	case strings.HasPrefix(line, "@@"):
+		tokenType = TokenHeading
+		state.oldLines, state.newLines = 0, 0
+		if submatches := diffHunkRegex.FindStringSubmatch(line); submatches != nil {
+			state.oldLines = parseDiffHunkCount(submatches[1])
+			state.newLines = parseDiffHunkCount(submatches[2])
+		}
+
+	case inHunk && line[0] == '+':
+		tokenType = TokenInserted
+		state.newLines--
+
+	case inHunk && line[0] == '-':
+		tokenType = TokenDeleted
+		state.oldLines--
+
+	case inHunk && line[0] == ' ':
+		state.oldLines--
+		state.newLines--
+
+	case line[0] == '\\':
+		// "\ No newline at end of file"
+
+	case isDiffHeader(line):
+		tokenType = TokenKeyword
+		state.oldLines, state.newLines = 0, 0
+
+	// Hand-written diffs often get the hunk's line counts wrong, so added
+	// and removed lines are recognised after it too
+	case line[0] == '+':
+		tokenType = TokenInserted
+
+	case line[0] == '-':
+		tokenType = TokenDeleted
+	}
+
+	return TokenSequence{{Type: tokenType, Text: line}}
+}
+
+// isDiffHeader reports whether line describes the files a diff applies to
+func isDiffHeader(line string) bool {
+	for _, prefix := range diffHeaderPrefixes {
+		if strings.HasPrefix(line, prefix) {
+			return true
+		}
+	}
+	return line == "---" || line == "+++"
+}
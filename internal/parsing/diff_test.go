@@ -0,0 +1,107 @@
+package parsing
+
+import (
+	"testing"
+)
+
+func TestDiffParser(t *testing.T) {
+	testCases := []struct {
+		name     string
+		input    string
+		expected []Token
+	}{
+		{
+			name:  "File headers and a hunk",
+			input: "--- a/app.py\n+++ b/app.py\n@@ -1,2 +1,2 @@ def main():\n-print(1)\n+print(2)\n return",
+			expected: []Token{
+				{Type: TokenKeyword, Text: "--- a/app.py"},
+				{Type: TokenWhitespace, Text: "\n"},
+				{Type: TokenKeyword, Text: "+++ b/app.py"},
+				{Type: TokenWhitespace, Text: "\n"},
+				{Type: TokenHeading, Text: "@@ -1,2 +1,2 @@ def main():"},
+				{Type: TokenWhitespace, Text: "\n"},
+				{Type: TokenDeleted, Text: "-print(1)"},
+				{Type: TokenWhitespace, Text: "\n"},
+				{Type: TokenInserted, Text: "+print(2)"},
+				{Type: TokenWhitespace, Text: "\n"},
+				{Type: TokenComment, Text: " return"},
+			},
+		},
+		{
+			name:  "Removed line that looks like a file header",
+			input: "@@ -1 +0,0 @@\n--- a heading underline\n--- a/next.md",
+			expected: []Token{
+				{Type: TokenHeading, Text: "@@ -1 +0,0 @@"},
+				{Type: TokenWhitespace, Text: "\n"},
+				{Type: TokenDeleted, Text: "--- a heading underline"},
+				{Type: TokenWhitespace, Text: "\n"},
+				{Type: TokenKeyword, Text: "--- a/next.md"},
+			},
+		},
+		{
+			name:  "Hunk with the wrong line counts",
+			input: "@@ -1 +1 @@\n-old\n+new\n+more\n\\ No newline at end of file",
+			expected: []Token{
+				{Type: TokenHeading, Text: "@@ -1 +1 @@"},
+				{Type: TokenWhitespace, Text: "\n"},
+				{Type: TokenDeleted, Text: "-old"},
+				{Type: TokenWhitespace, Text: "\n"},
+				{Type: TokenInserted, Text: "+new"},
+				{Type: TokenWhitespace, Text: "\n"},
+				{Type: TokenInserted, Text: "+more"},
+				{Type: TokenWhitespace, Text: "\n"},
+				{Type: TokenComment, Text: "\\ No newline at end of file"},
+			},
+		},
+		{
+			name:  "Git extended headers",
+			input: "diff --git a/old.go b/new.go\nsimilarity index 90%\nrename from old.go",
+			expected: []Token{
+				{Type: TokenKeyword, Text: "diff --git a/old.go b/new.go"},
+				{Type: TokenWhitespace, Text: "\n"},
+				{Type: TokenKeyword, Text: "similarity index 90%"},
+				{Type: TokenWhitespace, Text: "\n"},
+				{Type: TokenKeyword, Text: "rename from old.go"},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			tokens, err := ParseDiff(tc.input)
+			if err != nil {
+				t.Fatalf("Error parsing diff: %v", err)
+			}
+			if len(tokens) != len(tc.expected) {
+				t.Fatalf("Expected %d tokens, got %d. Tokens: %v", len(tc.expected), len(tokens), tokens)
+			}
+			for i := range tokens {
+				if tokens[i] != tc.expected[i] {
+					t.Errorf("Token %d = %+v, want %+v", i, tokens[i], tc.expected[i])
+				}
+			}
+		})
+	}
+}
+
+func TestDiffParseLine(t *testing.T) {
+	parser := &DiffParser{}
+	// The hunk has one old line left after the blank context line, so the
+	// line starting --- is removed rather than a file header
+	lines := []string{"@@ -1,2 +1,1 @@", "", "---"}
+
+	var state LineState
+	var tokens TokenSequence
+	for _, line := range lines {
+		var err error
+		tokens, state, err = parser.ParseLine(line, state)
+		if err != nil {
+			t.Fatalf("ParseLine(%q) error: %v", line, err)
+		}
+	}
+
+	want := Token{Type: TokenDeleted, Text: "---"}
+	if len(tokens) != 1 || tokens[0] != want {
+		t.Errorf("Last line tokens %+v, want %+v", tokens, want)
+	}
+}
@@ -0,0 +1,223 @@
+package parsing
+
+import (
+	"regexp"
+	"strings"
+)
+
+var (
+	// Dockerfile instructions, which are case-insensitive
+	dockerfileInstructions = map[string]bool{
+		"ADD":         true,
+		"ARG":         true,
+		"CMD":         true,
+		"COPY":        true,
+		"ENTRYPOINT":  true,
+		"ENV":         true,
+		"EXPOSE":      true,
+		"FROM":        true,
+		"HEALTHCHECK": true,
+		"LABEL":       true,
+		"MAINTAINER":  true,
+		"ONBUILD":     true,
+		"RUN":         true,
+		"SHELL":       true,
+		"STOPSIGNAL":  true,
+		"USER":        true,
+		"VOLUME":      true,
+		"WORKDIR":     true,
+	}
+
+	// Regular expressions for Dockerfile tokens
+	dockerfileVariableRegex = regexp.MustCompile(`^\$([a-zA-Z_][a-zA-Z0-9_]*|\{[^}\s]*\})`)
+	dockerfileFlagRegex     = regexp.MustCompile(`^--[a-zA-Z][a-zA-Z0-9-]*`)
+	dockerfileHeredocRegex  = regexp.MustCompile(`^<<-?\s*(["']?)([a-zA-Z_][a-zA-Z0-9_]*)(["']?)`)
+	dockerfileNumberRegex   = regexp.MustCompile(`^[0-9]+(\.[0-9]+)*`)
+)
+
+// dockerfileState is the state carried between lines while parsing a
+// Dockerfile: instructions continue onto the next line after a backslash, and
+// RUN and COPY may take here-documents
+type dockerfileState struct {
+	// continued is true if the previous line ended with a backslash
+	continued bool
+	// instruction is the uppercased instruction being read
+	instruction string
+	// heredoc is the delimiter of the here-document whose body is being read
+	heredoc string
+	// stripTabs is set if the here-document's lines may be indented by tabs
+	stripTabs bool
+}
+
+// DockerfileParser implements the Parser and LineParser interfaces for
+// Dockerfiles
+type DockerfileParser struct{}
+
+// Parse implements the Parser interface for Dockerfiles
+func (p *DockerfileParser) Parse(code string) (TokenSequence, error) {
+	return ParseDockerfile(code)
+}
+
+// ParseLine implements the LineParser interface for Dockerfiles, so continued
+// instructions and here-documents are recognised when a block is highlighted
+// a line at a time
+func (p *DockerfileParser) ParseLine(line string, state LineState) (TokenSequence, LineState, error) {
+	current, _ := state.(dockerfileState)
+	tokens := parseDockerfileLine(line, &current)
+	return tokens, current, nil
+}
+
+// ParseDockerfile parses a Dockerfile and returns a sequence of tokens
+func ParseDockerfile(code string) (TokenSequence, error) {
+	tokens := TokenSequence{}
+	state := dockerfileState{}
+	for i, line := range strings.Split(code, "\n") {
+		if i > 0 {
+			tokens = append(tokens, Token{Type: TokenWhitespace, Text: "\n"})
+		}
+		tokens = append(tokens, parseDockerfileLine(line, &state)...)
+	}
+	return tokens, nil
+}
+
+// findDockerfileStringEnd returns the length of the quoted string at the start
+// of code, or the length of code if it isn't closed on this line
+func findDockerfileStringEnd(code string) int {
+	quote := code[0]
+	for i := 1; i < len(code); i++ {
+		switch code[i] {
+		case '\\':
+			i++
+		case quote:
+			return i + 1
+		}
+	}
+	return len(code)
+}
+
+// isDockerfileContinuation reports whether code is a backslash ending the line,
+// with nothing but whitespace after it
+func isDockerfileContinuation(code string) bool {
+	return code[0] == '\\' && strings.TrimSpace(code[1:]) == ""
+}
+
+// parseDockerfileLine tokenizes a line of a Dockerfile, starting from and
+// updating the given state
+func parseDockerfileLine(line string, state *dockerfileState) TokenSequence {
+	tokens := TokenSequence{}
+
+	// Here-document bodies are literal text up to the delimiter line
+	if state.heredoc != "" {
+		candidate := strings.TrimSuffix(line, "\r")
+		if state.stripTabs {
+			candidate = strings.TrimLeft(candidate, "\t")
+		}
+		if candidate == state.heredoc {
+			state.heredoc = ""
+			return append(tokens, Token{Type: TokenOther, Text: line})
+		}
+		if line != "" {
+			tokens = append(tokens, Token{Type: TokenLiteral, Text: line})
+		}
+		return tokens
+	}
+
+	code := line
+	if n := scanWhitespace(code); n > 0 {
+		tokens = append(tokens, Token{Type: TokenWhitespace, Text: code[:n]})
+		code = code[n:]
+	}
+
+	// Comments are whole lines, and may sit among continued lines
+	if strings.HasPrefix(code, "#") {
+		return append(tokens, Token{Type: TokenComment, Text: code})
+	}
+
+	// A line that doesn't continue the last starts with an instruction
+	expectInstruction := !state.continued
+	if expectInstruction && code != "" {
+		state.instruction = ""
+	}
+	state.continued = false
+
+	for len(code) > 0 {
+		if n := scanWhitespace(code); n > 0 {
+			tokens = append(tokens, Token{Type: TokenWhitespace, Text: code[:n]})
+			code = code[n:]
+			continue
+		}
+
+		if isDockerfileContinuation(code) {
+			state.continued = true
+			tokens = append(tokens, Token{Type: TokenOther, Text: code[:1]})
+			code = code[1:]
+			continue
+		}
+
+		if code[0] == '"' || code[0] == '\'' {
+			n := findDockerfileStringEnd(code)
+			tokens = append(tokens, Token{Type: TokenLiteral, Text: code[:n]})
+			code = code[n:]
+			continue
+		}
+
+		if match := scanRegex(code, "$", dockerfileVariableRegex); match != "" {
+			tokens = append(tokens, Token{Type: TokenIdentifier, Text: match})
+			code = code[len(match):]
+			continue
+		}
+
+		// Options such as --from=builder and --chown=app
+		if match := scanRegex(code, "-", dockerfileFlagRegex); match != "" {
+			tokens = append(tokens, Token{Type: TokenKeyword, Text: match})
+			code = code[len(match):]
+			continue
+		}
+
+		// Here-documents such as <<EOF, whose bodies start on the next line
+		if strings.HasPrefix(code, "<<") {
+			if submatches := dockerfileHeredocRegex.FindStringSubmatch(code); submatches != nil && submatches[1] == submatches[3] {
+				state.heredoc = submatches[2]
+				state.stripTabs = strings.HasPrefix(code, "<<-")
+				tokens = append(tokens, Token{Type: TokenOther, Text: submatches[0]})
+				code = code[len(submatches[0]):]
+				continue
+			}
+		}
+
+		if match := scanRegex(code, digits, dockerfileNumberRegex); match != "" {
+			tokens = append(tokens, Token{Type: TokenLiteral, Text: match})
+			code = code[len(match):]
+			continue
+		}
+
+		if n := scanIdentifier(code, "", ""); n > 0 {
+			word := code[:n]
+			upper := strings.ToUpper(word)
+			tokenType := TokenIdentifier
+			switch {
+			case expectInstruction && dockerfileInstructions[upper]:
+				// ONBUILD and HEALTHCHECK are followed by another
+				// instruction
+				tokenType = TokenKeyword
+				expectInstruction = upper == "ONBUILD" || upper == "HEALTHCHECK"
+				state.instruction = upper
+			case state.instruction == "HEALTHCHECK" && (upper == "CMD" || upper == "NONE"),
+				state.instruction == "FROM" && upper == "AS":
+				tokenType = TokenKeyword
+			default:
+				expectInstruction = false
+			}
+			tokens = append(tokens, Token{Type: tokenType, Text: word})
+			code = code[n:]
+			continue
+		}
+
+		// Anything else is an operator or punctuation
+		n := scanChar(code)
+		tokens = append(tokens, Token{Type: TokenOther, Text: code[:n]})
+		code = code[n:]
+	}
+
+	return tokens
+}
@@ -0,0 +1,145 @@
+package parsing
+
+import (
+	"testing"
+)
+
+func TestDockerfileParser(t *testing.T) {
+	testCases := []struct {
+		name     string
+		input    string
+		expected []Token
+	}{
+		{
+			name:  "Stage and copy",
+			input: "from golang:1.21 AS build\nCOPY --from=build /out/app /app",
+			expected: []Token{
+				{Type: TokenKeyword, Text: "from"},
+				{Type: TokenWhitespace, Text: " "},
+				{Type: TokenIdentifier, Text: "golang"},
+				{Type: TokenOther, Text: ":"},
+				{Type: TokenLiteral, Text: "1.21"},
+				{Type: TokenWhitespace, Text: " "},
+				{Type: TokenKeyword, Text: "AS"},
+				{Type: TokenWhitespace, Text: " "},
+				{Type: TokenIdentifier, Text: "build"},
+				{Type: TokenWhitespace, Text: "\n"},
+				{Type: TokenKeyword, Text: "COPY"},
+				{Type: TokenWhitespace, Text: " "},
+				{Type: TokenKeyword, Text: "--from"},
+				{Type: TokenOther, Text: "="},
+				{Type: TokenIdentifier, Text: "build"},
+				{Type: TokenWhitespace, Text: " "},
+				{Type: TokenOther, Text: "/"},
+				{Type: TokenIdentifier, Text: "out"},
+				{Type: TokenOther, Text: "/"},
+				{Type: TokenIdentifier, Text: "app"},
+				{Type: TokenWhitespace, Text: " "},
+				{Type: TokenOther, Text: "/"},
+				{Type: TokenIdentifier, Text: "app"},
+			},
+		},
+		{
+			name:  "Continued line with a comment",
+			input: "RUN make \\\n  # build it\n  ENV=${MODE} \"run it\"",
+			expected: []Token{
+				{Type: TokenKeyword, Text: "RUN"},
+				{Type: TokenWhitespace, Text: " "},
+				{Type: TokenIdentifier, Text: "make"},
+				{Type: TokenWhitespace, Text: " "},
+				{Type: TokenOther, Text: "\\"},
+				{Type: TokenWhitespace, Text: "\n"},
+				{Type: TokenWhitespace, Text: "  "},
+				{Type: TokenComment, Text: "# build it"},
+				{Type: TokenWhitespace, Text: "\n"},
+				{Type: TokenWhitespace, Text: "  "},
+				{Type: TokenIdentifier, Text: "ENV"},
+				{Type: TokenOther, Text: "="},
+				{Type: TokenIdentifier, Text: "${MODE}"},
+				{Type: TokenWhitespace, Text: " "},
+				{Type: TokenLiteral, Text: `"run it"`},
+			},
+		},
+		{
+			name:  "Healthcheck and exec form",
+			input: `HEALTHCHECK --interval=30s CMD ["curl", "-f"]`,
+			expected: []Token{
+				{Type: TokenKeyword, Text: "HEALTHCHECK"},
+				{Type: TokenWhitespace, Text: " "},
+				{Type: TokenKeyword, Text: "--interval"},
+				{Type: TokenOther, Text: "="},
+				{Type: TokenLiteral, Text: "30"},
+				{Type: TokenIdentifier, Text: "s"},
+				{Type: TokenWhitespace, Text: " "},
+				{Type: TokenKeyword, Text: "CMD"},
+				{Type: TokenWhitespace, Text: " "},
+				{Type: TokenOther, Text: "["},
+				{Type: TokenLiteral, Text: `"curl"`},
+				{Type: TokenOther, Text: ","},
+				{Type: TokenWhitespace, Text: " "},
+				{Type: TokenLiteral, Text: `"-f"`},
+				{Type: TokenOther, Text: "]"},
+			},
+		},
+		{
+			name:  "Here-document",
+			input: "RUN <<EOF\necho # not a comment\nEOF\nUSER app",
+			expected: []Token{
+				{Type: TokenKeyword, Text: "RUN"},
+				{Type: TokenWhitespace, Text: " "},
+				{Type: TokenOther, Text: "<<EOF"},
+				{Type: TokenWhitespace, Text: "\n"},
+				{Type: TokenLiteral, Text: "echo # not a comment"},
+				{Type: TokenWhitespace, Text: "\n"},
+				{Type: TokenOther, Text: "EOF"},
+				{Type: TokenWhitespace, Text: "\n"},
+				{Type: TokenKeyword, Text: "USER"},
+				{Type: TokenWhitespace, Text: " "},
+				{Type: TokenIdentifier, Text: "app"},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			tokens, err := ParseDockerfile(tc.input)
+			if err != nil {
+				t.Fatalf("Error parsing Dockerfile: %v", err)
+			}
+			if len(tokens) != len(tc.expected) {
+				t.Fatalf("Expected %d tokens, got %d. Tokens: %v", len(tc.expected), len(tokens), tokens)
+			}
+			for i := range tokens {
+				if tokens[i] != tc.expected[i] {
+					t.Errorf("Token %d = %+v, want %+v", i, tokens[i], tc.expected[i])
+				}
+			}
+		})
+	}
+}
+
+func TestDockerfileParseLine(t *testing.T) {
+	parser := &DockerfileParser{}
+	lines := []string{"ONBUILD RUN apt-get update && \\", "    apt-get install -y curl", "expose 80"}
+	// The word after ONBUILD is an instruction, the continued line's first
+	// word isn't, and the next line starts a new instruction
+	indexes := []int{2, 1, 0}
+	want := []Token{
+		{Type: TokenKeyword, Text: "RUN"},
+		{Type: TokenIdentifier, Text: "apt"},
+		{Type: TokenKeyword, Text: "expose"},
+	}
+
+	var state LineState
+	for i, line := range lines {
+		tokens, next, err := parser.ParseLine(line, state)
+		if err != nil {
+			t.Fatalf("ParseLine(%q) error: %v", line, err)
+		}
+		state = next
+
+		if token := tokens[indexes[i]]; token != want[i] {
+			t.Errorf("Line %d token %+v, want %+v", i+1, tokens[indexes[i]], want[i])
+		}
+	}
+}
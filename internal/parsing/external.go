@@ -0,0 +1,108 @@
+package parsing
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// externalParserTimeout bounds how long a plugin may take to tokenize a chunk
+// of code, so a misbehaving plugin can't stall output
+const externalParserTimeout = 2 * time.Second
+
+// externalTokenTypes maps token type names in the plugin protocol to TokenType
+var externalTokenTypes = map[string]TokenType{
+	"other":      TokenOther,
+	"keyword":    TokenKeyword,
+	"identifier": TokenIdentifier,
+	"literal":    TokenLiteral,
+	"comment":    TokenComment,
+	"whitespace": TokenWhitespace,
+	"inserted":   TokenInserted,
+	"deleted":    TokenDeleted,
+	"heading":    TokenHeading,
+}
+
+// externalToken is a single token as emitted by a parser plugin
+type externalToken struct {
+	Type string `json:"type"`
+	Text string `json:"text"`
+}
+
+// ExternalParser implements the Parser interface by running a plugin process.
+//
+// The plugin receives the code on stdin and writes one JSON object per line to
+// stdout, each of the form {"type": "keyword", "text": "fn"}. Valid types are
+// other, keyword, identifier, literal, comment and whitespace, and inserted,
+// deleted and heading for diff-like output; unknown types are treated as
+// other.
+type ExternalParser struct {
+	Command string
+	Args    []string
+}
+
+// NewExternalParser creates an ExternalParser from a command line such as
+// "zig-tokenizer --mode=tokens"
+func NewExternalParser(commandLine string) (*ExternalParser, error) {
+	fields := strings.Fields(commandLine)
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("parser plugin command is empty")
+	}
+
+	return &ExternalParser{
+		Command: fields[0],
+		Args:    fields[1:],
+	}, nil
+}
+
+// ParsesWholeBlocks is true, since each parse starts the plugin process
+func (p *ExternalParser) ParsesWholeBlocks() bool {
+	return true
+}
+
+// Parse runs the plugin and converts its output to a sequence of tokens
+func (p *ExternalParser) Parse(code string) (TokenSequence, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), externalParserTimeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, p.Command, p.Args...)
+	cmd.Stdin = strings.NewReader(code)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("parser plugin %s failed: %v: %s", p.Command, err, strings.TrimSpace(stderr.String()))
+	}
+
+	var tokens TokenSequence
+	scanner := bufio.NewScanner(&stdout)
+	scanner.Buffer(make([]byte, 64*1024), len(code)+64*1024)
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+
+		var token externalToken
+		if err := json.Unmarshal(line, &token); err != nil {
+			return nil, fmt.Errorf("parser plugin %s emitted invalid token: %v", p.Command, err)
+		}
+
+		tokens = append(tokens, Token{
+			Type: externalTokenTypes[strings.ToLower(token.Type)],
+			Text: token.Text,
+		})
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("error reading parser plugin output: %v", err)
+	}
+
+	return tokens, nil
+}
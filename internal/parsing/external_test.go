@@ -0,0 +1,85 @@
+package parsing
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"testing"
+)
+
+// TestHelperPlugin is not a real test. It acts as a parser plugin when run as
+// a subprocess by the ExternalParser tests.
+func TestHelperPlugin(t *testing.T) {
+	if os.Getenv("AIPIPE_TEST_PLUGIN") != "1" {
+		return
+	}
+
+	code, _ := io.ReadAll(os.Stdin)
+	for i, word := range strings.Split(string(code), " ") {
+		if i > 0 {
+			fmt.Println(`{"type": "whitespace", "text": " "}`)
+		}
+		tokenType := "identifier"
+		if word == "fn" {
+			tokenType = "keyword"
+		}
+		fmt.Printf("{\"type\": %q, \"text\": %q}\n", tokenType, word)
+	}
+	os.Exit(0)
+}
+
+func TestExternalParser(t *testing.T) {
+	os.Setenv("AIPIPE_TEST_PLUGIN", "1")
+	defer os.Unsetenv("AIPIPE_TEST_PLUGIN")
+
+	parser := &ExternalParser{
+		Command: os.Args[0],
+		Args:    []string{"-test.run=TestHelperPlugin"},
+	}
+
+	tokens, err := parser.Parse("fn main")
+	if err != nil {
+		t.Fatalf("Error running parser plugin: %v", err)
+	}
+
+	expected := TokenSequence{
+		{Type: TokenKeyword, Text: "fn"},
+		{Type: TokenWhitespace, Text: " "},
+		{Type: TokenIdentifier, Text: "main"},
+	}
+
+	if len(tokens) != len(expected) {
+		t.Fatalf("Expected %d tokens, got %d. Tokens: %v", len(expected), len(tokens), tokens)
+	}
+	for i := range expected {
+		if tokens[i] != expected[i] {
+			t.Errorf("Token %d = %+v, want %+v", i, tokens[i], expected[i])
+		}
+	}
+}
+
+func TestExternalParserMissingCommand(t *testing.T) {
+	parser, err := NewExternalParser("aipipe-no-such-tokenizer --flag")
+	if err != nil {
+		t.Fatalf("Error creating parser: %v", err)
+	}
+
+	if _, err := parser.Parse("fn main"); err == nil {
+		t.Error("Expected an error for a missing plugin command")
+	}
+}
+
+func TestRegisterParser(t *testing.T) {
+	parser, err := NewExternalParser("zig-tokenizer")
+	if err != nil {
+		t.Fatalf("Error creating parser: %v", err)
+	}
+
+	RegisterParser("Zig", parser)
+	defer delete(registeredParsers, "zig")
+
+	if GetParser("zig") != parser {
+		t.Error("GetParser did not return the registered parser")
+	}
+}
@@ -1,25 +1,238 @@
 package parsing
 
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
 // Parser defines an interface for code parsers
 type Parser interface {
 	// Parse parses code and returns a sequence of tokens
 	Parse(code string) (TokenSequence, error)
 }
 
+// LineState is parser state carried from one line of a code block to the
+// next. Its contents are private to the parser that produced it.
+type LineState interface{}
+
+// LineParser is implemented by parsers for languages where a line can't be
+// tokenized correctly on its own, such as Bash here-documents. The pretty
+// printer highlights code a line at a time and uses it when available.
+type LineParser interface {
+	Parser
+	// ParseLine parses a single line, without its line terminator, given the
+	// state returned for the previous line (nil for the first line of a block)
+	ParseLine(line string, state LineState) (TokenSequence, LineState, error)
+}
+
+// BlockParser is implemented by parsers too slow to run for each line, such
+// as plugins that run as a separate process. Code in their languages is held
+// back until the whole code block has arrived and parsed once.
+type BlockParser interface {
+	Parser
+	// ParsesWholeBlocks reports whether code should be parsed a block at a
+	// time rather than a line at a time
+	ParsesWholeBlocks() bool
+}
+
+// ParsesWholeBlocks reports whether parser should be given whole code blocks
+// rather than single lines
+func ParsesWholeBlocks(parser Parser) bool {
+	blockParser, ok := parser.(BlockParser)
+	return ok && blockParser.ParsesWholeBlocks()
+}
+
+// Backend identifies an implementation of the built-in parsers
+type Backend string
+
+const (
+	// BackendRegex uses the hand-written regular expression tokenizers
+	BackendRegex Backend = "regex"
+	// BackendTreeSitter uses tree-sitter grammars, falling back to the regex
+	// tokenizers for languages without a grammar. It is only available in
+	// binaries built with the treesitter build tag.
+	BackendTreeSitter Backend = "treesitter"
+)
+
+// currentBackend is the backend used by GetParser
+var currentBackend = BackendRegex
+
+// SetBackend selects the backend used by GetParser
+func SetBackend(backend Backend) error {
+	switch backend {
+	case BackendRegex:
+	case BackendTreeSitter:
+		if !treeSitterAvailable {
+			return fmt.Errorf("this build of aipipe does not include tree-sitter support (build with -tags treesitter)")
+		}
+	default:
+		return fmt.Errorf("unknown highlighter backend %q", backend)
+	}
+
+	currentBackend = backend
+	return nil
+}
+
+// registeredParsers holds parsers added at runtime with RegisterParser
+var registeredParsers = map[string]Parser{}
+
+// RegisterParser makes a parser available to GetParser under the given
+// language name, taking precedence over any built-in parser for that language
+func RegisterParser(language string, parser Parser) {
+	registeredParsers[CanonicalLanguage(language)] = parser
+}
+
+// builtinParsers maps canonical language names to constructors for the
+// built-in regex parsers
+var builtinParsers = map[string]func() Parser{
+	"python":     func() Parser { return &PythonParser{} },
+	"typescript": func() Parser { return &TypeScriptParser{} },
+	"tsx":        func() Parser { return &TypeScriptParser{} },
+	"javascript": func() Parser { return &TypeScriptParser{} },
+	"bash":       func() Parser { return &BashParser{} },
+	"json":       func() Parser { return &JSONParser{} },
+	"csharp":     func() Parser { return &CsharpParser{} },
+	"powershell": func() Parser { return &PowerShellParser{} },
+	"rust":       func() Parser { return &RustParser{} },
+	"yaml":       func() Parser { return &YAMLParser{} },
+	"html":       func() Parser { return &MarkupParser{} },
+	"xml":        func() Parser { return &MarkupParser{} },
+	"svg":        func() Parser { return &MarkupParser{} },
+	"dockerfile": func() Parser { return &DockerfileParser{} },
+	"diff":       func() Parser { return &DiffParser{} },
+}
+
+// languageAliases maps alternative code fence labels to canonical language
+// names. Extra aliases can be added with RegisterAlias.
+var languageAliases = map[string]string{
+	"py":            "python",
+	"python3":       "python",
+	"ts":            "typescript",
+	"js":            "javascript",
+	"jsx":           "javascript",
+	"mjs":           "javascript",
+	"cjs":           "javascript",
+	"node":          "javascript",
+	"nodejs":        "javascript",
+	"sh":            "bash",
+	"shell":         "bash",
+	"zsh":           "bash",
+	"ksh":           "bash",
+	"console":       "bash",
+	"shell-session": "bash",
+	"shellsession":  "bash",
+	"jsonc":         "json",
+	"cs":            "csharp",
+	"c#":            "csharp",
+	"ps1":           "powershell",
+	"ps":            "powershell",
+	"pwsh":          "powershell",
+	"posh":          "powershell",
+	"golang":        "go",
+	"rs":            "rust",
+	"rb":            "ruby",
+	"yml":           "yaml",
+	"htm":           "html",
+	"xhtml":         "html",
+	"c++":           "cpp",
+	"cxx":           "cpp",
+	"tf":            "hcl",
+	"terraform":     "hcl",
+	"docker":        "dockerfile",
+	"patch":         "diff",
+	"udiff":         "diff",
+}
+
+// RegisterAlias makes alias another name for language, so code blocks labelled
+// with the alias are highlighted as that language
+func RegisterAlias(alias string, language string) {
+	languageAliases[strings.ToLower(alias)] = strings.ToLower(language)
+}
+
+// CanonicalLanguage resolves a code fence label to its canonical language name.
+// Labels that aren't aliases are returned lowercased.
+func CanonicalLanguage(language string) string {
+	language = strings.ToLower(strings.TrimSpace(language))
+
+	// Aliases may refer to other aliases; the limit guards against cycles
+	for i := 0; i < 8; i++ {
+		canonical, ok := languageAliases[language]
+		if !ok || canonical == language {
+			break
+		}
+		language = canonical
+	}
+
+	return language
+}
+
 // GetParser returns a parser for the specified language
 func GetParser(language string) Parser {
-	switch language {
-	case "python", "py":
-		return &PythonParser{}
-	case "typescript", "ts", "javascript", "js":
-		return &TypeScriptParser{}
-	case "bash", "sh", "shell":
-		return &BashParser{}
-	case "json":
-		return &JSONParser{}
-	case "csharp", "cs", "c#":
-		return &CsharpParser{}
-	default:
-		return nil
+	language = CanonicalLanguage(language)
+
+	if parser, ok := registeredParsers[language]; ok {
+		return parser
+	}
+
+	if currentBackend == BackendTreeSitter {
+		if parser := getTreeSitterParser(language); parser != nil {
+			return parser
+		}
+	}
+
+	if newParser, ok := builtinParsers[language]; ok {
+		return newParser()
+	}
+	return nil
+}
+
+// extensionLanguages maps file extensions to language identifiers understood by GetParser
+var extensionLanguages = map[string]string{
+	".py":    "python",
+	".ts":    "typescript",
+	".tsx":   "typescript",
+	".js":    "javascript",
+	".jsx":   "javascript",
+	".mjs":   "javascript",
+	".cjs":   "javascript",
+	".sh":    "bash",
+	".bash":  "bash",
+	".json":  "json",
+	".cs":    "csharp",
+	".ps1":   "powershell",
+	".psm1":  "powershell",
+	".rs":    "rust",
+	".yaml":  "yaml",
+	".yml":   "yaml",
+	".html":  "html",
+	".htm":   "html",
+	".xml":   "xml",
+	".svg":   "svg",
+	".diff":  "diff",
+	".patch": "diff",
+
+	".dockerfile": "dockerfile",
+}
+
+// nameLanguages maps the names of files conventionally named without an
+// extension to language identifiers understood by GetParser. The names are
+// matched case-sensitively, so Dockerfile.dev is a Dockerfile but
+// dockerfile.go isn't.
+var nameLanguages = map[string]string{
+	"Dockerfile":    "dockerfile",
+	"Containerfile": "dockerfile",
+}
+
+// LanguageFromFilename returns the language identifier for a file name based on
+// its extension, or an empty string if the extension is not recognised. Files
+// such as Dockerfile, and variants such as Dockerfile.dev, are recognised by
+// name.
+func LanguageFromFilename(filename string) string {
+	name, _, _ := strings.Cut(filepath.Base(filename), ".")
+	if language, ok := nameLanguages[name]; ok {
+		return language
 	}
+	ext := strings.ToLower(filepath.Ext(filename))
+	return extensionLanguages[ext]
 }
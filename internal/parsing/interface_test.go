@@ -0,0 +1,108 @@
+package parsing
+
+import (
+	"testing"
+)
+
+func TestLanguageFromFilename(t *testing.T) {
+	testCases := []struct {
+		filename string
+		expected string
+	}{
+		{"script.py", "python"},
+		{"src/app.tsx", "typescript"},
+		{"index.js", "javascript"},
+		{"build.sh", "bash"},
+		{"package.json", "json"},
+		{"Program.CS", "csharp"},
+		{"src/main.rs", "rust"},
+		{"deploy.yml", "yaml"},
+		{"index.HTML", "html"},
+		{"pom.xml", "xml"},
+		{"icon.svg", "svg"},
+		{"Dockerfile", "dockerfile"},
+		{"build/Dockerfile.dev", "dockerfile"},
+		{"api.dockerfile", "dockerfile"},
+		{"dockerfile.go", ""},
+		{"fix.patch", "diff"},
+		{"changes.diff", "diff"},
+		{"README.md", ""},
+		{"Makefile", ""},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.filename, func(t *testing.T) {
+			if got := LanguageFromFilename(tc.filename); got != tc.expected {
+				t.Errorf("LanguageFromFilename(%q) = %q, want %q", tc.filename, got, tc.expected)
+			}
+			if tc.expected != "" && GetParser(tc.expected) == nil {
+				t.Errorf("No parser registered for detected language %q", tc.expected)
+			}
+		})
+	}
+}
+
+func TestSetBackend(t *testing.T) {
+	defer SetBackend(BackendRegex)
+
+	if err := SetBackend("pygments"); err == nil {
+		t.Error("Expected an error for an unknown backend")
+	}
+
+	err := SetBackend(BackendTreeSitter)
+	if treeSitterAvailable && err != nil {
+		t.Errorf("SetBackend(BackendTreeSitter) error = %v", err)
+	}
+	if !treeSitterAvailable && err == nil {
+		t.Error("Expected an error selecting tree-sitter in a build without it")
+	}
+
+	// Languages without a grammar always fall back to the regex parsers
+	if _, ok := GetParser("json").(*JSONParser); !ok {
+		t.Error("Expected the regex JSON parser")
+	}
+}
+
+func TestCanonicalLanguage(t *testing.T) {
+	testCases := []struct {
+		input    string
+		expected string
+	}{
+		{"py", "python"},
+		{"Python", "python"},
+		{"node", "javascript"},
+		{"shell-session", "bash"},
+		{"golang", "go"},
+		{"C#", "csharp"},
+		{"  yml ", "yaml"},
+		{"htm", "html"},
+		{"brainfuck", "brainfuck"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.input, func(t *testing.T) {
+			if got := CanonicalLanguage(tc.input); got != tc.expected {
+				t.Errorf("CanonicalLanguage(%q) = %q, want %q", tc.input, got, tc.expected)
+			}
+		})
+	}
+}
+
+func TestRegisterAlias(t *testing.T) {
+	RegisterAlias("PS", "sh")
+	defer delete(languageAliases, "ps")
+
+	if got := CanonicalLanguage("ps"); got != "bash" {
+		t.Errorf("CanonicalLanguage(\"ps\") = %q, want \"bash\"", got)
+	}
+	if _, ok := GetParser("ps").(*BashParser); !ok {
+		t.Error("Expected the bash parser for a user-defined alias")
+	}
+
+	// Cycles must not hang
+	RegisterAlias("loop-a", "loop-b")
+	RegisterAlias("loop-b", "loop-a")
+	defer delete(languageAliases, "loop-a")
+	defer delete(languageAliases, "loop-b")
+	CanonicalLanguage("loop-a")
+}
@@ -13,9 +13,7 @@ var (
 	}
 
 	// Regular expressions for JSON tokens
-	jsonNumberRegex     = regexp.MustCompile(`^-?(?:0|[1-9]\d*)(?:\.\d+)?(?:[eE][+-]?\d+)?`)
-	jsonIdentifierRegex = regexp.MustCompile(`^[a-zA-Z_$][a-zA-Z0-9_$]*`)
-	jsonWhitespaceRegex = regexp.MustCompile(`^[ \t\r\n]+`)
+	jsonNumberRegex = regexp.MustCompile(`^-?(?:0|[1-9]\d*)(?:\.\d+)?(?:[eE][+-]?\d+)?`)
 )
 
 // JSONParser implements the Parser interface for JSON
@@ -57,9 +55,9 @@ func ParseJSON(code string) (TokenSequence, error) {
 
 	for len(code) > 0 {
 		// Try to match whitespace first to preserve formatting
-		if match := jsonWhitespaceRegex.FindString(code); match != "" {
-			tokens = append(tokens, Token{Type: TokenWhitespace, Text: match})
-			code = code[len(match):]
+		if n := scanWhitespace(code); n > 0 {
+			tokens = append(tokens, Token{Type: TokenWhitespace, Text: code[:n]})
+			code = code[n:]
 			continue
 		}
 
@@ -74,8 +72,8 @@ func ParseJSON(code string) (TokenSequence, error) {
 				// it's an object key and should be treated as an identifier
 				remaining := code[end:]
 				for len(remaining) > 0 {
-					if match := jsonWhitespaceRegex.FindString(remaining); match != "" {
-						remaining = remaining[len(match):]
+					if n := scanWhitespace(remaining); n > 0 {
+						remaining = remaining[n:]
 						continue
 					}
 					if len(remaining) > 0 && remaining[0] == ':' {
@@ -91,14 +89,15 @@ func ParseJSON(code string) (TokenSequence, error) {
 		}
 
 		// Try to match a number
-		if match := jsonNumberRegex.FindString(code); match != "" {
+		if match := scanRegex(code, "-"+digits, jsonNumberRegex); match != "" {
 			tokens = append(tokens, Token{Type: TokenLiteral, Text: match})
 			code = code[len(match):]
 			continue
 		}
 
 		// Try to match keywords (true, false, null)
-		if match := jsonIdentifierRegex.FindString(code); match != "" {
+		if n := scanIdentifier(code, "$", "$"); n > 0 {
+			match := code[:n]
 			if jsonKeywords[match] {
 				tokens = append(tokens, Token{Type: TokenKeyword, Text: match})
 			} else {
@@ -109,8 +108,9 @@ func ParseJSON(code string) (TokenSequence, error) {
 		}
 
 		// If none of the above matched, it's an "other" token (punctuation, etc.)
-		tokens = append(tokens, Token{Type: TokenOther, Text: string(code[0])})
-		code = code[1:]
+		n := scanChar(code)
+		tokens = append(tokens, Token{Type: TokenOther, Text: code[:n]})
+		code = code[n:]
 	}
 
 	return tokens, nil
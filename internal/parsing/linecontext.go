@@ -0,0 +1,43 @@
+package parsing
+
+// maxLineContext bounds how much of a code block parseLineInContext parses
+// again for each new line. Once the block is longer, it starts again from
+// the next line, trading accuracy for speed in very long blocks.
+const maxLineContext = 64 << 10
+
+// parseLineInContext implements ParseLine for parsers that only parse whole
+// texts, such as grammars and lexers that keep no state between lines. The
+// line is parsed after the earlier lines of its block, carried in state, so
+// strings and comments that span lines are seen whole, and the tokens of the
+// line alone are returned.
+func parseLineInContext(parser Parser, line string, state LineState) (TokenSequence, LineState, error) {
+	before, _ := state.(string)
+	if len(before) > maxLineContext {
+		before = ""
+	}
+
+	text := before + line
+	tokens, err := parser.Parse(text)
+	if err != nil {
+		return nil, nil, err
+	}
+	return sliceTokens(tokens, len(before), len(text)), text + "\n", nil
+}
+
+// sliceTokens returns the tokens covering the bytes from start to end of the
+// text they were parsed from, cutting those that run past either end
+func sliceTokens(tokens TokenSequence, start int, end int) TokenSequence {
+	var sliced TokenSequence
+	offset := 0
+	for _, token := range tokens {
+		tokenStart, tokenEnd := offset, offset+len(token.Text)
+		offset = tokenEnd
+		if tokenEnd <= start || tokenStart >= end {
+			continue
+		}
+
+		text := token.Text[max(start-tokenStart, 0) : min(end, tokenEnd)-tokenStart]
+		sliced = append(sliced, Token{Type: token.Type, Text: text})
+	}
+	return sliced
+}
@@ -0,0 +1,51 @@
+package parsing
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSliceTokens(t *testing.T) {
+	// A string literal spanning the second and third lines
+	tokens := TokenSequence{
+		{Type: TokenIdentifier, Text: "x"},
+		{Type: TokenWhitespace, Text: " "},
+		{Type: TokenOther, Text: "="},
+		{Type: TokenWhitespace, Text: "\n"},
+		{Type: TokenLiteral, Text: "\"\"\"a\nb\"\"\""},
+		{Type: TokenWhitespace, Text: "\n"},
+	}
+
+	tests := []struct {
+		name       string
+		start, end int
+		expected   TokenSequence
+	}{
+		{
+			name:     "First line",
+			start:    0,
+			end:      3,
+			expected: TokenSequence{{Type: TokenIdentifier, Text: "x"}, {Type: TokenWhitespace, Text: " "}, {Type: TokenOther, Text: "="}},
+		},
+		{
+			name:     "Start of the literal",
+			start:    4,
+			end:      8,
+			expected: TokenSequence{{Type: TokenLiteral, Text: "\"\"\"a"}},
+		},
+		{
+			name:     "End of the literal",
+			start:    9,
+			end:      13,
+			expected: TokenSequence{{Type: TokenLiteral, Text: "b\"\"\""}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if result := sliceTokens(tokens, tt.start, tt.end); !reflect.DeepEqual(result, tt.expected) {
+				t.Errorf("sliceTokens(%d, %d) = %+v, want %+v", tt.start, tt.end, result, tt.expected)
+			}
+		})
+	}
+}
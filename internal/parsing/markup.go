@@ -0,0 +1,286 @@
+package parsing
+
+import (
+	"regexp"
+	"strings"
+)
+
+var (
+	// markupEntityRegex matches character references such as &amp;, &#39;
+	// and &#x27;
+	markupEntityRegex = regexp.MustCompile(`^&(#[0-9]+|#[xX][0-9a-fA-F]+|[a-zA-Z][a-zA-Z0-9]*);`)
+
+	// markupRawTextTags are the HTML elements whose content is script or
+	// style rather than markup
+	markupRawTextTags = map[string]bool{
+		"script": true,
+		"style":  true,
+	}
+)
+
+// markupOpen identifies a construct that was still open at the end of a line
+type markupOpen int
+
+const (
+	markupOpenNone markupOpen = iota
+	markupOpenComment
+	markupOpenCDATA
+	markupOpenTag
+	markupOpenValue
+)
+
+// markupState is the state carried between lines while parsing markup:
+// comments, CDATA sections, tags and their attribute values may span lines
+type markupState struct {
+	open markupOpen
+	// quote is the quote around an open attribute value
+	quote byte
+	// expectValue is set after an attribute's =, until its value
+	expectValue bool
+	// tagName is the lowercased name of an open start tag
+	tagName string
+	// rawText is the name of the script or style element whose content is
+	// being read
+	rawText string
+}
+
+// MarkupParser implements the Parser and LineParser interfaces for HTML, XML
+// and SVG
+type MarkupParser struct{}
+
+// Parse implements the Parser interface for markup
+func (p *MarkupParser) Parse(code string) (TokenSequence, error) {
+	return ParseMarkup(code)
+}
+
+// ParseLine implements the LineParser interface for markup, so tags,
+// comments and script elements spanning lines are recognised when a block is
+// highlighted a line at a time
+func (p *MarkupParser) ParseLine(line string, state LineState) (TokenSequence, LineState, error) {
+	current, _ := state.(markupState)
+	tokens := parseMarkup(line, &current)
+	return tokens, current, nil
+}
+
+// ParseMarkup parses HTML, XML or SVG and returns a sequence of tokens. Tag
+// names are keywords, attribute names identifiers, and attribute values and
+// entities literals.
+func ParseMarkup(code string) (TokenSequence, error) {
+	return parseMarkup(code, &markupState{}), nil
+}
+
+// findMarkupEnd returns the length of the text at the start of code up to and
+// including terminator, and whether the terminator was found. Without it, the
+// whole of code is taken.
+func findMarkupEnd(code string, terminator string) (int, bool) {
+	if end := strings.Index(code, terminator); end >= 0 {
+		return end + len(terminator), true
+	}
+	return len(code), false
+}
+
+// findMarkupEndTag returns the offset of the end tag of the named element in
+// code, ignoring case, or the length of code if there isn't one
+func findMarkupEndTag(code string, name string) int {
+	for offset := 0; ; {
+		i := strings.Index(code[offset:], "</")
+		if i < 0 {
+			return len(code)
+		}
+		offset += i
+		if end := offset + 2 + len(name); end <= len(code) && strings.EqualFold(code[offset+2:end], name) {
+			return offset
+		}
+		offset += 2
+	}
+}
+
+// scanMarkupText matches text between tags, up to the next tag, entity or
+// whitespace
+func scanMarkupText(code string) int {
+	for i := 0; i < len(code); i++ {
+		switch code[i] {
+		case '<', '&', ' ', '\t', '\r', '\n':
+			if i > 0 {
+				return i
+			}
+			return scanChar(code)
+		}
+	}
+	return len(code)
+}
+
+// scanAttributeName matches an attribute name, or an unquoted attribute
+// value: anything up to whitespace, =, a quote or the end of the tag
+func scanAttributeName(code string) int {
+	for i := 0; i < len(code); i++ {
+		switch code[i] {
+		case ' ', '\t', '\r', '\n', '=', '>', '"', '\'':
+			return i
+		case '/':
+			if strings.HasPrefix(code[i:], "/>") {
+				return i
+			}
+		}
+	}
+	return len(code)
+}
+
+// parseMarkupTag returns the first token of code, which is inside a tag, and
+// updates state if it ends the tag or opens an attribute value
+func parseMarkupTag(code string, state *markupState) Token {
+	switch {
+	case code[0] == '>':
+		state.open = markupOpenNone
+		if markupRawTextTags[state.tagName] {
+			state.rawText = state.tagName
+		}
+		return Token{Type: TokenOther, Text: code[:1]}
+
+	case strings.HasPrefix(code, "/>"):
+		state.open = markupOpenNone
+		return Token{Type: TokenOther, Text: code[:2]}
+
+	case code[0] == '=':
+		state.expectValue = true
+		return Token{Type: TokenOther, Text: code[:1]}
+
+	case code[0] == '"' || code[0] == '\'':
+		state.expectValue = false
+		n, closed := findMarkupEnd(code[1:], code[:1])
+		if !closed {
+			state.open, state.quote = markupOpenValue, code[0]
+		}
+		return Token{Type: TokenLiteral, Text: code[:1+n]}
+	}
+
+	n := scanAttributeName(code)
+	if n == 0 {
+		return Token{Type: TokenOther, Text: code[:scanChar(code)]}
+	}
+	if state.expectValue {
+		state.expectValue = false
+		return Token{Type: TokenLiteral, Text: code[:n]}
+	}
+	return Token{Type: TokenIdentifier, Text: code[:n]}
+}
+
+// parseMarkup tokenizes markup, starting from and updating the given state
+func parseMarkup(code string, state *markupState) TokenSequence {
+	tokens := TokenSequence{}
+
+	for len(code) > 0 {
+		// Finish a construct started on an earlier line
+		switch state.open {
+		case markupOpenComment, markupOpenCDATA:
+			tokenType, terminator := TokenComment, "-->"
+			if state.open == markupOpenCDATA {
+				tokenType, terminator = TokenLiteral, "]]>"
+			}
+			n, closed := findMarkupEnd(code, terminator)
+			if closed {
+				state.open = markupOpenNone
+			}
+			tokens = append(tokens, Token{Type: tokenType, Text: code[:n]})
+			code = code[n:]
+			continue
+
+		case markupOpenValue:
+			n, closed := findMarkupEnd(code, string(state.quote))
+			if closed {
+				state.open = markupOpenTag
+			}
+			tokens = append(tokens, Token{Type: TokenLiteral, Text: code[:n]})
+			code = code[n:]
+			continue
+		}
+
+		if n := scanWhitespace(code); n > 0 {
+			tokens = append(tokens, Token{Type: TokenWhitespace, Text: code[:n]})
+			code = code[n:]
+			continue
+		}
+
+		if state.open == markupOpenTag {
+			token := parseMarkupTag(code, state)
+			tokens = append(tokens, token)
+			code = code[len(token.Text):]
+			continue
+		}
+
+		// The content of script and style elements isn't markup, and runs
+		// to their end tag
+		if state.rawText != "" {
+			end := findMarkupEndTag(code, state.rawText)
+			if end != 0 {
+				tokens = append(tokens, Token{Type: TokenOther, Text: code[:end]})
+				code = code[end:]
+				continue
+			}
+			state.rawText = ""
+		}
+
+		switch {
+		case strings.HasPrefix(code, "<!--"):
+			state.open = markupOpenComment
+			n, closed := findMarkupEnd(code[4:], "-->")
+			if closed {
+				state.open = markupOpenNone
+			}
+			tokens = append(tokens, Token{Type: TokenComment, Text: code[:4+n]})
+			code = code[4+n:]
+			continue
+
+		case strings.HasPrefix(code, "<![CDATA["):
+			state.open = markupOpenCDATA
+			n, closed := findMarkupEnd(code[9:], "]]>")
+			if closed {
+				state.open = markupOpenNone
+			}
+			tokens = append(tokens, Token{Type: TokenLiteral, Text: code[:9+n]})
+			code = code[9+n:]
+			continue
+
+		case strings.HasPrefix(code, "<!") || strings.HasPrefix(code, "<?"):
+			// Declarations such as <!DOCTYPE html> and processing
+			// instructions such as <?xml version="1.0"?>
+			n, _ := findMarkupEnd(code, ">")
+			tokens = append(tokens, Token{Type: TokenKeyword, Text: code[:n]})
+			code = code[n:]
+			continue
+
+		case strings.HasPrefix(code, "<"):
+			// Start and end tags, whose names may include a namespace
+			opening := 1
+			if strings.HasPrefix(code, "</") {
+				opening = 2
+			}
+			if n := scanIdentifier(code[opening:], "", "-:."); n > 0 {
+				name := code[opening : opening+n]
+				state.open, state.expectValue, state.tagName = markupOpenTag, false, ""
+				if opening == 1 {
+					state.tagName = strings.ToLower(name)
+				}
+				tokens = append(tokens,
+					Token{Type: TokenOther, Text: code[:opening]},
+					Token{Type: TokenKeyword, Text: name})
+				code = code[opening+n:]
+				continue
+			}
+
+		case code[0] == '&':
+			if match := markupEntityRegex.FindString(code); match != "" {
+				tokens = append(tokens, Token{Type: TokenLiteral, Text: match})
+				code = code[len(match):]
+				continue
+			}
+		}
+
+		// Anything else is text
+		n := scanMarkupText(code)
+		tokens = append(tokens, Token{Type: TokenOther, Text: code[:n]})
+		code = code[n:]
+	}
+
+	return tokens
+}
@@ -0,0 +1,143 @@
+package parsing
+
+import (
+	"testing"
+)
+
+func TestMarkupParser(t *testing.T) {
+	testCases := []struct {
+		name     string
+		input    string
+		expected []Token
+	}{
+		{
+			name:  "Tags and attributes",
+			input: `<a href="/x?a=1&amp;b=2" hidden class=nav>Fish &amp; chips</a>`,
+			expected: []Token{
+				{Type: TokenOther, Text: "<"},
+				{Type: TokenKeyword, Text: "a"},
+				{Type: TokenWhitespace, Text: " "},
+				{Type: TokenIdentifier, Text: "href"},
+				{Type: TokenOther, Text: "="},
+				{Type: TokenLiteral, Text: `"/x?a=1&amp;b=2"`},
+				{Type: TokenWhitespace, Text: " "},
+				{Type: TokenIdentifier, Text: "hidden"},
+				{Type: TokenWhitespace, Text: " "},
+				{Type: TokenIdentifier, Text: "class"},
+				{Type: TokenOther, Text: "="},
+				{Type: TokenLiteral, Text: "nav"},
+				{Type: TokenOther, Text: ">"},
+				{Type: TokenOther, Text: "Fish"},
+				{Type: TokenWhitespace, Text: " "},
+				{Type: TokenLiteral, Text: "&amp;"},
+				{Type: TokenWhitespace, Text: " "},
+				{Type: TokenOther, Text: "chips"},
+				{Type: TokenOther, Text: "</"},
+				{Type: TokenKeyword, Text: "a"},
+				{Type: TokenOther, Text: ">"},
+			},
+		},
+		{
+			name:  "Declarations, comments and CDATA",
+			input: "<?xml version=\"1.0\"?>\n<!-- a <b> -->\n<![CDATA[x < y]]>",
+			expected: []Token{
+				{Type: TokenKeyword, Text: `<?xml version="1.0"?>`},
+				{Type: TokenWhitespace, Text: "\n"},
+				{Type: TokenComment, Text: "<!-- a <b> -->"},
+				{Type: TokenWhitespace, Text: "\n"},
+				{Type: TokenLiteral, Text: "<![CDATA[x < y]]>"},
+			},
+		},
+		{
+			name:  "Namespaced self-closing tag",
+			input: `<svg:rect x='0'/>`,
+			expected: []Token{
+				{Type: TokenOther, Text: "<"},
+				{Type: TokenKeyword, Text: "svg:rect"},
+				{Type: TokenWhitespace, Text: " "},
+				{Type: TokenIdentifier, Text: "x"},
+				{Type: TokenOther, Text: "="},
+				{Type: TokenLiteral, Text: "'0'"},
+				{Type: TokenOther, Text: "/>"},
+			},
+		},
+		{
+			name:  "Script content isn't markup",
+			input: "<script>if (a <b) x()</SCRIPT>",
+			expected: []Token{
+				{Type: TokenOther, Text: "<"},
+				{Type: TokenKeyword, Text: "script"},
+				{Type: TokenOther, Text: ">"},
+				{Type: TokenOther, Text: "if (a <b) x()"},
+				{Type: TokenOther, Text: "</"},
+				{Type: TokenKeyword, Text: "SCRIPT"},
+				{Type: TokenOther, Text: ">"},
+			},
+		},
+		{
+			name:  "Stray angle bracket",
+			input: "1 < 2",
+			expected: []Token{
+				{Type: TokenOther, Text: "1"},
+				{Type: TokenWhitespace, Text: " "},
+				{Type: TokenOther, Text: "<"},
+				{Type: TokenWhitespace, Text: " "},
+				{Type: TokenOther, Text: "2"},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			tokens, err := ParseMarkup(tc.input)
+			if err != nil {
+				t.Fatalf("Error parsing markup: %v", err)
+			}
+			if len(tokens) != len(tc.expected) {
+				t.Fatalf("Expected %d tokens, got %d. Tokens: %v", len(tc.expected), len(tokens), tokens)
+			}
+			for i := range tokens {
+				if tokens[i] != tc.expected[i] {
+					t.Errorf("Token %d = %+v, want %+v", i, tokens[i], tc.expected[i])
+				}
+			}
+		})
+	}
+}
+
+func TestMarkupParseLine(t *testing.T) {
+	parser := &MarkupParser{}
+	lines := []string{
+		`<img src="a.png"`,
+		`     alt="two`,
+		`lines" />`,
+		`<!-- open`,
+		`comment -->`,
+	}
+	expected := []Token{
+		{Type: TokenWhitespace, Text: "     "},
+		{Type: TokenLiteral, Text: `lines"`},
+		{Type: TokenComment, Text: "<!-- open"},
+		{Type: TokenComment, Text: "comment -->"},
+	}
+
+	var state LineState
+	var firstTokens []TokenSequence
+	for _, line := range lines {
+		tokens, next, err := parser.ParseLine(line, state)
+		if err != nil {
+			t.Fatalf("ParseLine(%q) error: %v", line, err)
+		}
+		firstTokens = append(firstTokens, tokens)
+		state = next
+	}
+
+	for i, want := range expected {
+		if got := firstTokens[i+1][0]; got != want {
+			t.Errorf("First token of line %d = %+v, want %+v", i+2, got, want)
+		}
+	}
+	if got := firstTokens[1][1]; got.Type != TokenIdentifier || got.Text != "alt" {
+		t.Errorf("Attribute on a continued tag = %+v, want the identifier alt", got)
+	}
+}
@@ -0,0 +1,296 @@
+package parsing
+
+import (
+	"regexp"
+	"strings"
+)
+
+var (
+	// PowerShell keywords. PowerShell is case-insensitive, so identifiers are
+	// lowercased before lookup.
+	powershellKeywords = map[string]bool{
+		"begin":        true,
+		"break":        true,
+		"catch":        true,
+		"class":        true,
+		"continue":     true,
+		"data":         true,
+		"do":           true,
+		"dynamicparam": true,
+		"else":         true,
+		"elseif":       true,
+		"end":          true,
+		"enum":         true,
+		"exit":         true,
+		"filter":       true,
+		"finally":      true,
+		"for":          true,
+		"foreach":      true,
+		"function":     true,
+		"if":           true,
+		"in":           true,
+		"param":        true,
+		"process":      true,
+		"return":       true,
+		"switch":       true,
+		"throw":        true,
+		"trap":         true,
+		"try":          true,
+		"until":        true,
+		"using":        true,
+		"while":        true,
+	}
+
+	// Regular expressions for PowerShell tokens
+	powershellVariableRegex  = regexp.MustCompile(`^(\$|@)(\{[^}]*\}|[a-zA-Z_][a-zA-Z0-9_]*(:[a-zA-Z_][a-zA-Z0-9_]*)?|[?^$])`)
+	powershellNumberRegex    = regexp.MustCompile(`^(0[xX][0-9a-fA-F]+|[0-9]+(\.[0-9]+)?([eE][+-]?[0-9]+)?)([kKmMgGtTpP][bB])?`)
+	powershellParameterRegex = regexp.MustCompile(`^-[a-zA-Z][a-zA-Z0-9]*`)
+)
+
+// PowerShellParser implements the Parser interface for PowerShell scripts
+type PowerShellParser struct{}
+
+// Parse implements the Parser interface for PowerShell
+func (p *PowerShellParser) Parse(code string) (TokenSequence, error) {
+	return ParsePowerShell(code)
+}
+
+// findPowerShellSubexpressionEnd returns the index of the parenthesis closing
+// the subexpression at the start of code ("$(..."), or -1 if it is not
+// closed. Nested parentheses and quoted strings are skipped.
+func findPowerShellSubexpressionEnd(code string) int {
+	depth := 0
+	for i := 1; i < len(code); i++ {
+		switch code[i] {
+		case '`':
+			i++
+		case '\'':
+			end := findPowerShellSingleQuotedEnd(code[i:])
+			if end < 0 {
+				return -1
+			}
+			i += end - 1
+		case '"':
+			_, length := parsePowerShellExpandable(code[i:], `"`)
+			if length <= 1 || code[i+length-1] != '"' {
+				return -1
+			}
+			i += length - 1
+		case '(':
+			depth++
+		case ')':
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// findPowerShellSingleQuotedEnd returns the length of the single-quoted
+// string at the start of code, or -1 if it is not closed. Quotes are escaped
+// by doubling them.
+func findPowerShellSingleQuotedEnd(code string) int {
+	for i := 1; i < len(code); i++ {
+		if code[i] != '\'' {
+			continue
+		}
+		if i+1 < len(code) && code[i+1] == '\'' {
+			i++
+			continue
+		}
+		return i + 1
+	}
+	return -1
+}
+
+// parsePowerShellSubexpression tokenizes a complete subexpression, parsing
+// the code inside it as PowerShell
+func parsePowerShellSubexpression(subexpression string) TokenSequence {
+	tokens := TokenSequence{{Type: TokenOther, Text: "$("}}
+	inner, _ := ParsePowerShell(subexpression[2 : len(subexpression)-1])
+	tokens = append(tokens, inner...)
+	return append(tokens, Token{Type: TokenOther, Text: ")"})
+}
+
+// parsePowerShellExpandable tokenizes the expandable (double-quoted) string
+// or here-string at the start of code, returning its tokens and length.
+// terminator is the text closing the string: `"` or `"@`. Variables and
+// subexpressions inside the string are emitted as their own tokens; the rest
+// of the string is a literal.
+func parsePowerShellExpandable(code string, terminator string) (TokenSequence, int) {
+	var tokens TokenSequence
+	segmentStart := 0
+	start := 1
+	if terminator == `"@` {
+		start = 2 // skip the opening @"
+	}
+
+	flush := func(end int) {
+		if end > segmentStart {
+			tokens = append(tokens, Token{Type: TokenLiteral, Text: code[segmentStart:end]})
+		}
+	}
+
+	for i := start; i < len(code); i++ {
+		switch code[i] {
+		case '`':
+			i++
+		case '"':
+			if terminator == `"` {
+				// A doubled quote is an escaped quote
+				if i+1 < len(code) && code[i+1] == '"' {
+					i++
+					continue
+				}
+				flush(i + 1)
+				return tokens, i + 1
+			}
+			// Here-strings end with "@ at the start of a line
+			if strings.HasPrefix(code[i:], `"@`) && code[i-1] == '\n' {
+				flush(i + 2)
+				return tokens, i + 2
+			}
+		case '$':
+			if strings.HasPrefix(code[i:], "$(") {
+				end := findPowerShellSubexpressionEnd(code[i:])
+				if end < 0 {
+					continue
+				}
+				flush(i)
+				tokens = append(tokens, parsePowerShellSubexpression(code[i:i+end+1])...)
+				segmentStart = i + end + 1
+				i += end
+				continue
+			}
+			if match := powershellVariableRegex.FindString(code[i:]); match != "" {
+				flush(i)
+				tokens = append(tokens, Token{Type: TokenIdentifier, Text: match})
+				segmentStart = i + len(match)
+				i += len(match) - 1
+			}
+		}
+	}
+
+	// Unterminated string
+	flush(len(code))
+	return tokens, len(code)
+}
+
+// ParsePowerShell parses PowerShell code and returns a sequence of tokens
+func ParsePowerShell(code string) (TokenSequence, error) {
+	tokens := TokenSequence{}
+
+	for len(code) > 0 {
+		// Check for whitespace
+		if n := scanWhitespace(code); n > 0 {
+			tokens = append(tokens, Token{Type: TokenWhitespace, Text: code[:n]})
+			code = code[n:]
+			continue
+		}
+
+		// Check for block comments
+		if strings.HasPrefix(code, "<#") {
+			n := len(code)
+			if end := strings.Index(code[2:], "#>"); end >= 0 {
+				n = end + 4
+			}
+			tokens = append(tokens, Token{Type: TokenComment, Text: code[:n]})
+			code = code[n:]
+			continue
+		}
+
+		// Check for line comments
+		if code[0] == '#' {
+			n := scanToLineEnd(code)
+			tokens = append(tokens, Token{Type: TokenComment, Text: code[:n]})
+			code = code[n:]
+			continue
+		}
+
+		// Check for expandable strings and here-strings
+		if code[0] == '"' {
+			stringTokens, length := parsePowerShellExpandable(code, `"`)
+			tokens = append(tokens, stringTokens...)
+			code = code[length:]
+			continue
+		}
+		if strings.HasPrefix(code, `@"`) {
+			stringTokens, length := parsePowerShellExpandable(code, `"@`)
+			tokens = append(tokens, stringTokens...)
+			code = code[length:]
+			continue
+		}
+
+		// Check for verbatim strings and here-strings
+		if code[0] == '\'' {
+			n := findPowerShellSingleQuotedEnd(code)
+			if n < 0 {
+				n = len(code)
+			}
+			tokens = append(tokens, Token{Type: TokenLiteral, Text: code[:n]})
+			code = code[n:]
+			continue
+		}
+		if strings.HasPrefix(code, "@'") {
+			n := len(code)
+			if end := strings.Index(code[2:], "\n'@"); end >= 0 {
+				n = end + 5
+			}
+			tokens = append(tokens, Token{Type: TokenLiteral, Text: code[:n]})
+			code = code[n:]
+			continue
+		}
+
+		// Check for subexpressions
+		if strings.HasPrefix(code, "$(") {
+			if end := findPowerShellSubexpressionEnd(code); end > 0 {
+				tokens = append(tokens, parsePowerShellSubexpression(code[:end+1])...)
+				code = code[end+1:]
+				continue
+			}
+		}
+
+		// Check for variables
+		if match := scanRegex(code, "$@", powershellVariableRegex); match != "" {
+			tokens = append(tokens, Token{Type: TokenIdentifier, Text: match})
+			code = code[len(match):]
+			continue
+		}
+
+		// Check for numbers
+		if match := scanRegex(code, digits, powershellNumberRegex); match != "" {
+			tokens = append(tokens, Token{Type: TokenLiteral, Text: match})
+			code = code[len(match):]
+			continue
+		}
+
+		// Check for parameters and operators such as -Path and -eq
+		if match := scanRegex(code, "-", powershellParameterRegex); match != "" {
+			tokens = append(tokens, Token{Type: TokenOther, Text: match})
+			code = code[len(match):]
+			continue
+		}
+
+		// Check for keywords and identifiers. Cmdlet names contain hyphens,
+		// as in Get-ChildItem.
+		if n := scanIdentifier(code, "", "-"); n > 0 {
+			match := strings.TrimRight(code[:n], "-")
+			if powershellKeywords[strings.ToLower(match)] {
+				tokens = append(tokens, Token{Type: TokenKeyword, Text: match})
+			} else {
+				tokens = append(tokens, Token{Type: TokenIdentifier, Text: match})
+			}
+			code = code[len(match):]
+			continue
+		}
+
+		// Handle other characters
+		n := scanChar(code)
+		tokens = append(tokens, Token{Type: TokenOther, Text: code[:n]})
+		code = code[n:]
+	}
+
+	return tokens, nil
+}
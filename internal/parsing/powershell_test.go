@@ -0,0 +1,124 @@
+package parsing
+
+import (
+	"testing"
+)
+
+func TestPowerShellParser(t *testing.T) {
+	parser := &PowerShellParser{}
+
+	testCases := []struct {
+		name     string
+		input    string
+		expected int // Expected number of tokens
+	}{
+		{
+			name:     "Cmdlet with parameter",
+			input:    "Get-ChildItem -Path C:\\temp",
+			expected: 8, // "Get-ChildItem", " ", "-Path", " ", "C", ":", "\\", "temp"
+		},
+		{
+			name:     "Assignment",
+			input:    "$count = 42",
+			expected: 5, // "$count", " ", "=", " ", "42"
+		},
+		{
+			name:     "Verbatim string",
+			input:    "Write-Output 'it''s $literal'",
+			expected: 3, // "Write-Output", " ", "'it''s $literal'"
+		},
+		{
+			name:     "Line comment",
+			input:    "exit 1 # done",
+			expected: 5, // "exit", " ", "1", " ", "# done"
+		},
+		{
+			name:     "Block comment",
+			input:    "<# help\ntext #>\nparam()",
+			expected: 5, // "<# help\ntext #>", "\n", "param", "(", ")"
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			tokens, err := parser.Parse(tc.input)
+			if err != nil {
+				t.Fatalf("Error parsing PowerShell code: %v", err)
+			}
+
+			if len(tokens) != tc.expected {
+				t.Errorf("Expected %d tokens, got %d. Tokens: %v", tc.expected, len(tokens), tokens)
+			}
+		})
+	}
+}
+
+func TestPowerShellStringInterpolation(t *testing.T) {
+	testCases := []struct {
+		name     string
+		input    string
+		expected []Token
+	}{
+		{
+			name:  "Variables",
+			input: "\"Hello $name from $env:COMPUTERNAME\"",
+			expected: []Token{
+				{Type: TokenLiteral, Text: "\"Hello "},
+				{Type: TokenIdentifier, Text: "$name"},
+				{Type: TokenLiteral, Text: " from "},
+				{Type: TokenIdentifier, Text: "$env:COMPUTERNAME"},
+				{Type: TokenLiteral, Text: "\""},
+			},
+		},
+		{
+			name:  "Escaped dollar",
+			input: "\"costs `$5\"",
+			expected: []Token{
+				{Type: TokenLiteral, Text: "\"costs `$5\""},
+			},
+		},
+		{
+			name:  "Subexpression with nested string",
+			input: "\"Total: $($items.Count + \"x\")\"",
+			expected: []Token{
+				{Type: TokenLiteral, Text: "\"Total: "},
+				{Type: TokenOther, Text: "$("},
+				{Type: TokenIdentifier, Text: "$items"},
+				{Type: TokenOther, Text: "."},
+				{Type: TokenIdentifier, Text: "Count"},
+				{Type: TokenWhitespace, Text: " "},
+				{Type: TokenOther, Text: "+"},
+				{Type: TokenWhitespace, Text: " "},
+				{Type: TokenLiteral, Text: "\"x\""},
+				{Type: TokenOther, Text: ")"},
+				{Type: TokenLiteral, Text: "\""},
+			},
+		},
+		{
+			name:  "Here-string",
+			input: "@\"\nName: ${user name}\n\"@",
+			expected: []Token{
+				{Type: TokenLiteral, Text: "@\"\nName: "},
+				{Type: TokenIdentifier, Text: "${user name}"},
+				{Type: TokenLiteral, Text: "\n\"@"},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			tokens, err := ParsePowerShell(tc.input)
+			if err != nil {
+				t.Fatalf("Error parsing PowerShell code: %v", err)
+			}
+			if len(tokens) != len(tc.expected) {
+				t.Fatalf("Expected %d tokens, got %d. Tokens: %v", len(tc.expected), len(tokens), tokens)
+			}
+			for i := range tokens {
+				if tokens[i] != tc.expected[i] {
+					t.Errorf("Token %d = %+v, want %+v", i, tokens[i], tc.expected[i])
+				}
+			}
+		})
+	}
+}
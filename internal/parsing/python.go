@@ -45,10 +45,7 @@ var (
 	}
 
 	// Regular expressions for Python tokens
-	pythonNumberRegex     = regexp.MustCompile(`^[0-9]+(\.[0-9]+)?([eE][+-]?[0-9]+)?`)
-	pythonIdentifierRegex = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*`)
-	pythonCommentRegex    = regexp.MustCompile(`^#.*`)
-	pythonWhitespaceRegex = regexp.MustCompile(`^[ \t\r\n]+`)
+	pythonNumberRegex = regexp.MustCompile(`^[0-9]+(\.[0-9]+)?([eE][+-]?[0-9]+)?`)
 )
 
 // PythonParser implements the Parser interface for Python code
@@ -94,9 +91,9 @@ func ParsePython(code string) (TokenSequence, error) {
 
 	for len(code) > 0 {
 		// Try to match whitespace first to preserve indentation
-		if match := pythonWhitespaceRegex.FindString(code); match != "" {
-			tokens = append(tokens, Token{Type: TokenWhitespace, Text: match})
-			code = code[len(match):]
+		if n := scanWhitespace(code); n > 0 {
+			tokens = append(tokens, Token{Type: TokenWhitespace, Text: code[:n]})
+			code = code[n:]
 			continue
 		}
 
@@ -111,21 +108,23 @@ func ParsePython(code string) (TokenSequence, error) {
 		}
 
 		// Try to match a comment
-		if match := pythonCommentRegex.FindString(code); match != "" {
-			tokens = append(tokens, Token{Type: TokenComment, Text: match})
-			code = code[len(match):]
+		if code[0] == '#' {
+			n := scanToLineEnd(code)
+			tokens = append(tokens, Token{Type: TokenComment, Text: code[:n]})
+			code = code[n:]
 			continue
 		}
 
 		// Try to match a number
-		if match := pythonNumberRegex.FindString(code); match != "" {
+		if match := scanRegex(code, digits, pythonNumberRegex); match != "" {
 			tokens = append(tokens, Token{Type: TokenLiteral, Text: match})
 			code = code[len(match):]
 			continue
 		}
 
 		// Try to match an identifier or keyword
-		if match := pythonIdentifierRegex.FindString(code); match != "" {
+		if n := scanIdentifier(code, "", ""); n > 0 {
+			match := code[:n]
 			if pythonKeywords[match] {
 				tokens = append(tokens, Token{Type: TokenKeyword, Text: match})
 			} else {
@@ -136,8 +135,9 @@ func ParsePython(code string) (TokenSequence, error) {
 		}
 
 		// If none of the above matched, it's an "other" token (operator, punctuation, etc.)
-		tokens = append(tokens, Token{Type: TokenOther, Text: string(code[0])})
-		code = code[1:]
+		n := scanChar(code)
+		tokens = append(tokens, Token{Type: TokenOther, Text: code[:n]})
+		code = code[n:]
 	}
 
 	return tokens, nil
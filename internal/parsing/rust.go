@@ -0,0 +1,345 @@
+package parsing
+
+import (
+	"regexp"
+	"strings"
+)
+
+var (
+	// Rust keywords, including the primitive types
+	rustKeywords = map[string]bool{
+		"as":       true,
+		"async":    true,
+		"await":    true,
+		"bool":     true,
+		"break":    true,
+		"char":     true,
+		"const":    true,
+		"continue": true,
+		"crate":    true,
+		"dyn":      true,
+		"else":     true,
+		"enum":     true,
+		"extern":   true,
+		"f32":      true,
+		"f64":      true,
+		"false":    true,
+		"fn":       true,
+		"for":      true,
+		"i8":       true,
+		"i16":      true,
+		"i32":      true,
+		"i64":      true,
+		"i128":     true,
+		"if":       true,
+		"impl":     true,
+		"in":       true,
+		"isize":    true,
+		"let":      true,
+		"loop":     true,
+		"match":    true,
+		"mod":      true,
+		"move":     true,
+		"mut":      true,
+		"pub":      true,
+		"ref":      true,
+		"return":   true,
+		"self":     true,
+		"Self":     true,
+		"static":   true,
+		"str":      true,
+		"struct":   true,
+		"super":    true,
+		"trait":    true,
+		"true":     true,
+		"type":     true,
+		"u8":       true,
+		"u16":      true,
+		"u32":      true,
+		"u64":      true,
+		"u128":     true,
+		"union":    true,
+		"unsafe":   true,
+		"use":      true,
+		"usize":    true,
+		"where":    true,
+		"while":    true,
+		"yield":    true,
+	}
+
+	// Regular expressions for Rust tokens. Numbers may contain underscores
+	// and end with a type suffix, as in 1_000u32 or 2.5e3_f64.
+	rustNumberRegex    = regexp.MustCompile(`^(0x[0-9a-fA-F_]+|0o[0-7_]+|0b[01_]+|[0-9][0-9_]*(\.[0-9][0-9_]*)?([eE][+-]?[0-9_]+)?)(_?([ui](8|16|32|64|128|size)|f32|f64))?`)
+	rustCharRegex      = regexp.MustCompile(`^b?'(\\(x[0-9a-fA-F]{2}|u\{[0-9a-fA-F_]{1,6}\}|.)|[^'\\\n])'`)
+	rustLifetimeRegex  = regexp.MustCompile(`^'[a-zA-Z_][a-zA-Z0-9_]*`)
+	rustRawStringRegex = regexp.MustCompile(`^[bc]?r(#*)"`)
+	rustRawIdentRegex  = regexp.MustCompile(`^r#[a-zA-Z_][a-zA-Z0-9_]*`)
+)
+
+// rustOpen identifies a construct that was still open at the end of a line
+type rustOpen int
+
+const (
+	rustOpenNone rustOpen = iota
+	rustOpenComment
+	rustOpenString
+	rustOpenRawString
+)
+
+// rustState is the state carried between lines while parsing Rust: block
+// comments and strings, including raw strings, may span lines
+type rustState struct {
+	open rustOpen
+	// depth is the nesting depth of an open block comment
+	depth int
+	// hashes is the number of # around an open raw string
+	hashes int
+}
+
+// RustParser implements the Parser and LineParser interfaces for Rust code
+type RustParser struct{}
+
+// Parse implements the Parser interface for Rust
+func (p *RustParser) Parse(code string) (TokenSequence, error) {
+	return ParseRust(code)
+}
+
+// ParseLine implements the LineParser interface for Rust, so block comments
+// and strings spanning lines are recognised when a block is highlighted a line
+// at a time
+func (p *RustParser) ParseLine(line string, state LineState) (TokenSequence, LineState, error) {
+	current, _ := state.(rustState)
+	tokens := parseRust(line, &current)
+	return tokens, current, nil
+}
+
+// ParseRust parses Rust code and returns a sequence of tokens
+func ParseRust(code string) (TokenSequence, error) {
+	return parseRust(code, &rustState{}), nil
+}
+
+// findRustCommentEnd returns the length of the block comment text at the
+// start of code, which is inside a comment nested depth deep, and the depth
+// at its end. Block comments nest in Rust. The depth is zero if the comment
+// is closed.
+func findRustCommentEnd(code string, depth int) (int, int) {
+	for i := 0; i+1 < len(code); i++ {
+		switch code[i : i+2] {
+		case "/*":
+			depth++
+			i++
+		case "*/":
+			depth--
+			i++
+			if depth == 0 {
+				return i + 1, 0
+			}
+		}
+	}
+	return len(code), depth
+}
+
+// findRustStringEnd returns the length of the string text at the start of
+// code, up to and including the closing quote, or -1 if the string isn't
+// closed
+func findRustStringEnd(code string) int {
+	for i := 0; i < len(code); i++ {
+		switch code[i] {
+		case '\\':
+			i++
+		case '"':
+			return i + 1
+		}
+	}
+	return -1
+}
+
+// findRustRawStringEnd returns the length of the raw string text at the start
+// of code, up to and including the quote and hashes closing it, or -1 if the
+// string isn't closed
+func findRustRawStringEnd(code string, hashes int) int {
+	terminator := `"` + strings.Repeat("#", hashes)
+	if end := strings.Index(code, terminator); end >= 0 {
+		return end + len(terminator)
+	}
+	return -1
+}
+
+// rustStringStart returns the length of the opening of a string at the start
+// of code: a quote, with an optional b or c prefix. It is zero if code doesn't
+// start with a string.
+func rustStringStart(code string) int {
+	switch {
+	case strings.HasPrefix(code, `"`):
+		return 1
+	case strings.HasPrefix(code, `b"`), strings.HasPrefix(code, `c"`):
+		return 2
+	default:
+		return 0
+	}
+}
+
+// findRustAttributeEnd returns the length of the attribute at the start of
+// code ("#[" or "#!["), or -1 if its brackets aren't closed. Strings inside
+// the attribute are skipped.
+func findRustAttributeEnd(code string) int {
+	depth := 0
+	for i := 1; i < len(code); i++ {
+		switch code[i] {
+		case '"':
+			end := findRustStringEnd(code[i+1:])
+			if end < 0 {
+				return -1
+			}
+			i += end
+		case '[':
+			depth++
+		case ']':
+			depth--
+			if depth == 0 {
+				return i + 1
+			}
+		}
+	}
+	return -1
+}
+
+// continueRust returns the type and length of the text at the start of code
+// belonging to a comment or string left open by state, and updates state if
+// it is closed
+func continueRust(code string, state *rustState) (TokenType, int) {
+	if state.open == rustOpenComment {
+		n, depth := findRustCommentEnd(code, state.depth)
+		state.depth = depth
+		if depth == 0 {
+			state.open = rustOpenNone
+		}
+		return TokenComment, n
+	}
+
+	n := findRustStringEnd(code)
+	if state.open == rustOpenRawString {
+		n = findRustRawStringEnd(code, state.hashes)
+	}
+	if n < 0 {
+		return TokenLiteral, len(code)
+	}
+	state.open = rustOpenNone
+	return TokenLiteral, n
+}
+
+// openRust starts the comment or string opened by the first length bytes of
+// code, returning a token running to its end, or to the end of code if it
+// isn't closed
+func openRust(code string, length int, state *rustState) Token {
+	tokenType, n := continueRust(code[length:], state)
+	return Token{Type: tokenType, Text: code[:length+n]}
+}
+
+// parseRust tokenizes Rust code, starting from and updating the given state
+func parseRust(code string, state *rustState) TokenSequence {
+	tokens := TokenSequence{}
+
+	for len(code) > 0 {
+		// Finish a comment or string started on an earlier line
+		if state.open != rustOpenNone {
+			tokenType, n := continueRust(code, state)
+			tokens = append(tokens, Token{Type: tokenType, Text: code[:n]})
+			code = code[n:]
+			continue
+		}
+
+		if n := scanWhitespace(code); n > 0 {
+			tokens = append(tokens, Token{Type: TokenWhitespace, Text: code[:n]})
+			code = code[n:]
+			continue
+		}
+
+		// Comments, including doc comments
+		if strings.HasPrefix(code, "//") {
+			n := scanToLineEnd(code)
+			tokens = append(tokens, Token{Type: TokenComment, Text: code[:n]})
+			code = code[n:]
+			continue
+		}
+		if strings.HasPrefix(code, "/*") {
+			state.open, state.depth = rustOpenComment, 1
+			token := openRust(code, 2, state)
+			tokens = append(tokens, token)
+			code = code[len(token.Text):]
+			continue
+		}
+
+		// Attributes such as #[derive(Debug)] and #![allow(dead_code)]
+		if strings.HasPrefix(code, "#[") || strings.HasPrefix(code, "#![") {
+			if end := findRustAttributeEnd(code); end > 0 {
+				tokens = append(tokens, Token{Type: TokenKeyword, Text: code[:end]})
+				code = code[end:]
+				continue
+			}
+		}
+
+		// Raw strings such as r"C:\path" and r#"say "hi""#, with an optional
+		// byte or C string prefix
+		if match := scanRegex(code, "bcr", rustRawStringRegex); match != "" {
+			state.open, state.hashes = rustOpenRawString, strings.Count(match, "#")
+			token := openRust(code, len(match), state)
+			tokens = append(tokens, token)
+			code = code[len(token.Text):]
+			continue
+		}
+
+		// Raw identifiers such as r#type are identifiers, not keywords
+		if match := scanRegex(code, "r", rustRawIdentRegex); match != "" {
+			tokens = append(tokens, Token{Type: TokenIdentifier, Text: match})
+			code = code[len(match):]
+			continue
+		}
+
+		// Strings, with an optional byte or C string prefix
+		if n := rustStringStart(code); n > 0 {
+			state.open = rustOpenString
+			token := openRust(code, n, state)
+			tokens = append(tokens, token)
+			code = code[len(token.Text):]
+			continue
+		}
+
+		// Characters and bytes such as 'a', '\n' and b'\x7f', then lifetimes
+		// such as 'a and 'static, which share their opening quote
+		if match := scanRegex(code, "b'", rustCharRegex); match != "" {
+			tokens = append(tokens, Token{Type: TokenLiteral, Text: match})
+			code = code[len(match):]
+			continue
+		}
+		if match := scanRegex(code, "'", rustLifetimeRegex); match != "" {
+			tokens = append(tokens, Token{Type: TokenIdentifier, Text: match})
+			code = code[len(match):]
+			continue
+		}
+
+		if match := scanRegex(code, digits, rustNumberRegex); match != "" {
+			tokens = append(tokens, Token{Type: TokenLiteral, Text: match})
+			code = code[len(match):]
+			continue
+		}
+
+		if n := scanIdentifier(code, "", ""); n > 0 {
+			match := code[:n]
+			if rustKeywords[match] {
+				tokens = append(tokens, Token{Type: TokenKeyword, Text: match})
+			} else {
+				tokens = append(tokens, Token{Type: TokenIdentifier, Text: match})
+			}
+			code = code[n:]
+			continue
+		}
+
+		// Anything else is an operator or punctuation
+		n := scanChar(code)
+		tokens = append(tokens, Token{Type: TokenOther, Text: code[:n]})
+		code = code[n:]
+	}
+
+	return tokens
+}
@@ -0,0 +1,167 @@
+package parsing
+
+import (
+	"testing"
+)
+
+func TestRustParser(t *testing.T) {
+	testCases := []struct {
+		name     string
+		input    string
+		expected []Token
+	}{
+		{
+			name:  "Lifetimes and characters",
+			input: "fn f<'a>(c: char) -> bool { c == 'a' || c == '\\'' }",
+			expected: []Token{
+				{Type: TokenKeyword, Text: "fn"},
+				{Type: TokenWhitespace, Text: " "},
+				{Type: TokenIdentifier, Text: "f"},
+				{Type: TokenOther, Text: "<"},
+				{Type: TokenIdentifier, Text: "'a"},
+				{Type: TokenOther, Text: ">"},
+				{Type: TokenOther, Text: "("},
+				{Type: TokenIdentifier, Text: "c"},
+				{Type: TokenOther, Text: ":"},
+				{Type: TokenWhitespace, Text: " "},
+				{Type: TokenKeyword, Text: "char"},
+				{Type: TokenOther, Text: ")"},
+				{Type: TokenWhitespace, Text: " "},
+				{Type: TokenOther, Text: "-"},
+				{Type: TokenOther, Text: ">"},
+				{Type: TokenWhitespace, Text: " "},
+				{Type: TokenKeyword, Text: "bool"},
+				{Type: TokenWhitespace, Text: " "},
+				{Type: TokenOther, Text: "{"},
+				{Type: TokenWhitespace, Text: " "},
+				{Type: TokenIdentifier, Text: "c"},
+				{Type: TokenWhitespace, Text: " "},
+				{Type: TokenOther, Text: "="},
+				{Type: TokenOther, Text: "="},
+				{Type: TokenWhitespace, Text: " "},
+				{Type: TokenLiteral, Text: "'a'"},
+				{Type: TokenWhitespace, Text: " "},
+				{Type: TokenOther, Text: "|"},
+				{Type: TokenOther, Text: "|"},
+				{Type: TokenWhitespace, Text: " "},
+				{Type: TokenIdentifier, Text: "c"},
+				{Type: TokenWhitespace, Text: " "},
+				{Type: TokenOther, Text: "="},
+				{Type: TokenOther, Text: "="},
+				{Type: TokenWhitespace, Text: " "},
+				{Type: TokenLiteral, Text: "'\\''"},
+				{Type: TokenWhitespace, Text: " "},
+				{Type: TokenOther, Text: "}"},
+			},
+		},
+		{
+			name:  "Raw strings",
+			input: `let re = r#"say "hi""#; let b = br"\d";`,
+			expected: []Token{
+				{Type: TokenKeyword, Text: "let"},
+				{Type: TokenWhitespace, Text: " "},
+				{Type: TokenIdentifier, Text: "re"},
+				{Type: TokenWhitespace, Text: " "},
+				{Type: TokenOther, Text: "="},
+				{Type: TokenWhitespace, Text: " "},
+				{Type: TokenLiteral, Text: `r#"say "hi""#`},
+				{Type: TokenOther, Text: ";"},
+				{Type: TokenWhitespace, Text: " "},
+				{Type: TokenKeyword, Text: "let"},
+				{Type: TokenWhitespace, Text: " "},
+				{Type: TokenIdentifier, Text: "b"},
+				{Type: TokenWhitespace, Text: " "},
+				{Type: TokenOther, Text: "="},
+				{Type: TokenWhitespace, Text: " "},
+				{Type: TokenLiteral, Text: `br"\d"`},
+				{Type: TokenOther, Text: ";"},
+			},
+		},
+		{
+			name:  "Attributes",
+			input: "#![allow(dead_code)]\n#[cfg(feature = \"a]\")]",
+			expected: []Token{
+				{Type: TokenKeyword, Text: "#![allow(dead_code)]"},
+				{Type: TokenWhitespace, Text: "\n"},
+				{Type: TokenKeyword, Text: "#[cfg(feature = \"a]\")]"},
+			},
+		},
+		{
+			name:  "Numeric suffixes",
+			input: "1_000u32 0xffu8 2.5e3_f64 0..10",
+			expected: []Token{
+				{Type: TokenLiteral, Text: "1_000u32"},
+				{Type: TokenWhitespace, Text: " "},
+				{Type: TokenLiteral, Text: "0xffu8"},
+				{Type: TokenWhitespace, Text: " "},
+				{Type: TokenLiteral, Text: "2.5e3_f64"},
+				{Type: TokenWhitespace, Text: " "},
+				{Type: TokenLiteral, Text: "0"},
+				{Type: TokenOther, Text: "."},
+				{Type: TokenOther, Text: "."},
+				{Type: TokenLiteral, Text: "10"},
+			},
+		},
+		{
+			name:  "Nested block comment and raw identifier",
+			input: "/* a /* b */ c */ r#type",
+			expected: []Token{
+				{Type: TokenComment, Text: "/* a /* b */ c */"},
+				{Type: TokenWhitespace, Text: " "},
+				{Type: TokenIdentifier, Text: "r#type"},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			tokens, err := ParseRust(tc.input)
+			if err != nil {
+				t.Fatalf("Error parsing Rust code: %v", err)
+			}
+			if len(tokens) != len(tc.expected) {
+				t.Fatalf("Expected %d tokens, got %d. Tokens: %v", len(tc.expected), len(tokens), tokens)
+			}
+			for i := range tokens {
+				if tokens[i] != tc.expected[i] {
+					t.Errorf("Token %d = %+v, want %+v", i, tokens[i], tc.expected[i])
+				}
+			}
+		})
+	}
+}
+
+func TestRustParseLine(t *testing.T) {
+	parser := &RustParser{}
+	lines := []string{
+		`let s = r#"first`,
+		`  "quoted" line`,
+		`last"#; /* open`,
+		`comment */ x`,
+	}
+	expected := []Token{
+		{Type: TokenLiteral, Text: `  "quoted" line`},
+		{Type: TokenLiteral, Text: `last"#`},
+		{Type: TokenComment, Text: "comment */"},
+	}
+
+	var state LineState
+	var firstTokens []TokenSequence
+	for _, line := range lines {
+		tokens, next, err := parser.ParseLine(line, state)
+		if err != nil {
+			t.Fatalf("ParseLine(%q) error: %v", line, err)
+		}
+		firstTokens = append(firstTokens, tokens)
+		state = next
+	}
+
+	for i, want := range expected {
+		if got := firstTokens[i+1][0]; got != want {
+			t.Errorf("First token of line %d = %+v, want %+v", i+2, got, want)
+		}
+	}
+	if last := firstTokens[3][len(firstTokens[3])-1]; last.Type != TokenIdentifier {
+		t.Errorf("Code after the comment = %+v, want an identifier", last)
+	}
+}
@@ -0,0 +1,86 @@
+package parsing
+
+import (
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// digits is the set of characters a number can start with
+const digits = "0123456789"
+
+// These helpers replace anchored regular expressions in the hot paths of the
+// parsers. Each returns the length in bytes of the match at the start of code,
+// or zero if there is no match.
+
+// scanWhitespace matches a run of spaces, tabs, carriage returns and newlines
+func scanWhitespace(code string) int {
+	i := 0
+	for i < len(code) {
+		switch code[i] {
+		case ' ', '\t', '\r', '\n':
+			i++
+		default:
+			return i
+		}
+	}
+	return i
+}
+
+// scanIdentifier matches an ASCII identifier: a letter, underscore or one of
+// extraStart, followed by letters, digits, underscores or any of extra
+func scanIdentifier(code string, extraStart string, extra string) int {
+	if len(code) == 0 {
+		return 0
+	}
+
+	c := code[0]
+	if !isLetter(c) && c != '_' && strings.IndexByte(extraStart, c) < 0 {
+		return 0
+	}
+
+	i := 1
+	for i < len(code) {
+		c = code[i]
+		if !isLetter(c) && !isDigit(c) && c != '_' && strings.IndexByte(extra, c) < 0 {
+			break
+		}
+		i++
+	}
+	return i
+}
+
+// scanToLineEnd matches everything up to, but not including, the next newline
+func scanToLineEnd(code string) int {
+	if end := strings.IndexByte(code, '\n'); end >= 0 {
+		return end
+	}
+	return len(code)
+}
+
+// scanChar matches a single character, which may be several bytes long.
+// Splitting multi-byte characters would corrupt them once colour codes are
+// inserted between the bytes.
+func scanChar(code string) int {
+	_, size := utf8.DecodeRuneInString(code)
+	return size
+}
+
+// scanRegex returns the match of an anchored regular expression at the start
+// of code, only running it if code starts with one of the bytes in first
+func scanRegex(code string, first string, re *regexp.Regexp) string {
+	if len(code) == 0 || strings.IndexByte(first, code[0]) < 0 {
+		return ""
+	}
+	return re.FindString(code)
+}
+
+// isLetter reports whether c is an ASCII letter
+func isLetter(c byte) bool {
+	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+// isDigit reports whether c is an ASCII digit
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
@@ -1,5 +1,9 @@
 package parsing
 
+import (
+	"strings"
+)
+
 // TokenType represents the type of a token
 type TokenType int
 
@@ -16,6 +20,12 @@ const (
 	TokenComment
 	// TokenWhitespace represents spaces, tabs, newlines
 	TokenWhitespace
+	// TokenInserted represents lines added by a diff
+	TokenInserted
+	// TokenDeleted represents lines removed by a diff
+	TokenDeleted
+	// TokenHeading represents section headings, such as a diff's hunk headers
+	TokenHeading
 )
 
 // Token represents a single token in the parsed code
@@ -24,7 +34,41 @@ type Token struct {
 	Type TokenType
 	// Text is the actual text content of the token
 	Text string
+	// Line is the 1-based line the token starts on, or 0 if positions
+	// haven't been computed. Parsers leave positions unset; use
+	// TokenSequence.WithPositions or Tokenize to fill them in.
+	Line int
+	// Column is the 1-based byte offset of the token within its line, or 0 if
+	// positions haven't been computed
+	Column int
 }
 
 // TokenSequence is a sequence of tokens that represents parsed code
 type TokenSequence []Token
+
+// WithPositions returns a copy of the sequence with the Line and Column of
+// each token set, assuming the first token starts at line 1, column 1
+func (s TokenSequence) WithPositions() TokenSequence {
+	positioned := make(TokenSequence, len(s))
+	line, column := 1, 1
+	for i, token := range s {
+		token.Line, token.Column = line, column
+		positioned[i] = token
+		line, column = advancePosition(line, column, token.Text)
+	}
+	return positioned
+}
+
+// advancePosition returns the position following text, which starts at the
+// given line and column
+func advancePosition(line int, column int, text string) (int, int) {
+	for {
+		newline := strings.IndexByte(text, '\n')
+		if newline < 0 {
+			return line, column + len(text)
+		}
+		line++
+		column = 1
+		text = text[newline+1:]
+	}
+}
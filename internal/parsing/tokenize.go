@@ -0,0 +1,146 @@
+package parsing
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// TokenStream reads code from a reader and returns its tokens one at a time,
+// with positions set. Create one with Tokenize.
+type TokenStream struct {
+	reader  *bufio.Reader
+	parser  Parser
+	state   LineState
+	pending TokenSequence
+	line    int
+	err     error
+}
+
+// Tokenize returns a stream of the tokens in the code read from r, parsed as
+// the given language. The code is parsed a line at a time as it is read, the
+// same way the pretty printer highlights streamed output, so the reader may
+// be a pipe that is still being written to. Languages without a parser
+// produce a single TokenOther token per line.
+func Tokenize(r io.Reader, language string) *TokenStream {
+	parser := GetParser(language)
+	if parser == nil {
+		parser = GetChromaParser(language)
+	}
+
+	return &TokenStream{
+		reader: bufio.NewReader(r),
+		parser: parser,
+	}
+}
+
+// Next returns the next token. It returns io.EOF once every token has been
+// returned, or any error encountered reading or parsing the code.
+func (s *TokenStream) Next() (Token, error) {
+	for len(s.pending) == 0 {
+		if s.err != nil {
+			return Token{}, s.err
+		}
+		if ParsesWholeBlocks(s.parser) {
+			s.readAll()
+		} else {
+			s.readLine()
+		}
+	}
+
+	token := s.pending[0]
+	s.pending = s.pending[1:]
+	return token, nil
+}
+
+// All reads the rest of the stream, returning the tokens read
+func (s *TokenStream) All() (TokenSequence, error) {
+	var tokens TokenSequence
+	for {
+		token, err := s.Next()
+		if err == io.EOF {
+			return tokens, nil
+		}
+		if err != nil {
+			return tokens, err
+		}
+		tokens = append(tokens, token)
+	}
+}
+
+// readLine parses the next line of input into s.pending, setting s.err at
+// the end of input or on failure
+func (s *TokenStream) readLine() {
+	text, err := s.reader.ReadString('\n')
+	if err != nil && err != io.EOF {
+		s.err = err
+		return
+	}
+	if text == "" {
+		s.err = io.EOF
+		return
+	}
+	s.line++
+
+	line := strings.TrimSuffix(text, "\n")
+	tokens, parseErr := s.parseLine(line)
+	if parseErr != nil {
+		s.err = fmt.Errorf("error parsing line %d: %v", s.line, parseErr)
+		return
+	}
+	if len(line) < len(text) {
+		tokens = append(tokens, Token{Type: TokenWhitespace, Text: "\n"})
+	}
+
+	column := 1
+	for i := range tokens {
+		tokens[i].Line = s.line
+		tokens[i].Column = column
+		column += len(tokens[i].Text)
+	}
+	s.pending = tokens
+
+	if err == io.EOF {
+		s.err = io.EOF
+	}
+}
+
+// readAll parses the rest of the input at once into s.pending, for parsers
+// that take whole blocks, setting s.err at the end of input or on failure
+func (s *TokenStream) readAll() {
+	data, err := io.ReadAll(s.reader)
+	if err != nil {
+		s.err = err
+		return
+	}
+	s.err = io.EOF
+	if len(data) == 0 {
+		return
+	}
+
+	tokens, err := s.parser.Parse(string(data))
+	if err != nil {
+		s.err = fmt.Errorf("error parsing: %v", err)
+		return
+	}
+	s.pending = tokens.WithPositions()
+}
+
+// parseLine tokenizes one line, without its line terminator
+func (s *TokenStream) parseLine(line string) (TokenSequence, error) {
+	if line == "" {
+		return nil, nil
+	}
+
+	switch parser := s.parser.(type) {
+	case nil:
+		return TokenSequence{{Type: TokenOther, Text: line}}, nil
+	case LineParser:
+		tokens, state, err := parser.ParseLine(line, s.state)
+		s.state = state
+		return tokens, err
+	default:
+		return parser.Parse(line)
+	}
+}
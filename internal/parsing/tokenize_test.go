@@ -0,0 +1,95 @@
+package parsing
+
+import (
+	"io"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestTokenize(t *testing.T) {
+	tokens, err := Tokenize(strings.NewReader("cat <<EOF\n  $x\nEOF\n"), "sh").All()
+	if err != nil {
+		t.Fatalf("Tokenize() error: %v", err)
+	}
+
+	expected := []Token{
+		{Type: TokenIdentifier, Text: "cat", Line: 1, Column: 1},
+		{Type: TokenWhitespace, Text: " ", Line: 1, Column: 4},
+		{Type: TokenOther, Text: "<<EOF", Line: 1, Column: 5},
+		{Type: TokenWhitespace, Text: "\n", Line: 1, Column: 10},
+		{Type: TokenLiteral, Text: "  $x", Line: 2, Column: 1},
+		{Type: TokenWhitespace, Text: "\n", Line: 2, Column: 5},
+		{Type: TokenOther, Text: "EOF", Line: 3, Column: 1},
+		{Type: TokenWhitespace, Text: "\n", Line: 3, Column: 4},
+	}
+
+	if len(tokens) != len(expected) {
+		t.Fatalf("Expected %d tokens, got %d. Tokens: %v", len(expected), len(tokens), tokens)
+	}
+	for i := range tokens {
+		if tokens[i] != expected[i] {
+			t.Errorf("Token %d = %+v, want %+v", i, tokens[i], expected[i])
+		}
+	}
+}
+
+func TestTokenizeUnknownLanguage(t *testing.T) {
+	stream := Tokenize(strings.NewReader("some text\n\nmore"), "no-such-language")
+
+	var texts []string
+	for {
+		token, err := stream.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("Next() error: %v", err)
+		}
+		texts = append(texts, token.Text)
+	}
+
+	if got := strings.Join(texts, ""); got != "some text\n\nmore" {
+		t.Errorf("Tokens reproduce %q, want %q", got, "some text\n\nmore")
+	}
+}
+
+func TestWithPositions(t *testing.T) {
+	tokens, err := ParsePython("x = 1\n# done\ny")
+	if err != nil {
+		t.Fatalf("Error parsing python code: %v", err)
+	}
+
+	positioned := tokens.WithPositions()
+	last := positioned[len(positioned)-1]
+	if last.Text != "y" || last.Line != 3 || last.Column != 1 {
+		t.Errorf("Last token = %+v, want y at 3:1", last)
+	}
+	comment := positioned[len(positioned)-3]
+	if comment.Type != TokenComment || comment.Line != 2 || comment.Column != 1 {
+		t.Errorf("Comment token = %+v, want comment at 2:1", comment)
+	}
+	if tokens[0].Line != 0 {
+		t.Errorf("WithPositions() modified the original sequence")
+	}
+}
+
+func TestTokenizeWholeBlocks(t *testing.T) {
+	os.Setenv("AIPIPE_TEST_PLUGIN", "1")
+	defer os.Unsetenv("AIPIPE_TEST_PLUGIN")
+
+	RegisterParser("plugintest", &ExternalParser{Command: os.Args[0], Args: []string{"-test.run=TestHelperPlugin"}})
+	defer delete(registeredParsers, "plugintest")
+
+	// The plugin sees both lines at once, so its one token spans them
+	tokens, err := Tokenize(strings.NewReader("fn a\nfn b"), "plugintest").All()
+	if err != nil {
+		t.Fatalf("Tokenize() error: %v", err)
+	}
+	if len(tokens) != 5 || tokens[2].Text != "a\nfn" {
+		t.Fatalf("Tokenize() = %+v, want the plugin's tokens for the whole input", tokens)
+	}
+	if last := tokens[4]; last.Text != "b" || last.Line != 2 || last.Column != 4 {
+		t.Errorf("Last token = %+v, want b at 2:4", last)
+	}
+}
@@ -0,0 +1,205 @@
+//go:build treesitter
+
+package parsing
+
+import (
+	"context"
+	"strings"
+
+	sitter "github.com/smacker/go-tree-sitter"
+	"github.com/smacker/go-tree-sitter/bash"
+	"github.com/smacker/go-tree-sitter/c"
+	"github.com/smacker/go-tree-sitter/cpp"
+	"github.com/smacker/go-tree-sitter/csharp"
+	"github.com/smacker/go-tree-sitter/css"
+	"github.com/smacker/go-tree-sitter/dockerfile"
+	"github.com/smacker/go-tree-sitter/elixir"
+	"github.com/smacker/go-tree-sitter/golang"
+	"github.com/smacker/go-tree-sitter/hcl"
+	"github.com/smacker/go-tree-sitter/html"
+	"github.com/smacker/go-tree-sitter/java"
+	"github.com/smacker/go-tree-sitter/javascript"
+	"github.com/smacker/go-tree-sitter/kotlin"
+	"github.com/smacker/go-tree-sitter/lua"
+	"github.com/smacker/go-tree-sitter/php"
+	"github.com/smacker/go-tree-sitter/python"
+	"github.com/smacker/go-tree-sitter/ruby"
+	"github.com/smacker/go-tree-sitter/rust"
+	"github.com/smacker/go-tree-sitter/scala"
+	"github.com/smacker/go-tree-sitter/sql"
+	"github.com/smacker/go-tree-sitter/swift"
+	"github.com/smacker/go-tree-sitter/toml"
+	"github.com/smacker/go-tree-sitter/typescript/tsx"
+	"github.com/smacker/go-tree-sitter/typescript/typescript"
+	"github.com/smacker/go-tree-sitter/yaml"
+)
+
+// treeSitterAvailable reports whether tree-sitter support was compiled in
+const treeSitterAvailable = true
+
+// treeSitterLanguages maps canonical language names to tree-sitter grammars
+var treeSitterLanguages = map[string]func() *sitter.Language{
+	"bash":       bash.GetLanguage,
+	"c":          c.GetLanguage,
+	"cpp":        cpp.GetLanguage,
+	"csharp":     csharp.GetLanguage,
+	"css":        css.GetLanguage,
+	"dockerfile": dockerfile.GetLanguage,
+	"elixir":     elixir.GetLanguage,
+	"go":         golang.GetLanguage,
+	"hcl":        hcl.GetLanguage,
+	"html":       html.GetLanguage,
+	"java":       java.GetLanguage,
+	"javascript": javascript.GetLanguage,
+	"kotlin":     kotlin.GetLanguage,
+	"lua":        lua.GetLanguage,
+	"php":        php.GetLanguage,
+	"python":     python.GetLanguage,
+	"ruby":       ruby.GetLanguage,
+	"rust":       rust.GetLanguage,
+	"scala":      scala.GetLanguage,
+	"sql":        sql.GetLanguage,
+	"swift":      swift.GetLanguage,
+	"toml":       toml.GetLanguage,
+	"tsx":        tsx.GetLanguage,
+	"typescript": typescript.GetLanguage,
+	"yaml":       yaml.GetLanguage,
+}
+
+// getTreeSitterParser returns a tree-sitter parser for the language, or nil if
+// there is no grammar for it
+func getTreeSitterParser(language string) Parser {
+	grammar, ok := treeSitterLanguages[language]
+	if !ok {
+		return nil
+	}
+	return &TreeSitterParser{language: grammar()}
+}
+
+// TreeSitterParser implements the Parser interface using a tree-sitter grammar
+type TreeSitterParser struct {
+	language *sitter.Language
+}
+
+// ParsesWholeBlocks is true, since a grammar can only tell where a string,
+// comment or heredoc that spans lines ends once it has the whole block
+func (p *TreeSitterParser) ParsesWholeBlocks() bool {
+	return true
+}
+
+// Parse parses code with tree-sitter and flattens the syntax tree into tokens
+func (p *TreeSitterParser) Parse(code string) (TokenSequence, error) {
+	parser := sitter.NewParser()
+	defer parser.Close()
+	parser.SetLanguage(p.language)
+
+	source := []byte(code)
+	tree, err := parser.ParseCtx(context.Background(), nil, source)
+	if err != nil {
+		return nil, err
+	}
+	defer tree.Close()
+
+	var tokens TokenSequence
+	offset := 0
+
+	var visit func(node *sitter.Node)
+	visit = func(node *sitter.Node) {
+		tokenType, isLeaf := classifyTreeSitterNode(node)
+		if !isLeaf {
+			for i := 0; i < int(node.ChildCount()); i++ {
+				visit(node.Child(i))
+			}
+			return
+		}
+
+		start, end := int(node.StartByte()), int(node.EndByte())
+		if start < offset || end <= start {
+			return
+		}
+
+		// Anything between tokens is whitespace (or text the grammar skipped)
+		if start > offset {
+			tokens = append(tokens, gapTokens(code[offset:start])...)
+		}
+		tokens = append(tokens, Token{Type: tokenType, Text: code[start:end]})
+		offset = end
+	}
+	visit(tree.RootNode())
+
+	if offset < len(code) {
+		tokens = append(tokens, gapTokens(code[offset:])...)
+	}
+
+	return tokens, nil
+}
+
+// classifyTreeSitterNode returns the token type for a node and whether it
+// should be emitted as a single token rather than descended into
+func classifyTreeSitterNode(node *sitter.Node) (TokenType, bool) {
+	nodeType := node.Type()
+
+	switch {
+	case strings.Contains(nodeType, "comment"):
+		return TokenComment, true
+	case strings.Contains(nodeType, "string") || strings.Contains(nodeType, "char") ||
+		strings.Contains(nodeType, "heredoc"):
+		return TokenLiteral, true
+	case node.ChildCount() > 0:
+		return TokenOther, false
+	case strings.Contains(nodeType, "identifier") || nodeType == "variable_name" ||
+		nodeType == "type" || nodeType == "attribute_name" || nodeType == "tag_name":
+		return TokenIdentifier, true
+	case strings.Contains(nodeType, "number") || strings.Contains(nodeType, "integer") ||
+		strings.Contains(nodeType, "float") || strings.Contains(nodeType, "literal") ||
+		nodeType == "true" || nodeType == "false" || nodeType == "null" || nodeType == "none" || nodeType == "nil":
+		return TokenLiteral, true
+	case !node.IsNamed() && isWord(nodeType):
+		// Anonymous nodes spelled like words are the grammar's keywords
+		return TokenKeyword, true
+	case node.IsNamed() && isWord(nodeType):
+		return TokenIdentifier, true
+	default:
+		return TokenOther, true
+	}
+}
+
+// isWord reports whether s consists only of letters and underscores
+func isWord(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, r := range s {
+		if !(r == '_' || (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z')) {
+			return false
+		}
+	}
+	return true
+}
+
+// gapTokens converts text between syntax nodes into tokens, keeping whitespace
+// separate from anything else the grammar didn't cover
+func gapTokens(text string) TokenSequence {
+	var tokens TokenSequence
+	for len(text) > 0 {
+		end := strings.IndexFunc(text, func(r rune) bool {
+			return !(r == ' ' || r == '\t' || r == '\r' || r == '\n')
+		})
+		if end == -1 {
+			end = len(text)
+		}
+		if end > 0 {
+			tokens = append(tokens, Token{Type: TokenWhitespace, Text: text[:end]})
+			text = text[end:]
+			continue
+		}
+
+		end = strings.IndexAny(text, " \t\r\n")
+		if end == -1 {
+			end = len(text)
+		}
+		tokens = append(tokens, Token{Type: TokenOther, Text: text[:end]})
+		text = text[end:]
+	}
+	return tokens
+}
@@ -0,0 +1,11 @@
+//go:build !treesitter
+
+package parsing
+
+// treeSitterAvailable reports whether tree-sitter support was compiled in
+const treeSitterAvailable = false
+
+// getTreeSitterParser always returns nil when tree-sitter support is not compiled in
+func getTreeSitterParser(language string) Parser {
+	return nil
+}
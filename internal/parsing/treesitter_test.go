@@ -0,0 +1,127 @@
+//go:build treesitter
+
+package parsing
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestTreeSitterParser(t *testing.T) {
+	testCases := []struct {
+		name     string
+		language string
+		input    string
+		keywords []string
+		literals []string
+		comments []string
+	}{
+		{
+			name:     "Go function",
+			language: "go",
+			input:    "func main() {\n\t// say hi\n\tfmt.Println(\"hi\", 42)\n}\n",
+			keywords: []string{"func"},
+			literals: []string{"\"hi\"", "42"},
+			comments: []string{"// say hi"},
+		},
+		{
+			name:     "Python function",
+			language: "python",
+			input:    "def f(x):\n    return x + 1  # add one\n",
+			keywords: []string{"def", "return"},
+			literals: []string{"1"},
+			comments: []string{"# add one"},
+		},
+		{
+			name:     "Rust raw string",
+			language: "rust",
+			input:    "let s = r#\"a \"quoted\" word\"#;",
+			keywords: []string{"let"},
+			literals: []string{"r#\"a \"quoted\" word\"#"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			parser := getTreeSitterParser(tc.language)
+			if parser == nil {
+				t.Fatalf("No tree-sitter grammar for %s", tc.language)
+			}
+
+			tokens, err := parser.Parse(tc.input)
+			if err != nil {
+				t.Fatalf("Error parsing %s: %v", tc.language, err)
+			}
+
+			// The tokens must reproduce the input exactly
+			var rebuilt strings.Builder
+			found := map[TokenType][]string{}
+			for _, token := range tokens {
+				rebuilt.WriteString(token.Text)
+				found[token.Type] = append(found[token.Type], token.Text)
+			}
+			if rebuilt.String() != tc.input {
+				t.Errorf("Tokens rebuild to %q, want %q", rebuilt.String(), tc.input)
+			}
+
+			check := func(kind string, tokenType TokenType, expected []string) {
+				for _, text := range expected {
+					if !containsText(found[tokenType], text) {
+						t.Errorf("Expected %s %q, found %v", kind, text, found[tokenType])
+					}
+				}
+			}
+			check("keyword", TokenKeyword, tc.keywords)
+			check("literal", TokenLiteral, tc.literals)
+			check("comment", TokenComment, tc.comments)
+		})
+	}
+}
+
+func TestSetBackendTreeSitter(t *testing.T) {
+	if err := SetBackend(BackendTreeSitter); err != nil {
+		t.Fatalf("SetBackend() error = %v", err)
+	}
+	defer SetBackend(BackendRegex)
+
+	if _, ok := GetParser("go").(*TreeSitterParser); !ok {
+		t.Error("Expected a tree-sitter parser for go")
+	}
+	if _, ok := GetParser("json").(*JSONParser); !ok {
+		t.Error("Expected the regex JSON parser as a fallback")
+	}
+}
+
+func TestTreeSitterMultiLineString(t *testing.T) {
+	if err := SetBackend(BackendTreeSitter); err != nil {
+		t.Fatalf("SetBackend() error = %v", err)
+	}
+	defer SetBackend(BackendRegex)
+
+	parser := GetParser("python")
+	if !ParsesWholeBlocks(parser) {
+		t.Fatal("Expected the tree-sitter parser to parse whole blocks")
+	}
+
+	input := "doc = \"\"\"first\nif not a keyword\nlast\"\"\"\nif x:\n    pass\n"
+	tokens, err := Tokenize(strings.NewReader(input), "python").All()
+	if err != nil {
+		t.Fatalf("Tokenize() error = %v", err)
+	}
+
+	var literal, keywords []string
+	for _, token := range tokens {
+		switch token.Type {
+		case TokenLiteral:
+			literal = append(literal, token.Text)
+		case TokenKeyword:
+			keywords = append(keywords, token.Text)
+		}
+	}
+	if !containsText(literal, "\"\"\"first\nif not a keyword\nlast\"\"\"") {
+		t.Errorf("Expected the string to be one literal, found %q", literal)
+	}
+	if len(keywords) != 2 || keywords[0] != "if" || keywords[1] != "pass" {
+		t.Errorf("Expected only the keywords after the string, found %q", keywords)
+	}
+}
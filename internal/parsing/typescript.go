@@ -81,11 +81,9 @@ var (
 	}
 
 	// Regular expressions for TypeScript/JavaScript tokens
-	typescriptNumberRegex     = regexp.MustCompile(`^(0[xX][0-9a-fA-F]+|0[oO][0-7]+|0[bB][01]+|[0-9]+(\.[0-9]+)?([eE][+-]?[0-9]+)?)`)
-	typescriptIdentifierRegex = regexp.MustCompile(`^[a-zA-Z_$][a-zA-Z0-9_$]*`)
-	typescriptCommentRegex    = regexp.MustCompile(`^(//.*|/\*[\s\S]*?\*/)`)
-	typescriptWhitespaceRegex = regexp.MustCompile(`^[ \t\r\n]+`)
-	typescriptTemplateRegex   = regexp.MustCompile("^`(?:\\\\.|[^`\\\\])*?`")
+	typescriptNumberRegex   = regexp.MustCompile(`^(0[xX][0-9a-fA-F]+|0[oO][0-7]+|0[bB][01]+|[0-9]+(\.[0-9]+)?([eE][+-]?[0-9]+)?)`)
+	typescriptCommentRegex  = regexp.MustCompile(`^(//.*|/\*[\s\S]*?\*/)`)
+	typescriptTemplateRegex = regexp.MustCompile("^`(?:\\\\.|[^`\\\\])*?`")
 )
 
 // isStringStart checks if the code starts with a string delimiter
@@ -123,21 +121,21 @@ func ParseTypeScript(code string) (TokenSequence, error) {
 
 	for len(code) > 0 {
 		// Try to match whitespace first to preserve indentation
-		if match := typescriptWhitespaceRegex.FindString(code); match != "" {
-			tokens = append(tokens, Token{Type: TokenWhitespace, Text: match})
-			code = code[len(match):]
+		if n := scanWhitespace(code); n > 0 {
+			tokens = append(tokens, Token{Type: TokenWhitespace, Text: code[:n]})
+			code = code[n:]
 			continue
 		}
 
 		// Try to match a comment
-		if match := typescriptCommentRegex.FindString(code); match != "" {
+		if match := scanRegex(code, "/", typescriptCommentRegex); match != "" {
 			tokens = append(tokens, Token{Type: TokenComment, Text: match})
 			code = code[len(match):]
 			continue
 		}
 
 		// Try to match a template string
-		if match := typescriptTemplateRegex.FindString(code); match != "" {
+		if match := scanRegex(code, "`", typescriptTemplateRegex); match != "" {
 			tokens = append(tokens, Token{Type: TokenLiteral, Text: match})
 			code = code[len(match):]
 			continue
@@ -154,14 +152,15 @@ func ParseTypeScript(code string) (TokenSequence, error) {
 		}
 
 		// Try to match a number
-		if match := typescriptNumberRegex.FindString(code); match != "" {
+		if match := scanRegex(code, digits, typescriptNumberRegex); match != "" {
 			tokens = append(tokens, Token{Type: TokenLiteral, Text: match})
 			code = code[len(match):]
 			continue
 		}
 
 		// Try to match an identifier or keyword
-		if match := typescriptIdentifierRegex.FindString(code); match != "" {
+		if n := scanIdentifier(code, "$", "$"); n > 0 {
+			match := code[:n]
 			if typescriptKeywords[match] {
 				tokens = append(tokens, Token{Type: TokenKeyword, Text: match})
 			} else {
@@ -172,8 +171,9 @@ func ParseTypeScript(code string) (TokenSequence, error) {
 		}
 
 		// If none of the above matched, it's an "other" token (operator, punctuation, etc.)
-		tokens = append(tokens, Token{Type: TokenOther, Text: string(code[0])})
-		code = code[1:]
+		n := scanChar(code)
+		tokens = append(tokens, Token{Type: TokenOther, Text: code[:n]})
+		code = code[n:]
 	}
 
 	return tokens, nil
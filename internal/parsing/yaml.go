@@ -0,0 +1,257 @@
+package parsing
+
+import (
+	"regexp"
+	"strings"
+)
+
+var (
+	// YAML keywords: the plain scalars that aren't strings. YAML 1.1 reads
+	// yes, no, on and off as booleans too, and most tools still do.
+	yamlKeywords = map[string]bool{
+		"true":  true,
+		"false": true,
+		"yes":   true,
+		"no":    true,
+		"on":    true,
+		"off":   true,
+		"null":  true,
+		"~":     true,
+	}
+
+	// Regular expressions for YAML tokens. Anchors, aliases and tags run to
+	// the next space or flow indicator.
+	yamlPropertyRegex    = regexp.MustCompile(`^[&*!][^\s,\[\]{}]*`)
+	yamlBlockScalarRegex = regexp.MustCompile(`^[|>][-+0-9]*`)
+)
+
+// yamlState is the state carried between lines while parsing YAML
+type yamlState struct {
+	// inBlockScalar is true while consuming the lines of a literal (|) or
+	// folded (>) block scalar, which are those indented more than
+	// blockIndent
+	inBlockScalar bool
+	blockIndent   int
+	// flowDepth counts the flow collections ({ or [) open
+	flowDepth int
+}
+
+// YAMLParser implements the Parser and LineParser interfaces for YAML
+type YAMLParser struct{}
+
+// Parse implements the Parser interface for YAML
+func (p *YAMLParser) Parse(code string) (TokenSequence, error) {
+	return ParseYAML(code)
+}
+
+// ParseLine implements the LineParser interface for YAML, so block scalars
+// are recognised when a block is highlighted a line at a time
+func (p *YAMLParser) ParseLine(line string, state LineState) (TokenSequence, LineState, error) {
+	current, _ := state.(yamlState)
+	tokens := parseYAMLLine(line, &current)
+	return tokens, current, nil
+}
+
+// ParseYAML parses YAML and returns a sequence of tokens
+func ParseYAML(code string) (TokenSequence, error) {
+	tokens := TokenSequence{}
+	state := yamlState{}
+	for i, line := range strings.Split(code, "\n") {
+		if i > 0 {
+			tokens = append(tokens, Token{Type: TokenWhitespace, Text: "\n"})
+		}
+		tokens = append(tokens, parseYAMLLine(line, &state)...)
+	}
+	return tokens, nil
+}
+
+// isYAMLBreak reports whether code is empty or starts with whitespace, which
+// must follow indicators such as "- " and ": "
+func isYAMLBreak(code string) bool {
+	return len(code) == 0 || code[0] == ' ' || code[0] == '\t' || code[0] == '\r'
+}
+
+// findYAMLQuotedEnd returns the length of the quoted scalar at the start of
+// code, or the length of code if it isn't closed on this line. Double-quoted
+// scalars escape with backslashes and single-quoted ones by doubling quotes.
+func findYAMLQuotedEnd(code string) int {
+	quote := code[0]
+	for i := 1; i < len(code); i++ {
+		switch {
+		case quote == '"' && code[i] == '\\':
+			i++
+		case code[i] == quote && quote == '\'' && i+1 < len(code) && code[i+1] == '\'':
+			i++
+		case code[i] == quote:
+			return i + 1
+		}
+	}
+	return len(code)
+}
+
+// findYAMLPlainEnd returns the length of the plain (unquoted) scalar at the
+// start of code. It ends before a comment, a ": " separating a key from its
+// value, or in a flow collection, a flow indicator.
+func findYAMLPlainEnd(code string, inFlow bool) int {
+	for i := 0; i < len(code); i++ {
+		switch code[i] {
+		case '#':
+			if i > 0 && (code[i-1] == ' ' || code[i-1] == '\t') {
+				return i
+			}
+		case ':':
+			rest := code[i+1:]
+			if isYAMLBreak(rest) || (inFlow && strings.IndexByte(",[]{}", rest[0]) >= 0) {
+				return i
+			}
+		case ',', '[', ']', '{', '}':
+			if inFlow {
+				return i
+			}
+		}
+	}
+	return len(code)
+}
+
+// isYAMLKey reports whether the text following a scalar makes it a key:
+// a colon, after any spaces, followed by whitespace or the end of the line
+func isYAMLKey(rest string, inFlow bool) bool {
+	rest = strings.TrimLeft(rest, " \t")
+	if !strings.HasPrefix(rest, ":") {
+		return false
+	}
+	return isYAMLBreak(rest[1:]) || (inFlow && strings.IndexByte(",[]{}", rest[1]) >= 0)
+}
+
+// parseYAMLLine tokenizes a line of YAML, starting from and updating the
+// given state
+func parseYAMLLine(line string, state *yamlState) TokenSequence {
+	tokens := TokenSequence{}
+	indent := len(line) - len(strings.TrimLeft(line, " "))
+
+	// The lines of a block scalar are literal text, as are blank lines
+	// within it
+	if state.inBlockScalar {
+		if strings.TrimSpace(line) == "" || indent > state.blockIndent {
+			if indent > 0 {
+				tokens = append(tokens, Token{Type: TokenWhitespace, Text: line[:indent]})
+			}
+			if indent < len(line) {
+				tokens = append(tokens, Token{Type: TokenLiteral, Text: line[indent:]})
+			}
+			return tokens
+		}
+		state.inBlockScalar = false
+	}
+
+	code := line
+
+	// Directives and document markers
+	if strings.HasPrefix(code, "%") {
+		return append(tokens, Token{Type: TokenKeyword, Text: code})
+	}
+	if (strings.HasPrefix(code, "---") || strings.HasPrefix(code, "...")) && isYAMLBreak(code[3:]) {
+		tokens = append(tokens, Token{Type: TokenKeyword, Text: code[:3]})
+		code = code[3:]
+		state.flowDepth = 0
+	}
+
+	// nodeIndent is the column of the key or sequence entry a block scalar
+	// belongs to; its lines must be indented further
+	nodeIndent := indent
+
+	for len(code) > 0 {
+		column := len(line) - len(code)
+		inFlow := state.flowDepth > 0
+
+		if n := scanWhitespace(code); n > 0 {
+			tokens = append(tokens, Token{Type: TokenWhitespace, Text: code[:n]})
+			code = code[n:]
+			continue
+		}
+
+		switch c := code[0]; {
+		case c == '#':
+			return append(tokens, Token{Type: TokenComment, Text: code})
+
+		case (c == '-' || c == '?' || c == ':') && isYAMLBreak(code[1:]), c == ':' && inFlow:
+			// Sequence entries, complex keys and value separators
+			if c != ':' {
+				nodeIndent = column
+			}
+			tokens = append(tokens, Token{Type: TokenOther, Text: code[:1]})
+			code = code[1:]
+			continue
+
+		case c == '{' || c == '[':
+			state.flowDepth++
+			tokens = append(tokens, Token{Type: TokenOther, Text: code[:1]})
+			code = code[1:]
+			continue
+
+		case c == '}' || c == ']':
+			if state.flowDepth > 0 {
+				state.flowDepth--
+			}
+			tokens = append(tokens, Token{Type: TokenOther, Text: code[:1]})
+			code = code[1:]
+			continue
+
+		case c == ',' && inFlow:
+			tokens = append(tokens, Token{Type: TokenOther, Text: code[:1]})
+			code = code[1:]
+			continue
+
+		case c == '&' || c == '*' || c == '!':
+			// Anchors, aliases and tags
+			if match := yamlPropertyRegex.FindString(code); len(match) > 1 || c == '!' {
+				tokens = append(tokens, Token{Type: TokenKeyword, Text: match})
+				code = code[len(match):]
+				continue
+			}
+
+		case (c == '|' || c == '>') && !inFlow:
+			match := yamlBlockScalarRegex.FindString(code)
+			if rest := code[len(match):]; isYAMLBreak(rest) {
+				state.inBlockScalar = true
+				state.blockIndent = nodeIndent
+				tokens = append(tokens, Token{Type: TokenOther, Text: match})
+				code = rest
+				continue
+			}
+
+		case c == '"' || c == '\'':
+			n := findYAMLQuotedEnd(code)
+			tokenType := TokenLiteral
+			if isYAMLKey(code[n:], inFlow) {
+				tokenType = TokenIdentifier
+				nodeIndent = column
+			}
+			tokens = append(tokens, Token{Type: tokenType, Text: code[:n]})
+			code = code[n:]
+			continue
+		}
+
+		// A plain scalar, without trailing spaces
+		n := findYAMLPlainEnd(code, inFlow)
+		if n == 0 {
+			n = scanChar(code)
+		}
+		scalar := strings.TrimRight(code[:n], " \t\r")
+		if scalar == "" {
+			scalar = code[:n]
+		}
+
+		tokenType := TokenLiteral
+		if isYAMLKey(code[len(scalar):], inFlow) {
+			tokenType = TokenIdentifier
+			nodeIndent = column
+		} else if yamlKeywords[strings.ToLower(scalar)] {
+			tokenType = TokenKeyword
+		}
+		tokens = append(tokens, Token{Type: tokenType, Text: scalar})
+		code = code[len(scalar):]
+	}
+
+	return tokens
+}
@@ -0,0 +1,152 @@
+package parsing
+
+import (
+	"testing"
+)
+
+func TestYAMLParser(t *testing.T) {
+	testCases := []struct {
+		name     string
+		input    string
+		expected []Token
+	}{
+		{
+			name:  "Keys and values",
+			input: "image: nginx:1.25 # pinned\nenabled: Yes",
+			expected: []Token{
+				{Type: TokenIdentifier, Text: "image"},
+				{Type: TokenOther, Text: ":"},
+				{Type: TokenWhitespace, Text: " "},
+				{Type: TokenLiteral, Text: "nginx:1.25"},
+				{Type: TokenWhitespace, Text: " "},
+				{Type: TokenComment, Text: "# pinned"},
+				{Type: TokenWhitespace, Text: "\n"},
+				{Type: TokenIdentifier, Text: "enabled"},
+				{Type: TokenOther, Text: ":"},
+				{Type: TokenWhitespace, Text: " "},
+				{Type: TokenKeyword, Text: "Yes"},
+			},
+		},
+		{
+			name:  "Anchors, aliases and tags",
+			input: "- base: &defaults !!map\n  web: *defaults",
+			expected: []Token{
+				{Type: TokenOther, Text: "-"},
+				{Type: TokenWhitespace, Text: " "},
+				{Type: TokenIdentifier, Text: "base"},
+				{Type: TokenOther, Text: ":"},
+				{Type: TokenWhitespace, Text: " "},
+				{Type: TokenKeyword, Text: "&defaults"},
+				{Type: TokenWhitespace, Text: " "},
+				{Type: TokenKeyword, Text: "!!map"},
+				{Type: TokenWhitespace, Text: "\n"},
+				{Type: TokenWhitespace, Text: "  "},
+				{Type: TokenIdentifier, Text: "web"},
+				{Type: TokenOther, Text: ":"},
+				{Type: TokenWhitespace, Text: " "},
+				{Type: TokenKeyword, Text: "*defaults"},
+			},
+		},
+		{
+			name:  "Block scalar",
+			input: "script: |-\n  echo a: b # not a comment\n\n  exit 1\nnext: 2",
+			expected: []Token{
+				{Type: TokenIdentifier, Text: "script"},
+				{Type: TokenOther, Text: ":"},
+				{Type: TokenWhitespace, Text: " "},
+				{Type: TokenOther, Text: "|-"},
+				{Type: TokenWhitespace, Text: "\n"},
+				{Type: TokenWhitespace, Text: "  "},
+				{Type: TokenLiteral, Text: "echo a: b # not a comment"},
+				{Type: TokenWhitespace, Text: "\n"},
+				{Type: TokenWhitespace, Text: "\n"},
+				{Type: TokenWhitespace, Text: "  "},
+				{Type: TokenLiteral, Text: "exit 1"},
+				{Type: TokenWhitespace, Text: "\n"},
+				{Type: TokenIdentifier, Text: "next"},
+				{Type: TokenOther, Text: ":"},
+				{Type: TokenWhitespace, Text: " "},
+				{Type: TokenLiteral, Text: "2"},
+			},
+		},
+		{
+			name:  "Flow collection with quoted keys",
+			input: `{"a b": 'it''s', c: [1, ~]}`,
+			expected: []Token{
+				{Type: TokenOther, Text: "{"},
+				{Type: TokenIdentifier, Text: `"a b"`},
+				{Type: TokenOther, Text: ":"},
+				{Type: TokenWhitespace, Text: " "},
+				{Type: TokenLiteral, Text: `'it''s'`},
+				{Type: TokenOther, Text: ","},
+				{Type: TokenWhitespace, Text: " "},
+				{Type: TokenIdentifier, Text: "c"},
+				{Type: TokenOther, Text: ":"},
+				{Type: TokenWhitespace, Text: " "},
+				{Type: TokenOther, Text: "["},
+				{Type: TokenLiteral, Text: "1"},
+				{Type: TokenOther, Text: ","},
+				{Type: TokenWhitespace, Text: " "},
+				{Type: TokenKeyword, Text: "~"},
+				{Type: TokenOther, Text: "]"},
+				{Type: TokenOther, Text: "}"},
+			},
+		},
+		{
+			name:  "Document marker",
+			input: "--- # first\nurl: http://example.com",
+			expected: []Token{
+				{Type: TokenKeyword, Text: "---"},
+				{Type: TokenWhitespace, Text: " "},
+				{Type: TokenComment, Text: "# first"},
+				{Type: TokenWhitespace, Text: "\n"},
+				{Type: TokenIdentifier, Text: "url"},
+				{Type: TokenOther, Text: ":"},
+				{Type: TokenWhitespace, Text: " "},
+				{Type: TokenLiteral, Text: "http://example.com"},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			tokens, err := ParseYAML(tc.input)
+			if err != nil {
+				t.Fatalf("Error parsing YAML: %v", err)
+			}
+			if len(tokens) != len(tc.expected) {
+				t.Fatalf("Expected %d tokens, got %d. Tokens: %v", len(tc.expected), len(tokens), tokens)
+			}
+			for i := range tokens {
+				if tokens[i] != tc.expected[i] {
+					t.Errorf("Token %d = %+v, want %+v", i, tokens[i], tc.expected[i])
+				}
+			}
+		})
+	}
+}
+
+func TestYAMLParseLine(t *testing.T) {
+	parser := &YAMLParser{}
+	lines := []string{"- run: >", "    make test: all", "  name: build"}
+	want := []TokenType{TokenOther, TokenLiteral, TokenIdentifier}
+
+	var state LineState
+	for i, line := range lines {
+		tokens, next, err := parser.ParseLine(line, state)
+		if err != nil {
+			t.Fatalf("ParseLine(%q) error: %v", line, err)
+		}
+		state = next
+
+		// The last token of the first line, and the first after the
+		// indentation on the others
+		token := tokens[len(tokens)-1]
+		if i > 0 {
+			token = tokens[1]
+		}
+		if token.Type != want[i] {
+			t.Errorf("Line %d token %+v, want type %v", i+1, token, want[i])
+		}
+	}
+}
@@ -0,0 +1,175 @@
+package pricing
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/rba100/aipipe/internal/util"
+)
+
+// DefaultURL is where `aipipe prices update` fetches the price table from:
+// the copy of prices.json maintained in the repository
+const DefaultURL = "https://raw.githubusercontent.com/rba100/aipipe/main/internal/pricing/prices.json"
+
+// maxTableBytes limits the size of a price table fetched over HTTP(S)
+const maxTableBytes = 1 << 20
+
+// bundled is the price table built into aipipe, used until a newer one is
+// fetched
+//
+//go:embed prices.json
+var bundled []byte
+
+// Price is what a model costs, in US dollars per million tokens
+type Price struct {
+	Input  float64 `json:"input"`
+	Output float64 `json:"output"`
+}
+
+// Table is a price table. Version increases with each change, by convention
+// the date of the change as YYYYMMDD, so an older table never replaces a
+// newer one.
+type Table struct {
+	Version int              `json:"version"`
+	Models  map[string]Price `json:"models"`
+}
+
+// Parse reads and checks a price table
+func Parse(data []byte) (*Table, error) {
+	var table Table
+	if err := json.Unmarshal(data, &table); err != nil {
+		return nil, fmt.Errorf("not a price table: %w", err)
+	}
+	if table.Version <= 0 {
+		return nil, fmt.Errorf("the price table has no version")
+	}
+	if len(table.Models) == 0 {
+		return nil, fmt.Errorf("the price table has no models")
+	}
+	for model, price := range table.Models {
+		if price.Input < 0 || price.Output < 0 {
+			return nil, fmt.Errorf("the price of %s is negative", model)
+		}
+	}
+	return &table, nil
+}
+
+// Bundled returns the price table built into aipipe
+func Bundled() *Table {
+	table, err := Parse(bundled)
+	if err != nil {
+		panic("invalid bundled price table: " + err.Error())
+	}
+	return table
+}
+
+// path returns where a fetched price table is kept
+func path() (string, error) {
+	return util.DataPath("prices.json")
+}
+
+// Load returns the price table in use: the one last fetched, unless the
+// bundled table is newer
+func Load() (*Table, error) {
+	table := Bundled()
+
+	path, err := path()
+	if err != nil {
+		return table, err
+	}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return table, nil
+	}
+	if err != nil {
+		return table, fmt.Errorf("failed to read price table: %w", err)
+	}
+	fetched, err := Parse(data)
+	if err != nil {
+		return table, fmt.Errorf("%s: %w", path, err)
+	}
+	if fetched.Version >= table.Version {
+		return fetched, nil
+	}
+	return table, nil
+}
+
+// Update fetches the price table at url and saves it if it is newer than the
+// one in use, returning the table now in use and whether it changed
+func Update(url string) (*Table, bool, error) {
+	client := &http.Client{Timeout: 30 * time.Second}
+	resp, err := client.Get(url)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to fetch %s: %w", url, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, false, fmt.Errorf("failed to fetch %s: status %d", url, resp.StatusCode)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxTableBytes))
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to fetch %s: %w", url, err)
+	}
+	fetched, err := Parse(data)
+	if err != nil {
+		return nil, false, fmt.Errorf("%s: %w", url, err)
+	}
+
+	current, err := Load()
+	if err != nil {
+		return nil, false, err
+	}
+	if fetched.Version <= current.Version {
+		return current, false, nil
+	}
+
+	path, err := path()
+	if err != nil {
+		return nil, false, err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return nil, false, fmt.Errorf("failed to create data directory: %w", err)
+	}
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		return nil, false, fmt.Errorf("failed to save price table: %w", err)
+	}
+	return fetched, true, nil
+}
+
+// Lookup returns the price of a model. APIs often answer with a dated
+// version of the model requested, such as gpt-4o-2024-08-06, so without an
+// exact match the longest name the model starts with, followed by a dash, is
+// used.
+func (t *Table) Lookup(model string) (Price, bool) {
+	if price, ok := t.Models[model]; ok {
+		return price, true
+	}
+
+	best := ""
+	for name := range t.Models {
+		if strings.HasPrefix(model, name+"-") && len(name) > len(best) {
+			best = name
+		}
+	}
+	if best == "" {
+		return Price{}, false
+	}
+	return t.Models[best], true
+}
+
+// Cost returns what a completion by model cost in US dollars, or false if the
+// model's price isn't known
+func (t *Table) Cost(model string, promptTokens int, completionTokens int) (float64, bool) {
+	price, ok := t.Lookup(model)
+	if !ok {
+		return 0, false
+	}
+	return (float64(promptTokens)*price.Input + float64(completionTokens)*price.Output) / 1e6, true
+}
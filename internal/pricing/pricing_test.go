@@ -0,0 +1,89 @@
+package pricing
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestBundled(t *testing.T) {
+	table := Bundled()
+	for _, model := range []string{"gpt-4o", "gpt-4o-mini", "claude-sonnet-4-5", "llama-3.3-70b-versatile"} {
+		if _, ok := table.Lookup(model); !ok {
+			t.Errorf("the bundled price table has no price for %s", model)
+		}
+	}
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		wantErr bool
+	}{
+		{"valid", `{"version": 20260101, "models": {"m": {"input": 1, "output": 2}}}`, false},
+		{"not JSON", `models: {}`, true},
+		{"no version", `{"models": {"m": {"input": 1, "output": 2}}}`, true},
+		{"no models", `{"version": 20260101, "models": {}}`, true},
+		{"negative price", `{"version": 20260101, "models": {"m": {"input": -1, "output": 2}}}`, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Parse([]byte(tt.data)); (err != nil) != tt.wantErr {
+				t.Errorf("Parse() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestCost(t *testing.T) {
+	table := &Table{Version: 1, Models: map[string]Price{
+		"gpt-4o":      {Input: 2.5, Output: 10},
+		"gpt-4o-mini": {Input: 0.15, Output: 0.6},
+	}}
+
+	tests := []struct {
+		model  string
+		want   float64
+		wantOK bool
+	}{
+		{"gpt-4o", 0.0125, true},
+		{"gpt-4o-2024-08-06", 0.0125, true},
+		{"gpt-4o-mini-2024-07-18", 0.00075, true},
+		{"gpt-4oo", 0, false},
+		{"llama3.2", 0, false},
+	}
+	for _, tt := range tests {
+		got, ok := table.Cost(tt.model, 1000, 1000)
+		if ok != tt.wantOK || math.Abs(got-tt.want) > 1e-12 {
+			t.Errorf("Cost(%q) = %v, %v, want %v, %v", tt.model, got, ok, tt.want, tt.wantOK)
+		}
+	}
+}
+
+func TestUpdate(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("XDG_DATA_HOME", t.TempDir())
+
+	version := Bundled().Version + 1
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `{"version": %d, "models": {"new-model": {"input": 1, "output": 2}}}`, version)
+	}))
+	defer server.Close()
+
+	table, updated, err := Update(server.URL)
+	if err != nil || !updated || table.Version != version {
+		t.Fatalf("Update() = %+v, %v, %v, want version %d", table, updated, err, version)
+	}
+	if table, err := Load(); err != nil || table.Version != version {
+		t.Errorf("Load() after Update() = %+v, %v, want version %d", table, err, version)
+	}
+
+	// An older table is refused
+	version--
+	if table, updated, err := Update(server.URL); err != nil || updated || table.Version != version+1 {
+		t.Errorf("Update() with an older table = %+v, %v, %v, want no change", table, updated, err)
+	}
+}
@@ -0,0 +1,156 @@
+package snippets
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/rba100/aipipe/internal/util"
+)
+
+// validName matches snippet names, which are used as file names
+var validName = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_.-]*$`)
+
+// Snippet is a prompt saved under a name
+type Snippet struct {
+	Name   string
+	Prompt string
+	// Shared is true for snippets synced from the team's snippet source
+	Shared bool
+}
+
+// Store keeps each snippet in its own text file, so snippets can also be
+// written and edited by hand
+type Store struct {
+	dir string
+	// shared, if set, holds snippets synced from a shared source. They are
+	// used when there is no local snippet with the same name.
+	shared *Store
+}
+
+// NewStore creates a store that keeps its snippets in the given directory
+func NewStore(dir string) *Store {
+	return &Store{dir: dir}
+}
+
+// DefaultStore returns the store in the snippets directory of aipipe's data
+// directory, backed by the shared snippets synced by Sync
+func DefaultStore() (*Store, error) {
+	dir, err := util.DataPath("snippets")
+	if err != nil {
+		return nil, err
+	}
+	sharedDir, err := util.DataPath("shared-snippets")
+	if err != nil {
+		return nil, err
+	}
+
+	store := NewStore(dir)
+	store.shared = NewStore(sharedDir)
+	return store, nil
+}
+
+// path returns the file holding the named snippet
+func (s *Store) path(name string) (string, error) {
+	if !validName.MatchString(name) {
+		return "", fmt.Errorf("invalid snippet name %q (use letters, digits, '-', '_' and '.')", name)
+	}
+	return filepath.Join(s.dir, name+".txt"), nil
+}
+
+// Save stores a prompt under a name, replacing any snippet with that name
+func (s *Store) Save(name string, prompt string) error {
+	path, err := s.path(name)
+	if err != nil {
+		return err
+	}
+	prompt = strings.TrimSpace(prompt)
+	if prompt == "" {
+		return fmt.Errorf("the snippet's prompt is empty")
+	}
+
+	if err := os.MkdirAll(s.dir, 0700); err != nil {
+		return fmt.Errorf("failed to create snippets directory: %w", err)
+	}
+	if err := os.WriteFile(path, []byte(prompt+"\n"), 0600); err != nil {
+		return fmt.Errorf("failed to write snippet: %w", err)
+	}
+	return nil
+}
+
+// Get returns the prompt saved under a name
+func (s *Store) Get(name string) (string, error) {
+	path, err := s.path(name)
+	if err != nil {
+		return "", err
+	}
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		if s.shared != nil {
+			return s.shared.Get(name)
+		}
+		return "", fmt.Errorf("no snippet named %q", name)
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to read snippet: %w", err)
+	}
+	return strings.TrimSpace(string(data)), nil
+}
+
+// List returns the saved snippets, and the shared snippets that have no local
+// snippet of the same name, sorted by name
+func (s *Store) List() ([]Snippet, error) {
+	var snippets []Snippet
+	if s.shared != nil {
+		shared, err := s.shared.List()
+		if err != nil {
+			return nil, err
+		}
+		for _, snippet := range shared {
+			if _, err := os.Stat(filepath.Join(s.dir, snippet.Name+".txt")); os.IsNotExist(err) {
+				snippet.Shared = true
+				snippets = append(snippets, snippet)
+			}
+		}
+	}
+
+	entries, err := os.ReadDir(s.dir)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to read snippets directory: %w", err)
+	}
+	for _, entry := range entries {
+		name, ok := strings.CutSuffix(entry.Name(), ".txt")
+		if !ok || entry.IsDir() || !validName.MatchString(name) {
+			continue
+		}
+		prompt, err := s.Get(name)
+		if err != nil {
+			return nil, err
+		}
+		snippets = append(snippets, Snippet{Name: name, Prompt: prompt})
+	}
+
+	sort.Slice(snippets, func(i, j int) bool { return snippets[i].Name < snippets[j].Name })
+	return snippets, nil
+}
+
+// Delete removes the named snippet
+func (s *Store) Delete(name string) error {
+	path, err := s.path(name)
+	if err != nil {
+		return err
+	}
+
+	err = os.Remove(path)
+	if os.IsNotExist(err) {
+		return fmt.Errorf("no snippet named %q", name)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to remove snippet: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,50 @@
+package snippets
+
+import (
+	"testing"
+)
+
+func TestStore(t *testing.T) {
+	store := NewStore(t.TempDir())
+
+	snippets, err := store.List()
+	if err != nil || len(snippets) != 0 {
+		t.Fatalf("List() on an empty store = %v, %v; want no snippets", snippets, err)
+	}
+
+	if err := store.Save("summarize-pr", "  Summarise this diff as a PR description  "); err != nil {
+		t.Fatalf("Save() error: %v", err)
+	}
+	if err := store.Save("commit", "Write a commit message"); err != nil {
+		t.Fatalf("Save() error: %v", err)
+	}
+
+	prompt, err := store.Get("summarize-pr")
+	if err != nil || prompt != "Summarise this diff as a PR description" {
+		t.Errorf("Get() = %q, %v; want the trimmed prompt", prompt, err)
+	}
+
+	snippets, err = store.List()
+	if err != nil || len(snippets) != 2 || snippets[0].Name != "commit" || snippets[1].Name != "summarize-pr" {
+		t.Errorf("List() = %+v, %v; want both snippets sorted by name", snippets, err)
+	}
+
+	for _, name := range []string{"../escape", "", "a/b"} {
+		if err := store.Save(name, "prompt"); err == nil {
+			t.Errorf("Save(%q) succeeded, want an invalid name error", name)
+		}
+	}
+	if err := store.Save("empty", "  "); err == nil {
+		t.Errorf("Save() of an empty prompt succeeded, want an error")
+	}
+
+	if err := store.Delete("commit"); err != nil {
+		t.Fatalf("Delete() error: %v", err)
+	}
+	if _, err := store.Get("commit"); err == nil {
+		t.Errorf("Get() of a deleted snippet succeeded, want an error")
+	}
+	if err := store.Delete("commit"); err == nil {
+		t.Errorf("Delete() of a missing snippet succeeded, want an error")
+	}
+}
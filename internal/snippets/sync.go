@@ -0,0 +1,127 @@
+package snippets
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/rba100/aipipe/internal/util"
+	"gopkg.in/yaml.v3"
+)
+
+// maxSourceBytes limits the size of a snippet file fetched over HTTPS
+const maxSourceBytes = 1 << 20
+
+// isGitSource reports whether a snippet source is a git repository rather
+// than a YAML file served over HTTP(S)
+func isGitSource(source string) bool {
+	if strings.HasPrefix(source, "http://") || strings.HasPrefix(source, "https://") {
+		return strings.HasSuffix(source, ".git")
+	}
+	return true
+}
+
+// Sync replaces the shared snippets of the default store with those from a
+// team's snippet source, returning how many there are. The source is either
+// a git repository, whose .txt files at the top level are the snippets, or
+// the HTTP(S) URL of a YAML file mapping snippet names to prompts.
+func Sync(source string) (int, error) {
+	dir, err := util.DataPath("shared-snippets")
+	if err != nil {
+		return 0, err
+	}
+	if err := syncDir(source, dir); err != nil {
+		return 0, err
+	}
+
+	snippets, err := NewStore(dir).List()
+	return len(snippets), err
+}
+
+// syncDir updates dir from a snippet source
+func syncDir(source string, dir string) error {
+	if err := os.MkdirAll(filepath.Dir(dir), 0700); err != nil {
+		return fmt.Errorf("failed to create data directory: %w", err)
+	}
+
+	if isGitSource(source) {
+		return syncGit(source, dir)
+	}
+	return syncYAML(source, dir)
+}
+
+// syncGit clones a git repository into dir, or pulls it if it has already been
+// cloned from the same place
+func syncGit(source string, dir string) error {
+	if _, err := exec.LookPath("git"); err != nil {
+		return fmt.Errorf("git is needed to sync snippets from %s, and is not installed", source)
+	}
+
+	if origin, err := exec.Command("git", "-C", dir, "remote", "get-url", "origin").Output(); err == nil && strings.TrimSpace(string(origin)) == source {
+		if output, err := exec.Command("git", "-C", dir, "pull", "--ff-only", "--quiet").CombinedOutput(); err != nil {
+			return fmt.Errorf("git pull failed: %s", strings.TrimSpace(string(output)))
+		}
+		return nil
+	}
+
+	// The source changed, or there is no clone yet
+	if err := os.RemoveAll(dir); err != nil {
+		return fmt.Errorf("failed to remove old shared snippets: %w", err)
+	}
+	if output, err := exec.Command("git", "clone", "--depth", "1", "--quiet", source, dir).CombinedOutput(); err != nil {
+		return fmt.Errorf("git clone failed: %s", strings.TrimSpace(string(output)))
+	}
+	return nil
+}
+
+// syncYAML downloads a YAML file of snippets and writes each to dir, in place
+// of what was there before
+func syncYAML(source string, dir string) error {
+	client := &http.Client{Timeout: 30 * time.Second}
+	resp, err := client.Get(source)
+	if err != nil {
+		return fmt.Errorf("failed to fetch %s: %w", source, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("failed to fetch %s: status %d", source, resp.StatusCode)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxSourceBytes))
+	if err != nil {
+		return fmt.Errorf("failed to fetch %s: %w", source, err)
+	}
+
+	var prompts map[string]string
+	if err := yaml.Unmarshal(data, &prompts); err != nil {
+		return fmt.Errorf("%s is not a YAML map of snippet names to prompts: %w", source, err)
+	}
+
+	// Write the new snippets alongside the old ones, then swap them over
+	staging := dir + ".new"
+	if err := os.RemoveAll(staging); err != nil {
+		return fmt.Errorf("failed to prepare shared snippets: %w", err)
+	}
+	store := NewStore(staging)
+	for name, prompt := range prompts {
+		if err := store.Save(name, prompt); err != nil {
+			os.RemoveAll(staging)
+			return fmt.Errorf("snippet %q: %w", name, err)
+		}
+	}
+	if err := os.MkdirAll(staging, 0700); err != nil {
+		return fmt.Errorf("failed to prepare shared snippets: %w", err)
+	}
+
+	if err := os.RemoveAll(dir); err != nil {
+		return fmt.Errorf("failed to remove old shared snippets: %w", err)
+	}
+	if err := os.Rename(staging, dir); err != nil {
+		return fmt.Errorf("failed to save shared snippets: %w", err)
+	}
+	return nil
+}
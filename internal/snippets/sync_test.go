@@ -0,0 +1,118 @@
+package snippets
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+)
+
+func TestIsGitSource(t *testing.T) {
+	tests := map[string]bool{
+		"https://github.com/team/prompts.git":   true,
+		"git@github.com:team/prompts.git":       true,
+		"/srv/prompts":                          true,
+		"https://example.com/team/snippets.yml": false,
+	}
+	for source, expected := range tests {
+		if got := isGitSource(source); got != expected {
+			t.Errorf("isGitSource(%q) = %v, expected %v", source, got, expected)
+		}
+	}
+}
+
+func TestSyncYAML(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "review: Review this diff for bugs\nchangelog: |\n  Write a changelog entry\n")
+	}))
+	defer server.Close()
+
+	dir := filepath.Join(t.TempDir(), "shared")
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "stale.txt"), []byte("old"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := syncDir(server.URL+"/snippets.yml", dir); err != nil {
+		t.Fatalf("syncDir() error = %v", err)
+	}
+	snippets, err := NewStore(dir).List()
+	if err != nil || len(snippets) != 2 || snippets[0].Name != "changelog" || snippets[1].Prompt != "Review this diff for bugs" {
+		t.Errorf("List() after sync = %+v, %v", snippets, err)
+	}
+}
+
+func TestSyncGit(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+
+	repo := t.TempDir()
+	git := func(args ...string) {
+		cmd := exec.Command("git", append([]string{"-C", repo, "-c", "user.name=test", "-c", "user.email=test@example.com"}, args...)...)
+		if output, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v failed: %v: %s", args, err, output)
+		}
+	}
+	git("init", "--quiet")
+	if err := os.WriteFile(filepath.Join(repo, "review.txt"), []byte("Review this diff\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	git("add", ".")
+	git("commit", "--quiet", "-m", "Add review")
+
+	dir := filepath.Join(t.TempDir(), "shared")
+	if err := syncDir(repo, dir); err != nil {
+		t.Fatalf("syncDir() error = %v", err)
+	}
+	if prompt, err := NewStore(dir).Get("review"); err != nil || prompt != "Review this diff" {
+		t.Errorf("Get() after clone = %q, %v", prompt, err)
+	}
+
+	if err := os.WriteFile(filepath.Join(repo, "review.txt"), []byte("Review this diff carefully\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	git("commit", "--quiet", "-am", "Update review")
+	if err := syncDir(repo, dir); err != nil {
+		t.Fatalf("syncDir() error = %v", err)
+	}
+	if prompt, err := NewStore(dir).Get("review"); err != nil || prompt != "Review this diff carefully" {
+		t.Errorf("Get() after pull = %q, %v", prompt, err)
+	}
+}
+
+func TestSharedSnippets(t *testing.T) {
+	store := NewStore(t.TempDir())
+	store.shared = NewStore(t.TempDir())
+
+	if err := store.shared.Save("review", "Shared review prompt"); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.shared.Save("commit", "Shared commit prompt"); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Save("commit", "My commit prompt"); err != nil {
+		t.Fatal(err)
+	}
+
+	if prompt, err := store.Get("review"); err != nil || prompt != "Shared review prompt" {
+		t.Errorf("Get() of a shared snippet = %q, %v", prompt, err)
+	}
+	if prompt, err := store.Get("commit"); err != nil || prompt != "My commit prompt" {
+		t.Errorf("Get() of an overridden snippet = %q, %v", prompt, err)
+	}
+
+	snippets, err := store.List()
+	expected := []Snippet{
+		{Name: "commit", Prompt: "My commit prompt"},
+		{Name: "review", Prompt: "Shared review prompt", Shared: true},
+	}
+	if err != nil || len(snippets) != 2 || snippets[0] != expected[0] || snippets[1] != expected[1] {
+		t.Errorf("List() = %+v, %v, expected %+v", snippets, err, expected)
+	}
+}
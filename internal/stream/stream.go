@@ -0,0 +1,176 @@
+// Package stream builds pipelines of stages connected by channels, such as
+// those that carry a streamed answer from the model to the terminal. Every
+// stage closes its output once its input is closed or its context is
+// cancelled, so abandoning a pipeline part way through only takes cancelling
+// its context.
+package stream
+
+import "context"
+
+// Emit sends a value to the next stage. It returns false if the pipeline has
+// been cancelled, in which case the stage should stop.
+type Emit[T any] func(value T) bool
+
+// Transform starts a stage that may hold state between values. step is called
+// with each value from in and may emit any number of values; it returns false
+// to stop reading early. Once in is closed, flush, if not nil, emits anything
+// the stage held back. It isn't called if the stage stops early or is
+// cancelled. Whatever is left of in is drained in the background, so the
+// stages before it can finish.
+func Transform[T, U any](ctx context.Context, in <-chan T, step func(value T, emit Emit[U]) bool, flush func(emit Emit[U])) <-chan U {
+	out := make(chan U)
+	emit := func(value U) bool {
+		select {
+		case out <- value:
+			return true
+		case <-ctx.Done():
+			return false
+		}
+	}
+
+	go func() {
+		defer close(out)
+		for {
+			select {
+			case value, ok := <-in:
+				if !ok {
+					if flush != nil {
+						flush(emit)
+					}
+					return
+				}
+				if !step(value, emit) {
+					go Drain(in)
+					return
+				}
+			case <-ctx.Done():
+				go Drain(in)
+				return
+			}
+		}
+	}()
+
+	return out
+}
+
+// Map starts a stage that passes on each value converted by fn
+func Map[T, U any](ctx context.Context, in <-chan T, fn func(T) U) <-chan U {
+	return Transform(ctx, in, func(value T, emit Emit[U]) bool {
+		return emit(fn(value))
+	}, nil)
+}
+
+// Filter starts a stage that passes on the values keep accepts
+func Filter[T any](ctx context.Context, in <-chan T, keep func(T) bool) <-chan T {
+	return Transform(ctx, in, func(value T, emit Emit[T]) bool {
+		return !keep(value) || emit(value)
+	}, nil)
+}
+
+// Tee starts a stage that passes values on unchanged, also giving each to
+// sink, as tee(1) copies its input to a file. Unless the pipeline is
+// cancelled, sink has seen every value once the output is closed.
+func Tee[T any](ctx context.Context, in <-chan T, sink func(T)) <-chan T {
+	return Transform(ctx, in, func(value T, emit Emit[T]) bool {
+		sink(value)
+		return emit(value)
+	}, nil)
+}
+
+// Buffer starts a stage that holds up to size values the next stage hasn't
+// taken yet, so the stages before it aren't held up by a consumer that is
+// briefly slow
+func Buffer[T any](ctx context.Context, in <-chan T, size int) <-chan T {
+	out := make(chan T, size)
+
+	go func() {
+		defer close(out)
+		for {
+			select {
+			case value, ok := <-in:
+				if !ok {
+					return
+				}
+				select {
+				case out <- value:
+				case <-ctx.Done():
+					go Drain(in)
+					return
+				}
+			case <-ctx.Done():
+				go Drain(in)
+				return
+			}
+		}
+	}()
+
+	return out
+}
+
+// Queue starts a stage that keeps reading in while the next stage is busy,
+// queueing what it reads, so the stages before it aren't held up by a consumer
+// that is slow for a while. Unlike Buffer it is bounded by the total size of
+// the queued values, as measured by size, rather than their number: it stops
+// reading once they reach highWater, and resumes as the queue is taken.
+func Queue[T any](ctx context.Context, in <-chan T, highWater int, size func(T) int) <-chan T {
+	out := make(chan T)
+
+	go func() {
+		defer close(out)
+		var queue []T
+		queued := 0
+		for in != nil || len(queue) > 0 {
+			// A nil channel is never ready, so leaving reading or sending
+			// nil takes that case out of the select
+			reading := in
+			if queued >= highWater {
+				reading = nil
+			}
+			var sending chan<- T
+			var next T
+			if len(queue) > 0 {
+				sending, next = out, queue[0]
+			}
+
+			select {
+			case value, ok := <-reading:
+				if !ok {
+					in = nil
+					continue
+				}
+				queue = append(queue, value)
+				queued += size(value)
+			case sending <- next:
+				var zero T
+				queue[0] = zero
+				queue = queue[1:]
+				queued -= size(next)
+			case <-ctx.Done():
+				if in != nil {
+					go Drain(in)
+				}
+				return
+			}
+		}
+	}()
+
+	return out
+}
+
+// Of returns a closed channel holding values, to feed a pipeline from values
+// already at hand
+func Of[T any](values ...T) <-chan T {
+	out := make(chan T, len(values))
+	for _, value := range values {
+		out <- value
+	}
+	close(out)
+	return out
+}
+
+// Drain reads in until it is closed, discarding the values, so the stage
+// writing it can finish
+func Drain[T any](in <-chan T) {
+	for range in {
+	}
+}
@@ -0,0 +1,193 @@
+package stream
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// collect reads a channel until it is closed
+func collect[T any](in <-chan T) []T {
+	var values []T
+	for value := range in {
+		values = append(values, value)
+	}
+	return values
+}
+
+func TestStages(t *testing.T) {
+	ctx := context.Background()
+
+	upper := collect(Map(ctx, Of("a", "b"), strings.ToUpper))
+	if want := []string{"A", "B"}; !reflect.DeepEqual(upper, want) {
+		t.Errorf("Map() = %q, want %q", upper, want)
+	}
+
+	even := collect(Filter(ctx, Of(1, 2, 3, 4), func(n int) bool { return n%2 == 0 }))
+	if want := []int{2, 4}; !reflect.DeepEqual(even, want) {
+		t.Errorf("Filter() = %v, want %v", even, want)
+	}
+
+	var seen []string
+	teed := collect(Tee(ctx, Of("x", "y"), func(s string) { seen = append(seen, s) }))
+	if want := []string{"x", "y"}; !reflect.DeepEqual(teed, want) || !reflect.DeepEqual(seen, want) {
+		t.Errorf("Tee() = %q with %q seen, want %q for both", teed, seen, want)
+	}
+
+	buffered := collect(Buffer(ctx, Of(1, 2, 3), 2))
+	if want := []int{1, 2, 3}; !reflect.DeepEqual(buffered, want) {
+		t.Errorf("Buffer() = %v, want %v", buffered, want)
+	}
+}
+
+func TestTransform(t *testing.T) {
+	// Join parts into lines, holding back a partial line until the end
+	var pending string
+	lines := Transform(context.Background(), Of("a\nb", "c\nd"), func(part string, emit Emit[string]) bool {
+		pending += part
+		for {
+			newline := strings.IndexByte(pending, '\n')
+			if newline < 0 {
+				return true
+			}
+			if !emit(pending[:newline]) {
+				return false
+			}
+			pending = pending[newline+1:]
+		}
+	}, func(emit Emit[string]) {
+		emit(pending)
+	})
+
+	if got, want := collect(lines), []string{"a", "bc", "d"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Transform() = %q, want %q", got, want)
+	}
+}
+
+func TestTransformStopsEarly(t *testing.T) {
+	in := make(chan int)
+	sent := make(chan struct{})
+	go func() {
+		defer close(sent)
+		for i := 0; i < 5; i++ {
+			in <- i
+		}
+		close(in)
+	}()
+
+	first := Transform(context.Background(), in, func(n int, emit Emit[int]) bool {
+		emit(n)
+		return false
+	}, func(emit Emit[int]) {
+		t.Error("flush called after the stage stopped early")
+	})
+
+	if got := collect(first); !reflect.DeepEqual(got, []int{0}) {
+		t.Errorf("Transform() = %v, want only the first value", got)
+	}
+
+	// The rest of the input is drained, so its writer isn't left blocked
+	select {
+	case <-sent:
+	case <-time.After(time.Second):
+		t.Fatal("the writer of the input was left blocked")
+	}
+}
+
+func TestCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	in := make(chan string)
+	out := Tee(ctx, Buffer(ctx, in, 1), func(string) {})
+
+	in <- "first"
+	if got := <-out; got != "first" {
+		t.Fatalf("first value = %q, want first", got)
+	}
+
+	cancel()
+	select {
+	case _, ok := <-out:
+		if ok {
+			// A value already on its way may still arrive; the next read
+			// must find the channel closed
+			if _, ok := <-out; ok {
+				t.Error("output still open after cancelling")
+			}
+		}
+	case <-time.After(time.Second):
+		t.Fatal("output not closed after cancelling")
+	}
+
+	// The input is drained once the stages stop
+	select {
+	case in <- "after":
+	case <-time.After(time.Second):
+		t.Fatal("the input was not drained after cancelling")
+	}
+}
+
+func TestQueue(t *testing.T) {
+	in := make(chan string)
+	out := Queue(context.Background(), in, 6, func(s string) int { return len(s) })
+
+	// The queue takes values while nothing reads its output, up to the
+	// high-water mark
+	for _, part := range []string{"abc", "def"} {
+		select {
+		case in <- part:
+		case <-time.After(time.Second):
+			t.Fatalf("Queue() didn't take %q while below its high-water mark", part)
+		}
+	}
+	select {
+	case in <- "ghi":
+		t.Fatal("Queue() took a value beyond its high-water mark")
+	case <-time.After(10 * time.Millisecond):
+	}
+
+	// Taking a value makes room for the next
+	if got := <-out; got != "abc" {
+		t.Fatalf("first value = %q, want abc", got)
+	}
+	in <- "ghi"
+	close(in)
+
+	if got, want := collect(out), []string{"def", "ghi"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Queue() = %q, want %q", got, want)
+	}
+}
+
+// benchmarkParts is a reply split into parts about the size of the tokens a
+// model streams
+func benchmarkParts() []string {
+	reply := strings.Repeat("Here is the change to `orders.py`, with a test:\n```python\nif order.amount > 100:\n    refund(order)\n```\n", 200)
+	var parts []string
+	for len(reply) > 0 {
+		n := min(4, len(reply))
+		parts = append(parts, reply[:n])
+		reply = reply[n:]
+	}
+	return parts
+}
+
+func BenchmarkTransform(b *testing.B) {
+	parts := benchmarkParts()
+	ctx := context.Background()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		Drain(Map(ctx, Of(parts...), strings.ToUpper))
+	}
+}
+
+func BenchmarkQueue(b *testing.B) {
+	parts := benchmarkParts()
+	ctx := context.Background()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		Drain(Queue(ctx, Of(parts...), 1<<20, func(part string) int { return len(part) }))
+	}
+}
@@ -0,0 +1,180 @@
+// Package tokenizer counts the tokens a model would see in some text, for
+// showing usage and keeping prompts within a model's limits.
+package tokenizer
+
+import (
+	"math"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Tokenizer counts tokens in text for a model
+type Tokenizer interface {
+	// Count returns the number of tokens in text
+	Count(text string) int
+}
+
+// pieceRegex splits text the way the cl100k and o200k pre-tokenizers do
+// before byte pair encoding: words with their leading space, runs of up to
+// three digits, punctuation, and whitespace. BPE merges rarely cross these
+// boundaries, so most pieces are a single token.
+var pieceRegex = regexp.MustCompile(`'(?:s|t|re|ve|m|ll|d)|[^\r\n\p{L}\p{N}]?\p{L}+|\p{N}{1,3}| ?[^\s\p{L}\p{N}]+[\r\n]*|\s*[\r\n]+|\s+`)
+
+// Approximate estimates token counts without a vocabulary, from how byte pair
+// encoders typically split each piece of text. The estimate is calibrated for
+// the cl100k vocabulary; Factor scales it for other vocabularies.
+type Approximate struct {
+	// Factor is the number of tokens the model's vocabulary uses for text
+	// that takes one cl100k token. Zero is treated as 1.
+	Factor float64
+}
+
+// Count implements the Tokenizer interface
+func (a Approximate) Count(text string) int {
+	tokens := 0
+	for _, piece := range pieceRegex.FindAllString(text, -1) {
+		tokens += pieceTokens(piece)
+	}
+
+	if a.Factor == 0 || a.Factor == 1 {
+		return tokens
+	}
+	return int(math.Round(float64(tokens) * a.Factor))
+}
+
+// pieceTokens estimates the number of tokens in a single piece of text
+func pieceTokens(piece string) int {
+	letters := strings.TrimLeftFunc(piece, func(r rune) bool { return !unicode.IsLetter(r) })
+	if letters == "" {
+		// Numbers and whitespace are a token per piece; common punctuation
+		// pairs such as "()" and ");" are merged
+		if strings.TrimSpace(piece) == "" || unicode.IsDigit([]rune(piece)[0]) {
+			return 1
+		}
+		return (utf8.RuneCountInString(strings.TrimSpace(piece)) + 1) / 2
+	}
+
+	n := utf8.RuneCountInString(letters)
+	switch {
+	case isASCII(letters):
+		// Common words are one token; longer, rarer words are split into
+		// chunks of several letters
+		return 1 + (n-1)/8
+	case isIdeographic(letters):
+		return n
+	case isLatin(letters):
+		// Accented letters often take a token of their own
+		return 1 + n/3
+	default:
+		// Other alphabets, such as Cyrillic and Greek, are less well
+		// represented in vocabularies built mostly from English
+		return 1 + n/2
+	}
+}
+
+// isASCII reports whether s only contains ASCII characters
+func isASCII(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] >= utf8.RuneSelf {
+			return false
+		}
+	}
+	return true
+}
+
+// isIdeographic reports whether s is written in a script where each
+// character is a word or syllable, such as Chinese, Japanese or Korean
+func isIdeographic(s string) bool {
+	for _, r := range s {
+		if !unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Hangul) {
+			return false
+		}
+	}
+	return true
+}
+
+// isLatin reports whether s is written in the Latin alphabet
+func isLatin(s string) bool {
+	for _, r := range s {
+		if !unicode.Is(unicode.Latin, r) {
+			return false
+		}
+	}
+	return true
+}
+
+// families maps model name prefixes to tokenizers for their vocabularies.
+// Factors compare each vocabulary with cl100k on a mix of English prose and
+// code.
+var families = map[string]Tokenizer{
+	"gpt-3.5":   Approximate{Factor: 1},
+	"gpt-4":     Approximate{Factor: 1},
+	"gpt-4o":    Approximate{Factor: 0.95},
+	"gpt-4.1":   Approximate{Factor: 0.95},
+	"gpt-5":     Approximate{Factor: 0.95},
+	"chatgpt":   Approximate{Factor: 0.95},
+	"o1":        Approximate{Factor: 0.95},
+	"o3":        Approximate{Factor: 0.95},
+	"o4":        Approximate{Factor: 0.95},
+	"llama-3":   Approximate{Factor: 1},
+	"llama3":    Approximate{Factor: 1},
+	"llama-4":   Approximate{Factor: 0.95},
+	"llama2":    Approximate{Factor: 1.2},
+	"llama-2":   Approximate{Factor: 1.2},
+	"claude":    Approximate{Factor: 1.15},
+	"mistral":   Approximate{Factor: 1.15},
+	"mixtral":   Approximate{Factor: 1.15},
+	"codestral": Approximate{Factor: 1.15},
+	"gemma":     Approximate{Factor: 0.95},
+	"gemini":    Approximate{Factor: 0.95},
+	"qwen":      Approximate{Factor: 1},
+	"deepseek":  Approximate{Factor: 1.05},
+}
+
+// registered holds tokenizers added at runtime with Register
+var registered = map[string]Tokenizer{}
+
+// Register makes tokenizer the one used for models whose names start with
+// prefix, taking precedence over the built-in approximations. It is how an
+// exact tokenizer for a vocabulary can be plugged in.
+func Register(prefix string, tokenizer Tokenizer) {
+	registered[strings.ToLower(prefix)] = tokenizer
+}
+
+// ForModel returns the tokenizer for a model, chosen by the longest matching
+// prefix of its name. Provider prefixes such as "openai/" are ignored.
+// Unrecognised models are estimated as cl100k.
+func ForModel(model string) Tokenizer {
+	name := strings.ToLower(model)
+	if i := strings.LastIndex(name, "/"); i >= 0 {
+		name = name[i+1:]
+	}
+
+	for _, tokenizers := range []map[string]Tokenizer{registered, families} {
+		if tokenizer := longestPrefixMatch(tokenizers, name); tokenizer != nil {
+			return tokenizer
+		}
+	}
+	return Approximate{Factor: 1}
+}
+
+// longestPrefixMatch returns the tokenizer whose prefix is the longest one
+// that name starts with, or nil if there isn't one
+func longestPrefixMatch(tokenizers map[string]Tokenizer, name string) Tokenizer {
+	var match Tokenizer
+	longest := -1
+	for prefix, tokenizer := range tokenizers {
+		if strings.HasPrefix(name, prefix) && len(prefix) > longest {
+			match = tokenizer
+			longest = len(prefix)
+		}
+	}
+	return match
+}
+
+// Count returns the number of tokens in text for a model
+func Count(model string, text string) int {
+	return ForModel(model).Count(text)
+}
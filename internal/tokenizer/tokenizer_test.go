@@ -0,0 +1,93 @@
+package tokenizer
+
+import "testing"
+
+func TestApproximateCount(t *testing.T) {
+	// Expected counts are within a token or two of the cl100k counts
+	tests := []struct {
+		name     string
+		text     string
+		expected int
+	}{
+		{
+			name:     "Empty",
+			text:     "",
+			expected: 0,
+		},
+		{
+			name:     "Greeting",
+			text:     "Hello, world!",
+			expected: 4,
+		},
+		{
+			name:     "Sentence",
+			text:     "The quick brown fox jumps over the lazy dog.",
+			expected: 10,
+		},
+		{
+			name:     "Numbers",
+			text:     "12345",
+			expected: 2,
+		},
+		{
+			name:     "Code",
+			text:     "func main() {\n\tfmt.Println(x)\n}",
+			expected: 9,
+		},
+		{
+			name:     "Chinese",
+			text:     "你好世界",
+			expected: 4,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := (Approximate{}).Count(tt.text); got != tt.expected {
+				t.Errorf("Count(%q) = %d, expected %d", tt.text, got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestApproximateFactor(t *testing.T) {
+	text := "The quick brown fox jumps over the lazy dog."
+	if got := (Approximate{Factor: 1.2}).Count(text); got != 12 {
+		t.Errorf("Count() with factor 1.2 = %d, expected 12", got)
+	}
+}
+
+type fixedTokenizer int
+
+func (f fixedTokenizer) Count(text string) int {
+	return int(f)
+}
+
+func TestForModel(t *testing.T) {
+	tests := []struct {
+		model    string
+		expected Tokenizer
+	}{
+		{"gpt-4", Approximate{Factor: 1}},
+		{"gpt-4o-mini", Approximate{Factor: 0.95}},
+		{"openai/gpt-4o", Approximate{Factor: 0.95}},
+		{"Claude-3-5-Sonnet", Approximate{Factor: 1.15}},
+		{"meta-llama/llama-3.3-70b-versatile", Approximate{Factor: 1}},
+		{"unknown-model", Approximate{Factor: 1}},
+	}
+
+	for _, tt := range tests {
+		if got := ForModel(tt.model); got != tt.expected {
+			t.Errorf("ForModel(%q) = %v, expected %v", tt.model, got, tt.expected)
+		}
+	}
+
+	Register("gpt-4o", fixedTokenizer(7))
+	defer delete(registered, "gpt-4o")
+	if got := Count("gpt-4o-mini", "anything"); got != 7 {
+		t.Errorf("Count() with a registered tokenizer = %d, expected 7", got)
+	}
+	if got := ForModel("gpt-4"); got != (Approximate{Factor: 1}) {
+		t.Errorf("ForModel(%q) = %v after registering another prefix", "gpt-4", got)
+	}
+}
@@ -0,0 +1,80 @@
+package util
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// Annotation is a finding reported by the model, such as a bug in a diff,
+// which can be shown against a line of a file
+type Annotation struct {
+	// Level is error, warning or notice
+	Level   string `json:"level"`
+	File    string `json:"file"`
+	Line    int    `json:"line"`
+	Title   string `json:"title"`
+	Message string `json:"message"`
+}
+
+// ParseAnnotations reads the annotations in a response written as JSON
+// lines, one object per line. Code fences and lines that aren't annotations
+// are skipped, and unknown levels are treated as warnings.
+func ParseAnnotations(response string) []Annotation {
+	var annotations []Annotation
+	for _, line := range strings.Split(response, "\n") {
+		line = strings.TrimSpace(line)
+		if !strings.HasPrefix(line, "{") {
+			continue
+		}
+
+		var annotation Annotation
+		if err := json.Unmarshal([]byte(line), &annotation); err != nil || annotation.Message == "" {
+			continue
+		}
+
+		annotation.Level = strings.ToLower(strings.TrimSpace(annotation.Level))
+		switch annotation.Level {
+		case "error", "warning", "notice":
+		default:
+			annotation.Level = "warning"
+		}
+		annotations = append(annotations, annotation)
+	}
+	return annotations
+}
+
+// FormatGitHubAnnotation formats an annotation as a GitHub Actions workflow
+// command, such as "::error file=app.go,line=12,title=Bug::message"
+func FormatGitHubAnnotation(annotation Annotation) string {
+	var properties []string
+	if annotation.File != "" {
+		properties = append(properties, "file="+escapeGitHubProperty(annotation.File))
+		if annotation.Line > 0 {
+			properties = append(properties, fmt.Sprintf("line=%d", annotation.Line))
+		}
+	}
+	if annotation.Title != "" {
+		properties = append(properties, "title="+escapeGitHubProperty(annotation.Title))
+	}
+
+	command := "::" + annotation.Level
+	if len(properties) > 0 {
+		command += " " + strings.Join(properties, ",")
+	}
+	return command + "::" + escapeGitHubData(annotation.Message)
+}
+
+// escapeGitHubData escapes the message of a workflow command
+func escapeGitHubData(s string) string {
+	s = strings.ReplaceAll(s, "%", "%25")
+	s = strings.ReplaceAll(s, "\r", "%0D")
+	return strings.ReplaceAll(s, "\n", "%0A")
+}
+
+// escapeGitHubProperty escapes a property value of a workflow command
+func escapeGitHubProperty(s string) string {
+	s = escapeGitHubData(s)
+	s = strings.ReplaceAll(s, ":", "%3A")
+	return strings.ReplaceAll(s, ",", "%2C")
+}
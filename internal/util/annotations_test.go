@@ -0,0 +1,54 @@
+package util
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseAnnotations(t *testing.T) {
+	response := "```json\n" +
+		`{"level": "Error", "file": "app.go", "line": 12, "title": "Nil map", "message": "m is never initialised"}` + "\n" +
+		"Some commentary the model added anyway\n" +
+		`{"level": "suggestion", "message": "consider a test"}` + "\n" +
+		`{"level": "error"}` + "\n" +
+		"{not json}\n" +
+		"```"
+
+	expected := []Annotation{
+		{Level: "error", File: "app.go", Line: 12, Title: "Nil map", Message: "m is never initialised"},
+		{Level: "warning", Message: "consider a test"},
+	}
+	if annotations := ParseAnnotations(response); !reflect.DeepEqual(annotations, expected) {
+		t.Errorf("ParseAnnotations() = %+v, want %+v", annotations, expected)
+	}
+
+	if annotations := ParseAnnotations("NONE"); len(annotations) != 0 {
+		t.Errorf("ParseAnnotations(NONE) = %+v, want none", annotations)
+	}
+}
+
+func TestFormatGitHubAnnotation(t *testing.T) {
+	tests := []struct {
+		annotation Annotation
+		expected   string
+	}{
+		{
+			annotation: Annotation{Level: "error", File: "cmd/main.go", Line: 12, Title: "Bug: nil, map", Message: "50% of\nruns fail"},
+			expected:   "::error file=cmd/main.go,line=12,title=Bug%3A nil%2C map::50%25 of%0Aruns fail",
+		},
+		{
+			annotation: Annotation{Level: "notice", Message: "Looks good"},
+			expected:   "::notice::Looks good",
+		},
+		{
+			annotation: Annotation{Level: "warning", Line: 3, Message: "no file"},
+			expected:   "::warning::no file",
+		},
+	}
+
+	for _, tt := range tests {
+		if got := FormatGitHubAnnotation(tt.annotation); got != tt.expected {
+			t.Errorf("FormatGitHubAnnotation(%+v) = %q, want %q", tt.annotation, got, tt.expected)
+		}
+	}
+}
@@ -0,0 +1,14 @@
+package util
+
+import "regexp"
+
+// ansiEscapeRegex matches terminal escape sequences: CSI sequences such as
+// colours and cursor movement, OSC sequences such as hyperlinks and window
+// titles, other string sequences, and two-character escapes
+var ansiEscapeRegex = regexp.MustCompile(`\x1b(?:\[[0-?]*[ -/]*[@-~]|\][^\x07\x1b]*(?:\x07|\x1b\\)?|[PX^_][^\x1b]*(?:\x1b\\)?|[ -/]*[0-~])`)
+
+// StripANSI removes terminal escape sequences from text, such as the colours
+// in the output of grep --color or test runners
+func StripANSI(text string) string {
+	return ansiEscapeRegex.ReplaceAllString(text, "")
+}
@@ -0,0 +1,30 @@
+package util
+
+import "testing"
+
+func TestStripANSI(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{"plain text", "hello world\n", "hello world\n"},
+		{"colours", "\x1b[31merror\x1b[0m: failed\n", "error: failed\n"},
+		{"grep match", "main.go:\x1b[01;31m\x1b[Kfunc\x1b[m\x1b[K main()", "main.go:func main()"},
+		{"256 colours", "\x1b[38;5;208mwarn\x1b[39m", "warn"},
+		{"cursor movement", "50%\x1b[2K\x1b[1G100%", "50%100%"},
+		{"hyperlink", "\x1b]8;;https://example.com\x1b\\link\x1b]8;;\x1b\\", "link"},
+		{"window title", "\x1b]0;title\x07prompt", "prompt"},
+		{"charset selection", "\x1b(Bbox", "box"},
+		{"keypad mode", "\x1b=text\x1b>", "text"},
+		{"unicode kept", "\x1b[1mcafé ✓\x1b[0m", "café ✓"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := StripANSI(tt.input); got != tt.want {
+				t.Errorf("StripANSI(%q) = %q, want %q", tt.input, got, tt.want)
+			}
+		})
+	}
+}
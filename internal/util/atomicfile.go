@@ -0,0 +1,58 @@
+package util
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// WriteFileAtomic writes content to the file at path, replacing it, or with
+// appendToFile adds content to the end of it. The new file is written
+// alongside and renamed into place, so the file is never left half written.
+// An existing file keeps its permissions, and a symbolic link is followed
+// rather than replaced.
+func WriteFileAtomic(path string, content string, appendToFile bool) error {
+	mode := os.FileMode(0644)
+	var existing []byte
+	if info, err := os.Stat(path); err == nil {
+		if !info.Mode().IsRegular() {
+			return fmt.Errorf("error writing %s: not a regular file", path)
+		}
+		mode = info.Mode().Perm()
+		if path, err = filepath.EvalSymlinks(path); err != nil {
+			return fmt.Errorf("error writing %s: %v", path, err)
+		}
+		if appendToFile {
+			if existing, err = os.ReadFile(path); err != nil {
+				return fmt.Errorf("error reading %s: %v", path, err)
+			}
+		}
+	} else if !os.IsNotExist(err) {
+		return fmt.Errorf("error writing %s: %v", path, err)
+	}
+
+	temp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("error writing %s: %v", path, err)
+	}
+	// Once renamed, the temporary file is gone and this does nothing
+	defer os.Remove(temp.Name())
+
+	_, err = temp.Write(existing)
+	if err == nil {
+		_, err = temp.WriteString(content)
+	}
+	if err == nil {
+		err = temp.Chmod(mode)
+	}
+	if closeErr := temp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(temp.Name(), path)
+	}
+	if err != nil {
+		return fmt.Errorf("error writing %s: %v", path, err)
+	}
+	return nil
+}
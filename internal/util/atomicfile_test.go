@@ -0,0 +1,48 @@
+package util
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestWriteFileAtomic(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "Makefile")
+
+	steps := []struct {
+		content      string
+		appendToFile bool
+		expected     string
+	}{
+		{"all:\n", true, "all:\n"},
+		{"build:\n", false, "build:\n"},
+		{"test:\n", true, "build:\ntest:\n"},
+	}
+
+	for _, step := range steps {
+		if err := WriteFileAtomic(path, step.content, step.appendToFile); err != nil {
+			t.Fatalf("WriteFileAtomic(%q, %t) error: %v", step.content, step.appendToFile, err)
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(data) != step.expected {
+			t.Errorf("After writing %q with append %t the file holds %q, want %q", step.content, step.appendToFile, data, step.expected)
+		}
+	}
+
+	// Nothing is left behind but the file itself
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 {
+		t.Errorf("Expected only the written file in the directory, found %d entries", len(entries))
+	}
+
+	if err := WriteFileAtomic(dir, "x", false); err == nil {
+		t.Error("Expected an error writing over a directory")
+	}
+}
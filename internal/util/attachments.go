@@ -0,0 +1,156 @@
+package util
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/rba100/aipipe/internal/parsing"
+)
+
+const (
+	// MaxAttachmentBytes is the largest file that can be attached to a prompt
+	MaxAttachmentBytes = 256 << 10
+	// MaxAttachmentsBytes is the most that can be attached to one prompt
+	MaxAttachmentsBytes = 1 << 20
+)
+
+// attachmentTrailing are characters that may follow an @path in a sentence
+// without being part of the path, as in "compare @a.go, @b.go."
+const attachmentTrailing = ".,;:!?)]}'\""
+
+// ExpandAttachments attaches the files named as @path in prompt. Each mention
+// is replaced by the path, and the contents of the files are added after the
+// prompt as code blocks tagged with their language, in the order they are
+// first mentioned. A mention must start a word, so email addresses are left
+// alone, and @@ is written as a literal @. Mentions of paths that aren't
+// files are left as written. It returns the prompt and the paths attached.
+func ExpandAttachments(prompt string) (string, []string, error) {
+	if !strings.Contains(prompt, "@") {
+		return prompt, nil, nil
+	}
+
+	var text strings.Builder
+	var attached []string
+	var files strings.Builder
+	seen := map[string]bool{}
+	total := 0
+
+	for i := 0; i < len(prompt); {
+		at := strings.IndexByte(prompt[i:], '@')
+		if at < 0 {
+			text.WriteString(prompt[i:])
+			break
+		}
+		at += i
+		text.WriteString(prompt[i:at])
+		i = at + 1
+
+		if at > 0 && !startsAttachmentWord(prompt[at-1]) {
+			text.WriteByte('@')
+			continue
+		}
+		if strings.HasPrefix(prompt[i:], "@") {
+			// An escaped @, which is never a mention
+			text.WriteByte('@')
+			i++
+			continue
+		}
+
+		word := prompt[i:]
+		if end := strings.IndexAny(word, " \t\r\n"); end >= 0 {
+			word = word[:end]
+		}
+		path := attachmentPath(word)
+		if path == "" {
+			text.WriteByte('@')
+			continue
+		}
+		text.WriteString(path)
+		i += len(path)
+
+		if seen[path] {
+			continue
+		}
+		seen[path] = true
+
+		content, err := readAttachment(path)
+		if err != nil {
+			return "", nil, err
+		}
+		total += len(content)
+		if total > MaxAttachmentsBytes {
+			return "", nil, fmt.Errorf("the attached files come to more than the %d KB limit for one prompt", MaxAttachmentsBytes>>10)
+		}
+		files.WriteString(formatAttachment(path, content))
+		attached = append(attached, path)
+	}
+
+	if len(attached) == 0 {
+		return text.String(), nil, nil
+	}
+	return strings.TrimRight(text.String(), "\n") + "\n\n" + strings.TrimRight(files.String(), "\n") + "\n", attached, nil
+}
+
+// startsAttachmentWord reports whether a mention may follow c
+func startsAttachmentWord(c byte) bool {
+	return strings.IndexByte(" \t\r\n([{\"'", c) >= 0
+}
+
+// attachmentPath returns the longest prefix of word that names a file,
+// dropping punctuation that ends the sentence around it, or an empty string
+// if there isn't one
+func attachmentPath(word string) string {
+	for word != "" {
+		if info, err := os.Stat(word); err == nil && info.Mode().IsRegular() {
+			return word
+		}
+		if !strings.ContainsAny(word[len(word)-1:], attachmentTrailing) {
+			return ""
+		}
+		word = word[:len(word)-1]
+	}
+	return ""
+}
+
+// readAttachment reads a file to attach, refusing ones too large to send or
+// that aren't text
+func readAttachment(path string) (string, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return "", fmt.Errorf("error reading @%s: %v", path, err)
+	}
+	if info.Size() > MaxAttachmentBytes {
+		return "", fmt.Errorf("@%s is %d KB, more than the %d KB limit for an attached file", path, info.Size()>>10, MaxAttachmentBytes>>10)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("error reading @%s: %v", path, err)
+	}
+	if LooksBinary(data[:min(len(data), BinarySniffLength)]) {
+		return "", fmt.Errorf("@%s looks like binary data, not text; only text files can be attached", path)
+	}
+
+	text, _ := DecodeText(data)
+	return NormalizeNewlines(text), nil
+}
+
+// formatAttachment formats an attached file as a code block under its path,
+// with a fence longer than any run of backticks in the file
+func formatAttachment(path string, content string) string {
+	language := parsing.LanguageFromFilename(path)
+	if language == "" {
+		language = strings.TrimPrefix(filepath.Ext(path), ".")
+	}
+
+	fence := "```"
+	for strings.Contains(content, fence) {
+		fence += "`"
+	}
+	if content != "" && !strings.HasSuffix(content, "\n") {
+		content += "\n"
+	}
+	return fmt.Sprintf("%s:\n%s%s\n%s%s\n\n", path, fence, language, content, fence)
+}
@@ -0,0 +1,87 @@
+package util
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestExpandAttachments(t *testing.T) {
+	dir := t.TempDir()
+	mainGo := filepath.Join(dir, "main.go")
+	notes := filepath.Join(dir, "notes")
+	for path, content := range map[string]string{
+		mainGo: "package main\n",
+		notes:  "a ``` fence",
+	} {
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name     string
+		prompt   string
+		expected string
+		attached []string
+	}{
+		{
+			name:     "No mentions",
+			prompt:   "mail me@example.com",
+			expected: "mail me@example.com",
+		},
+		{
+			name:     "File with trailing punctuation",
+			prompt:   "explain @" + mainGo + ".",
+			expected: "explain " + mainGo + ".\n\n" + mainGo + ":\n```go\npackage main\n```\n",
+			attached: []string{mainGo},
+		},
+		{
+			name:   "Repeated mentions and a longer fence",
+			prompt: "compare @" + notes + " with @" + mainGo + " and @" + notes,
+			expected: "compare " + notes + " with " + mainGo + " and " + notes + "\n\n" +
+				notes + ":\n````\na ``` fence\n````\n\n" + mainGo + ":\n```go\npackage main\n```\n",
+			attached: []string{notes, mainGo},
+		},
+		{
+			name:     "Escaped and missing files",
+			prompt:   "@@" + mainGo + " and @nowhere.go",
+			expected: "@" + mainGo + " and @nowhere.go",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, attached, err := ExpandAttachments(tt.prompt)
+			if err != nil {
+				t.Fatalf("ExpandAttachments() error: %v", err)
+			}
+			if result != tt.expected {
+				t.Errorf("ExpandAttachments() = %q, want %q", result, tt.expected)
+			}
+			if !reflect.DeepEqual(attached, tt.attached) {
+				t.Errorf("ExpandAttachments() attached %q, want %q", attached, tt.attached)
+			}
+		})
+	}
+}
+
+func TestExpandAttachmentsLimits(t *testing.T) {
+	dir := t.TempDir()
+	large := filepath.Join(dir, "large.log")
+	binary := filepath.Join(dir, "image.png")
+	if err := os.WriteFile(large, []byte(strings.Repeat("x", MaxAttachmentBytes+1)), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(binary, []byte("\x89PNG\r\n\x1a\n\x00\x00"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, path := range []string{large, binary} {
+		if _, _, err := ExpandAttachments("look at @" + path); err == nil {
+			t.Errorf("Expected an error attaching %s", filepath.Base(path))
+		}
+	}
+}
@@ -0,0 +1,60 @@
+package util
+
+import (
+	"bytes"
+	"unicode/utf8"
+)
+
+// BinarySniffLength is how much of the input LooksBinary needs to see to
+// decide whether it is text
+const BinarySniffLength = 8192
+
+// LooksBinary reports whether data, the start of some input, looks like binary
+// data such as an archive or an image rather than text. Input containing a NUL
+// byte is binary, unless it is UTF-16 text, as is input where more than a
+// tenth of the characters are control characters or invalid UTF-8. Input with
+// no multibyte UTF-8 characters at all is read as Windows-1252 by DecodeText,
+// so only bytes undefined in Windows-1252 count as invalid in it.
+func LooksBinary(data []byte) bool {
+	if len(data) == 0 {
+		return false
+	}
+	if bytes.HasPrefix(data, utf16LEBOM) || bytes.HasPrefix(data, utf16BEBOM) || guessUTF16(data) != "" {
+		return false
+	}
+	if bytes.IndexByte(data, 0) >= 0 {
+		return true
+	}
+
+	latin1 := !hasMultibyteRune(data)
+	suspicious := 0
+	characters := 0
+	for i := 0; i < len(data); {
+		r, size := utf8.DecodeRune(data[i:])
+		if r == utf8.RuneError && size == 1 {
+			// A character cut off at the end of the sample is not an error
+			if len(data)-i < utf8.UTFMax && !utf8.FullRune(data[i:]) {
+				break
+			}
+			if !latin1 || undefinedInWindows1252(data[i]) {
+				suspicious++
+			}
+		} else if r < 0x20 && !isTextControl(r) {
+			suspicious++
+		}
+		characters++
+		i += size
+	}
+
+	return suspicious*10 > characters
+}
+
+// isTextControl reports whether r is a control character commonly found in
+// text: whitespace, backspace and the escape that starts terminal colour codes
+func isTextControl(r rune) bool {
+	switch r {
+	case '\t', '\n', '\r', '\f', '\v', '\b', 0x1b:
+		return true
+	}
+	return false
+}
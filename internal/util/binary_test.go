@@ -0,0 +1,75 @@
+package util
+
+import "testing"
+
+func TestLooksBinary(t *testing.T) {
+	tests := []struct {
+		name     string
+		data     []byte
+		expected bool
+	}{
+		{
+			name:     "Empty",
+			data:     nil,
+			expected: false,
+		},
+		{
+			name:     "Plain text",
+			data:     []byte("hello world\nsecond line\r\n\ttabbed\n"),
+			expected: false,
+		},
+		{
+			name:     "UTF-8 text",
+			data:     []byte("naïve café — ünïcödé 日本語\n"),
+			expected: false,
+		},
+		{
+			name:     "Terminal colours",
+			data:     []byte("\x1b[31merror\x1b[0m: something failed\n"),
+			expected: false,
+		},
+		{
+			name:     "NUL byte",
+			data:     []byte("ustar\x00\x00file.txt"),
+			expected: true,
+		},
+		{
+			name:     "Gzip header",
+			data:     []byte{0x1f, 0x8b, 0x08, 0x00, 0x12, 0x34, 0x56, 0x78, 0x00, 0x03, 0xcb, 0x48, 0xcd},
+			expected: true,
+		},
+		{
+			name:     "Invalid UTF-8",
+			data:     []byte{0x89, 'P', 'N', 'G', '\r', '\n', 0x1a, '\n', 0xc3, 0xff, 0xfe, 0x90},
+			expected: true,
+		},
+		{
+			name:     "Character cut off at the end",
+			data:     []byte("price: 10 \xe2\x82"),
+			expected: false,
+		},
+		{
+			name:     "UTF-16 with byte order mark",
+			data:     []byte{0xff, 0xfe, 'h', 0, 'i', 0, '\n', 0},
+			expected: false,
+		},
+		{
+			name:     "Latin-1 text",
+			data:     []byte("caf\xe9\n"),
+			expected: false,
+		},
+		{
+			name:     "UTF-16 without byte order mark",
+			data:     []byte{'h', 0, 'e', 0, 'l', 0, 'l', 0, 'o', 0, '\n', 0},
+			expected: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := LooksBinary(tt.data); got != tt.expected {
+				t.Errorf("LooksBinary(%q) = %v, expected %v", tt.data, got, tt.expected)
+			}
+		})
+	}
+}
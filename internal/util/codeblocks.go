@@ -1,14 +1,22 @@
 package util
 
 import (
+	"context"
+	"fmt"
+	"path/filepath"
 	"regexp"
 	"strings"
+
+	"github.com/rba100/aipipe/internal/stream"
 )
 
 // CodeBlockResult represents the result of extracting a code block
 type CodeBlockResult struct {
 	Text string
 	Type string
+	// Index is the zero-based position of the block in the input. It is only
+	// set by ExtractCodeBlocksStream.
+	Index int
 }
 
 // ExtractCodeBlock extracts a code block from a string
@@ -28,6 +36,99 @@ func ExtractCodeBlock(input string) CodeBlockResult {
 	}
 }
 
+// ExtractAllCodeBlocks returns every fenced code block in input, in order,
+// following the same rules as ExtractCodeBlocksStream
+func ExtractAllCodeBlocks(input string) []CodeBlockResult {
+	var blocks []CodeBlockResult
+	for result := range ExtractCodeBlocksStream(context.Background(), stream.Of(input)) {
+		if len(blocks) == 0 || blocks[len(blocks)-1].Index != result.Index {
+			blocks = append(blocks, CodeBlockResult{Type: result.Type, Index: result.Index})
+		}
+		blocks[len(blocks)-1].Text += result.Text
+	}
+
+	// Like ExtractCodeBlock, leave off the newline before the closing fence
+	for i := range blocks {
+		blocks[i].Text = strings.TrimSuffix(strings.TrimSuffix(blocks[i].Text, "\n"), "\r")
+	}
+	return blocks
+}
+
+// SelectCodeBlock returns the nth code block of input, counting from 1, as
+// numbered by ExtractAllCodeBlocks. An answer without any code blocks is
+// returned whole as its first block, for models that leave off the fences.
+func SelectCodeBlock(input string, n int) (CodeBlockResult, error) {
+	if n < 1 {
+		return CodeBlockResult{}, fmt.Errorf("code blocks are numbered from 1")
+	}
+
+	blocks := ExtractAllCodeBlocks(input)
+	if len(blocks) == 0 && n == 1 {
+		return CodeBlockResult{Text: input}, nil
+	}
+	if n > len(blocks) {
+		return CodeBlockResult{}, fmt.Errorf("there is no code block %d; the answer has %d", n, len(blocks))
+	}
+	return blocks[n-1], nil
+}
+
+// SelectCodeBlockStream streams the nth code block of a stream, counting from
+// 1, as SelectCodeBlock selects it from the whole text. Like
+// ExtractAllCodeBlocks it leaves off the newline before the closing fence.
+// A stream without any code blocks is passed on whole once it ends.
+func SelectCodeBlockStream(ctx context.Context, inputStream <-chan string, n int) <-chan CodeBlockResult {
+	var input strings.Builder
+	lines := ExtractCodeBlocksStream(ctx, stream.Tee(ctx, inputStream, func(part string) {
+		input.WriteString(part)
+	}))
+
+	// Blocks are counted as they are seen, as ExtractAllCodeBlocks counts
+	// them, and each line's terminator is held back until the next line
+	// shows it isn't the last. Blocks after the nth are read and dropped
+	// rather than ending the stage, so the stages before it see the whole
+	// stream before the output closes.
+	seen := 0
+	lastIndex := -1
+	terminator := ""
+
+	step := func(result CodeBlockResult, emit stream.Emit[CodeBlockResult]) bool {
+		if result.Index != lastIndex {
+			seen++
+			lastIndex = result.Index
+		}
+		if seen != n {
+			return true
+		}
+
+		text := strings.TrimSuffix(strings.TrimSuffix(result.Text, "\n"), "\r")
+		if !strings.HasSuffix(result.Text, "\n") {
+			text = result.Text
+		}
+		output := terminator + text
+		terminator = result.Text[len(text):]
+		return emit(CodeBlockResult{Text: output, Type: result.Type, Index: result.Index})
+	}
+
+	flush := func(emit stream.Emit[CodeBlockResult]) {
+		if seen == 0 && n == 1 && input.Len() > 0 {
+			emit(CodeBlockResult{Text: input.String()})
+		}
+	}
+
+	return stream.Transform(ctx, lines, step, flush)
+}
+
+// NumberedPath returns path with -n added before its extension, such as
+// main-2.go for main.go, for writing several code blocks to files
+func NumberedPath(path string, n int) string {
+	ext := filepath.Ext(path)
+	if ext == filepath.Base(path) {
+		// A dot file such as .env has no extension
+		ext = ""
+	}
+	return fmt.Sprintf("%s-%d%s", strings.TrimSuffix(path, ext), n, ext)
+}
+
 // CodeBlockState represents the state of code block extraction
 type CodeBlockState int
 
@@ -37,105 +138,186 @@ const (
 	Closed
 )
 
-// ExtractCodeBlockStream extracts code blocks from a stream
-func ExtractCodeBlockStream(inputStream <-chan string) <-chan CodeBlockResult {
-	outputStream := make(chan CodeBlockResult)
+// ExtractCodeBlockStream extracts the first code block from a stream
+func ExtractCodeBlockStream(ctx context.Context, inputStream <-chan string) <-chan CodeBlockResult {
 	openingRe := regexp.MustCompile("```([a-zA-Z0-9.]*)(?:\n)")
 	potentialClosingRe := regexp.MustCompile("\n`{0,2}$")
 	potentialNoNewLineClosingRe := regexp.MustCompile("^`{1,2}$")
 
-	go func() {
-		defer close(outputStream)
+	buffer := strings.Builder{}
+	state := SearchingOpening
+	var blockType string = ""
+	var totalCharsEmitted int = 0
 
-		buffer := strings.Builder{}
-		state := SearchingOpening
-		var blockType string = ""
-		var totalCharsEmitted int = 0
+	step := func(part string, emit stream.Emit[CodeBlockResult]) bool {
+		buffer.WriteString(part)
+		bufStr := buffer.String()
 
-		for part := range inputStream {
-			if state == Closed {
-				break
-			}
+		if state == SearchingOpening {
+			// Look for opening marker with optional language type
+			match := openingRe.FindStringSubmatchIndex(bufStr)
 
-			buffer.WriteString(part)
-			bufStr := buffer.String()
-
-			if state == SearchingOpening {
-				// Look for opening marker with optional language type
-				match := openingRe.FindStringSubmatchIndex(bufStr)
-
-				if len(match) > 0 {
-					// Extract the language type if present
-					if match[2] != -1 && match[3] != -1 {
-						blockType = bufStr[match[2]:match[3]]
-					}
-
-					// Move to the content after the opening marker
-					remainingContent := bufStr[match[1]:]
-					buffer.Reset()
-					buffer.WriteString(remainingContent)
-					state = Open
-					continue
+			if len(match) > 0 {
+				// Extract the language type if present
+				if match[2] != -1 && match[3] != -1 {
+					blockType = bufStr[match[2]:match[3]]
 				}
-			}
 
-			if state == Open {
-				// Check for potential closing marker at the end
-				if potentialClosingRe.MatchString(bufStr) {
-					continue
-				}
-
-				if totalCharsEmitted == 0 && potentialNoNewLineClosingRe.MatchString(bufStr) {
-					continue
-				}
+				// Move to the content after the opening marker
+				remainingContent := bufStr[match[1]:]
+				buffer.Reset()
+				buffer.WriteString(remainingContent)
+				state = Open
+				return true
+			}
+		}
 
-				// Check for actual closing marker
-				closePos := strings.Index(bufStr, "\n```")
-				if closePos >= 0 {
-					output := bufStr[:closePos]
-					state = Closed
-					buffer.Reset()
-					outputStream <- CodeBlockResult{
-						Text: output,
-						Type: blockType,
-					}
-					totalCharsEmitted += len(output)
-					break
-				}
+		if state == Open {
+			// Check for potential closing marker at the end
+			if potentialClosingRe.MatchString(bufStr) {
+				return true
+			}
 
-				// Check for rare case of empty code block
-				if totalCharsEmitted == 0 && strings.HasPrefix(bufStr, "```") {
-					buffer.Reset()
-					state = Closed
-					break
-				}
+			if totalCharsEmitted == 0 && potentialNoNewLineClosingRe.MatchString(bufStr) {
+				return true
+			}
 
-				// If we're still processing and have content, return it and clear buffer
-				output := bufStr
+			// Check for actual closing marker, after which there is nothing
+			// more to read
+			closePos := strings.Index(bufStr, "\n```")
+			if closePos >= 0 {
+				output := bufStr[:closePos]
+				state = Closed
 				buffer.Reset()
-				outputStream <- CodeBlockResult{
+				emit(CodeBlockResult{
 					Text: output,
 					Type: blockType,
-				}
+				})
 				totalCharsEmitted += len(output)
+				return false
+			}
+
+			// Check for rare case of empty code block
+			if totalCharsEmitted == 0 && strings.HasPrefix(bufStr, "```") {
+				buffer.Reset()
+				state = Closed
+				return false
 			}
+
+			// If we're still processing and have content, return it and clear buffer
+			output := bufStr
+			buffer.Reset()
+			totalCharsEmitted += len(output)
+			return emit(CodeBlockResult{
+				Text: output,
+				Type: blockType,
+			})
 		}
 
-		// If we never closed the code block but have content, return what we have
-		if state != Closed && buffer.Len() > 0 {
-			remainingContent := buffer.String()
+		return true
+	}
+
+	// If we never closed the code block but have content, return what we have
+	flush := func(emit stream.Emit[CodeBlockResult]) {
+		if state == Closed || buffer.Len() == 0 {
+			return
+		}
+		remainingContent := buffer.String()
+		if strings.HasPrefix(remainingContent, "```") {
+			return
+		}
+		emit(CodeBlockResult{
+			Text: remainingContent,
+			Type: blockType,
+		})
+	}
+
+	return stream.Transform(ctx, inputStream, step, flush)
+}
+
+// ExtractCodeBlocksStream extracts every fenced code block from a stream. Unlike
+// ExtractCodeBlockStream it does not stop at the first block, and fences must
+// start their line, so ```code``` in the middle of a sentence isn't a block.
+// A closing fence may end a line of code, and a block may open and close on
+// one line. Content is emitted a line at a time, including the line
+// terminator, with Index identifying which block the line belongs to.
+func ExtractCodeBlocksStream(ctx context.Context, inputStream <-chan string) <-chan CodeBlockResult {
+	openingRe := regexp.MustCompile("^(\\s*)```([a-zA-Z0-9.+#-]*)\\s*$")
+	closingRe := regexp.MustCompile("^\\s*```\\s*$")
+	oneLineRe := regexp.MustCompile("^\\s*```([^`].*?)```\\s*$")
+
+	buffer := strings.Builder{}
+	inBlock := false
+	blockType := ""
+	indent := ""
+	index := -1
 
-			if strings.HasPrefix(remainingContent, "```") {
-				return
+	processLine := func(line string, terminator string, emit stream.Emit[CodeBlockResult]) bool {
+		trimmed := strings.TrimSuffix(line, "\r")
+		if !inBlock {
+			if match := openingRe.FindStringSubmatch(trimmed); match != nil {
+				inBlock = true
+				indent = match[1]
+				blockType = match[2]
+				index++
+			} else if match := oneLineRe.FindStringSubmatch(trimmed); match != nil {
+				index++
+				return emit(CodeBlockResult{
+					Text:  strings.TrimSpace(match[1]) + terminator,
+					Index: index,
+				})
 			}
+			return true
+		}
 
-			outputStream <- CodeBlockResult{
-				Text: remainingContent,
-				Type: blockType,
+		if closingRe.MatchString(trimmed) {
+			inBlock = false
+			return true
+		}
+
+		// A closing fence at the end of the last line of code
+		if code, ok := strings.CutSuffix(strings.TrimRight(trimmed, " \t"), "```"); ok {
+			inBlock = false
+			return emit(CodeBlockResult{
+				Text:  strings.TrimPrefix(code, indent) + terminator,
+				Type:  blockType,
+				Index: index,
+			})
+		}
+
+		return emit(CodeBlockResult{
+			Text:  strings.TrimPrefix(line, indent) + terminator,
+			Type:  blockType,
+			Index: index,
+		})
+	}
+
+	step := func(part string, emit stream.Emit[CodeBlockResult]) bool {
+		buffer.WriteString(part)
+		bufStr := buffer.String()
+
+		lastNewline := strings.LastIndex(bufStr, "\n")
+		if lastNewline < 0 {
+			return true
+		}
+
+		for _, line := range strings.Split(bufStr[:lastNewline], "\n") {
+			if !processLine(line, "\n", emit) {
+				return false
 			}
-			totalCharsEmitted += len(remainingContent)
 		}
-	}()
 
-	return outputStream
+		buffer.Reset()
+		buffer.WriteString(bufStr[lastNewline+1:])
+		return true
+	}
+
+	// Handle a final line without a terminator
+	flush := func(emit stream.Emit[CodeBlockResult]) {
+		if buffer.Len() > 0 {
+			processLine(buffer.String(), "", emit)
+		}
+	}
+
+	return stream.Transform(ctx, inputStream, step, flush)
 }
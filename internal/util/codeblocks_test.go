@@ -1,8 +1,13 @@
 package util
 
 import (
+	"context"
+	"reflect"
+	"strings"
 	"testing"
 	"time"
+
+	"github.com/rba100/aipipe/internal/stream"
 )
 
 func TestExtractCodeBlock(t *testing.T) {
@@ -250,7 +255,7 @@ func TestExtractCodeBlockStream(t *testing.T) {
 			}()
 
 			// Get output channel
-			outputChan := ExtractCodeBlockStream(inputChan)
+			outputChan := ExtractCodeBlockStream(context.Background(), inputChan)
 
 			// Collect results
 			var results []CodeBlockResult
@@ -269,3 +274,280 @@ func TestExtractCodeBlockStream(t *testing.T) {
 		})
 	}
 }
+
+func TestExtractCodeBlocksStream(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    []string
+		expected []CodeBlockResult
+	}{
+		{
+			name: "Multiple blocks",
+			input: []string{
+				"First:\n```go\nfunc main() {}\n```\n",
+				"Second:\n```py",
+				"thon\nprint('hi')\nprint('bye')\n``",
+				"`\nDone.",
+			},
+			expected: []CodeBlockResult{
+				{Text: "func main() {}\n", Type: "go", Index: 0},
+				{Text: "print('hi')\nprint('bye')\n", Type: "python", Index: 1},
+			},
+		},
+		{
+			name: "Inline fences are ignored",
+			input: []string{
+				"Use ```code``` sparingly.\n",
+				"```\nls -la\n```",
+			},
+			expected: []CodeBlockResult{
+				{Text: "ls -la\n", Type: "", Index: 0},
+			},
+		},
+		{
+			name: "Closing fence after the code",
+			input: []string{
+				"```python\nx = 1\ny = 2```\nDone.",
+			},
+			expected: []CodeBlockResult{
+				{Text: "x = 1\ny = 2\n", Type: "python", Index: 0},
+			},
+		},
+		{
+			name: "Block on one line",
+			input: []string{
+				"```x = 1```\n",
+				"Then:\n```sh\nls\n```",
+			},
+			expected: []CodeBlockResult{
+				{Text: "x = 1\n", Type: "", Index: 0},
+				{Text: "ls\n", Type: "sh", Index: 1},
+			},
+		},
+		{
+			name: "Indented block inside a list",
+			input: []string{
+				"1. Run this:\n   ```bash\n   make build\n   ```\n",
+			},
+			expected: []CodeBlockResult{
+				{Text: "make build\n", Type: "bash", Index: 0},
+			},
+		},
+		{
+			name: "Unterminated block",
+			input: []string{
+				"```json\n{\"a\": 1}",
+			},
+			expected: []CodeBlockResult{
+				{Text: "{\"a\": 1}", Type: "json", Index: 0},
+			},
+		},
+		{
+			name: "No code blocks",
+			input: []string{
+				"Just some text\nover two lines",
+			},
+			expected: nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			inputChan := make(chan string)
+			go func() {
+				defer close(inputChan)
+				for _, part := range tt.input {
+					inputChan <- part
+				}
+			}()
+
+			// Join consecutive lines of the same block to compare whole blocks
+			var results []CodeBlockResult
+			for part := range ExtractCodeBlocksStream(context.Background(), inputChan) {
+				if len(results) > 0 && results[len(results)-1].Index == part.Index {
+					results[len(results)-1].Text += part.Text
+					continue
+				}
+				results = append(results, part)
+			}
+
+			if len(results) != len(tt.expected) {
+				t.Fatalf("ExtractCodeBlocksStream() returned %d blocks, want %d: %+v", len(results), len(tt.expected), results)
+			}
+			for i := range results {
+				if results[i] != tt.expected[i] {
+					t.Errorf("block %d = %+v, want %+v", i, results[i], tt.expected[i])
+				}
+			}
+		})
+	}
+}
+
+func TestExtractAllCodeBlocks(t *testing.T) {
+	input := "First:\n```go\nfunc main() {}\n```\nSecond:\n```python\nprint('hi')\nprint('bye')\n```\nDone."
+	blocks := ExtractAllCodeBlocks(input)
+
+	expected := []CodeBlockResult{
+		{Text: "func main() {}", Type: "go", Index: 0},
+		{Text: "print('hi')\nprint('bye')", Type: "python", Index: 1},
+	}
+	if !reflect.DeepEqual(blocks, expected) {
+		t.Errorf("ExtractAllCodeBlocks() = %+v, want %+v", blocks, expected)
+	}
+
+	if blocks := ExtractAllCodeBlocks("no code here"); len(blocks) != 0 {
+		t.Errorf("ExtractAllCodeBlocks() without code blocks = %+v, want none", blocks)
+	}
+}
+
+func TestSelectCodeBlock(t *testing.T) {
+	input := "First:\n```go\nfunc main() {}\n```\nSecond:\n```python\nprint('hi')\n```\nDone."
+
+	tests := []struct {
+		name     string
+		input    string
+		n        int
+		expected CodeBlockResult
+		wantErr  bool
+	}{
+		{
+			name:     "First block",
+			input:    input,
+			n:        1,
+			expected: CodeBlockResult{Text: "func main() {}", Type: "go"},
+		},
+		{
+			name:     "Second block",
+			input:    input,
+			n:        2,
+			expected: CodeBlockResult{Text: "print('hi')", Type: "python", Index: 1},
+		},
+		{
+			name:     "No code blocks",
+			input:    "no code here",
+			n:        1,
+			expected: CodeBlockResult{Text: "no code here"},
+		},
+		{
+			name:     "Inline fences don't count",
+			input:    "Wrap it in ```x``` like this:\n```sh\necho hi\n```\n",
+			n:        1,
+			expected: CodeBlockResult{Text: "echo hi", Type: "sh"},
+		},
+		{
+			name:     "Block on one line",
+			input:    "```x = 1```",
+			n:        1,
+			expected: CodeBlockResult{Text: "x = 1"},
+		},
+		{
+			name:     "Closing fence after the code",
+			input:    "Try:\n```go\nfmt.Println(1)```\n",
+			n:        1,
+			expected: CodeBlockResult{Text: "fmt.Println(1)", Type: "go"},
+		},
+		{
+			name:     "Indented fences",
+			input:    "1. Run:\n   ```sh\n   make\n   ```\n2. Then:\n   ```sh\n   make test\n   ```\n",
+			n:        2,
+			expected: CodeBlockResult{Text: "make test", Type: "sh", Index: 1},
+		},
+		{
+			name:    "Past the last block",
+			input:   input,
+			n:       3,
+			wantErr: true,
+		},
+		{
+			name:    "Zero",
+			input:   input,
+			n:       0,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := SelectCodeBlock(tt.input, tt.n)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("SelectCodeBlock() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if result != tt.expected {
+				t.Errorf("SelectCodeBlock() = %+v, want %+v", result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestSelectCodeBlockStream(t *testing.T) {
+	inputs := []string{
+		"First:\n```go\nfunc main() {}\n```\nSecond:\n```python\nprint('hi')\r\nprint('bye')\r\n```\nDone.",
+		"Wrap it in ```x``` like this:\n```sh\necho hi\n```",
+		"```\nunclosed\nblock",
+		"```x = 1```",
+		"```go\nfmt.Println(1)```\nNext:\n```sh\nls\n```\n",
+		"no code here",
+	}
+
+	for _, input := range inputs {
+		for n := 1; n <= 2; n++ {
+			expected, err := SelectCodeBlock(input, n)
+			if err != nil {
+				expected = CodeBlockResult{}
+			}
+
+			// Every way of splitting the stream gives the same block
+			for size := 1; size <= len(input); size++ {
+				var parts []string
+				for rest := input; len(rest) > 0; rest = rest[min(size, len(rest)):] {
+					parts = append(parts, rest[:min(size, len(rest))])
+				}
+
+				var result CodeBlockResult
+				for part := range SelectCodeBlockStream(context.Background(), stream.Of(parts...), n) {
+					result.Text += part.Text
+					result.Type = part.Type
+					result.Index = part.Index
+				}
+				if result != expected {
+					t.Fatalf("SelectCodeBlockStream(%q, %d) in parts of %d = %+v, want %+v", input, n, size, result, expected)
+				}
+			}
+		}
+	}
+}
+
+func TestSelectCodeBlockStreamReadsWholeStream(t *testing.T) {
+	parts := []string{"```go\nfirst\n```\n", "```go\nsecond\n```\n", "trailing text"}
+
+	// The reply is collected before the selector, as the query does, and must
+	// be complete once the selected block's stream closes
+	var reply strings.Builder
+	upstream := stream.Tee(context.Background(), stream.Of(parts...), func(part string) {
+		reply.WriteString(part)
+	})
+	for range SelectCodeBlockStream(context.Background(), upstream, 1) {
+	}
+
+	if expected := strings.Join(parts, ""); reply.String() != expected {
+		t.Errorf("reply = %q, want %q", reply.String(), expected)
+	}
+}
+
+func TestNumberedPath(t *testing.T) {
+	tests := []struct {
+		path     string
+		expected string
+	}{
+		{path: "main.go", expected: "main-2.go"},
+		{path: "out/archive.tar.gz", expected: "out/archive.tar-2.gz"},
+		{path: "Makefile", expected: "Makefile-2"},
+		{path: "config/.env", expected: "config/.env-2"},
+	}
+
+	for _, tt := range tests {
+		if result := NumberedPath(tt.path, 2); result != tt.expected {
+			t.Errorf("NumberedPath(%q) = %q, want %q", tt.path, result, tt.expected)
+		}
+	}
+}
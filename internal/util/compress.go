@@ -0,0 +1,94 @@
+package util
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+var (
+	// logNoiseRegex matches the parts of log lines that change from one line
+	// to the next without changing what it says: timestamps, numbers, hex ids
+	logNoiseRegex = regexp.MustCompile(`\b(\d{4}-\d{2}-\d{2}[T ]\d{2}:\d{2}:\d{2}(\.\d+)?(Z|[+-]\d{2}:?\d{2})?|\d{2}:\d{2}:\d{2}(\.\d+)?|0x[0-9a-fA-F]+|[0-9a-fA-F]{8,}|\d+(\.\d+)?)\b`)
+	// proseRegex matches lines made only of words and sentence punctuation,
+	// which are safe to remove stopwords from
+	proseRegex = regexp.MustCompile(`^[\p{L}\p{N}\s,.;:!?'"()-]+$`)
+)
+
+// stopwords are common English words that carry little meaning, removed from
+// prose lines when compressing
+var stopwords = map[string]bool{
+	"a": true, "an": true, "the": true, "and": true, "of": true, "to": true,
+	"in": true, "on": true, "at": true, "for": true, "with": true, "by": true,
+	"is": true, "are": true, "was": true, "were": true, "be": true, "been": true,
+	"that": true, "this": true, "these": true, "those": true, "it": true,
+	"its": true, "as": true, "so": true, "very": true, "just": true,
+	"really": true, "quite": true, "also": true, "then": true, "there": true,
+}
+
+// minimumSimilarLines is how many consecutive lines must differ only in
+// numbers and timestamps before they are folded into one
+const minimumSimilarLines = 3
+
+// CompressInput shortens text before it is sent to a model, to cut the
+// tokens spent on long inputs such as logs. Trailing spaces and repeated
+// blank lines are removed, runs of lines that differ only in timestamps and
+// numbers are folded into the first of them with a count, and common
+// stopwords are dropped from lines of plain prose. Code and structured text
+// keep their stopwords.
+func CompressInput(text string) string {
+	lines := strings.Split(text, "\n")
+	var result []string
+
+	for i := 0; i < len(lines); {
+		line := strings.TrimRight(lines[i], " \t\r")
+
+		// Keep one blank line of a run
+		if line == "" {
+			if len(result) == 0 || result[len(result)-1] != "" {
+				result = append(result, "")
+			}
+			i++
+			continue
+		}
+
+		// Fold runs of similar lines
+		pattern := logNoiseRegex.ReplaceAllString(line, "#")
+		run := 1
+		for i+run < len(lines) && logNoiseRegex.ReplaceAllString(strings.TrimRight(lines[i+run], " \t\r"), "#") == pattern {
+			run++
+		}
+		if run > 1 && (run >= minimumSimilarLines || strings.TrimRight(lines[i+1], " \t\r") == line) {
+			result = append(result, fmt.Sprintf("%s [and %d more like this]", line, run-1))
+			i += run
+			continue
+		}
+
+		result = append(result, removeStopwords(line))
+		i++
+	}
+
+	return strings.Join(result, "\n")
+}
+
+// removeStopwords drops stopwords from a line of prose, keeping its
+// indentation. Other lines are returned as they are.
+func removeStopwords(line string) string {
+	if !proseRegex.MatchString(line) {
+		return line
+	}
+
+	words := strings.Fields(line)
+	kept := words[:0]
+	for _, word := range words {
+		if !stopwords[strings.ToLower(strings.Trim(word, ",.;:!?'\"()"))] {
+			kept = append(kept, word)
+		}
+	}
+	if len(kept) == 0 {
+		return line
+	}
+
+	indent := line[:len(line)-len(strings.TrimLeft(line, " \t"))]
+	return indent + strings.Join(kept, " ")
+}
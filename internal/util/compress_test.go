@@ -0,0 +1,54 @@
+package util
+
+import "testing"
+
+func TestCompressInput(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{
+			name:     "Stopwords are removed from prose",
+			input:    "The server is down and the logs are in the usual place.",
+			expected: "server down logs usual place.",
+		},
+		{
+			name:     "Code is unchanged",
+			input:    "if (a == the) {\n    return this.value;\n}",
+			expected: "if (a == the) {\n    return this.value;\n}",
+		},
+		{
+			name:     "Repeated lines are folded",
+			input:    "ERROR connection refused\nERROR connection refused\nok",
+			expected: "ERROR connection refused [and 1 more like this]\nok",
+		},
+		{
+			name: "Lines differing in timestamps are folded",
+			input: "2024-05-01T10:00:01Z retry 1 failed: timeout\n" +
+				"2024-05-01T10:00:02Z retry 2 failed: timeout\n" +
+				"2024-05-01T10:00:04Z retry 3 failed: timeout\n" +
+				"2024-05-01T10:00:08Z giving up",
+			expected: "2024-05-01T10:00:01Z retry 1 failed: timeout [and 2 more like this]\n" +
+				"2024-05-01T10:00:08Z giving up",
+		},
+		{
+			name:     "Two similar lines are kept",
+			input:    "step 1: build\nstep 2: test",
+			expected: "step 1: build\nstep 2: test",
+		},
+		{
+			name:     "Blank lines and trailing spaces",
+			input:    "first:   \n\n\n\nsecond:\t",
+			expected: "first:\n\nsecond:",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := CompressInput(tt.input); got != tt.expected {
+				t.Errorf("CompressInput(%q) = %q, expected %q", tt.input, got, tt.expected)
+			}
+		})
+	}
+}
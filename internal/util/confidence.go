@@ -0,0 +1,35 @@
+package util
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// confidenceScoreRegex matches the line with the confidence score asked for
+// by llm.GetConfidenceScorePrompt, allowing for markdown emphasis and a
+// percentage
+var confidenceScoreRegex = regexp.MustCompile(`(?im)^[\s*_]*confidence[\s*_]*[:=][\s*_]*(\d+(?:\.\d+)?|\.\d+)\s*(%?)[\s*_]*$`)
+
+// ExtractConfidenceScore removes the confidence score line from a response,
+// returning the rest of the response and the score from 0 to 1. Scores given
+// as percentages are converted. If there are several, the last counts. ok is
+// false if the response has no score.
+func ExtractConfidenceScore(response string) (answer string, score float64, ok bool) {
+	matches := confidenceScoreRegex.FindAllStringSubmatchIndex(response, -1)
+	if len(matches) == 0 {
+		return response, 0, false
+	}
+
+	match := matches[len(matches)-1]
+	score, err := strconv.ParseFloat(response[match[2]:match[3]], 64)
+	if err != nil {
+		return response, 0, false
+	}
+	if match[5] > match[4] || score > 1 {
+		score /= 100
+	}
+
+	answer = strings.TrimRight(response[:match[0]], " \t\n") + response[match[1]:]
+	return strings.TrimRight(answer, " \t\n"), min(score, 1), true
+}
@@ -0,0 +1,65 @@
+package util
+
+import "testing"
+
+func TestExtractConfidenceScore(t *testing.T) {
+	tests := []struct {
+		name     string
+		response string
+		answer   string
+		score    float64
+		ok       bool
+	}{
+		{
+			name:     "Score on the last line",
+			response: "Use `git rebase -i`.\n\nCONFIDENCE: 0.85\n",
+			answer:   "Use `git rebase -i`.",
+			score:    0.85,
+			ok:       true,
+		},
+		{
+			name:     "Percentage",
+			response: "Yes.\nConfidence: 40%",
+			answer:   "Yes.",
+			score:    0.4,
+			ok:       true,
+		},
+		{
+			name:     "Percentage without a sign",
+			response: "Yes.\nconfidence: 90",
+			answer:   "Yes.",
+			score:    0.9,
+			ok:       true,
+		},
+		{
+			name:     "Markdown emphasis",
+			response: "Yes.\n\n**Confidence:** 0.3",
+			answer:   "Yes.",
+			score:    0.3,
+			ok:       true,
+		},
+		{
+			name:     "Last score counts",
+			response: "The old confidence: 0.9 rule\nconfidence: 0.9\nActually no.\nconfidence: 0.2",
+			answer:   "The old confidence: 0.9 rule\nconfidence: 0.9\nActually no.",
+			score:    0.2,
+			ok:       true,
+		},
+		{
+			name:     "No score",
+			response: "My confidence is high.",
+			answer:   "My confidence is high.",
+			score:    0,
+			ok:       false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			answer, score, ok := ExtractConfidenceScore(tt.response)
+			if answer != tt.answer || score != tt.score || ok != tt.ok {
+				t.Errorf("ExtractConfidenceScore(%q) = %q, %v, %v, want %q, %v, %v", tt.response, answer, score, ok, tt.answer, tt.score, tt.ok)
+			}
+		})
+	}
+}
@@ -0,0 +1,287 @@
+package util
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// configKind is the type of value a config key takes
+type configKind int
+
+const (
+	configString configKind = iota
+	configStringMap
+	configStringList
+	configBool
+	configIntMap
+	configInt
+)
+
+// configKey describes a key accepted in config.yaml
+type configKey struct {
+	// name is the key as spelled in the documentation
+	name string
+	kind configKind
+	// values, if not empty, lists the values accepted for a string, or for
+	// each item of a list
+	values []string
+}
+
+// configSchema lists the keys accepted in config.yaml, keyed by lowercased
+// name since keys are matched case-insensitively
+var configSchema = map[string]configKey{
+	"endpoint":         {name: "endpoint", kind: configString},
+	"apikey":           {name: "apiKey", kind: configString},
+	"defaultmodel":     {name: "defaultModel", kind: configString},
+	"fastmodel":        {name: "fastModel", kind: configString},
+	"reasoningmodel":   {name: "reasoningModel", kind: configString},
+	"localmodel":       {name: "localModel", kind: configString},
+	"localbaseurl":     {name: "localBaseUrl", kind: configString},
+	"parsers":          {name: "parsers", kind: configStringMap},
+	"languagealiases":  {name: "languageAliases", kind: configStringMap},
+	"formatters":       {name: "formatters", kind: configStringMap},
+	"contextvars":      {name: "contextVars", kind: configStringList, values: ContextVariables},
+	"memory":           {name: "memory", kind: configBool},
+	"memoryextraction": {name: "memoryExtraction", kind: configBool},
+	"prompthistory":    {name: "promptHistory", kind: configBool},
+	"highlighter":      {name: "highlighter", kind: configString, values: []string{"regex", "treesitter"}},
+	"snippetsource":    {name: "snippetSource", kind: configString},
+	"pricesurl":        {name: "pricesUrl", kind: configString},
+	"sharetarget":      {name: "shareTarget", kind: configString},
+	"fileheader":       {name: "fileHeader", kind: configString},
+	"user":             {name: "user", kind: configString},
+	"metadata":         {name: "metadata", kind: configStringMap},
+	"headers":          {name: "headers", kind: configStringMap},
+	"organization":     {name: "organization", kind: configString},
+	"project":          {name: "project", kind: configString},
+	"readonly":         {name: "readOnly", kind: configBool},
+	"inputrole":        {name: "inputRole", kind: configString, values: []string{"prompt", "user", "system"}},
+	"provider":         {name: "provider", kind: configString, values: []string{"openai", "anthropic"}},
+	"contextwindows":   {name: "contextWindows", kind: configIntMap},
+	"streambuffer":     {name: "streamBuffer", kind: configInt},
+	"accessible":       {name: "accessible", kind: configBool},
+}
+
+// ValidateConfig checks config.yaml for keys aipipe doesn't know and values of
+// the wrong type, which would otherwise be ignored. It returns the path of the
+// file and a description of each problem, or no problems if there is no file.
+func ValidateConfig() (string, []string, error) {
+	path, configMap, err := readConfigFile()
+	if err != nil {
+		return path, nil, err
+	}
+	return path, validateConfigMap(configMap), nil
+}
+
+// validateConfigMap checks the top-level keys of a config file, as written,
+// against configSchema
+func validateConfigMap(configMap map[string]interface{}) []string {
+	keys := make([]string, 0, len(configMap))
+	for key := range configMap {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var problems []string
+	seen := make(map[string]string)
+	for _, key := range keys {
+		lower := strings.ToLower(key)
+		schema, ok := configSchema[lower]
+		if !ok {
+			problems = append(problems, fmt.Sprintf("unknown key %q%s", key, didYouMean(lower, configKeyNames())))
+			continue
+		}
+
+		if previous, ok := seen[lower]; ok {
+			problems = append(problems, fmt.Sprintf("%q and %q are the same key; only one is used", previous, key))
+			continue
+		}
+		seen[lower] = key
+
+		if problem := validateConfigValue(schema, configMap[key]); problem != "" {
+			problems = append(problems, fmt.Sprintf("%s: %s", key, problem))
+		}
+	}
+
+	return problems
+}
+
+// validateConfigValue checks the value of a key, returning a description of
+// the problem or an empty string
+func validateConfigValue(schema configKey, value interface{}) string {
+	switch schema.kind {
+	case configString:
+		str, ok := value.(string)
+		if !ok {
+			return fmt.Sprintf("should be a string, not %s", describeYAMLValue(value))
+		}
+		return validateChoice(schema, str)
+
+	case configStringMap:
+		mapping, ok := value.(map[string]interface{})
+		if !ok {
+			return fmt.Sprintf("should be a mapping of names to strings, not %s", describeYAMLValue(value))
+		}
+		var names []string
+		for name, item := range mapping {
+			if _, ok := item.(string); !ok {
+				names = append(names, name)
+			}
+		}
+		if len(names) > 0 {
+			sort.Strings(names)
+			return fmt.Sprintf("the values of %s should be strings", strings.Join(names, ", "))
+		}
+
+	case configIntMap:
+		mapping, ok := value.(map[string]interface{})
+		if !ok {
+			return fmt.Sprintf("should be a mapping of names to numbers of tokens, not %s", describeYAMLValue(value))
+		}
+		var names []string
+		for name, item := range mapping {
+			if number, ok := item.(int); !ok || number <= 0 {
+				names = append(names, name)
+			}
+		}
+		if len(names) > 0 {
+			sort.Strings(names)
+			return fmt.Sprintf("the values of %s should be whole numbers of tokens", strings.Join(names, ", "))
+		}
+
+	case configInt:
+		if number, ok := value.(int); !ok || number < 0 {
+			return fmt.Sprintf("should be a whole number of bytes, not %s", describeYAMLValue(value))
+		}
+
+	case configStringList:
+		var items []interface{}
+		switch value := value.(type) {
+		case string:
+			items = []interface{}{value}
+		case []interface{}:
+			items = value
+		default:
+			return fmt.Sprintf("should be a list of strings, not %s", describeYAMLValue(value))
+		}
+		var problems []string
+		for _, item := range items {
+			str, ok := item.(string)
+			if !ok {
+				problems = append(problems, fmt.Sprintf("items should be strings, not %s", describeYAMLValue(item)))
+			} else if problem := validateChoice(schema, str); problem != "" {
+				problems = append(problems, problem)
+			}
+		}
+		return strings.Join(problems, "; ")
+
+	case configBool:
+		switch value := value.(type) {
+		case bool:
+			return ""
+		case string:
+			switch strings.ToLower(value) {
+			case "on", "off", "true", "false", "yes", "no":
+				return ""
+			}
+		}
+		return fmt.Sprintf("should be on or off, not %s", describeYAMLValue(value))
+	}
+
+	return ""
+}
+
+// validateChoice checks a string against the values a key accepts, if it
+// lists them
+func validateChoice(schema configKey, value string) string {
+	if len(schema.values) == 0 {
+		return ""
+	}
+	lower := strings.ToLower(strings.TrimSpace(value))
+	for _, allowed := range schema.values {
+		if lower == allowed {
+			return ""
+		}
+	}
+	return fmt.Sprintf("unknown value %q%s (expected one of %s)", value, didYouMean(lower, schema.values), strings.Join(schema.values, ", "))
+}
+
+// describeYAMLValue names the type of a value decoded from YAML, for error
+// messages
+func describeYAMLValue(value interface{}) string {
+	switch value := value.(type) {
+	case nil:
+		return "an empty value"
+	case bool:
+		return fmt.Sprintf("%t", value)
+	case int, int64, uint64, float64:
+		return fmt.Sprintf("the number %v", value)
+	case string:
+		return fmt.Sprintf("%q", value)
+	case []interface{}:
+		return "a list"
+	case map[string]interface{}:
+		return "a mapping"
+	default:
+		return fmt.Sprintf("%v", value)
+	}
+}
+
+// configKeyNames returns the documented names of the config keys
+func configKeyNames() []string {
+	names := make([]string, 0, len(configSchema))
+	for _, schema := range configSchema {
+		names = append(names, schema.name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// didYouMean suggests the candidate closest to a misspelled word, formatted
+// to follow an error message, or returns an empty string if none is close
+func didYouMean(word string, candidates []string) string {
+	best := ""
+	bestDistance := 0
+	for _, candidate := range candidates {
+		distance := editDistance(word, strings.ToLower(candidate))
+		if best == "" || distance < bestDistance {
+			best, bestDistance = candidate, distance
+		}
+	}
+
+	// Allow roughly one mistake per three characters, and at least two so
+	// transposed letters are caught
+	limit := len(word) / 3
+	if limit < 2 {
+		limit = 2
+	}
+	if best == "" || bestDistance > limit {
+		return ""
+	}
+	return fmt.Sprintf(" (did you mean %q?)", best)
+}
+
+// editDistance returns the Levenshtein distance between a and b
+func editDistance(a string, b string) int {
+	source, target := []rune(a), []rune(b)
+	previous := make([]int, len(target)+1)
+	current := make([]int, len(target)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+
+	for i := 1; i <= len(source); i++ {
+		current[0] = i
+		for j := 1; j <= len(target); j++ {
+			cost := 1
+			if source[i-1] == target[j-1] {
+				cost = 0
+			}
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+		previous, current = current, previous
+	}
+
+	return previous[len(target)]
+}
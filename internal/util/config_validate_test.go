@@ -0,0 +1,100 @@
+package util
+
+import (
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestValidateConfigMap(t *testing.T) {
+	tests := []struct {
+		name   string
+		config string
+		want   []string
+	}{
+		{
+			name: "valid config",
+			config: `
+endpoint: https://openrouter.ai/api/v1
+DefaultModel: gpt-5o
+formatters:
+  go: gofmt
+contextVars: [datetime, cwd]
+memory: off
+memoryExtraction: true
+highlighter: treesitter
+`,
+		},
+		{
+			name:   "misspelled key",
+			config: "defualtModel: gpt-5o\n",
+			want:   []string{`unknown key "defualtModel" (did you mean "defaultModel"?)`},
+		},
+		{
+			name:   "unknown key with no close match",
+			config: "colourScheme: dark\n",
+			want:   []string{`unknown key "colourScheme"`},
+		},
+		{
+			name:   "wrong types",
+			config: "fastModel: 3\nformatters: gofmt\nmemory: sometimes\ncontextWindows: {llama3.2: 8192, mistral: 32k}\nstreamBuffer: 1MB\n",
+			want: []string{
+				"contextWindows: the values of mistral should be whole numbers of tokens",
+				"fastModel: should be a string, not the number 3",
+				`formatters: should be a mapping of names to strings, not "gofmt"`,
+				`memory: should be on or off, not "sometimes"`,
+				`streamBuffer: should be a whole number of bytes, not "1MB"`,
+			},
+		},
+		{
+			name:   "unknown values",
+			config: "contextVars: [datetime, timzone]\nhighlighter: tree-sitter\n",
+			want: []string{
+				`contextVars: unknown value "timzone" (did you mean "timezone"?)`,
+				`highlighter: unknown value "tree-sitter" (did you mean "treesitter"?)`,
+			},
+		},
+		{
+			name:   "same key twice",
+			config: "fastModel: a\nfastmodel: b\n",
+			want:   []string{`"fastModel" and "fastmodel" are the same key; only one is used`},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var configMap map[string]interface{}
+			if err := yaml.Unmarshal([]byte(tt.config), &configMap); err != nil {
+				t.Fatalf("invalid test config: %v", err)
+			}
+
+			problems := validateConfigMap(configMap)
+			if len(problems) != len(tt.want) {
+				t.Fatalf("validateConfigMap() = %q, want %d problems", problems, len(tt.want))
+			}
+			for i, want := range tt.want {
+				if !strings.HasPrefix(problems[i], want) {
+					t.Errorf("problem %d = %q, want %q", i, problems[i], want)
+				}
+			}
+		})
+	}
+}
+
+func TestEditDistance(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"", "abc", 3},
+		{"defualtmodel", "defaultmodel", 2},
+		{"kitten", "sitting", 3},
+		{"same", "same", 0},
+	}
+	for _, tt := range tests {
+		if got := editDistance(tt.a, tt.b); got != tt.want {
+			t.Errorf("editDistance(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,56 @@
+package util
+
+import (
+	"os"
+	"time"
+)
+
+// fileStamp identifies a version of a file by its size and modification time.
+// The zero stamp stands for a missing file.
+type fileStamp struct {
+	size    int64
+	modTime time.Time
+}
+
+// ConfigWatcher tells long-running commands when config.yaml or the system
+// config file has changed, so they can apply the new settings. It checks the
+// files' sizes and modification times when asked rather than watching them.
+type ConfigWatcher struct {
+	paths  []string
+	stamps []fileStamp
+}
+
+// NewConfigWatcher creates a watcher of the config files as they are now
+func NewConfigWatcher() *ConfigWatcher {
+	watcher := &ConfigWatcher{paths: []string{SystemConfigPath()}}
+	if path, err := ConfigPath("config.yaml"); err == nil {
+		watcher.paths = append(watcher.paths, path)
+	}
+	watcher.stamps = watcher.stampFiles()
+	return watcher
+}
+
+// Changed reports whether a config file was written, created or removed
+// since the watcher was created or last reported a change
+func (w *ConfigWatcher) Changed() bool {
+	stamps := w.stampFiles()
+	changed := false
+	for i := range stamps {
+		if stamps[i].size != w.stamps[i].size || !stamps[i].modTime.Equal(w.stamps[i].modTime) {
+			changed = true
+		}
+	}
+	w.stamps = stamps
+	return changed
+}
+
+// stampFiles returns the current stamp of each watched file
+func (w *ConfigWatcher) stampFiles() []fileStamp {
+	stamps := make([]fileStamp, len(w.paths))
+	for i, path := range w.paths {
+		if info, err := os.Stat(path); err == nil {
+			stamps[i] = fileStamp{size: info.Size(), modTime: info.ModTime()}
+		}
+	}
+	return stamps
+}
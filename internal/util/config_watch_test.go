@@ -0,0 +1,54 @@
+package util
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestConfigWatcher(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("USERPROFILE", home)
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(home, "config"))
+
+	originalPath := systemConfigPath
+	defer func() { systemConfigPath = originalPath }()
+	systemConfigPath = filepath.Join(home, "etc", "config.yaml")
+
+	userPath := filepath.Join(home, "config", "aipipe", "config.yaml")
+	if err := os.MkdirAll(filepath.Dir(userPath), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	watcher := NewConfigWatcher()
+	if watcher.Changed() {
+		t.Error("Changed() = true before any config file was written")
+	}
+
+	// Modification times may be coarse, so each version is given its own
+	modified := time.Now().Add(-time.Hour)
+	steps := []struct {
+		name  string
+		write func() error
+	}{
+		{"Created", func() error { return os.WriteFile(userPath, []byte("defaultModel: a\n"), 0644) }},
+		{"Rewritten", func() error { return os.WriteFile(userPath, []byte("defaultModel: b\n"), 0644) }},
+		{"Removed", func() error { return os.Remove(userPath) }},
+	}
+	for _, step := range steps {
+		if err := step.write(); err != nil {
+			t.Fatal(err)
+		}
+		modified = modified.Add(time.Minute)
+		os.Chtimes(userPath, modified, modified)
+
+		if !watcher.Changed() {
+			t.Errorf("%s: Changed() = false, want true", step.name)
+		}
+		if watcher.Changed() {
+			t.Errorf("%s: Changed() = true again without another change", step.name)
+		}
+	}
+}
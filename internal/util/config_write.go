@@ -0,0 +1,80 @@
+package util
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ConfigSetting is a top-level string key of the config file and its value
+type ConfigSetting struct {
+	Key   string
+	Value string
+}
+
+// WriteConfigSettings sets top-level keys in the config file at path,
+// creating it if needed. Keys are matched case-insensitively and an empty
+// value removes the key. Other keys and comments are kept.
+func WriteConfigSettings(path string, settings []ConfigSetting) error {
+	var document yaml.Node
+	data, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to read config file: %w", err)
+	}
+	if err := yaml.Unmarshal(data, &document); err != nil {
+		return fmt.Errorf("failed to parse config file: %w", err)
+	}
+
+	if document.Kind == 0 {
+		// An empty or missing file
+		document = yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{{Kind: yaml.MappingNode}}}
+	}
+	mapping := document.Content[0]
+	if mapping.Kind != yaml.MappingNode {
+		return fmt.Errorf("failed to update config file: it isn't a mapping of keys to values")
+	}
+
+	for _, setting := range settings {
+		index := -1
+		for i := 0; i+1 < len(mapping.Content); i += 2 {
+			if strings.EqualFold(mapping.Content[i].Value, setting.Key) {
+				index = i
+				break
+			}
+		}
+
+		value := &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: setting.Value}
+		switch {
+		case setting.Value == "" && index >= 0:
+			mapping.Content = append(mapping.Content[:index], mapping.Content[index+2:]...)
+		case setting.Value == "":
+		case index >= 0:
+			mapping.Content[index+1] = value
+		default:
+			key := &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: setting.Key}
+			mapping.Content = append(mapping.Content, key, value)
+		}
+	}
+
+	var output bytes.Buffer
+	encoder := yaml.NewEncoder(&output)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(&document); err != nil {
+		return fmt.Errorf("failed to encode config file: %w", err)
+	}
+	if err := encoder.Close(); err != nil {
+		return fmt.Errorf("failed to encode config file: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return fmt.Errorf("failed to create config directory: %w", err)
+	}
+	if err := os.WriteFile(path, output.Bytes(), 0600); err != nil {
+		return fmt.Errorf("failed to write config file: %w", err)
+	}
+	return nil
+}
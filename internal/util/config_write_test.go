@@ -0,0 +1,47 @@
+package util
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestWriteConfigSettings(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "aipipe", "config.yaml")
+
+	err := WriteConfigSettings(path, []ConfigSetting{
+		{Key: "endpoint", Value: "https://api.groq.com/openai/v1"},
+		{Key: "apiKey", Value: "secret"},
+		{Key: "fastModel", Value: ""},
+	})
+	if err != nil {
+		t.Fatalf("WriteConfigSettings() error: %v", err)
+	}
+	data, _ := os.ReadFile(path)
+	want := "endpoint: https://api.groq.com/openai/v1\napiKey: secret\n"
+	if string(data) != want {
+		t.Errorf("new config file = %q, want %q", data, want)
+	}
+
+	existing := "# my settings\nFastModel: old\nformatters:\n  go: gofmt\napikey: secret\n"
+	if err := os.WriteFile(path, []byte(existing), 0600); err != nil {
+		t.Fatal(err)
+	}
+	err = WriteConfigSettings(path, []ConfigSetting{
+		{Key: "fastModel", Value: "new"},
+		{Key: "apiKey", Value: ""},
+	})
+	if err != nil {
+		t.Fatalf("WriteConfigSettings() error: %v", err)
+	}
+	data, _ = os.ReadFile(path)
+	for _, want := range []string{"# my settings", "FastModel: new", "go: gofmt"} {
+		if !strings.Contains(string(data), want) {
+			t.Errorf("updated config file = %q, want it to contain %q", data, want)
+		}
+	}
+	if strings.Contains(string(data), "secret") {
+		t.Errorf("updated config file = %q, want apikey removed", data)
+	}
+}
@@ -1,9 +1,10 @@
 package util
 
 import (
+	"errors"
 	"fmt"
+	"net/url"
 	"os"
-	"path/filepath"
 	"strings"
 
 	"gopkg.in/yaml.v3"
@@ -16,6 +17,32 @@ type APIConfig struct {
 	DefaultModel   string
 	FastModel      string
 	ReasoningModel string
+
+	// Provider is "anthropic" to use Anthropic's Messages API, "ollama" for
+	// a local Ollama server, or empty for an OpenAI compatible API
+	Provider string
+
+	// LocalModel and LocalBaseURL choose the Ollama model and server used by
+	// --local, from the `localModel` and `localBaseUrl` keys of the config file
+	LocalModel   string
+	LocalBaseURL string
+
+	// User, Metadata and Headers attribute requests to a user or team, from
+	// the `user`, `metadata` and `headers` keys of the config file
+	User     string
+	Metadata map[string]string
+	Headers  map[string]string
+
+	// Organization and Project choose which OpenAI organization and project
+	// a request is billed to, for keys that belong to more than one. They
+	// come from OPENAI_ORG_ID and OPENAI_PROJECT, or the `organization` and
+	// `project` keys of the config file.
+	Organization string
+	Project      string
+
+	// ContextWindows overrides the context window sizes of models, keyed by
+	// model name, from the `contextWindows` key of the config file
+	ContextWindows map[string]int
 }
 
 // UserConfig holds the user's configuration from YAML file
@@ -27,29 +54,57 @@ type UserConfig struct {
 	ReasoningModel string `yaml:"reasoningModel"`
 }
 
-// LoadUserConfig loads configuration from ~/.aipipe/config.yaml if it exists
-// and merges it with the existing APIConfig
-func LoadUserConfig(config *APIConfig) error {
-	homeDir, err := os.UserHomeDir()
+// readConfigFile reads config.yaml from the config directory, returning its
+// path and its top-level keys as written. The map is nil if the file does not
+// exist.
+func readConfigFile() (string, map[string]interface{}, error) {
+	configPath, err := ConfigPath("config.yaml")
 	if err != nil {
-		return fmt.Errorf("failed to get user home directory: %w", err)
+		return "", nil, err
 	}
+	configMap, err := readYAMLConfig(configPath)
+	return configPath, configMap, err
+}
 
-	configPath := filepath.Join(homeDir, ".aipipe", "config.yaml")
+// readYAMLConfig reads the top-level keys of a config file as written. The
+// map is nil if the file does not exist.
+func readYAMLConfig(configPath string) (map[string]interface{}, error) {
 	if _, err := os.Stat(configPath); os.IsNotExist(err) {
 		// Config file doesn't exist, just return without error
-		return nil
+		return nil, nil
 	}
 
 	data, err := os.ReadFile(configPath)
 	if err != nil {
-		return fmt.Errorf("failed to read config file: %w", err)
+		return nil, fmt.Errorf("failed to read config file: %w", err)
 	}
 
-	// Create a map for case-insensitive parsing
 	var configMap map[string]interface{}
 	if err := yaml.Unmarshal(data, &configMap); err != nil {
-		return fmt.Errorf("failed to parse config file: %w", err)
+		return nil, fmt.Errorf("failed to parse config file: %w", err)
+	}
+	if configMap == nil {
+		// An empty file
+		configMap = map[string]interface{}{}
+	}
+	return configMap, nil
+}
+
+// loadConfigMap reads config.yaml from the config directory and the system
+// config file, and returns their top-level keys lowercased for
+// case-insensitive matching. Keys in the system config file take precedence.
+// It returns nil if neither file exists.
+func loadConfigMap() (map[string]interface{}, error) {
+	_, configMap, err := readConfigFile()
+	if err != nil {
+		return nil, err
+	}
+	systemMap, err := readYAMLConfig(SystemConfigPath())
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", SystemConfigPath(), err)
+	}
+	if configMap == nil && systemMap == nil {
+		return nil, nil
 	}
 
 	// Convert keys to lowercase for case-insensitive matching
@@ -57,6 +112,20 @@ func LoadUserConfig(config *APIConfig) error {
 	for k, v := range configMap {
 		normalizedMap[strings.ToLower(k)] = v
 	}
+	for k, v := range systemMap {
+		normalizedMap[strings.ToLower(k)] = v
+	}
+
+	return normalizedMap, nil
+}
+
+// LoadUserConfig loads configuration from the config file if it exists
+// and merges it with the existing APIConfig
+func LoadUserConfig(config *APIConfig) error {
+	normalizedMap, err := loadConfigMap()
+	if err != nil {
+		return err
+	}
 
 	// Extract values with case-insensitive keys
 	if endpoint, ok := normalizedMap["endpoint"]; ok && endpoint != "" {
@@ -89,9 +158,379 @@ func LoadUserConfig(config *APIConfig) error {
 		}
 	}
 
+	if localModel, ok := normalizedMap["localmodel"].(string); ok && localModel != "" {
+		config.LocalModel = localModel
+	}
+	if localBaseURL, ok := normalizedMap["localbaseurl"].(string); ok && localBaseURL != "" {
+		config.LocalBaseURL = localBaseURL
+	}
+
+	if user, ok := normalizedMap["user"].(string); ok {
+		config.User = user
+	}
+	config.Metadata = stringMapValue(normalizedMap["metadata"])
+	config.Headers = stringMapValue(normalizedMap["headers"])
+
+	if organization, ok := normalizedMap["organization"].(string); ok && organization != "" {
+		config.Organization = organization
+	}
+	if project, ok := normalizedMap["project"].(string); ok && project != "" {
+		config.Project = project
+	}
+	config.ContextWindows = intMapValue(normalizedMap["contextwindows"])
+
 	return nil
 }
 
+// intMapValue returns the positive whole number values of a mapping from the
+// config file with their names as written, or nil if value isn't a mapping
+func intMapValue(value interface{}) map[string]int {
+	mapping, ok := value.(map[string]interface{})
+	if !ok || len(mapping) == 0 {
+		return nil
+	}
+
+	result := make(map[string]int)
+	for name, item := range mapping {
+		if number, ok := item.(int); ok && number > 0 {
+			result[name] = number
+		}
+	}
+	return result
+}
+
+// stringMapValue returns the non-empty string values of a mapping from the
+// config file with their names as written, or nil if value isn't a mapping
+func stringMapValue(value interface{}) map[string]string {
+	mapping, ok := value.(map[string]interface{})
+	if !ok || len(mapping) == 0 {
+		return nil
+	}
+
+	result := make(map[string]string)
+	for name, item := range mapping {
+		if str, ok := item.(string); ok && str != "" {
+			result[name] = str
+		}
+	}
+	return result
+}
+
+// getStringMap returns the string values of a mapping in the config file,
+// keyed by lowercased name. Values that aren't non-empty strings are skipped.
+func getStringMap(key string) (map[string]string, error) {
+	normalizedMap, err := loadConfigMap()
+	if err != nil {
+		return nil, err
+	}
+
+	result := make(map[string]string)
+	mapping, ok := normalizedMap[key].(map[string]interface{})
+	if !ok {
+		return result, nil
+	}
+
+	for name, value := range mapping {
+		if str, ok := value.(string); ok && str != "" {
+			result[strings.ToLower(name)] = str
+		}
+	}
+
+	return result, nil
+}
+
+// getStringList returns the string values of a list in the config file,
+// lowercased. A single string is treated as a list of one.
+func getStringList(key string) ([]string, error) {
+	normalizedMap, err := loadConfigMap()
+	if err != nil {
+		return nil, err
+	}
+
+	var result []string
+	switch value := normalizedMap[key].(type) {
+	case string:
+		result = append(result, strings.ToLower(value))
+	case []interface{}:
+		for _, item := range value {
+			if str, ok := item.(string); ok && str != "" {
+				result = append(result, strings.ToLower(str))
+			}
+		}
+	}
+
+	return result, nil
+}
+
+// GetParserPlugins returns the external parser commands configured under the
+// `parsers` key of the config file, keyed by lowercased language name. There
+// are none in read-only mode, since they are commands to run.
+func GetParserPlugins() (map[string]string, error) {
+	if readOnly, err := GetReadOnly(); err != nil || readOnly {
+		return map[string]string{}, err
+	}
+	return getStringMap("parsers")
+}
+
+// GetLanguageAliases returns the code fence aliases configured under the
+// `languageAliases` key of the config file, mapping each alias to the
+// language it should be highlighted as
+func GetLanguageAliases() (map[string]string, error) {
+	return getStringMap("languagealiases")
+}
+
+// GetFormatters returns the formatter commands configured under the
+// `formatters` key of the config file, keyed by lowercased language name.
+// There are none in read-only mode, since they are commands to run.
+func GetFormatters() (map[string]string, error) {
+	if readOnly, err := GetReadOnly(); err != nil || readOnly {
+		return map[string]string{}, err
+	}
+	return getStringMap("formatters")
+}
+
+// GetContextVars returns the names listed under the `contextVars` key of the
+// config file, naming details of the environment to include in the system
+// prompt
+func GetContextVars() ([]string, error) {
+	return getStringList("contextvars")
+}
+
+// getBool returns a boolean setting from the config file, accepting YAML
+// booleans as well as "on" and "off". It returns defaultValue if the key is
+// missing or not a recognised value.
+func getBool(key string, defaultValue bool) (bool, error) {
+	normalizedMap, err := loadConfigMap()
+	if err != nil {
+		return defaultValue, err
+	}
+
+	switch value := normalizedMap[key].(type) {
+	case bool:
+		return value, nil
+	case string:
+		switch strings.ToLower(value) {
+		case "on", "true", "yes":
+			return true, nil
+		case "off", "false", "no":
+			return false, nil
+		}
+	}
+	return defaultValue, nil
+}
+
+// getInt returns a whole number setting from the config file, or
+// defaultValue if the key is missing or not a whole number of at least 0
+func getInt(key string, defaultValue int) (int, error) {
+	normalizedMap, err := loadConfigMap()
+	if err != nil {
+		return defaultValue, err
+	}
+
+	if value, ok := normalizedMap[key].(int); ok && value >= 0 {
+		return value, nil
+	}
+	return defaultValue, nil
+}
+
+// GetMemoryEnabled reports whether remembered facts should be included in
+// prompts. It is true unless the `memory` key of the config file is off.
+func GetMemoryEnabled() (bool, error) {
+	return getBool("memory", true)
+}
+
+// GetPromptHistoryEnabled reports whether prompts typed on the command line
+// should be recorded. It is true unless the `promptHistory` key of the config
+// file is off or aipipe is in read-only mode.
+func GetPromptHistoryEnabled() (bool, error) {
+	if readOnly, err := GetReadOnly(); err != nil || readOnly {
+		return false, err
+	}
+	return getBool("prompthistory", true)
+}
+
+// GetReadOnly reports whether the `readOnly` key of the config file is on.
+// In read-only mode aipipe only answers queries: it doesn't run commands,
+// write files or record history. The key is meant for the system config
+// file, where it takes precedence over the user's.
+func GetReadOnly() (bool, error) {
+	return getBool("readonly", false)
+}
+
+// GetAccessible reports whether the `accessible` key of the config file is
+// on, asking for output suited to screen readers and braille terminals
+// without passing --accessible each time
+func GetAccessible() (bool, error) {
+	return getBool("accessible", false)
+}
+
+// getString returns a string setting from the config file, or an empty
+// string if it is missing or not a string
+func getString(key string) (string, error) {
+	normalizedMap, err := loadConfigMap()
+	if err != nil {
+		return "", err
+	}
+
+	value, _ := normalizedMap[key].(string)
+	return value, nil
+}
+
+// GetSnippetSource returns the `snippetSource` key of the config file: the
+// git repository or YAML file URL that shared snippets are synced from
+func GetSnippetSource() (string, error) {
+	return getString("snippetsource")
+}
+
+// GetFileHeader returns the `fileHeader` key of the config file: a header,
+// such as a licence notice, put as a comment at the top of code written to
+// files, in which {model} and {date} are filled in
+func GetFileHeader() (string, error) {
+	return getString("fileheader")
+}
+
+// GetPricesURL returns the `pricesUrl` key of the config file: where
+// `aipipe prices update` fetches the price table from, if not the repository
+func GetPricesURL() (string, error) {
+	return getString("pricesurl")
+}
+
+// GetShareTarget returns the `shareTarget` key of the config file: where
+// `aipipe history share` uploads conversations, "gist" or a paste service's
+// endpoint URL, or an empty string if unset
+func GetShareTarget() (string, error) {
+	return getString("sharetarget")
+}
+
+// GetInputRole returns the `inputRole` key of the config file: how piped
+// input is sent when there is also an instruction, as "prompt", "user" or
+// "system", or an empty string if unset
+func GetInputRole() (string, error) {
+	role, err := getString("inputrole")
+	return strings.ToLower(role), err
+}
+
+// GetMemoryExtraction reports whether the `memoryExtraction` key of the config
+// file is on, offering to remember facts about the user learned from each
+// conversation. It is always off in read-only mode.
+func GetMemoryExtraction() (bool, error) {
+	if readOnly, err := GetReadOnly(); err != nil || readOnly {
+		return false, err
+	}
+	return getBool("memoryextraction", false)
+}
+
+// DefaultStreamBuffer is the most reply text, in bytes, held while the
+// terminal catches up with a streamed reply
+const DefaultStreamBuffer = 1 << 20
+
+// GetStreamBuffer returns the `streamBuffer` key of the config file: the most
+// reply text, in bytes, held while the terminal catches up with a streamed
+// reply, or 0 if the reply shouldn't be buffered
+func GetStreamBuffer() (int, error) {
+	return getInt("streambuffer", DefaultStreamBuffer)
+}
+
+// GetHighlighterBackend returns the syntax highlighting backend named by the
+// `highlighter` key of the config file, or an empty string if unset
+func GetHighlighterBackend() (string, error) {
+	backend, err := getString("highlighter")
+	return strings.ToLower(backend), err
+}
+
+// Provider describes an API with built-in defaults
+type Provider struct {
+	Name string
+	// KeyVariable is the environment variable holding the API key
+	KeyVariable    string
+	Endpoint       string
+	DefaultModel   string
+	FastModel      string
+	ReasoningModel string
+}
+
+var (
+	// GroqProvider is used when GROQ_API_KEY is set
+	GroqProvider = Provider{
+		Name:           "groq",
+		KeyVariable:    "GROQ_API_KEY",
+		Endpoint:       "https://api.groq.com/openai/v1",
+		DefaultModel:   "llama-3.3-70b-versatile",
+		FastModel:      "llama-3.1-8b-instant",
+		ReasoningModel: "qwen-qwq-32b",
+	}
+
+	// OpenAIProvider is used when OPENAI_API_KEY is set and GROQ_API_KEY isn't
+	OpenAIProvider = Provider{
+		Name:           "openai",
+		KeyVariable:    "OPENAI_API_KEY",
+		Endpoint:       "https://api.openai.com/v1",
+		DefaultModel:   "gpt-4o",
+		FastModel:      "gpt-4o-mini",
+		ReasoningModel: "o3-mini",
+	}
+
+	// OllamaProvider is a local Ollama server, used with --local or when no
+	// API key is set. Its model is whichever the user has pulled.
+	OllamaProvider = Provider{
+		Name:     "ollama",
+		Endpoint: "http://localhost:11434",
+	}
+
+	// AnthropicProvider is used when `provider: anthropic` is in the config
+	// file, or ANTHROPIC_API_KEY is the only key set. Unlike the others it
+	// doesn't speak the OpenAI API.
+	AnthropicProvider = Provider{
+		Name:           "anthropic",
+		KeyVariable:    "ANTHROPIC_API_KEY",
+		Endpoint:       "https://api.anthropic.com/v1",
+		DefaultModel:   "claude-sonnet-4-5",
+		FastModel:      "claude-haiku-4-5",
+		ReasoningModel: "claude-opus-4-1",
+	}
+)
+
+// ErrNoAPIKey is returned by GetAPIConfig when no API key is set
+var ErrNoAPIKey = errors.New("AIPIPE_API_KEY or GROQ_API_KEY or OPENAI_API_KEY or ANTHROPIC_API_KEY environment variable is not set and no API key found in config file")
+
+// GetLocalAPIConfig returns the configuration for a local Ollama server, at
+// `localBaseUrl` from the config file, OLLAMA_HOST or localhost. Every model
+// type uses `localModel`, which may be empty if it isn't set.
+func GetLocalAPIConfig() (*APIConfig, error) {
+	config := &APIConfig{}
+	if err := LoadUserConfig(config); err != nil {
+		return nil, fmt.Errorf("failed to load user config: %w", err)
+	}
+
+	config.Provider = OllamaProvider.Name
+	config.APIToken = ""
+	config.APIEndpoint = config.LocalBaseURL
+	if config.APIEndpoint == "" {
+		config.APIEndpoint = ollamaHost(os.Getenv("OLLAMA_HOST"))
+	}
+	config.DefaultModel = config.LocalModel
+	config.FastModel = config.LocalModel
+	config.ReasoningModel = config.LocalModel
+	return config, nil
+}
+
+// ollamaHost returns the URL of the Ollama server named by OLLAMA_HOST, or
+// the default server if it is empty. As in Ollama, a host without a scheme
+// uses http and port 11434.
+func ollamaHost(host string) string {
+	if host == "" {
+		return OllamaProvider.Endpoint
+	}
+	if strings.Contains(host, "://") {
+		return host
+	}
+	if u, err := url.Parse("http://" + host); err == nil && u.Port() == "" {
+		u.Host += ":11434"
+		return u.String()
+	}
+	return "http://" + host
+}
+
 // GetAPIConfig retrieves API configuration from environment variables and config file
 func GetAPIConfig() (*APIConfig, error) {
 	config := &APIConfig{}
@@ -100,34 +539,60 @@ func GetAPIConfig() (*APIConfig, error) {
 	isGroq := false
 	isOpenAI := false
 
+	// `provider: anthropic` in the config file selects Anthropic's API. An
+	// unreadable config file is reported by LoadUserConfig below.
+	providerName, _ := getString("provider")
+	isAnthropic := strings.EqualFold(providerName, AnthropicProvider.Name)
+
 	// Check for API keys in order of preference
 	config.APIToken = os.Getenv("AIPIPE_API_KEY")
 	if config.APIToken != "" {
 		isAipipe = true
 	}
 
-	if config.APIToken == "" {
+	if config.APIToken == "" && isAnthropic {
+		config.APIToken = os.Getenv("ANTHROPIC_API_KEY")
+	}
+
+	// The other providers' keys are no use to Anthropic's API
+	if config.APIToken == "" && !isAnthropic {
 		config.APIToken = os.Getenv("GROQ_API_KEY")
 		if config.APIToken != "" {
 			isGroq = true
 		}
 	}
 
-	if config.APIToken == "" {
+	if config.APIToken == "" && !isAnthropic {
 		config.APIToken = os.Getenv("OPENAI_API_KEY")
 		if config.APIToken != "" {
 			isOpenAI = true
 		}
 	}
 
-	config.DefaultModel = "llama-3.3-70b-versatile"
-	config.FastModel = "llama-3.1-8b-instant"
-	config.ReasoningModel = "qwen-qwq-32b"
+	if config.APIToken == "" {
+		config.APIToken = os.Getenv("ANTHROPIC_API_KEY")
+		if config.APIToken != "" {
+			isAnthropic = true
+		}
+	}
 
+	provider := GroqProvider
 	if isOpenAI {
-		config.DefaultModel = "gpt-4o"
-		config.FastModel = "gpt-4o-mini"
-		config.ReasoningModel = "o3-mini"
+		provider = OpenAIProvider
+	}
+	if isAnthropic {
+		provider = AnthropicProvider
+		config.Provider = AnthropicProvider.Name
+	}
+	config.DefaultModel = provider.DefaultModel
+	config.FastModel = provider.FastModel
+	config.ReasoningModel = provider.ReasoningModel
+
+	// The OpenAI variables only apply to OpenAI's keys, and to AIPIPE_API_KEY
+	// since its endpoint may be OpenAI's or a proxy for it
+	if isOpenAI || (isAipipe && !isAnthropic) {
+		config.Organization = os.Getenv("OPENAI_ORG_ID")
+		config.Project = os.Getenv("OPENAI_PROJECT")
 	}
 
 	// Try to load configuration from YAML file
@@ -139,22 +604,25 @@ func GetAPIConfig() (*APIConfig, error) {
 
 	// Final check if we have an API token
 	if config.APIToken == "" {
-		return nil, fmt.Errorf("AIPIPE_API_KEY or GROQ_API_KEY or OPENAI_API_KEY environment variable is not set and no API key found in config file")
+		return nil, ErrNoAPIKey
 	}
 
 	// Set API endpoint based on the service type if not already set
 	if config.APIEndpoint == "" {
 		config.APIEndpoint = os.Getenv("AIPIPE_ENDPOINT")
+		if isAnthropic && config.APIEndpoint == "" {
+			config.APIEndpoint = AnthropicProvider.Endpoint
+		}
 		if isAipipe && config.APIEndpoint == "" {
 			return nil, fmt.Errorf("AIPIPE_ENDPOINT environment variable is not set and no endpoint found in config file")
 		}
 
 		if isOpenAI {
-			config.APIEndpoint = "https://api.openai.com/v1"
+			config.APIEndpoint = OpenAIProvider.Endpoint
 		}
 
 		if isGroq {
-			config.APIEndpoint = "https://api.groq.com/openai/v1"
+			config.APIEndpoint = GroqProvider.Endpoint
 		}
 	}
 
@@ -3,6 +3,7 @@ package util
 import (
 	"os"
 	"path/filepath"
+	"reflect"
 	"testing"
 )
 
@@ -11,6 +12,7 @@ func TestGetAPIConfig(t *testing.T) {
 	originalAipipeKey := os.Getenv("AIPIPE_API_KEY")
 	originalGroqKey := os.Getenv("GROQ_API_KEY")
 	originalOpenAIKey := os.Getenv("OPENAI_API_KEY")
+	originalAnthropicKey := os.Getenv("ANTHROPIC_API_KEY")
 	originalAipipeEndpoint := os.Getenv("AIPIPE_ENDPOINT")
 
 	// Restore environment variables after test
@@ -18,6 +20,7 @@ func TestGetAPIConfig(t *testing.T) {
 		os.Setenv("AIPIPE_API_KEY", originalAipipeKey)
 		os.Setenv("GROQ_API_KEY", originalGroqKey)
 		os.Setenv("OPENAI_API_KEY", originalOpenAIKey)
+		os.Setenv("ANTHROPIC_API_KEY", originalAnthropicKey)
 		os.Setenv("AIPIPE_ENDPOINT", originalAipipeEndpoint)
 	}()
 
@@ -31,10 +34,11 @@ func TestGetAPIConfig(t *testing.T) {
 		{
 			name: "AIPIPE_API_KEY set",
 			envVars: map[string]string{
-				"AIPIPE_API_KEY":  "test-aipipe-key",
-				"AIPIPE_ENDPOINT": "https://test-aipipe-endpoint.com",
-				"GROQ_API_KEY":    "",
-				"OPENAI_API_KEY":  "",
+				"AIPIPE_API_KEY":    "test-aipipe-key",
+				"AIPIPE_ENDPOINT":   "https://test-aipipe-endpoint.com",
+				"GROQ_API_KEY":      "",
+				"OPENAI_API_KEY":    "",
+				"ANTHROPIC_API_KEY": "",
 			},
 			expectError: false,
 			expectedConfig: &APIConfig{
@@ -45,10 +49,11 @@ func TestGetAPIConfig(t *testing.T) {
 		{
 			name: "GROQ_API_KEY set",
 			envVars: map[string]string{
-				"AIPIPE_API_KEY":  "",
-				"AIPIPE_ENDPOINT": "",
-				"GROQ_API_KEY":    "test-groq-key",
-				"OPENAI_API_KEY":  "",
+				"AIPIPE_API_KEY":    "",
+				"AIPIPE_ENDPOINT":   "",
+				"GROQ_API_KEY":      "test-groq-key",
+				"OPENAI_API_KEY":    "",
+				"ANTHROPIC_API_KEY": "",
 			},
 			expectError: false,
 			expectedConfig: &APIConfig{
@@ -59,10 +64,11 @@ func TestGetAPIConfig(t *testing.T) {
 		{
 			name: "OPENAI_API_KEY set",
 			envVars: map[string]string{
-				"AIPIPE_API_KEY":  "",
-				"AIPIPE_ENDPOINT": "",
-				"GROQ_API_KEY":    "",
-				"OPENAI_API_KEY":  "test-openai-key",
+				"AIPIPE_API_KEY":    "",
+				"AIPIPE_ENDPOINT":   "",
+				"GROQ_API_KEY":      "",
+				"OPENAI_API_KEY":    "test-openai-key",
+				"ANTHROPIC_API_KEY": "",
 			},
 			expectError: false,
 			expectedConfig: &APIConfig{
@@ -70,23 +76,41 @@ func TestGetAPIConfig(t *testing.T) {
 				APIEndpoint: "https://api.openai.com/v1",
 			},
 		},
+		{
+			name: "ANTHROPIC_API_KEY set",
+			envVars: map[string]string{
+				"AIPIPE_API_KEY":    "",
+				"AIPIPE_ENDPOINT":   "",
+				"GROQ_API_KEY":      "",
+				"OPENAI_API_KEY":    "",
+				"ANTHROPIC_API_KEY": "test-anthropic-key",
+			},
+			expectError: false,
+			expectedConfig: &APIConfig{
+				APIToken:    "test-anthropic-key",
+				APIEndpoint: "https://api.anthropic.com/v1",
+				Provider:    "anthropic",
+			},
+		},
 		{
 			name: "No API keys set",
 			envVars: map[string]string{
-				"AIPIPE_API_KEY":  "",
-				"AIPIPE_ENDPOINT": "",
-				"GROQ_API_KEY":    "",
-				"OPENAI_API_KEY":  "",
+				"AIPIPE_API_KEY":    "",
+				"AIPIPE_ENDPOINT":   "",
+				"GROQ_API_KEY":      "",
+				"OPENAI_API_KEY":    "",
+				"ANTHROPIC_API_KEY": "",
 			},
 			expectError: true,
 		},
 		{
 			name: "AIPIPE_API_KEY set but no endpoint",
 			envVars: map[string]string{
-				"AIPIPE_API_KEY":  "test-aipipe-key",
-				"AIPIPE_ENDPOINT": "",
-				"GROQ_API_KEY":    "",
-				"OPENAI_API_KEY":  "",
+				"AIPIPE_API_KEY":    "test-aipipe-key",
+				"AIPIPE_ENDPOINT":   "",
+				"GROQ_API_KEY":      "",
+				"OPENAI_API_KEY":    "",
+				"ANTHROPIC_API_KEY": "",
 			},
 			expectError: true,
 		},
@@ -122,6 +146,9 @@ func TestGetAPIConfig(t *testing.T) {
 			if config.APIEndpoint != tt.expectedConfig.APIEndpoint {
 				t.Errorf("APIEndpoint = %v, want %v", config.APIEndpoint, tt.expectedConfig.APIEndpoint)
 			}
+			if config.Provider != tt.expectedConfig.Provider {
+				t.Errorf("Provider = %v, want %v", config.Provider, tt.expectedConfig.Provider)
+			}
 			if config.DefaultModel == "" {
 				t.Error("DefaultModel should not be empty")
 			}
@@ -135,6 +162,42 @@ func TestGetAPIConfig(t *testing.T) {
 	}
 }
 
+func TestGetAPIConfigOrganization(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("USERPROFILE", home)
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(home, "config"))
+	t.Setenv("AIPIPE_API_KEY", "")
+	t.Setenv("ANTHROPIC_API_KEY", "")
+	t.Setenv("OPENAI_ORG_ID", "org-123")
+	t.Setenv("OPENAI_PROJECT", "proj_456")
+
+	tests := []struct {
+		name             string
+		groqKey          string
+		openAIKey        string
+		wantOrganization string
+		wantProject      string
+	}{
+		{"OpenAI", "", "test-openai-key", "org-123", "proj_456"},
+		{"Groq", "test-groq-key", "", "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("GROQ_API_KEY", tt.groqKey)
+			t.Setenv("OPENAI_API_KEY", tt.openAIKey)
+
+			config, err := GetAPIConfig()
+			if err != nil {
+				t.Fatalf("GetAPIConfig() error = %v", err)
+			}
+			if config.Organization != tt.wantOrganization || config.Project != tt.wantProject {
+				t.Errorf("Organization, Project = %q, %q, want %q, %q", config.Organization, config.Project, tt.wantOrganization, tt.wantProject)
+			}
+		})
+	}
+}
+
 func TestLoadUserConfig(t *testing.T) {
 	// Create a temporary directory for the test
 	tempDir, err := os.MkdirTemp("", "aipipe-test")
@@ -242,6 +305,28 @@ endpoint: https://custom-endpoint.com
 			},
 			expectError: false,
 		},
+		{
+			name: "Attribution settings",
+			configContent: `
+user: alice@example.com
+metadata:
+  team: platform
+  purpose: ci
+headers:
+  X-Team: platform
+organization: org-123
+project: proj_456
+`,
+			initialConfig: &APIConfig{},
+			expectedConfig: &APIConfig{
+				User:         "alice@example.com",
+				Metadata:     map[string]string{"team": "platform", "purpose": "ci"},
+				Headers:      map[string]string{"X-Team": "platform"},
+				Organization: "org-123",
+				Project:      "proj_456",
+			},
+			expectError: false,
+		},
 		{
 			name:          "Invalid YAML",
 			configContent: `invalid: yaml: :`,
@@ -305,6 +390,93 @@ endpoint: https://custom-endpoint.com
 			if config.ReasoningModel != tt.expectedConfig.ReasoningModel {
 				t.Errorf("ReasoningModel = %v, want %v", config.ReasoningModel, tt.expectedConfig.ReasoningModel)
 			}
+			if config.User != tt.expectedConfig.User {
+				t.Errorf("User = %v, want %v", config.User, tt.expectedConfig.User)
+			}
+			if !reflect.DeepEqual(config.Metadata, tt.expectedConfig.Metadata) {
+				t.Errorf("Metadata = %v, want %v", config.Metadata, tt.expectedConfig.Metadata)
+			}
+			if !reflect.DeepEqual(config.Headers, tt.expectedConfig.Headers) {
+				t.Errorf("Headers = %v, want %v", config.Headers, tt.expectedConfig.Headers)
+			}
+			if config.Organization != tt.expectedConfig.Organization || config.Project != tt.expectedConfig.Project {
+				t.Errorf("Organization, Project = %q, %q, want %q, %q", config.Organization, config.Project, tt.expectedConfig.Organization, tt.expectedConfig.Project)
+			}
 		})
 	}
 }
+
+func TestSystemConfig(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("USERPROFILE", home)
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(home, "config"))
+
+	userPath := filepath.Join(home, "config", "aipipe", "config.yaml")
+	if err := os.MkdirAll(filepath.Dir(userPath), 0755); err != nil {
+		t.Fatal(err)
+	}
+	userConfig := "defaultModel: user-model\nfastModel: user-fast\nreadOnly: off\nformatters:\n  go: gofmt\n"
+	if err := os.WriteFile(userPath, []byte(userConfig), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	originalPath := systemConfigPath
+	defer func() { systemConfigPath = originalPath }()
+	systemConfigPath = filepath.Join(home, "etc", "config.yaml")
+
+	// Without a system config file, the user's settings apply
+	if readOnly, err := GetReadOnly(); err != nil || readOnly {
+		t.Errorf("GetReadOnly() = %v, %v; want false", readOnly, err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(systemConfigPath), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(systemConfigPath, []byte("ReadOnly: true\ndefaultModel: system-model\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	config := &APIConfig{}
+	if err := LoadUserConfig(config); err != nil {
+		t.Fatalf("LoadUserConfig() error = %v", err)
+	}
+	if config.DefaultModel != "system-model" || config.FastModel != "user-fast" {
+		t.Errorf("models = %q, %q; want the system default model and the user's fast model", config.DefaultModel, config.FastModel)
+	}
+
+	if readOnly, err := GetReadOnly(); err != nil || !readOnly {
+		t.Errorf("GetReadOnly() = %v, %v; want the system config to take precedence", readOnly, err)
+	}
+	if enabled, _ := GetPromptHistoryEnabled(); enabled {
+		t.Error("GetPromptHistoryEnabled() = true in read-only mode")
+	}
+	if enabled, _ := GetMemoryExtraction(); enabled {
+		t.Error("GetMemoryExtraction() = true in read-only mode")
+	}
+	if formatters, _ := GetFormatters(); len(formatters) != 0 {
+		t.Errorf("GetFormatters() = %v in read-only mode, want none", formatters)
+	}
+	if err := ValidateCode("if true; then echo hi", "bash"); err != nil {
+		t.Errorf("ValidateCode() = %v in read-only mode, want nil without running bash", err)
+	}
+}
+
+func TestOllamaHost(t *testing.T) {
+	tests := []struct {
+		host     string
+		expected string
+	}{
+		{"", "http://localhost:11434"},
+		{"127.0.0.1", "http://127.0.0.1:11434"},
+		{"0.0.0.0:8080", "http://0.0.0.0:8080"},
+		{"https://ollama.example.com", "https://ollama.example.com"},
+		{"http://gpu-box:11434", "http://gpu-box:11434"},
+	}
+
+	for _, tt := range tests {
+		if got := ollamaHost(tt.host); got != tt.expected {
+			t.Errorf("ollamaHost(%q) = %q, want %q", tt.host, got, tt.expected)
+		}
+	}
+}
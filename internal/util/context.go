@@ -0,0 +1,93 @@
+package util
+
+import (
+	"fmt"
+	"os"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// ContextVariables lists the names accepted in the contextVars config, in the
+// order they are written into the system prompt
+var ContextVariables = []string{"datetime", "timezone", "locale", "os", "shell", "cwd"}
+
+// BuildContext describes the user's environment for the system prompt,
+// including only the named variables. The output doesn't depend on the order
+// of names, so identical environments give identical prompts. It returns an
+// empty string if names is empty, and an error for unknown names.
+func BuildContext(names []string, now time.Time) (string, error) {
+	requested := make(map[string]bool)
+	for _, name := range names {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if !isContextVariable(name) {
+			return "", fmt.Errorf("unknown context variable %q (expected one of %s)", name, strings.Join(ContextVariables, ", "))
+		}
+		requested[name] = true
+	}
+
+	var lines []string
+	for _, name := range ContextVariables {
+		if !requested[name] {
+			continue
+		}
+		if value := contextValue(name, now); value != "" {
+			lines = append(lines, "- "+contextLabels[name]+": "+value)
+		}
+	}
+
+	if len(lines) == 0 {
+		return "", nil
+	}
+	return "Details of the user's environment, to use when relevant:\n" + strings.Join(lines, "\n"), nil
+}
+
+// contextLabels describes each context variable in the prompt
+var contextLabels = map[string]string{
+	"datetime": "Current date and time",
+	"timezone": "Time zone",
+	"locale":   "Locale",
+	"os":       "Operating system",
+	"shell":    "Shell",
+	"cwd":      "Working directory",
+}
+
+// isContextVariable reports whether name is one of ContextVariables
+func isContextVariable(name string) bool {
+	for _, known := range ContextVariables {
+		if name == known {
+			return true
+		}
+	}
+	return false
+}
+
+// contextValue returns the value of a context variable, or an empty string if
+// it can't be determined
+func contextValue(name string, now time.Time) string {
+	switch name {
+	case "datetime":
+		return now.Format("Monday 2 January 2006 15:04 -07:00")
+	case "timezone":
+		zone, _ := now.Zone()
+		if location := now.Location().String(); location != "Local" && location != zone {
+			return location + " (" + zone + ", UTC" + now.Format("-07:00") + ")"
+		}
+		return zone + " (UTC" + now.Format("-07:00") + ")"
+	case "locale":
+		for _, key := range []string{"LC_ALL", "LC_MESSAGES", "LANG"} {
+			if value := os.Getenv(key); value != "" {
+				return value
+			}
+		}
+	case "os":
+		return runtime.GOOS + "/" + runtime.GOARCH
+	case "shell":
+		return userShell()
+	case "cwd":
+		if cwd, err := os.Getwd(); err == nil {
+			return cwd
+		}
+	}
+	return ""
+}
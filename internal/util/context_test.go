@@ -0,0 +1,44 @@
+package util
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBuildContext(t *testing.T) {
+	t.Setenv("SHELL", "/usr/bin/zsh")
+	t.Setenv("LC_ALL", "")
+	t.Setenv("LC_MESSAGES", "")
+	t.Setenv("LANG", "en_GB.UTF-8")
+
+	now := time.Date(2024, time.March, 5, 9, 30, 0, 0, time.FixedZone("IST", 5*60*60+30*60))
+
+	context, err := BuildContext([]string{"shell", "DateTime", "locale", "timezone"}, now)
+	if err != nil {
+		t.Fatalf("BuildContext() error: %v", err)
+	}
+
+	expected := "Details of the user's environment, to use when relevant:\n" +
+		"- Current date and time: Tuesday 5 March 2024 09:30 +05:30\n" +
+		"- Time zone: IST (UTC+05:30)\n" +
+		"- Locale: en_GB.UTF-8\n" +
+		"- Shell: zsh"
+	if context != expected {
+		t.Errorf("BuildContext() = %q, want %q", context, expected)
+	}
+}
+
+func TestBuildContextEmpty(t *testing.T) {
+	context, err := BuildContext(nil, time.Now())
+	if err != nil || context != "" {
+		t.Errorf("BuildContext(nil) = %q, %v; want empty", context, err)
+	}
+}
+
+func TestBuildContextUnknownVariable(t *testing.T) {
+	_, err := BuildContext([]string{"os", "weather"}, time.Now())
+	if err == nil || !strings.Contains(err.Error(), "weather") {
+		t.Errorf("BuildContext() error = %v, want an error naming the unknown variable", err)
+	}
+}
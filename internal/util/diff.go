@@ -0,0 +1,136 @@
+package util
+
+import (
+	"regexp"
+	"strings"
+)
+
+// DiffOp says which of two texts a part of a diff comes from
+type DiffOp int
+
+const (
+	// DiffEqual is text in both
+	DiffEqual DiffOp = iota
+	// DiffDelete is text only in the first
+	DiffDelete
+	// DiffInsert is text only in the second
+	DiffInsert
+)
+
+// DiffPart is a run of text from a diff
+type DiffPart struct {
+	Op   DiffOp
+	Text string
+}
+
+// diffWordRegex splits text into words, runs of whitespace and punctuation
+var diffWordRegex = regexp.MustCompile(`\w+|\s+|[^\w\s]`)
+
+// maxWordDiffCells caps the work of comparing a changed block word by word,
+// as the product of the number of words either side. Bigger blocks are shown
+// as whole lines.
+const maxWordDiffCells = 4000000
+
+// DiffLines compares two texts line by line and returns the differences,
+// with lines only in a prefixed "-", lines only in b prefixed "+" and lines
+// in both prefixed " ". It returns an empty string if the texts are the same.
+func DiffLines(a string, b string) string {
+	if a == b {
+		return ""
+	}
+
+	var builder strings.Builder
+	prefixes := map[DiffOp]string{DiffEqual: " ", DiffDelete: "-", DiffInsert: "+"}
+	for _, part := range diffTokens(strings.Split(a, "\n"), strings.Split(b, "\n")) {
+		builder.WriteString(prefixes[part.Op] + part.Text + "\n")
+	}
+	return builder.String()
+}
+
+// DiffWords compares two texts word by word. Lines are compared first, and
+// the words of each block of changed lines are then compared, so unchanged
+// lines stay whole. Adjacent parts with the same op are merged.
+func DiffWords(a string, b string) []DiffPart {
+	var parts []DiffPart
+	var deleted, inserted strings.Builder
+	flush := func() {
+		wordsA := diffWordRegex.FindAllString(deleted.String(), -1)
+		wordsB := diffWordRegex.FindAllString(inserted.String(), -1)
+		if len(wordsA)*len(wordsB) > maxWordDiffCells {
+			parts = appendDiffPart(parts, DiffDelete, deleted.String())
+			parts = appendDiffPart(parts, DiffInsert, inserted.String())
+		} else {
+			for _, part := range diffTokens(wordsA, wordsB) {
+				parts = appendDiffPart(parts, part.Op, part.Text)
+			}
+		}
+		deleted.Reset()
+		inserted.Reset()
+	}
+
+	for _, part := range diffTokens(strings.SplitAfter(a, "\n"), strings.SplitAfter(b, "\n")) {
+		switch part.Op {
+		case DiffDelete:
+			deleted.WriteString(part.Text)
+		case DiffInsert:
+			inserted.WriteString(part.Text)
+		default:
+			flush()
+			parts = appendDiffPart(parts, DiffEqual, part.Text)
+		}
+	}
+	flush()
+
+	return parts
+}
+
+// appendDiffPart appends text to a diff, merging it with the last part if it
+// has the same op
+func appendDiffPart(parts []DiffPart, op DiffOp, text string) []DiffPart {
+	if text == "" {
+		return parts
+	}
+	if len(parts) > 0 && parts[len(parts)-1].Op == op {
+		parts[len(parts)-1].Text += text
+		return parts
+	}
+	return append(parts, DiffPart{Op: op, Text: text})
+}
+
+// diffTokens compares two sequences of tokens, returning a part for each
+// token, using the longest common subsequence
+func diffTokens(a []string, b []string) []DiffPart {
+	// common[i][j] is the length of the longest common subsequence of
+	// a[i:] and b[j:]
+	common := make([][]int, len(a)+1)
+	for i := range common {
+		common[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				common[i][j] = common[i+1][j+1] + 1
+			} else {
+				common[i][j] = max(common[i+1][j], common[i][j+1])
+			}
+		}
+	}
+
+	var parts []DiffPart
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			parts = append(parts, DiffPart{Op: DiffEqual, Text: a[i]})
+			i++
+			j++
+		case i < len(a) && (j == len(b) || common[i+1][j] >= common[i][j+1]):
+			parts = append(parts, DiffPart{Op: DiffDelete, Text: a[i]})
+			i++
+		default:
+			parts = append(parts, DiffPart{Op: DiffInsert, Text: b[j]})
+			j++
+		}
+	}
+	return parts
+}
@@ -0,0 +1,100 @@
+package util
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDiffLines(t *testing.T) {
+	tests := []struct {
+		name     string
+		a        string
+		b        string
+		expected string
+	}{
+		{
+			name:     "Same",
+			a:        "one\ntwo",
+			b:        "one\ntwo",
+			expected: "",
+		},
+		{
+			name:     "Changed line",
+			a:        "one\ntwo\nthree",
+			b:        "one\n2\nthree",
+			expected: " one\n-two\n+2\n three\n",
+		},
+		{
+			name:     "Added and removed lines",
+			a:        "a\nb\nc",
+			b:        "b\nc\nd",
+			expected: "-a\n b\n c\n+d\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := DiffLines(tt.a, tt.b); got != tt.expected {
+				t.Errorf("DiffLines() = %q, expected %q", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestDiffWords(t *testing.T) {
+	tests := []struct {
+		name     string
+		a        string
+		b        string
+		expected []DiffPart
+	}{
+		{
+			name:     "Same",
+			a:        "one two\nthree",
+			b:        "one two\nthree",
+			expected: []DiffPart{{DiffEqual, "one two\nthree"}},
+		},
+		{
+			name: "Changed word",
+			a:    "title: Old\nport: 8080\nhost: example.com\n",
+			b:    "title: Old\nport: 9090\nhost: example.com\n",
+			expected: []DiffPart{
+				{DiffEqual, "title: Old\nport: "},
+				{DiffDelete, "8080"},
+				{DiffInsert, "9090"},
+				{DiffEqual, "\nhost: example.com\n"},
+			},
+		},
+		{
+			name: "Added line",
+			a:    "a\nc\n",
+			b:    "a\nb\nc\n",
+			expected: []DiffPart{
+				{DiffEqual, "a\n"},
+				{DiffInsert, "b\n"},
+				{DiffEqual, "c\n"},
+			},
+		},
+		{
+			name: "Inserted words",
+			a:    "The cat sat.",
+			b:    "The black cat sat down.",
+			expected: []DiffPart{
+				{DiffEqual, "The "},
+				{DiffInsert, "black "},
+				{DiffEqual, "cat sat"},
+				{DiffInsert, " down"},
+				{DiffEqual, "."},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := DiffWords(tt.a, tt.b)
+			if !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("DiffWords() = %q, expected %q", got, tt.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,130 @@
+package util
+
+import (
+	"bytes"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// Byte order marks that identify the encoding of text
+var (
+	utf8BOM    = []byte{0xef, 0xbb, 0xbf}
+	utf16LEBOM = []byte{0xff, 0xfe}
+	utf16BEBOM = []byte{0xfe, 0xff}
+)
+
+// DecodeText converts text to UTF-8. The encoding is identified by a byte
+// order mark, which is removed, or failing that guessed: text with a NUL in
+// every other byte is UTF-16, as Windows PowerShell writes it, and text that
+// isn't valid UTF-8 and has no multibyte UTF-8 characters is Windows-1252,
+// the Windows superset of Latin-1. It returns the name of the encoding
+// converted from, or an empty string if data was already UTF-8.
+func DecodeText(data []byte) (string, string) {
+	switch {
+	case bytes.HasPrefix(data, utf8BOM):
+		return string(data[len(utf8BOM):]), ""
+	case bytes.HasPrefix(data, utf16LEBOM):
+		return decodeUTF16(data[len(utf16LEBOM):], false), "UTF-16LE"
+	case bytes.HasPrefix(data, utf16BEBOM):
+		return decodeUTF16(data[len(utf16BEBOM):], true), "UTF-16BE"
+	}
+
+	if encoding := guessUTF16(data); encoding != "" {
+		return decodeUTF16(data, encoding == "UTF-16BE"), encoding
+	}
+
+	if !utf8.Valid(data) && !hasMultibyteRune(data) {
+		return decodeWindows1252(data), "Windows-1252"
+	}
+	return string(data), ""
+}
+
+// hasMultibyteRune reports whether data contains a valid UTF-8 encoded
+// character of more than one byte, showing that text with some invalid bytes
+// is still meant to be UTF-8
+func hasMultibyteRune(data []byte) bool {
+	for i := 0; i < len(data); {
+		r, size := utf8.DecodeRune(data[i:])
+		if r != utf8.RuneError && size > 1 {
+			return true
+		}
+		i += size
+	}
+	return false
+}
+
+// guessUTF16 returns "UTF-16LE" or "UTF-16BE" if data looks like UTF-16
+// without a byte order mark, or an empty string. Mostly-ASCII UTF-16 has a NUL
+// in nearly every high byte and almost none in the low bytes.
+func guessUTF16(data []byte) string {
+	if len(data) < 4 {
+		return ""
+	}
+
+	var evenNULs, oddNULs int
+	for i := 0; i+1 < len(data); i += 2 {
+		if data[i] == 0 {
+			evenNULs++
+		}
+		if data[i+1] == 0 {
+			oddNULs++
+		}
+	}
+
+	units := len(data) / 2
+	switch {
+	case oddNULs*10 >= units*7 && evenNULs*10 < units:
+		return "UTF-16LE"
+	case evenNULs*10 >= units*7 && oddNULs*10 < units:
+		return "UTF-16BE"
+	}
+	return ""
+}
+
+// decodeUTF16 converts UTF-16 text to UTF-8. A trailing odd byte is dropped.
+func decodeUTF16(data []byte, bigEndian bool) string {
+	units := make([]uint16, len(data)/2)
+	for i := range units {
+		if bigEndian {
+			units[i] = uint16(data[2*i])<<8 | uint16(data[2*i+1])
+		} else {
+			units[i] = uint16(data[2*i]) | uint16(data[2*i+1])<<8
+		}
+	}
+	return string(utf16.Decode(units))
+}
+
+// windows1252 maps bytes 0x80 to 0x9f of Windows-1252 to Unicode. The rest of
+// the code page is the same as Latin-1, whose code points match Unicode's.
+// The five undefined bytes are kept as the control characters Latin-1 has
+// there.
+var windows1252 = [32]rune{
+	'€', 0x81, '‚', 'ƒ', '„', '…', '†', '‡', 'ˆ', '‰', 'Š', '‹', 'Œ', 0x8d, 'Ž', 0x8f,
+	0x90, '‘', '’', '“', '”', '•', '–', '—', '˜', '™', 'š', '›', 'œ', 0x9d, 'ž', 'Ÿ',
+}
+
+// undefinedInWindows1252 reports whether b is one of the bytes Windows-1252
+// doesn't define a character for
+func undefinedInWindows1252(b byte) bool {
+	return b >= 0x80 && b < 0xa0 && windows1252[b-0x80] == rune(b)
+}
+
+// decodeWindows1252 converts Windows-1252 text to UTF-8
+func decodeWindows1252(data []byte) string {
+	var result strings.Builder
+	result.Grow(len(data) + len(data)/8)
+	for _, b := range data {
+		if b >= 0x80 && b < 0xa0 {
+			result.WriteRune(windows1252[b-0x80])
+		} else {
+			result.WriteRune(rune(b))
+		}
+	}
+	return result.String()
+}
+
+// NormalizeNewlines converts Windows line endings to "\n"
+func NormalizeNewlines(text string) string {
+	return strings.ReplaceAll(text, "\r\n", "\n")
+}
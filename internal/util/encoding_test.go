@@ -0,0 +1,41 @@
+package util
+
+import "testing"
+
+func TestDecodeText(t *testing.T) {
+	tests := []struct {
+		name     string
+		data     []byte
+		want     string
+		encoding string
+	}{
+		{"plain UTF-8", []byte("héllo\n"), "héllo\n", ""},
+		{"UTF-8 with BOM", []byte("\xef\xbb\xbfhéllo"), "héllo", ""},
+		{"UTF-16LE", []byte{0xff, 0xfe, 'h', 0, 0xe9, 0, '\n', 0}, "hé\n", "UTF-16LE"},
+		{"UTF-16BE", []byte{0xfe, 0xff, 0, 'h', 0, 0xe9, 0, '\n'}, "hé\n", "UTF-16BE"},
+		{"UTF-16LE surrogate pair", []byte{0xff, 0xfe, 0x3d, 0xd8, 0x00, 0xde}, "😀", "UTF-16LE"},
+		{"UTF-16LE odd length", []byte{0xff, 0xfe, 'h', 0, 'i'}, "h", "UTF-16LE"},
+		{"UTF-16LE without BOM", []byte{'h', 0, 'i', 0, '\n', 0}, "hi\n", "UTF-16LE"},
+		{"UTF-16BE without BOM", []byte{0, 'h', 0, 'i', 0, '\n'}, "hi\n", "UTF-16BE"},
+		{"Latin-1", []byte("caf\xe9 cr\xe8me\n"), "café crème\n", "Windows-1252"},
+		{"Windows-1252 quotes", []byte("\x93quoted\x94 \x80 5"), "“quoted” € 5", "Windows-1252"},
+		{"UTF-8 with a stray byte", []byte("café \xff"), "café \xff", ""},
+		{"NULs in UTF-8", []byte("a\x00b\x00cdefgh"), "a\x00b\x00cdefgh", ""},
+		{"empty", nil, "", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, encoding := DecodeText(tt.data)
+			if got != tt.want || encoding != tt.encoding {
+				t.Errorf("DecodeText() = %q, %q; want %q, %q", got, encoding, tt.want, tt.encoding)
+			}
+		})
+	}
+}
+
+func TestNormalizeNewlines(t *testing.T) {
+	if got := NormalizeNewlines("a\r\nb\nc\r\n"); got != "a\nb\nc\n" {
+		t.Errorf("NormalizeNewlines() = %q", got)
+	}
+}
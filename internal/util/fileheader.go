@@ -0,0 +1,93 @@
+package util
+
+import (
+	"strings"
+	"time"
+
+	"github.com/rba100/aipipe/internal/parsing"
+)
+
+// commentSyntax is how a language writes a comment: each line starts with
+// prefix or, for languages without line comments, the lines go between start
+// and end
+type commentSyntax struct {
+	prefix string
+	start  string
+	end    string
+}
+
+// commentSyntaxes maps canonical language names to their comment syntax.
+// Languages without comments, such as JSON, are left out.
+var commentSyntaxes = map[string]commentSyntax{
+	"go":         {prefix: "// "},
+	"c":          {prefix: "// "},
+	"cpp":        {prefix: "// "},
+	"csharp":     {prefix: "// "},
+	"java":       {prefix: "// "},
+	"kotlin":     {prefix: "// "},
+	"scala":      {prefix: "// "},
+	"swift":      {prefix: "// "},
+	"rust":       {prefix: "// "},
+	"javascript": {prefix: "// "},
+	"typescript": {prefix: "// "},
+	"tsx":        {prefix: "// "},
+	"php":        {prefix: "// "},
+	"dart":       {prefix: "// "},
+	"python":     {prefix: "# "},
+	"bash":       {prefix: "# "},
+	"ruby":       {prefix: "# "},
+	"perl":       {prefix: "# "},
+	"r":          {prefix: "# "},
+	"yaml":       {prefix: "# "},
+	"toml":       {prefix: "# "},
+	"hcl":        {prefix: "# "},
+	"dockerfile": {prefix: "# "},
+	"makefile":   {prefix: "# "},
+	"powershell": {prefix: "# "},
+	"elixir":     {prefix: "# "},
+	"sql":        {prefix: "-- "},
+	"lua":        {prefix: "-- "},
+	"haskell":    {prefix: "-- "},
+	"clojure":    {prefix: ";; "},
+	"lisp":       {prefix: ";; "},
+	"vim":        {prefix: "\" "},
+	"css":        {start: "/*", end: "*/"},
+	"html":       {start: "<!--", end: "-->"},
+	"xml":        {start: "<!--", end: "-->"},
+	"svg":        {start: "<!--", end: "-->"},
+	"markdown":   {start: "<!--", end: "-->"},
+}
+
+// ExpandHeader fills in a file header template, replacing {model} with the
+// model that wrote the file and {date} with today's date
+func ExpandHeader(template string, model string, now time.Time) string {
+	return strings.NewReplacer("{model}", model, "{date}", now.Format("2006-01-02")).Replace(template)
+}
+
+// AddHeader puts header at the top of code, as comments in the syntax of
+// language, after any #! line or XML declaration that must stay first. The
+// code is returned unchanged if the header is empty or the language's comment
+// syntax isn't known.
+func AddHeader(code string, language string, header string) string {
+	header = strings.TrimRight(header, "\n")
+	syntax, ok := commentSyntaxes[parsing.CanonicalLanguage(language)]
+	if header == "" || !ok {
+		return code
+	}
+
+	var comment strings.Builder
+	if syntax.start != "" {
+		comment.WriteString(syntax.start + "\n" + header + "\n" + syntax.end + "\n")
+	} else {
+		for _, line := range strings.Split(header, "\n") {
+			comment.WriteString(strings.TrimRight(syntax.prefix+line, " ") + "\n")
+		}
+	}
+
+	// A #! line, XML declaration or PHP opening tag has to come first
+	first, rest, _ := strings.Cut(code, "\n")
+	if strings.HasPrefix(first, "#!") || strings.HasPrefix(first, "<?") {
+		return first + "\n" + comment.String() + rest
+	}
+	return comment.String() + code
+}
@@ -0,0 +1,90 @@
+package util
+
+import (
+	"testing"
+	"time"
+)
+
+func TestExpandHeader(t *testing.T) {
+	now := time.Date(2026, 3, 4, 15, 0, 0, 0, time.UTC)
+	got := ExpandHeader("Generated by aipipe with {model} on {date}", "gpt-4o", now)
+	if want := "Generated by aipipe with gpt-4o on 2026-03-04"; got != want {
+		t.Errorf("ExpandHeader() = %q, want %q", got, want)
+	}
+}
+
+func TestAddHeader(t *testing.T) {
+	header := "Copyright Example Ltd\n\nGenerated by aipipe\n"
+	tests := []struct {
+		name     string
+		code     string
+		language string
+		header   string
+		expected string
+	}{
+		{
+			name:     "Line comments",
+			code:     "package main\n",
+			language: "go",
+			header:   header,
+			expected: "// Copyright Example Ltd\n//\n// Generated by aipipe\npackage main\n",
+		},
+		{
+			name:     "Alias",
+			code:     "print(1)",
+			language: "py",
+			header:   "Generated",
+			expected: "# Generated\nprint(1)",
+		},
+		{
+			name:     "Block comment",
+			code:     "body { margin: 0 }",
+			language: "css",
+			header:   header,
+			expected: "/*\nCopyright Example Ltd\n\nGenerated by aipipe\n*/\nbody { margin: 0 }",
+		},
+		{
+			name:     "After a #! line",
+			code:     "#!/bin/sh\necho hi\n",
+			language: "bash",
+			header:   "Generated",
+			expected: "#!/bin/sh\n# Generated\necho hi\n",
+		},
+		{
+			name:     "After an XML declaration",
+			code:     "<?xml version=\"1.0\"?>\n<a/>",
+			language: "xml",
+			header:   "Generated",
+			expected: "<?xml version=\"1.0\"?>\n<!--\nGenerated\n-->\n<a/>",
+		},
+		{
+			name:     "No comments in JSON",
+			code:     "{}",
+			language: "json",
+			header:   "Generated",
+			expected: "{}",
+		},
+		{
+			name:     "Unknown language",
+			code:     "x",
+			language: "",
+			header:   "Generated",
+			expected: "x",
+		},
+		{
+			name:     "Empty header",
+			code:     "package main",
+			language: "go",
+			header:   "",
+			expected: "package main",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if result := AddHeader(tt.code, tt.language, tt.header); result != tt.expected {
+				t.Errorf("AddHeader() = %q, want %q", result, tt.expected)
+			}
+		})
+	}
+}
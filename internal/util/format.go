@@ -0,0 +1,43 @@
+package util
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// formatterTimeout bounds how long a formatter may take, so a misbehaving
+// formatter can't hang aipipe
+const formatterTimeout = 10 * time.Second
+
+// FormatCode pipes code through a formatter command line such as "black -q -",
+// which must read the code on stdin and write the formatted code to stdout
+func FormatCode(code string, commandLine string) (string, error) {
+	fields := strings.Fields(commandLine)
+	if len(fields) == 0 {
+		return "", fmt.Errorf("formatter command is empty")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), formatterTimeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, fields[0], fields[1:]...)
+	cmd.Stdin = strings.NewReader(code)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("formatter %s failed: %v: %s", fields[0], err, strings.TrimSpace(stderr.String()))
+	}
+
+	// Keep the code's trailing newline convention rather than the formatter's
+	formatted := stdout.String()
+	if !strings.HasSuffix(code, "\n") {
+		formatted = strings.TrimRight(formatted, "\n")
+	}
+	return formatted, nil
+}
@@ -0,0 +1,29 @@
+package util
+
+import (
+	"os/exec"
+	"strings"
+	"testing"
+)
+
+func TestFormatCode(t *testing.T) {
+	if _, err := exec.LookPath("tr"); err != nil {
+		t.Skip("tr is not installed")
+	}
+
+	formatted, err := FormatCode("hello\nworld", "tr a-z A-Z")
+	if err != nil {
+		t.Fatalf("FormatCode() error: %v", err)
+	}
+	if formatted != "HELLO\nWORLD" {
+		t.Errorf("FormatCode() = %q, want %q", formatted, "HELLO\nWORLD")
+	}
+
+	if _, err := FormatCode("code", ""); err == nil {
+		t.Errorf("FormatCode() with an empty command succeeded, want an error")
+	}
+
+	if _, err := FormatCode("code", "no-such-formatter-command"); err == nil || !strings.Contains(err.Error(), "no-such-formatter-command") {
+		t.Errorf("FormatCode() error = %v, want an error naming the formatter", err)
+	}
+}
@@ -0,0 +1,125 @@
+package util
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+// HTTPRequest is a request written by the model for the user to send
+type HTTPRequest struct {
+	Method  string            `json:"method"`
+	URL     string            `json:"url"`
+	Headers map[string]string `json:"headers,omitempty"`
+	Body    string            `json:"body,omitempty"`
+}
+
+// httpMethods are the methods a generated request may use
+var httpMethods = map[string]bool{
+	http.MethodGet:    true,
+	http.MethodHead:   true,
+	http.MethodPost:   true,
+	http.MethodPut:    true,
+	http.MethodPatch:  true,
+	http.MethodDelete: true,
+}
+
+// ParseHTTPRequest reads the request described by a JSON object in a model's
+// response, in a code block or on its own, and checks it can be sent
+func ParseHTTPRequest(response string) (*HTTPRequest, error) {
+	text := strings.TrimSpace(ExtractCodeBlock(response).Text)
+	if start, end := strings.Index(text, "{"), strings.LastIndex(text, "}"); start >= 0 && end > start {
+		text = text[start : end+1]
+	}
+
+	var request HTTPRequest
+	if err := json.Unmarshal([]byte(text), &request); err != nil {
+		return nil, fmt.Errorf("the model didn't describe a request: %v", err)
+	}
+
+	request.Method = strings.ToUpper(request.Method)
+	if request.Method == "" {
+		request.Method = http.MethodGet
+	}
+	if !httpMethods[request.Method] {
+		return nil, fmt.Errorf("unsupported HTTP method %q", request.Method)
+	}
+	parsed, err := url.Parse(request.URL)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return nil, fmt.Errorf("invalid request URL %q", request.URL)
+	}
+	return &request, nil
+}
+
+// Curl returns the curl command that sends the request, for showing to the
+// user
+func (r *HTTPRequest) Curl() string {
+	parts := []string{"curl"}
+	if r.Method != http.MethodGet {
+		parts = append(parts, "-X", r.Method)
+	}
+	for _, name := range r.headerNames() {
+		parts = append(parts, "-H", shellQuote(name+": "+r.Headers[name]))
+	}
+	if r.Body != "" {
+		parts = append(parts, "--data", shellQuote(r.Body))
+	}
+	parts = append(parts, shellQuote(r.URL))
+	return strings.Join(parts, " ")
+}
+
+// headerNames returns the names of the request's headers in order
+func (r *HTTPRequest) headerNames() []string {
+	names := make([]string, 0, len(r.Headers))
+	for name := range r.Headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// shellQuote quotes s for a POSIX shell if it contains anything but safe
+// characters
+func shellQuote(s string) string {
+	if s != "" && strings.Trim(s, "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789-_./:=,@%+") == "" {
+		return s
+	}
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// Send sends the request with the extra headers given, as "Name: value", and
+// returns the response status line and at most maxBytes of its body
+func (r *HTTPRequest) Send(extraHeaders []string, maxBytes int64) (string, string, error) {
+	request, err := http.NewRequest(r.Method, r.URL, strings.NewReader(r.Body))
+	if err != nil {
+		return "", "", fmt.Errorf("error creating request: %v", err)
+	}
+	for name, value := range r.Headers {
+		request.Header.Set(name, value)
+	}
+	for _, header := range extraHeaders {
+		name, value, ok := strings.Cut(header, ":")
+		if !ok {
+			return "", "", fmt.Errorf("invalid header %q, expected \"Name: value\"", header)
+		}
+		request.Header.Set(strings.TrimSpace(name), strings.TrimSpace(value))
+	}
+
+	client := &http.Client{Timeout: 30 * time.Second}
+	response, err := client.Do(request)
+	if err != nil {
+		return "", "", fmt.Errorf("error sending request: %v", err)
+	}
+	defer response.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(response.Body, maxBytes))
+	if err != nil {
+		return "", "", fmt.Errorf("error reading response: %v", err)
+	}
+	return response.Proto + " " + response.Status, string(body), nil
+}
@@ -0,0 +1,60 @@
+package util
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestParseHTTPRequest(t *testing.T) {
+	response := "Here's the request:\n```json\n" +
+		`{"method": "get", "url": "https://api.github.com/repos/o/r/issues?per_page=5", "headers": {"Accept": "application/vnd.github+json"}}` +
+		"\n```"
+	expected := &HTTPRequest{
+		Method:  "GET",
+		URL:     "https://api.github.com/repos/o/r/issues?per_page=5",
+		Headers: map[string]string{"Accept": "application/vnd.github+json"},
+	}
+	got, err := ParseHTTPRequest(response)
+	if err != nil || !reflect.DeepEqual(got, expected) {
+		t.Errorf("ParseHTTPRequest() = %+v, %v, expected %+v", got, err, expected)
+	}
+
+	for _, invalid := range []string{
+		"I can't help with that.",
+		`{"method": "CONNECT", "url": "https://example.com"}`,
+		`{"method": "GET", "url": "file:///etc/passwd"}`,
+	} {
+		if _, err := ParseHTTPRequest(invalid); err == nil {
+			t.Errorf("ParseHTTPRequest(%q) accepted an invalid request", invalid)
+		}
+	}
+}
+
+func TestHTTPRequestCurl(t *testing.T) {
+	request := &HTTPRequest{
+		Method:  "POST",
+		URL:     "https://example.com/api?q=1&x=2",
+		Headers: map[string]string{"Content-Type": "application/json"},
+		Body:    `{"name": "it's"}`,
+	}
+	expected := `curl -X POST -H 'Content-Type: application/json' --data '{"name": "it'\''s"}' 'https://example.com/api?q=1&x=2'`
+	if got := request.Curl(); got != expected {
+		t.Errorf("Curl() = %s, expected %s", got, expected)
+	}
+}
+
+func TestHTTPRequestSend(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, "%s %s", r.Method, r.Header.Get("Authorization"))
+	}))
+	defer server.Close()
+
+	request := &HTTPRequest{Method: "GET", URL: server.URL}
+	status, body, err := request.Send([]string{"Authorization: Bearer secret"}, 1000)
+	if err != nil || status != "HTTP/1.1 200 OK" || body != "GET Bearer secret" {
+		t.Errorf("Send() = %q, %q, %v", status, body, err)
+	}
+}
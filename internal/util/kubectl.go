@@ -0,0 +1,147 @@
+package util
+
+import (
+	"fmt"
+	"strings"
+)
+
+// kubectlVerbs are the kubectl subcommands the model may ask to run. They
+// only read from the cluster.
+var kubectlVerbs = map[string]bool{
+	"get":      true,
+	"describe": true,
+	"logs":     true,
+}
+
+// kubectlForbiddenFlags are flags the model may not pass: the namespace and
+// cluster are chosen by the user, and watching or following never finishes
+var kubectlForbiddenFlags = map[string]bool{
+	"-n":               true,
+	"--namespace":      true,
+	"-A":               true,
+	"--all-namespaces": true,
+	"--context":        true,
+	"--cluster":        true,
+	"--user":           true,
+	"--kubeconfig":     true,
+	"--server":         true,
+	"-s":               true,
+	"--token":          true,
+	"--as":             true,
+	"--as-group":       true,
+	"-w":               true,
+	"--watch":          true,
+	"--watch-only":     true,
+	"-f":               true,
+	"--follow":         true,
+}
+
+// kubectlValueShorthands are the one letter flags of kubectl get, describe and
+// logs that take a value, which may be attached, as in -ojson
+var kubectlValueShorthands = map[byte]bool{
+	'o': true,
+	'l': true,
+	'L': true,
+	'c': true,
+	'f': true,
+	'k': true,
+	'n': true,
+	's': true,
+}
+
+// argFlags returns the flags named by a command line argument, the way
+// kubectl reads them: --flag=value names --flag, and a group of one letter
+// flags such as -Aw or -nkube-system names each letter up to the first that
+// takes a value, which takes the rest
+func argFlags(arg string) []string {
+	if strings.HasPrefix(arg, "--") {
+		flag, _, _ := strings.Cut(arg, "=")
+		return []string{flag}
+	}
+	if !strings.HasPrefix(arg, "-") || len(arg) < 2 {
+		return nil
+	}
+
+	var flags []string
+	for i := 1; i < len(arg); i++ {
+		flags = append(flags, "-"+arg[i:i+1])
+		if kubectlValueShorthands[arg[i]] {
+			break
+		}
+	}
+	return flags
+}
+
+// ParseKubectlRequest returns the arguments of the kubectl command the model
+// asked to run with a "RUN: kubectl ..." line, or false if the reply is an
+// answer instead
+func ParseKubectlRequest(reply string) ([]string, bool) {
+	for _, line := range strings.Split(reply, "\n") {
+		line = strings.Trim(strings.TrimSpace(line), "`")
+		command, ok := strings.CutPrefix(line, "RUN:")
+		if !ok {
+			continue
+		}
+
+		args := strings.Fields(command)
+		if len(args) > 0 && args[0] == "kubectl" {
+			args = args[1:]
+		}
+		return args, true
+	}
+	return nil, false
+}
+
+// CheckKubectlArgs returns an error, to be passed back to the model, if a
+// kubectl command isn't one of the read-only commands it may run
+func CheckKubectlArgs(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("no kubectl command was given")
+	}
+	if !kubectlVerbs[args[0]] {
+		return fmt.Errorf("only kubectl get, describe and logs may be run, not %q", args[0])
+	}
+
+	for _, arg := range args[1:] {
+		for _, flag := range argFlags(arg) {
+			if kubectlForbiddenFlags[flag] {
+				return fmt.Errorf("the %s flag isn't allowed", flag)
+			}
+		}
+		if strings.ContainsAny(arg, "|;&<>`$") {
+			return fmt.Errorf("commands are run without a shell, so %q can't be used", arg)
+		}
+		if args[0] == "get" && strings.Contains(strings.ToLower(arg), "secret") {
+			return fmt.Errorf("secrets may not be read")
+		}
+	}
+	return nil
+}
+
+// KubectlCommandLine returns the full argument list for running a checked
+// kubectl command in the namespace and context chosen by the user. Either may
+// be empty to use kubectl's defaults. Logs are limited to their last lines
+// unless the command says otherwise.
+func KubectlCommandLine(args []string, namespace string, context string, logLines int) []string {
+	var line []string
+	if context != "" {
+		line = append(line, "--context", context)
+	}
+	if namespace != "" {
+		line = append(line, "--namespace", namespace)
+	}
+	line = append(line, args...)
+
+	if args[0] == "logs" {
+		hasTail := false
+		for _, arg := range args {
+			if arg == "--tail" || strings.HasPrefix(arg, "--tail=") {
+				hasTail = true
+			}
+		}
+		if !hasTail {
+			line = append(line, fmt.Sprintf("--tail=%d", logLines))
+		}
+	}
+	return line
+}
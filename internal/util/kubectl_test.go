@@ -0,0 +1,69 @@
+package util
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseKubectlRequest(t *testing.T) {
+	args, ok := ParseKubectlRequest("RUN: kubectl get pods -o wide")
+	if !ok || !reflect.DeepEqual(args, []string{"get", "pods", "-o", "wide"}) {
+		t.Errorf("ParseKubectlRequest() = %v, %v", args, ok)
+	}
+
+	args, ok = ParseKubectlRequest("Let me check.\n`RUN: kubectl describe pod web-1`")
+	if !ok || !reflect.DeepEqual(args, []string{"describe", "pod", "web-1"}) {
+		t.Errorf("ParseKubectlRequest() = %v, %v", args, ok)
+	}
+
+	if _, ok := ParseKubectlRequest("## Cause\nThe pod runs out of memory."); ok {
+		t.Errorf("ParseKubectlRequest() found a command in an answer")
+	}
+}
+
+func TestCheckKubectlArgs(t *testing.T) {
+	tests := []struct {
+		args    []string
+		allowed bool
+	}{
+		{[]string{"get", "pods"}, true},
+		{[]string{"describe", "pod", "web-1"}, true},
+		{[]string{"logs", "web-1", "--previous", "--tail=50"}, true},
+		{nil, false},
+		{[]string{"delete", "pod", "web-1"}, false},
+		{[]string{"exec", "web-1", "--", "sh"}, false},
+		{[]string{"get", "pods", "-n", "kube-system"}, false},
+		{[]string{"get", "pods", "--namespace=kube-system"}, false},
+		{[]string{"get", "pods", "--context", "prod"}, false},
+		{[]string{"get", "pods", "-nkube-system"}, false},
+		{[]string{"get", "pods", "-n=kube-system"}, false},
+		{[]string{"get", "pods", "-Aw"}, false},
+		{[]string{"logs", "web-1", "-pf"}, false},
+		{[]string{"get", "pods", "-ojsonpath={.items[*].metadata.name}"}, true},
+		{[]string{"logs", "web-1", "-cnginx"}, true},
+		{[]string{"logs", "web-1", "-f"}, false},
+		{[]string{"get", "pods", "-w"}, false},
+		{[]string{"get", "secret", "db-password", "-o", "yaml"}, false},
+		{[]string{"get", "pods", ">", "out.txt"}, false},
+	}
+
+	for _, tt := range tests {
+		err := CheckKubectlArgs(tt.args)
+		if (err == nil) != tt.allowed {
+			t.Errorf("CheckKubectlArgs(%v) = %v, expected allowed = %v", tt.args, err, tt.allowed)
+		}
+	}
+}
+
+func TestKubectlCommandLine(t *testing.T) {
+	got := KubectlCommandLine([]string{"logs", "web-1"}, "shop", "prod", 200)
+	expected := []string{"--context", "prod", "--namespace", "shop", "logs", "web-1", "--tail=200"}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("KubectlCommandLine() = %v, expected %v", got, expected)
+	}
+
+	got = KubectlCommandLine([]string{"get", "pods"}, "", "", 200)
+	if !reflect.DeepEqual(got, []string{"get", "pods"}) {
+		t.Errorf("KubectlCommandLine() = %v", got)
+	}
+}
@@ -0,0 +1,189 @@
+package util
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+var (
+	// logTimestampRegexes match the timestamps of common log formats: ISO
+	// 8601, syslog, Apache common log format and bare times of day
+	logTimestampRegexes = []*regexp.Regexp{
+		regexp.MustCompile(`\d{4}-\d{2}-\d{2}[T ]\d{2}:\d{2}:\d{2}([.,]\d+)?(Z|[+-]\d{2}:?\d{2})?`),
+		regexp.MustCompile(`\b(Jan|Feb|Mar|Apr|May|Jun|Jul|Aug|Sep|Oct|Nov|Dec) [ \d]\d \d{2}:\d{2}:\d{2}`),
+		regexp.MustCompile(`\d{2}/(Jan|Feb|Mar|Apr|May|Jun|Jul|Aug|Sep|Oct|Nov|Dec)/\d{4}:\d{2}:\d{2}:\d{2}( [+-]\d{4})?`),
+		regexp.MustCompile(`\b\d{2}:\d{2}:\d{2}([.,]\d+)?\b`),
+	}
+	logUUIDRegex = regexp.MustCompile(`\b[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}\b`)
+	// logHexIDRegex matches long hexadecimal ids such as trace ids and
+	// commit hashes, which must contain a digit so words aren't matched
+	logHexIDRegex = regexp.MustCompile(`\b(0x)?[0-9a-fA-F]*[0-9][0-9a-fA-F]*\b`)
+	logErrorRegex = regexp.MustCompile(`(?i)\b(error|err|fatal|panic|exception|fail|failed|failure|critical|crit|severe|emerg|alert)\b`)
+)
+
+// logMessageKeys and logLevelKeys are the fields JSON log lines commonly keep
+// their message and level in; logTimeKeys are dropped
+var (
+	logMessageKeys = []string{"msg", "message", "MESSAGE", "log"}
+	logLevelKeys   = []string{"level", "lvl", "severity", "levelname", "PRIORITY"}
+	logTimeKeys    = []string{"time", "ts", "timestamp", "@timestamp", "t", "date", "asctime"}
+)
+
+// logEntry is a distinct line of a log and how often it appeared
+type logEntry struct {
+	text    string
+	count   int
+	isError bool
+	// last is the index of the line's last appearance
+	last int
+}
+
+// PreprocessLogs condenses a log so a model can make sense of it within its
+// context window. Timestamps, UUIDs and long hex ids are replaced with
+// placeholders, JSON log lines are rewritten as "level message key=value",
+// and repeated lines are kept once with a count. If more than maxLines
+// distinct lines remain, the most recent error lines are kept, and the rest of
+// the space goes to the end of the log. It returns the condensed log and the
+// number of distinct lines it found.
+func PreprocessLogs(log string, maxLines int) (string, int) {
+	var entries []*logEntry
+	index := map[string]*logEntry{}
+	total := 0
+
+	for i, line := range strings.Split(strings.TrimRight(log, "\n"), "\n") {
+		line = normalizeLogLine(line)
+		if line == "" {
+			continue
+		}
+		total++
+
+		entry, ok := index[line]
+		if !ok {
+			entry = &logEntry{text: line, isError: logErrorRegex.MatchString(line)}
+			index[line] = entry
+			entries = append(entries, entry)
+		}
+		entry.count++
+		entry.last = i
+	}
+
+	kept := entries
+	if len(entries) > maxLines {
+		kept = selectLogEntries(entries, maxLines)
+	}
+
+	var builder strings.Builder
+	fmt.Fprintf(&builder, "[Log of %d lines with %d distinct lines. Timestamps and ids are replaced by placeholders and repeated lines are shown once with a count.", total, len(entries))
+	if len(kept) < len(entries) {
+		fmt.Fprintf(&builder, " Only the last %d distinct lines, including the last errors, are shown.", len(kept))
+	}
+	builder.WriteString("]\n")
+
+	for _, entry := range kept {
+		if entry.count > 1 {
+			fmt.Fprintf(&builder, "(x%d) ", entry.count)
+		}
+		builder.WriteString(entry.text)
+		builder.WriteString("\n")
+	}
+
+	return builder.String(), len(entries)
+}
+
+// selectLogEntries chooses at most maxLines entries to show: the most recent
+// distinct errors, up to half the space, then the most recent of the rest.
+// They are returned in the order they first appeared.
+func selectLogEntries(entries []*logEntry, maxLines int) []*logEntry {
+	byLast := append([]*logEntry(nil), entries...)
+	sort.SliceStable(byLast, func(i, j int) bool { return byLast[i].last > byLast[j].last })
+
+	chosen := map[*logEntry]bool{}
+	for _, entry := range byLast {
+		if len(chosen) >= maxLines/2 {
+			break
+		}
+		if entry.isError {
+			chosen[entry] = true
+		}
+	}
+	for _, entry := range byLast {
+		if len(chosen) >= maxLines {
+			break
+		}
+		chosen[entry] = true
+	}
+
+	var kept []*logEntry
+	for _, entry := range entries {
+		if chosen[entry] {
+			kept = append(kept, entry)
+		}
+	}
+	return kept
+}
+
+// normalizeLogLine rewrites JSON log lines as text and replaces the parts of
+// a line that change every time it is logged with placeholders
+func normalizeLogLine(line string) string {
+	line = strings.TrimSpace(line)
+	if strings.HasPrefix(line, "{") {
+		if text, ok := jsonLogText(line); ok {
+			line = text
+		}
+	}
+
+	for _, timestampRegex := range logTimestampRegexes {
+		line = timestampRegex.ReplaceAllString(line, "<time>")
+	}
+	line = logUUIDRegex.ReplaceAllString(line, "<uuid>")
+	line = logHexIDRegex.ReplaceAllStringFunc(line, func(match string) string {
+		if len(strings.TrimPrefix(match, "0x")) < 12 {
+			return match
+		}
+		return "<id>"
+	})
+	return line
+}
+
+// jsonLogText rewrites a structured JSON log line as its level, its message
+// and its other fields in key order, leaving out the timestamp
+func jsonLogText(line string) (string, bool) {
+	var fields map[string]interface{}
+	if err := json.Unmarshal([]byte(line), &fields); err != nil {
+		return "", false
+	}
+
+	var parts []string
+	take := func(keys []string) {
+		for _, key := range keys {
+			if value, ok := fields[key]; ok {
+				parts = append(parts, fmt.Sprint(value))
+				delete(fields, key)
+				return
+			}
+		}
+	}
+	take(logLevelKeys)
+	take(logMessageKeys)
+	for _, key := range logTimeKeys {
+		delete(fields, key)
+	}
+
+	keys := make([]string, 0, len(fields))
+	for key := range fields {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		value, err := json.Marshal(fields[key])
+		if err != nil {
+			continue
+		}
+		parts = append(parts, key+"="+string(value))
+	}
+
+	return strings.Join(parts, " "), true
+}
@@ -0,0 +1,78 @@
+package util
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestNormalizeLogLine(t *testing.T) {
+	tests := []struct {
+		name     string
+		line     string
+		expected string
+	}{
+		{
+			name:     "ISO timestamp and UUID",
+			line:     "2024-05-01T10:00:01.123Z INFO request 3f2b8c1e-9a4d-4c2e-8f1a-1234567890ab done",
+			expected: "<time> INFO request <uuid> done",
+		},
+		{
+			name:     "Syslog",
+			line:     "May  1 10:00:01 host sshd[123]: Accepted publickey",
+			expected: "<time> host sshd[123]: Accepted publickey",
+		},
+		{
+			name:     "Common log format",
+			line:     `127.0.0.1 - - [10/Oct/2000:13:55:36 -0700] "GET / HTTP/1.0" 200 2326`,
+			expected: `127.0.0.1 - - [<time>] "GET / HTTP/1.0" 200 2326`,
+		},
+		{
+			name:     "Trace id",
+			line:     "span 4bf92f3577b34da6a3ce929d0e0e4736 ended",
+			expected: "span <id> ended",
+		},
+		{
+			name:     "JSON",
+			line:     `{"time":"2024-05-01T10:00:01Z","level":"error","msg":"query failed","table":"users","attempt":3}`,
+			expected: `error query failed attempt=3 table="users"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := normalizeLogLine(tt.line); got != tt.expected {
+				t.Errorf("normalizeLogLine(%q) = %q, expected %q", tt.line, got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestPreprocessLogs(t *testing.T) {
+	log := "10:00:01 starting\n10:00:02 ERROR db unreachable\n10:00:03 ERROR db unreachable\n10:00:04 ready\n"
+	got, distinct := PreprocessLogs(log, 100)
+	expected := "[Log of 4 lines with 3 distinct lines. Timestamps and ids are replaced by placeholders and repeated lines are shown once with a count.]\n" +
+		"<time> starting\n(x2) <time> ERROR db unreachable\n<time> ready\n"
+	if got != expected || distinct != 3 {
+		t.Errorf("PreprocessLogs() = %q, %d, expected %q, 3", got, distinct, expected)
+	}
+}
+
+func TestPreprocessLogsKeepsErrors(t *testing.T) {
+	var lines []string
+	lines = append(lines, "ERROR disk full")
+	for i := 0; i < 20; i++ {
+		lines = append(lines, fmt.Sprintf("processed item %d", i))
+	}
+
+	got, _ := PreprocessLogs(strings.Join(lines, "\n"), 4)
+	if !strings.Contains(got, "ERROR disk full\n") {
+		t.Errorf("PreprocessLogs() dropped the error line: %q", got)
+	}
+	if !strings.Contains(got, "processed item 19\n") || strings.Contains(got, "processed item 15\n") {
+		t.Errorf("PreprocessLogs() didn't keep the end of the log: %q", got)
+	}
+	if !strings.Contains(got, "Only the last 4 distinct lines") {
+		t.Errorf("PreprocessLogs() didn't say lines were left out: %q", got)
+	}
+}
@@ -0,0 +1,63 @@
+package util
+
+import (
+	"fmt"
+	"os/exec"
+	"runtime"
+	"strings"
+)
+
+// Notify shows a desktop notification using notify-send on Linux and BSD,
+// osascript on macOS or a PowerShell toast on Windows. It does nothing if the
+// notifier isn't installed.
+func Notify(title string, message string) error {
+	name, args := notificationCommand(runtime.GOOS, title, message)
+	if name == "" {
+		return nil
+	}
+	if _, err := exec.LookPath(name); err != nil {
+		return nil
+	}
+
+	output, err := exec.Command(name, args...).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("%s failed: %v: %s", name, err, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
+
+// notificationCommand returns the command that shows a notification on the
+// given operating system, or an empty name if there isn't one
+func notificationCommand(goos string, title string, message string) (string, []string) {
+	switch goos {
+	case "darwin":
+		script := fmt.Sprintf("display notification %s with title %s", appleScriptString(message), appleScriptString(title))
+		return "osascript", []string{"-e", script}
+	case "windows":
+		script := strings.Join([]string{
+			"[Windows.UI.Notifications.ToastNotificationManager, Windows.UI.Notifications, ContentType = WindowsRuntime] | Out-Null",
+			"$template = [Windows.UI.Notifications.ToastNotificationManager]::GetTemplateContent([Windows.UI.Notifications.ToastTemplateType]::ToastText02)",
+			"$text = $template.GetElementsByTagName('text')",
+			"$text.Item(0).AppendChild($template.CreateTextNode(" + powerShellString(title) + ")) | Out-Null",
+			"$text.Item(1).AppendChild($template.CreateTextNode(" + powerShellString(message) + ")) | Out-Null",
+			"[Windows.UI.Notifications.ToastNotificationManager]::CreateToastNotifier('aipipe').Show([Windows.UI.Notifications.ToastNotification]::new($template))",
+		}, "; ")
+		return "powershell", []string{"-NoProfile", "-NonInteractive", "-Command", script}
+	case "linux", "freebsd", "openbsd", "netbsd":
+		return "notify-send", []string{"--app-name=aipipe", title, message}
+	default:
+		return "", nil
+	}
+}
+
+// appleScriptString quotes s as an AppleScript string literal
+func appleScriptString(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	s = strings.ReplaceAll(s, `"`, `\"`)
+	return `"` + s + `"`
+}
+
+// powerShellString quotes s as a PowerShell verbatim string literal
+func powerShellString(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
@@ -0,0 +1,28 @@
+package util
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestNotificationCommand(t *testing.T) {
+	name, args := notificationCommand("linux", "aipipe", "Answer ready")
+	if name != "notify-send" || args[len(args)-2] != "aipipe" || args[len(args)-1] != "Answer ready" {
+		t.Errorf("notificationCommand(linux) = %s %q", name, args)
+	}
+
+	name, args = notificationCommand("darwin", "aipipe", `say "hi" \o/`)
+	want := `display notification "say \"hi\" \\o/" with title "aipipe"`
+	if name != "osascript" || args[1] != want {
+		t.Errorf("notificationCommand(darwin) = %s %q, want script %q", name, args, want)
+	}
+
+	name, args = notificationCommand("windows", "aipipe", "it's done")
+	if name != "powershell" || !strings.Contains(args[len(args)-1], "'it''s done'") {
+		t.Errorf("notificationCommand(windows) = %s %q, want the message quoted", name, args)
+	}
+
+	if name, _ := notificationCommand("plan9", "aipipe", "Answer ready"); name != "" {
+		t.Errorf("notificationCommand(plan9) = %s, want no command", name)
+	}
+}
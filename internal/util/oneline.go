@@ -0,0 +1,37 @@
+package util
+
+import (
+	"regexp"
+	"strings"
+)
+
+// confidenceRegex matches the confidence rating requested by the one line
+// system prompt, wherever the model put it
+var confidenceRegex = regexp.MustCompile(`(?i)[\s(\[-]*confidence\s*:\s*(high|medium|low)[\s)\]]*`)
+
+// FormatOneLine reduces a model response to a single line answer, for use in
+// command substitution. Code fences, blank lines and anything after the first
+// line of the answer are dropped. If withConfidence is true and the response
+// includes a confidence rating, it is appended as " (confidence: level)".
+func FormatOneLine(response string, withConfidence bool) string {
+	confidence := ""
+	if match := confidenceRegex.FindStringSubmatch(response); match != nil {
+		confidence = strings.ToLower(match[1])
+		response = confidenceRegex.ReplaceAllString(response, "\n")
+	}
+
+	answer := ""
+	for _, line := range strings.Split(response, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "```") {
+			continue
+		}
+		answer = strings.Trim(line, "`")
+		break
+	}
+
+	if withConfidence && confidence != "" {
+		answer += " (confidence: " + confidence + ")"
+	}
+	return answer
+}
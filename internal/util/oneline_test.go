@@ -0,0 +1,64 @@
+package util
+
+import "testing"
+
+func TestFormatOneLine(t *testing.T) {
+	tests := []struct {
+		name           string
+		response       string
+		withConfidence bool
+		expected       string
+	}{
+		{
+			name:     "Plain answer",
+			response: "+05:30\n",
+			expected: "+05:30",
+		},
+		{
+			name:     "Extra lines are dropped",
+			response: "\n  UTC+5:30  \nIndia Standard Time is ahead of UTC.",
+			expected: "UTC+5:30",
+		},
+		{
+			name:     "Code fence",
+			response: "```bash\nls -la\n```",
+			expected: "ls -la",
+		},
+		{
+			name:     "Inline code",
+			response: "`git status`",
+			expected: "git status",
+		},
+		{
+			name:           "Confidence on second line",
+			response:       "+05:30\nConfidence: High",
+			withConfidence: true,
+			expected:       "+05:30 (confidence: high)",
+		},
+		{
+			name:           "Inline confidence",
+			response:       "42 (confidence: low)",
+			withConfidence: true,
+			expected:       "42 (confidence: low)",
+		},
+		{
+			name:     "Confidence dropped when not requested",
+			response: "42\nconfidence: medium",
+			expected: "42",
+		},
+		{
+			name:           "Missing confidence",
+			response:       "42",
+			withConfidence: true,
+			expected:       "42",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := FormatOneLine(tt.response, tt.withConfidence); got != tt.expected {
+				t.Errorf("FormatOneLine() = %q, want %q", got, tt.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,92 @@
+package util
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// oneLinerTools are the programs a generated one-liner may run, by the
+// language they take
+var oneLinerTools = map[string]string{
+	"jq":   "jq",
+	"awk":  "awk",
+	"gawk": "awk",
+	"mawk": "awk",
+	"sed":  "sed",
+	"gsed": "sed",
+}
+
+// awkUnsafe are awk features that run commands, read files or write files.
+// They aren't needed to transform stdin to stdout.
+var awkUnsafe = []string{"system", "getline", "print >", "printf >", "print>", "printf>", "| \"", "|\""}
+
+// oneLinerTimeout limits how long a one-liner may run on the sample input
+const oneLinerTimeout = 10 * time.Second
+
+// CheckOneLiner returns an error if a generated one-liner, split into words,
+// isn't a jq, awk or sed command that only transforms stdin to stdout
+func CheckOneLiner(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("the command is empty")
+	}
+	language, ok := oneLinerTools[filepath.Base(args[0])]
+	if !ok {
+		return fmt.Errorf("only jq, awk and sed can be run, not %q", args[0])
+	}
+
+	for _, arg := range args[1:] {
+		switch language {
+		case "sed":
+			if arg == "-i" || strings.HasPrefix(arg, "--in-place") || (strings.HasPrefix(arg, "-") && !strings.HasPrefix(arg, "--") && strings.Contains(arg, "i")) {
+				return fmt.Errorf("sed may not edit files in place")
+			}
+		case "awk":
+			for _, unsafe := range awkUnsafe {
+				if strings.Contains(arg, unsafe) {
+					return fmt.Errorf("awk programs may not use %q", strings.TrimSpace(unsafe))
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// RunOneLiner runs a checked one-liner with input on stdin and returns its
+// output. GNU sed and gawk are run in their sandbox modes, which stop them
+// running commands and reading or writing files.
+func RunOneLiner(args []string, input string) (string, error) {
+	if err := CheckOneLiner(args); err != nil {
+		return "", err
+	}
+	if oneLinerTools[filepath.Base(args[0])] != "jq" && isGNUTool(args[0]) {
+		args = append([]string{args[0], "--sandbox"}, args[1:]...)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), oneLinerTimeout)
+	defer cancel()
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, args[0], args[1:]...)
+	cmd.Stdin = strings.NewReader(input)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if message := strings.TrimSpace(stderr.String()); message != "" {
+			return stdout.String(), fmt.Errorf("%s", message)
+		}
+		return stdout.String(), err
+	}
+	return stdout.String(), nil
+}
+
+// isGNUTool reports whether a program is the GNU version, which supports
+// --sandbox
+func isGNUTool(name string) bool {
+	output, err := exec.Command(name, "--version").Output()
+	return err == nil && strings.Contains(string(output), "GNU")
+}
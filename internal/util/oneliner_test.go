@@ -0,0 +1,46 @@
+package util
+
+import (
+	"os/exec"
+	"testing"
+)
+
+func TestCheckOneLiner(t *testing.T) {
+	tests := []struct {
+		args    []string
+		allowed bool
+	}{
+		{[]string{"jq", "-r", ".name"}, true},
+		{[]string{"awk", "-F,", "{ print $2 }"}, true},
+		{[]string{"sed", "-E", "s/a/b/"}, true},
+		{[]string{"/usr/bin/gawk", "NR > 1"}, true},
+		{nil, false},
+		{[]string{"python3", "-c", "print(1)"}, false},
+		{[]string{"sed", "-i", "s/a/b/", "file"}, false},
+		{[]string{"sed", "-Ei", "s/a/b/", "file"}, false},
+		{[]string{"awk", `{ system("rm -rf /") }`}, false},
+		{[]string{"awk", `{ print > "out.txt" }`}, false},
+		{[]string{"awk", `{ print | "sh" }`}, false},
+	}
+
+	for _, tt := range tests {
+		if err := CheckOneLiner(tt.args); (err == nil) != tt.allowed {
+			t.Errorf("CheckOneLiner(%q) = %v, expected allowed = %v", tt.args, err, tt.allowed)
+		}
+	}
+}
+
+func TestRunOneLiner(t *testing.T) {
+	if _, err := exec.LookPath("sed"); err != nil {
+		t.Skip("sed is not installed")
+	}
+
+	output, err := RunOneLiner([]string{"sed", "s/cat/dog/"}, "the cat sat\n")
+	if err != nil || output != "the dog sat\n" {
+		t.Errorf("RunOneLiner() = %q, %v", output, err)
+	}
+
+	if _, err := RunOneLiner([]string{"sed", "s/unterminated"}, "x\n"); err == nil {
+		t.Errorf("RunOneLiner() didn't report sed's error")
+	}
+}
@@ -0,0 +1,176 @@
+package util
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+)
+
+// aipipe's files follow the XDG base directory specification: settings in
+// the config directory, things the user creates in the data directory and
+// history in the state directory. Older versions kept everything in
+// ~/.aipipe, which is still used for anything that hasn't been migrated.
+
+// legacyDir returns ~/.aipipe, where older versions kept all their files
+func legacyDir() (string, error) {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get user home directory: %w", err)
+	}
+	return filepath.Join(homeDir, ".aipipe"), nil
+}
+
+// xdgDir returns aipipe's directory in the base directory named by the
+// environment variable env, or in defaultDir under the home directory when it
+// isn't set. Relative paths are ignored, as the specification requires. On
+// Windows, where XDG isn't a convention, the default is ~/.aipipe.
+func xdgDir(env string, defaultDir ...string) (string, error) {
+	if dir := os.Getenv(env); dir != "" && filepath.IsAbs(dir) {
+		return filepath.Join(dir, "aipipe"), nil
+	}
+	if runtime.GOOS == "windows" {
+		return legacyDir()
+	}
+
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get user home directory: %w", err)
+	}
+	return filepath.Join(append(append([]string{homeDir}, defaultDir...), "aipipe")...), nil
+}
+
+// ConfigDir returns aipipe's config directory, $XDG_CONFIG_HOME/aipipe
+func ConfigDir() (string, error) {
+	return xdgDir("XDG_CONFIG_HOME", ".config")
+}
+
+// DataDir returns aipipe's data directory, $XDG_DATA_HOME/aipipe
+func DataDir() (string, error) {
+	return xdgDir("XDG_DATA_HOME", ".local", "share")
+}
+
+// StateDir returns aipipe's state directory, $XDG_STATE_HOME/aipipe
+func StateDir() (string, error) {
+	return xdgDir("XDG_STATE_HOME", ".local", "state")
+}
+
+// locate returns the path of name in dir, unless it only exists in ~/.aipipe,
+// in which case that path is returned instead
+func locate(dir func() (string, error), name string) (string, error) {
+	base, err := dir()
+	if err != nil {
+		return "", err
+	}
+	path := filepath.Join(base, name)
+	if _, err := os.Stat(path); err == nil {
+		return path, nil
+	}
+
+	legacy, err := legacyDir()
+	if err != nil {
+		return path, nil
+	}
+	legacyPath := filepath.Join(legacy, name)
+	if _, err := os.Stat(legacyPath); err == nil {
+		return legacyPath, nil
+	}
+	return path, nil
+}
+
+// systemConfigPath is the system-wide config file, set by administrators for
+// every user of the machine
+var systemConfigPath = defaultSystemConfigPath()
+
+// defaultSystemConfigPath returns /etc/aipipe/config.yaml, or
+// %ProgramData%\aipipe\config.yaml on Windows
+func defaultSystemConfigPath() string {
+	if runtime.GOOS == "windows" {
+		programData := os.Getenv("ProgramData")
+		if programData == "" {
+			programData = `C:\ProgramData`
+		}
+		return filepath.Join(programData, "aipipe", "config.yaml")
+	}
+	return "/etc/aipipe/config.yaml"
+}
+
+// SystemConfigPath returns the path of the system-wide config file. Its
+// settings take precedence over those in the user's config file.
+func SystemConfigPath() string {
+	return systemConfigPath
+}
+
+// ConfigPath returns the path of a file or directory in the config directory
+func ConfigPath(name string) (string, error) {
+	return locate(ConfigDir, name)
+}
+
+// DataPath returns the path of a file or directory in the data directory
+func DataPath(name string) (string, error) {
+	return locate(DataDir, name)
+}
+
+// StatePath returns the path of a file or directory in the state directory
+func StatePath(name string) (string, error) {
+	return locate(StateDir, name)
+}
+
+// legacyFiles lists the files and directories older versions kept in
+// ~/.aipipe, with the directory each belongs in now
+var legacyFiles = []struct {
+	name string
+	dir  func() (string, error)
+}{
+	{"config.yaml", ConfigDir},
+	{"memory", DataDir},
+	{"snippets", DataDir},
+	{"prompt_history", StateDir},
+}
+
+// MigrateLegacyDir moves files from ~/.aipipe into the XDG base directories,
+// returning a description of each move. Files are left where they are if
+// something already exists at the new path. ~/.aipipe is removed once it is
+// empty, so the migration only happens once.
+func MigrateLegacyDir() ([]string, error) {
+	legacy, err := legacyDir()
+	if err != nil {
+		return nil, err
+	}
+	if _, err := os.Stat(legacy); os.IsNotExist(err) {
+		return nil, nil
+	}
+
+	var moved []string
+	for _, file := range legacyFiles {
+		from := filepath.Join(legacy, file.name)
+		if _, err := os.Stat(from); err != nil {
+			continue
+		}
+
+		dir, err := file.dir()
+		if err != nil {
+			return moved, err
+		}
+		to := filepath.Join(dir, file.name)
+		if to == from {
+			continue
+		}
+		if _, err := os.Stat(to); err == nil {
+			continue
+		}
+
+		if err := os.MkdirAll(dir, 0700); err != nil {
+			return moved, fmt.Errorf("failed to create %s: %w", dir, err)
+		}
+		if err := os.Rename(from, to); err != nil {
+			return moved, fmt.Errorf("failed to move %s to %s: %w", from, to, err)
+		}
+		moved = append(moved, fmt.Sprintf("%s -> %s", from, to))
+	}
+
+	// Only succeeds if nothing else was left behind
+	os.Remove(legacy)
+
+	return moved, nil
+}
@@ -0,0 +1,91 @@
+package util
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+// setTestHome points the home and XDG directories at a temporary directory
+func setTestHome(t *testing.T) string {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("USERPROFILE", home)
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(home, "config"))
+	t.Setenv("XDG_DATA_HOME", filepath.Join(home, "data"))
+	t.Setenv("XDG_STATE_HOME", filepath.Join(home, "state"))
+	return home
+}
+
+func TestXDGDirs(t *testing.T) {
+	home := setTestHome(t)
+
+	dir, err := ConfigDir()
+	if err != nil || dir != filepath.Join(home, "config", "aipipe") {
+		t.Errorf("ConfigDir() = %q, %v; want the aipipe directory in XDG_CONFIG_HOME", dir, err)
+	}
+
+	// Relative paths are ignored
+	t.Setenv("XDG_DATA_HOME", "relative")
+	dir, err = DataDir()
+	want := filepath.Join(home, ".local", "share", "aipipe")
+	if runtime.GOOS == "windows" {
+		want = filepath.Join(home, ".aipipe")
+	}
+	if err != nil || dir != want {
+		t.Errorf("DataDir() with a relative XDG_DATA_HOME = %q, %v; want %q", dir, err, want)
+	}
+}
+
+func TestLegacyFallbackAndMigration(t *testing.T) {
+	home := setTestHome(t)
+	legacy := filepath.Join(home, ".aipipe")
+	if err := os.MkdirAll(filepath.Join(legacy, "memory"), 0700); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"config.yaml", "prompt_history", filepath.Join("memory", "facts.jsonl")} {
+		if err := os.WriteFile(filepath.Join(legacy, name), []byte("x"), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Files that haven't been migrated are still found
+	path, err := ConfigPath("config.yaml")
+	if err != nil || path != filepath.Join(legacy, "config.yaml") {
+		t.Errorf("ConfigPath() before migration = %q, %v; want the legacy file", path, err)
+	}
+	path, err = DataPath("snippets")
+	if err != nil || path != filepath.Join(home, "data", "aipipe", "snippets") {
+		t.Errorf("DataPath() of a new directory = %q, %v; want it in the data directory", path, err)
+	}
+
+	moved, err := MigrateLegacyDir()
+	if err != nil {
+		t.Fatalf("MigrateLegacyDir() error: %v", err)
+	}
+	if len(moved) != 3 {
+		t.Errorf("MigrateLegacyDir() moved %q, want 3 entries", moved)
+	}
+	for _, path := range []string{
+		filepath.Join(home, "config", "aipipe", "config.yaml"),
+		filepath.Join(home, "data", "aipipe", "memory", "facts.jsonl"),
+		filepath.Join(home, "state", "aipipe", "prompt_history"),
+	} {
+		if _, err := os.Stat(path); err != nil {
+			t.Errorf("after migration %s is missing: %v", path, err)
+		}
+	}
+	if _, err := os.Stat(legacy); !os.IsNotExist(err) {
+		t.Errorf("after migration ~/.aipipe still exists: %v", err)
+	}
+
+	path, err = ConfigPath("config.yaml")
+	if err != nil || path != filepath.Join(home, "config", "aipipe", "config.yaml") {
+		t.Errorf("ConfigPath() after migration = %q, %v; want the new file", path, err)
+	}
+
+	if moved, err := MigrateLegacyDir(); err != nil || len(moved) != 0 {
+		t.Errorf("second MigrateLegacyDir() = %q, %v; want nothing to do", moved, err)
+	}
+}
@@ -0,0 +1,100 @@
+package util
+
+import (
+	"bufio"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// packageManagers lists the package managers looked for by PlatformHints, in
+// order of preference
+var packageManagers = []string{
+	"apt", "dnf", "yum", "zypper", "pacman", "apk", "emerge", "nix",
+	"brew", "port",
+	"winget", "choco", "scoop",
+}
+
+// PlatformHints describes the platform shell commands will run on, for the
+// system prompt: the operating system and distribution, the user's shell and
+// the package managers installed
+func PlatformHints() string {
+	lines := []string{"- Operating system: " + describeOS()}
+
+	if shell := userShell(); shell != "" {
+		lines = append(lines, "- Shell: "+shell)
+	}
+
+	if found := detectPackageManagers(exec.LookPath); len(found) > 0 {
+		lines = append(lines, "- Package managers: "+strings.Join(found, ", "))
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// userShell names the user's shell, from $SHELL or, on Windows, whether
+// PowerShell is running. It returns an empty string if it can't tell.
+func userShell() string {
+	if shell := os.Getenv("SHELL"); shell != "" {
+		return filepath.Base(shell)
+	}
+	if runtime.GOOS == "windows" {
+		if os.Getenv("PSModulePath") != "" {
+			return "powershell"
+		}
+		return "cmd"
+	}
+	return ""
+}
+
+// describeOS names the operating system, including the distribution on Linux
+func describeOS() string {
+	switch runtime.GOOS {
+	case "linux":
+		data, err := os.ReadFile("/etc/os-release")
+		if err == nil {
+			if name := parseOSRelease(string(data)); name != "" {
+				return "Linux (" + name + ")"
+			}
+		}
+		return "Linux"
+	case "darwin":
+		return "macOS"
+	case "windows":
+		return "Windows"
+	default:
+		return runtime.GOOS
+	}
+}
+
+// parseOSRelease returns the distribution name from the contents of an
+// os-release file, preferring PRETTY_NAME over NAME
+func parseOSRelease(content string) string {
+	values := make(map[string]string)
+	scanner := bufio.NewScanner(strings.NewReader(content))
+	for scanner.Scan() {
+		key, value, ok := strings.Cut(strings.TrimSpace(scanner.Text()), "=")
+		if !ok {
+			continue
+		}
+		values[key] = strings.Trim(value, `"'`)
+	}
+
+	if name := values["PRETTY_NAME"]; name != "" {
+		return name
+	}
+	return values["NAME"]
+}
+
+// detectPackageManagers returns the package managers found by lookPath
+func detectPackageManagers(lookPath func(string) (string, error)) []string {
+	var found []string
+	for _, name := range packageManagers {
+		if _, err := lookPath(name); err == nil {
+			found = append(found, name)
+		}
+	}
+	return found
+}
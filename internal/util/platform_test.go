@@ -0,0 +1,62 @@
+package util
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseOSRelease(t *testing.T) {
+	tests := []struct {
+		name     string
+		content  string
+		expected string
+	}{
+		{
+			name:     "Pretty name",
+			content:  "NAME=\"Ubuntu\"\nVERSION_ID=\"22.04\"\nPRETTY_NAME=\"Ubuntu 22.04.4 LTS\"\n",
+			expected: "Ubuntu 22.04.4 LTS",
+		},
+		{
+			name:     "Name only",
+			content:  "NAME=Alpine\nID=alpine\n",
+			expected: "Alpine",
+		},
+		{
+			name:     "Empty",
+			content:  "",
+			expected: "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := parseOSRelease(tt.content); got != tt.expected {
+				t.Errorf("parseOSRelease() = %q, want %q", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestDetectPackageManagers(t *testing.T) {
+	installed := map[string]bool{"brew": true, "apt": true}
+	lookPath := func(name string) (string, error) {
+		if installed[name] {
+			return "/usr/bin/" + name, nil
+		}
+		return "", errors.New("not found")
+	}
+
+	expected := []string{"apt", "brew"}
+	if got := detectPackageManagers(lookPath); !reflect.DeepEqual(got, expected) {
+		t.Errorf("detectPackageManagers() = %v, want %v", got, expected)
+	}
+}
+
+func TestPlatformHintsShell(t *testing.T) {
+	t.Setenv("SHELL", "/usr/bin/fish")
+	if hints := PlatformHints(); !strings.Contains(hints, "\n- Shell: fish") {
+		t.Errorf("PlatformHints() = %q, want the shell named in $SHELL", hints)
+	}
+}
@@ -0,0 +1,56 @@
+package util
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// RegexTestCase is a string a generated regular expression must, or must
+// not, match
+type RegexTestCase struct {
+	Text        string
+	ShouldMatch bool
+}
+
+// ParseRegexTests reads test cases, one per line. Lines starting with "- "
+// must not match; lines starting with "+ ", or with neither, must match.
+// Blank lines and lines starting with "#" are ignored.
+func ParseRegexTests(content string) []RegexTestCase {
+	var tests []RegexTestCase
+	for _, line := range strings.Split(content, "\n") {
+		line = strings.TrimRight(line, "\r")
+		if strings.TrimSpace(line) == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		if text, ok := strings.CutPrefix(line, "- "); ok {
+			tests = append(tests, RegexTestCase{Text: text, ShouldMatch: false})
+		} else {
+			tests = append(tests, RegexTestCase{Text: strings.TrimPrefix(line, "+ "), ShouldMatch: true})
+		}
+	}
+	return tests
+}
+
+// CheckRegex compiles pattern with Go's regexp package and runs the test
+// cases against it, returning a description of each failure. A pattern
+// matches a test case if it matches anywhere in the text.
+func CheckRegex(pattern string, tests []RegexTestCase) ([]string, error) {
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, err
+	}
+
+	var failures []string
+	for _, test := range tests {
+		if matched := re.MatchString(test.Text); matched != test.ShouldMatch {
+			if test.ShouldMatch {
+				failures = append(failures, fmt.Sprintf("%q should match but doesn't", test.Text))
+			} else {
+				failures = append(failures, fmt.Sprintf("%q shouldn't match but does", test.Text))
+			}
+		}
+	}
+	return failures, nil
+}
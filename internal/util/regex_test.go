@@ -0,0 +1,41 @@
+package util
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseRegexTests(t *testing.T) {
+	content := "# ISO dates\n2024-01-31\n+ 1999-12-01\r\n\n- 2024-1-31\n-5\n"
+	expected := []RegexTestCase{
+		{Text: "2024-01-31", ShouldMatch: true},
+		{Text: "1999-12-01", ShouldMatch: true},
+		{Text: "2024-1-31", ShouldMatch: false},
+		{Text: "-5", ShouldMatch: true},
+	}
+	if got := ParseRegexTests(content); !reflect.DeepEqual(got, expected) {
+		t.Errorf("ParseRegexTests() = %v, expected %v", got, expected)
+	}
+}
+
+func TestCheckRegex(t *testing.T) {
+	tests := []RegexTestCase{
+		{Text: "2024-01-31", ShouldMatch: true},
+		{Text: "2024-1-31", ShouldMatch: false},
+	}
+
+	failures, err := CheckRegex(`^\d{4}-\d{2}-\d{2}$`, tests)
+	if err != nil || len(failures) != 0 {
+		t.Errorf("CheckRegex() = %v, %v, expected no failures", failures, err)
+	}
+
+	failures, err = CheckRegex(`\d+-\d+-\d+`, tests)
+	expected := []string{`"2024-1-31" shouldn't match but does`}
+	if err != nil || !reflect.DeepEqual(failures, expected) {
+		t.Errorf("CheckRegex() = %v, %v, expected %v", failures, err, expected)
+	}
+
+	if _, err := CheckRegex(`(?<=x)y`, tests); err == nil {
+		t.Errorf("CheckRegex() accepted a lookbehind, which Go doesn't support")
+	}
+}
@@ -0,0 +1,121 @@
+package util
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// GeneratedFile is a file written by the model using the multi-file
+// convention described by the scaffold system prompt
+type GeneratedFile struct {
+	// Path is the file's path relative to the output directory
+	Path string
+	// Language is the language tag of the file's code fence, if any
+	Language string
+	// Content is the file's content
+	Content string
+}
+
+var (
+	// fileMarkerRegex matches a line naming the file in the following code
+	// block, such as "### FILE: app/main.py" or "**File: `app/main.py`**"
+	fileMarkerRegex  = regexp.MustCompile("(?i)^\\s*(?:#{1,6}\\s*|\\*\\*)?file:\\s*`?([^`*\\s]+)`?(?:\\*\\*)?\\s*$")
+	fileOpeningRegex = regexp.MustCompile("^\\s*```(.*)$")
+	fileClosingRegex = regexp.MustCompile("^\\s*```\\s*$")
+)
+
+// ExtractFiles returns the files in a response using the multi-file
+// convention: each file is a code block whose fence names the path, as in
+// "```python path=app/main.py", or which follows a "### FILE: path" line.
+// Code blocks without a path are ignored.
+func ExtractFiles(response string) []GeneratedFile {
+	var files []GeneratedFile
+	var current *GeneratedFile
+	var content []string
+	pendingPath := ""
+
+	for _, line := range strings.Split(response, "\n") {
+		line = strings.TrimSuffix(line, "\r")
+
+		if current != nil {
+			if fileClosingRegex.MatchString(line) {
+				current.Content = strings.Join(content, "\n")
+				if len(content) > 0 {
+					current.Content += "\n"
+				}
+				files = append(files, *current)
+				current = nil
+				continue
+			}
+			content = append(content, line)
+			continue
+		}
+
+		if match := fileMarkerRegex.FindStringSubmatch(line); match != nil {
+			pendingPath = match[1]
+			continue
+		}
+
+		match := fileOpeningRegex.FindStringSubmatch(line)
+		if match == nil {
+			continue
+		}
+
+		language, path := parseFenceInfo(match[1])
+		if path == "" {
+			path = pendingPath
+		}
+		pendingPath = ""
+
+		// Blocks without a path are collected too, so their content isn't
+		// mistaken for markers, and dropped at the end
+		current = &GeneratedFile{Path: path, Language: language}
+		content = nil
+	}
+
+	named := files[:0]
+	for _, file := range files {
+		if file.Path != "" {
+			named = append(named, file)
+		}
+	}
+	return named
+}
+
+// parseFenceInfo splits a code fence info string such as
+// "python path=app/main.py" into its language and path
+func parseFenceInfo(info string) (string, string) {
+	language := ""
+	path := ""
+	for _, field := range strings.Fields(info) {
+		key, value, ok := strings.Cut(field, "=")
+		if !ok {
+			if language == "" {
+				language = field
+			}
+			continue
+		}
+		switch strings.ToLower(key) {
+		case "path", "file", "filename":
+			path = strings.Trim(value, `"'`)
+		}
+	}
+	return language, path
+}
+
+// ScaffoldPath returns where a generated file should be written inside dir,
+// rejecting absolute paths and paths that would escape dir
+func ScaffoldPath(dir string, path string) (string, error) {
+	if filepath.IsAbs(path) || strings.HasPrefix(path, "/") || strings.HasPrefix(path, "\\") {
+		return "", fmt.Errorf("refusing to write %s: path is absolute", path)
+	}
+
+	cleaned := filepath.Clean(filepath.FromSlash(path))
+	if cleaned == "." || cleaned == ".." || strings.HasPrefix(cleaned, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("refusing to write %s: path is outside the output directory", path)
+	}
+
+	return filepath.Join(dir, cleaned), nil
+}
@@ -0,0 +1,40 @@
+package util
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestExtractFiles(t *testing.T) {
+	response := "Here is your app.\n\n" +
+		"```python path=app/main.py\nfrom flask import Flask\napp = Flask(__name__)\n```\n\n" +
+		"### FILE: requirements.txt\n```\nflask\n```\n\n" +
+		"**File: `templates/index.html`**\n```html\n<h1>Hi</h1>\n```\n\n" +
+		"Run it with:\n```bash\n### FILE: not-a-marker.txt\nflask run\n```\n"
+
+	expected := []GeneratedFile{
+		{Path: "app/main.py", Language: "python", Content: "from flask import Flask\napp = Flask(__name__)\n"},
+		{Path: "requirements.txt", Language: "", Content: "flask\n"},
+		{Path: "templates/index.html", Language: "html", Content: "<h1>Hi</h1>\n"},
+	}
+
+	if got := ExtractFiles(response); !reflect.DeepEqual(got, expected) {
+		t.Errorf("ExtractFiles() = %+v, want %+v", got, expected)
+	}
+}
+
+func TestScaffoldPath(t *testing.T) {
+	dir := filepath.Join("out", "app")
+
+	path, err := ScaffoldPath(dir, "src/./main.go")
+	if err != nil || path != filepath.Join(dir, "src", "main.go") {
+		t.Errorf("ScaffoldPath() = %q, %v; want %q", path, err, filepath.Join(dir, "src", "main.go"))
+	}
+
+	for _, bad := range []string{"/etc/passwd", "../outside.txt", "src/../../outside.txt", "."} {
+		if _, err := ScaffoldPath(dir, bad); err == nil {
+			t.Errorf("ScaffoldPath(%q) succeeded, want an error", bad)
+		}
+	}
+}
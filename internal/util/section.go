@@ -0,0 +1,66 @@
+package util
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+var (
+	sectionHeadingRegex = regexp.MustCompile(`^\s{0,3}(#{1,6})\s+(.*?)(\s+#+)?\s*$`)
+	sectionFenceRegex   = regexp.MustCompile("^\\s*(\x60\x60\x60|~~~)")
+)
+
+// ExtractSection returns the body of the section of markdown under the named
+// heading, up to the next heading of the same or a higher level. The heading
+// is matched case-insensitively, as "## Usage" to match only a level 2
+// heading or "Usage" to match any level. Headings inside code blocks are
+// ignored. An error lists the headings found if there is no such section.
+func ExtractSection(markdown string, heading string) (string, error) {
+	wantLevel := 0
+	if match := sectionHeadingRegex.FindStringSubmatch(heading); match != nil {
+		wantLevel = len(match[1])
+		heading = match[2]
+	}
+	heading = strings.TrimSpace(heading)
+
+	var section []string
+	var headings []string
+	level := 0 // the level of the section being extracted, if any
+	inCodeBlock := false
+	fence := ""
+	for _, line := range strings.Split(markdown, "\n") {
+		if inCodeBlock {
+			if strings.TrimSpace(line) == fence {
+				inCodeBlock = false
+			}
+		} else if match := sectionFenceRegex.FindStringSubmatch(line); match != nil {
+			inCodeBlock = true
+			fence = match[1]
+		} else if match := sectionHeadingRegex.FindStringSubmatch(line); match != nil {
+			headingLevel := len(match[1])
+			if level > 0 && headingLevel <= level {
+				break
+			}
+			if level == 0 {
+				headings = append(headings, strings.TrimSpace(line))
+				if strings.EqualFold(match[2], heading) && (wantLevel == 0 || wantLevel == headingLevel) {
+					level = headingLevel
+					continue
+				}
+			}
+		}
+
+		if level > 0 {
+			section = append(section, line)
+		}
+	}
+
+	if level == 0 {
+		if len(headings) == 0 {
+			return "", fmt.Errorf("the answer has no section %q; it has no headings", heading)
+		}
+		return "", fmt.Errorf("the answer has no section %q; its headings are: %s", heading, strings.Join(headings, ", "))
+	}
+	return strings.Trim(strings.Join(section, "\n"), "\n"), nil
+}
@@ -0,0 +1,90 @@
+package util
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestExtractSection(t *testing.T) {
+	markdown := `# Tool
+
+Intro text.
+
+## Usage
+
+Run it like this:
+
+` + "```bash\n# not a heading\ntool --help\n```" + `
+
+### Options
+
+- -v: verbose
+
+## Installation
+
+Download it.
+`
+
+	tests := []struct {
+		name    string
+		heading string
+		want    string
+		wantErr string
+	}{
+		{
+			name:    "level and title",
+			heading: "## Usage",
+			want:    "Run it like this:\n\n```bash\n# not a heading\ntool --help\n```\n\n### Options\n\n- -v: verbose",
+		},
+		{
+			name:    "title only, any case",
+			heading: "installation",
+			want:    "Download it.",
+		},
+		{
+			name:    "subsection",
+			heading: "### Options",
+			want:    "- -v: verbose",
+		},
+		{
+			name:    "top level section runs to the end",
+			heading: "# Tool",
+			want:    strings.TrimSuffix(markdown[len("# Tool\n\n"):], "\n"),
+		},
+		{
+			name:    "wrong level",
+			heading: "# Usage",
+			wantErr: `the answer has no section "Usage"; its headings are: # Tool, ## Usage, ### Options, ## Installation`,
+		},
+		{
+			name:    "headings in code blocks are ignored",
+			heading: "not a heading",
+			wantErr: `no section "not a heading"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ExtractSection(markdown, tt.heading)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("ExtractSection() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ExtractSection() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("ExtractSection() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExtractSectionWithoutHeadings(t *testing.T) {
+	_, err := ExtractSection("just text", "Usage")
+	if err == nil || !strings.Contains(err.Error(), "it has no headings") {
+		t.Errorf("ExtractSection() error = %v, want one saying there are no headings", err)
+	}
+}
@@ -0,0 +1,101 @@
+package util
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// gistAPIURL is where gists are created
+var gistAPIURL = "https://api.github.com/gists"
+
+// maxShareResponseBytes limits how much of a reply to an upload is read
+const maxShareResponseBytes = 1 << 20
+
+// CreateGist uploads content as a file of a new GitHub gist, which is secret
+// unless public, and returns the gist's URL. The token needs the gist scope.
+func CreateGist(token string, filename string, description string, content string, public bool) (string, error) {
+	body, err := json.Marshal(map[string]interface{}{
+		"description": description,
+		"public":      public,
+		"files": map[string]interface{}{
+			filename: map[string]string{"content": content},
+		},
+	})
+	if err != nil {
+		return "", fmt.Errorf("error encoding gist: %v", err)
+	}
+
+	req, err := http.NewRequest("POST", gistAPIURL, bytes.NewReader(body))
+	if err != nil {
+		return "", fmt.Errorf("error creating gist request: %v", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("Accept", "application/vnd.github+json")
+	req.Header.Set("Content-Type", "application/json")
+
+	data, err := sendShareRequest(req)
+	if err != nil {
+		return "", fmt.Errorf("error creating gist: %v", err)
+	}
+
+	var gist struct {
+		HTMLURL string `json:"html_url"`
+	}
+	if err := json.Unmarshal(data, &gist); err != nil || gist.HTMLURL == "" {
+		return "", fmt.Errorf("error creating gist: the reply has no html_url")
+	}
+	return gist.HTMLURL, nil
+}
+
+// UploadPaste posts content as the body of a request to a paste service, such
+// as https://paste.rs, and returns the paste's URL. The service must reply
+// with the URL, either alone or as the url field of a JSON object.
+func UploadPaste(endpoint string, content string) (string, error) {
+	req, err := http.NewRequest("POST", endpoint, strings.NewReader(content))
+	if err != nil {
+		return "", fmt.Errorf("error creating paste request: %v", err)
+	}
+	req.Header.Set("Content-Type", "text/markdown; charset=utf-8")
+
+	data, err := sendShareRequest(req)
+	if err != nil {
+		return "", fmt.Errorf("error uploading to %s: %v", endpoint, err)
+	}
+
+	link := strings.TrimSpace(string(data))
+	var paste struct {
+		URL string `json:"url"`
+	}
+	if json.Unmarshal(data, &paste) == nil && paste.URL != "" {
+		link = paste.URL
+	}
+	if parsed, err := url.Parse(link); err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return "", fmt.Errorf("error uploading to %s: the reply isn't the URL of the paste", endpoint)
+	}
+	return link, nil
+}
+
+// sendShareRequest sends an upload and returns the body of a successful reply
+func sendShareRequest(req *http.Request) ([]byte, error) {
+	client := &http.Client{Timeout: 30 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxShareResponseBytes))
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil, fmt.Errorf("status %d: %s", resp.StatusCode, TruncateText(strings.TrimSpace(string(data)), 200))
+	}
+	return data, nil
+}
@@ -0,0 +1,73 @@
+package util
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestCreateGist(t *testing.T) {
+	var request map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			t.Errorf("Authorization = %q, want the token", r.Header.Get("Authorization"))
+		}
+		json.NewDecoder(r.Body).Decode(&request)
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"id": "abc", "html_url": "https://gist.github.com/abc"}`))
+	}))
+	defer server.Close()
+
+	original := gistAPIURL
+	defer func() { gistAPIURL = original }()
+	gistAPIURL = server.URL
+
+	link, err := CreateGist("secret", "chat.md", "A chat", "# Hello\n", false)
+	if err != nil || link != "https://gist.github.com/abc" {
+		t.Fatalf("CreateGist() = %q, %v; want the gist's URL", link, err)
+	}
+
+	files, _ := request["files"].(map[string]interface{})
+	file, _ := files["chat.md"].(map[string]interface{})
+	if request["public"] != false || request["description"] != "A chat" || file["content"] != "# Hello\n" {
+		t.Errorf("request = %v, want a secret gist with the file", request)
+	}
+}
+
+func TestUploadPaste(t *testing.T) {
+	tests := []struct {
+		name     string
+		status   int
+		reply    string
+		expected string
+		wantErr  bool
+	}{
+		{name: "URL", status: http.StatusCreated, reply: "https://paste.example.com/xyz\n", expected: "https://paste.example.com/xyz"},
+		{name: "JSON", status: http.StatusOK, reply: `{"url": "https://paste.example.com/xyz"}`, expected: "https://paste.example.com/xyz"},
+		{name: "Not a URL", status: http.StatusOK, reply: "thanks!", wantErr: true},
+		{name: "Error status", status: http.StatusRequestEntityTooLarge, reply: "too big", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var body string
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				data, _ := io.ReadAll(r.Body)
+				body = string(data)
+				w.WriteHeader(tt.status)
+				w.Write([]byte(tt.reply))
+			}))
+			defer server.Close()
+
+			link, err := UploadPaste(server.URL, "# Hello\n")
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("UploadPaste() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if link != tt.expected || body != "# Hello\n" {
+				t.Errorf("UploadPaste() = %q after sending %q, want %q", link, body, tt.expected)
+			}
+		})
+	}
+}
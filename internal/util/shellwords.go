@@ -0,0 +1,70 @@
+package util
+
+import (
+	"fmt"
+	"strings"
+)
+
+// SplitShellWords splits a command line into words the way a POSIX shell
+// would, handling single quotes, double quotes and backslashes. It returns an
+// error if the command uses anything that needs a shell to run, such as a
+// pipe, redirection, variable or command substitution.
+func SplitShellWords(command string) ([]string, error) {
+	var words []string
+	var word strings.Builder
+	inWord := false
+
+	for i := 0; i < len(command); i++ {
+		c := command[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n':
+			if inWord {
+				words = append(words, word.String())
+				word.Reset()
+				inWord = false
+			}
+		case c == '\'':
+			end := strings.IndexByte(command[i+1:], '\'')
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated single quote")
+			}
+			word.WriteString(command[i+1 : i+1+end])
+			i += end + 1
+			inWord = true
+		case c == '"':
+			i++
+			for ; i < len(command) && command[i] != '"'; i++ {
+				switch command[i] {
+				case '\\':
+					// Only these characters are escaped inside double quotes
+					if i+1 < len(command) && strings.IndexByte("$`\"\\\n", command[i+1]) >= 0 {
+						i++
+					}
+				case '$', '`':
+					return nil, fmt.Errorf("%q needs a shell to run", command[i:i+1])
+				}
+				word.WriteByte(command[i])
+			}
+			if i >= len(command) {
+				return nil, fmt.Errorf("unterminated double quote")
+			}
+			inWord = true
+		case c == '\\':
+			if i+1 < len(command) {
+				i++
+				word.WriteByte(command[i])
+			}
+			inWord = true
+		case strings.IndexByte("|&;<>()$`*?[", c) >= 0:
+			return nil, fmt.Errorf("%q needs a shell to run", string(c))
+		default:
+			word.WriteByte(c)
+			inWord = true
+		}
+	}
+
+	if inWord {
+		words = append(words, word.String())
+	}
+	return words, nil
+}
@@ -0,0 +1,38 @@
+package util
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSplitShellWords(t *testing.T) {
+	tests := []struct {
+		command  string
+		expected []string
+	}{
+		{`jq '.items[] | .name'`, []string{"jq", ".items[] | .name"}},
+		{`awk -F, '{ print $2 }'`, []string{"awk", "-F,", "{ print $2 }"}},
+		{`sed -E "s/a\"b/\$/g"`, []string{"sed", "-E", `s/a"b/$/g`}},
+		{`jq -r .name\ first`, []string{"jq", "-r", ".name first"}},
+		{`jq  -c   '.'  `, []string{"jq", "-c", "."}},
+	}
+	for _, tt := range tests {
+		got, err := SplitShellWords(tt.command)
+		if err != nil || !reflect.DeepEqual(got, tt.expected) {
+			t.Errorf("SplitShellWords(%q) = %q, %v, expected %q", tt.command, got, err, tt.expected)
+		}
+	}
+
+	for _, command := range []string{
+		`jq . | head`,
+		`sed s/a/b/ > out`,
+		`awk "{ print $HOME }"`,
+		"jq $(cat filter)",
+		`jq 'unterminated`,
+		`jq *.json`,
+	} {
+		if _, err := SplitShellWords(command); err == nil {
+			t.Errorf("SplitShellWords(%q) accepted a command that needs a shell", command)
+		}
+	}
+}
@@ -0,0 +1,42 @@
+package util
+
+import (
+	"sort"
+	"strings"
+)
+
+// ClosestMatches returns up to n candidates that are similar to word, closest
+// first, such as the models an API offers that look like one it doesn't.
+// Candidates that differ in more than half of word are left out, unless they
+// start with it.
+func ClosestMatches(word string, candidates []string, n int) []string {
+	type match struct {
+		candidate string
+		distance  int
+	}
+
+	word = strings.ToLower(word)
+	limit := max(len(word)/2, 2)
+	var matches []match
+	for _, candidate := range candidates {
+		distance := editDistance(word, strings.ToLower(candidate))
+		if strings.HasPrefix(strings.ToLower(candidate), word) {
+			distance = min(distance, 1)
+		}
+		if distance <= limit {
+			matches = append(matches, match{candidate, distance})
+		}
+	}
+	sort.Slice(matches, func(i, j int) bool {
+		if matches[i].distance != matches[j].distance {
+			return matches[i].distance < matches[j].distance
+		}
+		return matches[i].candidate < matches[j].candidate
+	})
+
+	var closest []string
+	for _, match := range matches[:min(len(matches), n)] {
+		closest = append(closest, match.candidate)
+	}
+	return closest
+}
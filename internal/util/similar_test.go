@@ -0,0 +1,27 @@
+package util
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestClosestMatches(t *testing.T) {
+	models := []string{"llama-3.3-70b-versatile", "llama-3.1-8b-instant", "qwen/qwen3-32b", "whisper-large-v3", "gemma2-9b-it"}
+
+	tests := []struct {
+		word string
+		n    int
+		want []string
+	}{
+		{"llama-3.1-70b-versatile", 3, []string{"llama-3.3-70b-versatile", "llama-3.1-8b-instant"}},
+		{"llama-3.1-8b", 3, []string{"llama-3.1-8b-instant"}},
+		{"llama-3.2-8b-instant", 1, []string{"llama-3.1-8b-instant"}},
+		{"qwen-qwq-32b", 3, []string{"qwen/qwen3-32b"}},
+		{"gpt-4o", 3, nil},
+	}
+	for _, tt := range tests {
+		if got := ClosestMatches(tt.word, models, tt.n); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("ClosestMatches(%q) = %v, want %v", tt.word, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,158 @@
+package util
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// stackFrameRegexes match a file and line number in the stack trace formats
+// of common languages. Each has a "file" and a "line" group.
+var stackFrameRegexes = []*regexp.Regexp{
+	// Python: File "app/main.py", line 12, in handler
+	regexp.MustCompile(`File "(?P<file>[^"]+)", line (?P<line>\d+)`),
+	// Java and Kotlin: at com.example.Foo.bar(Foo.java:12)
+	regexp.MustCompile(`\((?P<file>[\w.$-]+\.(?:java|kt|scala)):(?P<line>\d+)\)`),
+	// Go, JavaScript, Rust, C# and most others: path/to/file.go:12 or
+	// (path/to/file.js:12:5)
+	regexp.MustCompile(`(?P<file>(?:[A-Za-z]:)?[\w./\\~-]*\.[A-Za-z][\w]{0,5}):(?P<line>\d+)`),
+}
+
+// SourceReference is a line of a file named in a stack trace
+type SourceReference struct {
+	File string
+	Line int
+}
+
+// FindSourceReferences returns the file and line references in stack traces
+// in text, in the order they appear, without duplicates
+func FindSourceReferences(text string) []SourceReference {
+	var references []SourceReference
+	seen := map[SourceReference]bool{}
+
+	for _, line := range strings.Split(text, "\n") {
+		for _, frameRegex := range stackFrameRegexes {
+			match := frameRegex.FindStringSubmatch(line)
+			if match == nil {
+				continue
+			}
+
+			number, err := strconv.Atoi(match[frameRegex.SubexpIndex("line")])
+			if err != nil || number < 1 {
+				continue
+			}
+			reference := SourceReference{File: match[frameRegex.SubexpIndex("file")], Line: number}
+			if !seen[reference] {
+				seen[reference] = true
+				references = append(references, reference)
+			}
+			break
+		}
+	}
+
+	return references
+}
+
+// maxSourceFileBytes is the largest file SourceContext will read
+const maxSourceFileBytes = 1 << 20
+
+// SourceContext returns the lines within radius of each reference, for the
+// references to files that exist under the current directory, formatted as
+// numbered code blocks with the referenced lines marked. At most maxFiles
+// files are included. It returns an empty string if none of the files exist,
+// which is usual for library code and stack traces from other machines.
+func SourceContext(references []SourceReference, radius int, maxFiles int) string {
+	var files []string
+	lines := map[string][]int{}
+	for _, reference := range references {
+		path, ok := localSourcePath(reference.File)
+		if !ok {
+			continue
+		}
+		if _, ok := lines[path]; !ok {
+			if len(files) >= maxFiles {
+				continue
+			}
+			files = append(files, path)
+		}
+		lines[path] = append(lines[path], reference.Line)
+	}
+
+	var builder strings.Builder
+	for _, path := range files {
+		content, err := os.ReadFile(path)
+		if err != nil {
+			continue
+		}
+		builder.WriteString(formatSourceLines(path, strings.Split(string(content), "\n"), lines[path], radius))
+	}
+	return builder.String()
+}
+
+// localSourcePath returns the path to a file named in a stack trace, if it
+// is a reasonably small file under the current directory. Absolute paths are
+// accepted if they are under the current directory.
+func localSourcePath(file string) (string, bool) {
+	file = filepath.FromSlash(strings.ReplaceAll(file, `\`, "/"))
+	cwd, err := os.Getwd()
+	if err != nil {
+		return "", false
+	}
+
+	path := file
+	if filepath.IsAbs(path) {
+		relative, err := filepath.Rel(cwd, path)
+		if err != nil || relative == ".." || strings.HasPrefix(relative, ".."+string(filepath.Separator)) {
+			return "", false
+		}
+		path = relative
+	} else if strings.HasPrefix(filepath.Clean(path), "..") {
+		return "", false
+	}
+
+	info, err := os.Stat(path)
+	if err != nil || !info.Mode().IsRegular() || info.Size() > maxSourceFileBytes {
+		return "", false
+	}
+	return filepath.ToSlash(path), true
+}
+
+// formatSourceLines formats the lines within radius of each of the given line
+// numbers as a code block, merging ranges that overlap. Referenced lines are
+// marked with ">".
+func formatSourceLines(path string, content []string, numbers []int, radius int) string {
+	sort.Ints(numbers)
+	marked := map[int]bool{}
+	for _, number := range numbers {
+		marked[number] = true
+	}
+
+	var builder strings.Builder
+	fmt.Fprintf(&builder, "%s:\n```%s\n", path, strings.TrimPrefix(filepath.Ext(path), "."))
+	width := len(strconv.Itoa(min(numbers[len(numbers)-1]+radius, len(content))))
+	last := 0
+	for _, number := range numbers {
+		start := max(number-radius, last+1, 1)
+		end := min(number+radius, len(content))
+		if start > end {
+			continue
+		}
+		if last > 0 && start > last+1 {
+			builder.WriteString("...\n")
+		}
+		for i := start; i <= end; i++ {
+			marker := " "
+			if marked[i] {
+				marker = ">"
+			}
+			fmt.Fprintf(&builder, "%s%*d | %s\n", marker, width, i, content[i-1])
+		}
+		last = end
+	}
+	builder.WriteString("```\n\n")
+	return builder.String()
+}
@@ -0,0 +1,90 @@
+package util
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestFindSourceReferences(t *testing.T) {
+	tests := []struct {
+		name     string
+		trace    string
+		expected []SourceReference
+	}{
+		{
+			name: "Go panic",
+			trace: "panic: runtime error: index out of range [3] with length 3\n\n" +
+				"goroutine 1 [running]:\nmain.parse(...)\n\t/home/me/app/parse.go:42 +0x1d\n" +
+				"main.main()\n\t/home/me/app/main.go:12 +0x25\nexit status 2",
+			expected: []SourceReference{{"/home/me/app/parse.go", 42}, {"/home/me/app/main.go", 12}},
+		},
+		{
+			name: "Python traceback",
+			trace: "Traceback (most recent call last):\n" +
+				"  File \"app/main.py\", line 8, in <module>\n    run()\n" +
+				"  File \"app/main.py\", line 4, in run\n    1/0\nZeroDivisionError: division by zero",
+			expected: []SourceReference{{"app/main.py", 8}, {"app/main.py", 4}},
+		},
+		{
+			name: "Node.js",
+			trace: "TypeError: x is undefined\n" +
+				"    at handler (src/server.js:20:11)\n    at src/server.js:20:11",
+			expected: []SourceReference{{"src/server.js", 20}},
+		},
+		{
+			name:     "Java",
+			trace:    "java.lang.NullPointerException\n\tat com.example.App.main(App.java:7)",
+			expected: []SourceReference{{"App.java", 7}},
+		},
+		{
+			name:     "No trace",
+			trace:    "connecting to 127.0.0.1:8080 failed",
+			expected: nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := FindSourceReferences(tt.trace); !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("FindSourceReferences() = %v, expected %v", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestSourceContext(t *testing.T) {
+	dir := t.TempDir()
+	var lines []string
+	for _, word := range strings.Fields("one two three four five six seven eight nine ten eleven twelve") {
+		lines = append(lines, word)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "main.go"), []byte(strings.Join(lines, "\n")), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(wd)
+
+	references := []SourceReference{
+		{"main.go", 3},
+		{filepath.Join(dir, "main.go"), 4},
+		{"main.go", 11},
+		{"missing.go", 1},
+		{"../outside.go", 1},
+	}
+	expected := "main.go:\n```go\n" +
+		"  2 | two\n> 3 | three\n> 4 | four\n  5 | five\n...\n" +
+		" 10 | ten\n>11 | eleven\n 12 | twelve\n```\n\n"
+	if got := SourceContext(references, 1, 5); got != expected {
+		t.Errorf("SourceContext() = %q, expected %q", got, expected)
+	}
+}
@@ -1,8 +1,11 @@
 package util
 
 import (
+	"context"
 	"regexp"
 	"strings"
+
+	"github.com/rba100/aipipe/internal/stream"
 )
 
 // ThinkTagState represents the current state of the think tag processor
@@ -22,80 +25,71 @@ func StripThinkTags(input string) string {
 }
 
 // StripThinkTagsStream processes a stream of text and strips think tags
-func StripThinkTagsStream(inputStream <-chan string) <-chan string {
+func StripThinkTagsStream(ctx context.Context, inputStream <-chan string) <-chan string {
 	startingRegex := regexp.MustCompile(`^[\s]*<think>`)
-	outputStream := make(chan string)
 	firstEmit := true
+	state := Searching
+	var buffer strings.Builder
 
-	go func() {
-		defer close(outputStream)
-
-		state := Searching
-		var buffer strings.Builder
-
-		for chunk := range inputStream {
+	// emitAfterThinking emits the text following the closing tag in the
+	// buffer, if it has arrived
+	emitAfterThinking := func(currentBuffer string, emit stream.Emit[string]) bool {
+		closingIndex := strings.Index(currentBuffer, "</think>")
+		if closingIndex == -1 {
+			return true
+		}
+		state = Emitting
+		remainingText := currentBuffer[closingIndex+len("</think>"):]
+		remainingText = strings.TrimLeft(remainingText, " \n\t")
+		if len(remainingText) == 0 {
+			return true
+		}
+		firstEmit = false
+		return emit(remainingText)
+	}
 
-			if state == Emitting {
-				if firstEmit {
-					chunk = strings.TrimLeft(chunk, " \n\t")
-					if len(chunk) > 0 {
-						outputStream <- chunk
-						firstEmit = false
-					}
-				} else {
-					outputStream <- chunk
-				}
-				continue
+	step := func(chunk string, emit stream.Emit[string]) bool {
+		if state == Emitting {
+			if !firstEmit {
+				return emit(chunk)
 			}
+			chunk = strings.TrimLeft(chunk, " \n\t")
+			if len(chunk) == 0 {
+				return true
+			}
+			firstEmit = false
+			return emit(chunk)
+		}
 
-			buffer.WriteString(chunk)
-			currentBuffer := buffer.String()
-
-			switch state {
-			case Searching:
-				if buffer.Len() < 10 {
-					continue
-				}
-				if startingRegex.MatchString(currentBuffer) {
-					state = Thinking
-					closingIndex := strings.Index(currentBuffer, "</think>")
-					if closingIndex != -1 {
-						state = Emitting
-						remainingText := currentBuffer[closingIndex+len("</think>"):]
-						remainingText = strings.TrimLeft(remainingText, " \n\t")
-						if len(remainingText) > 0 {
-							outputStream <- remainingText
-							firstEmit = false
-						}
-					}
-					continue
-				}
-				state = Emitting
-				outputStream <- currentBuffer
-				firstEmit = false
-				continue
+		buffer.WriteString(chunk)
+		currentBuffer := buffer.String()
 
-			case Thinking:
-				closingIndex := strings.Index(currentBuffer, "</think>")
-				if closingIndex != -1 {
-					state = Emitting
-					remainingText := currentBuffer[closingIndex+len("</think>"):]
-					remainingText = strings.TrimLeft(remainingText, " \n\t")
-					if len(remainingText) > 0 {
-						outputStream <- remainingText
-						firstEmit = false
-					}
-					continue
-				}
+		switch state {
+		case Searching:
+			if buffer.Len() < 10 {
+				return true
 			}
+			if startingRegex.MatchString(currentBuffer) {
+				state = Thinking
+				return emitAfterThinking(currentBuffer, emit)
+			}
+			state = Emitting
+			firstEmit = false
+			return emit(currentBuffer)
+
+		case Thinking:
+			return emitAfterThinking(currentBuffer, emit)
 		}
+		return true
+	}
 
-		// End of stream handling
+	// If we're still in thinking mode at the end, emit with the <think>
+	// prefix
+	flush := func(emit stream.Emit[string]) {
 		if state != Emitting {
-			// If we're still in thinking mode, emit with <think> prefix
-			outputStream <- buffer.String()
+			emit(buffer.String())
 		}
-	}()
+	}
 
-	return outputStream
+	return stream.Transform(ctx, inputStream, step, flush)
 }
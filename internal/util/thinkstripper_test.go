@@ -1,6 +1,7 @@
 package util
 
 import (
+	"context"
 	"reflect"
 	"testing"
 	"time"
@@ -67,7 +68,7 @@ func TestStripThinkTagsStream(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			inputStream := make(chan string)
-			resultStream := StripThinkTagsStream(inputStream)
+			resultStream := StripThinkTagsStream(context.Background(), inputStream)
 
 			go func() {
 				defer close(inputStream)
@@ -92,7 +93,7 @@ func TestStripThinkTagsStream_emission_order(t *testing.T) {
 	t.Run("single non-thinking part", func(t *testing.T) {
 
 		inputStream := make(chan string)
-		resultStream := StripThinkTagsStream(inputStream)
+		resultStream := StripThinkTagsStream(context.Background(), inputStream)
 
 		inputStream <- "Hi!"
 		close(inputStream)
@@ -105,7 +106,7 @@ func TestStripThinkTagsStream_emission_order(t *testing.T) {
 	t.Run("multiple non-thinking parts", func(t *testing.T) {
 
 		inputStream := make(chan string)
-		resultStream := StripThinkTagsStream(inputStream)
+		resultStream := StripThinkTagsStream(context.Background(), inputStream)
 
 		inputStream <- "Hello, World!"
 
@@ -0,0 +1,55 @@
+package util
+
+import (
+	"regexp"
+	"strings"
+)
+
+// untrustedTag delimits content that comes from web pages, API responses or
+// command output rather than the user
+const untrustedTag = "untrusted-content"
+
+var (
+	// untrustedTagRegex matches the delimiters, so content can't close its
+	// section early and carry on as if it were the user
+	untrustedTagRegex = regexp.MustCompile(`(?i)<(/?)\s*` + untrustedTag)
+
+	// injectionRegexes match phrases commonly used to smuggle instructions
+	// to a model in content it is asked to read
+	injectionRegexes = []*regexp.Regexp{
+		regexp.MustCompile(`(?i)\b(ignore|disregard|forget)\s+(all\s+)?(of\s+)?(the\s+|your\s+)?(previous|prior|above|earlier|preceding)\s+(instructions|prompts|messages|rules)`),
+		regexp.MustCompile(`(?i)\byou\s+are\s+now\s+(a|an|in)\b`),
+		regexp.MustCompile(`(?i)\b(new|updated)\s+(system\s+)?instructions\s*:`),
+		regexp.MustCompile(`(?i)\b(reveal|print|repeat|show)\s+(me\s+)?(your|the)\s+system\s+prompt`),
+		regexp.MustCompile(`<\|im_start\|>|<\|system\|>|\[INST\]|<<SYS>>`),
+	}
+)
+
+// WrapUntrusted marks text from a source other than the user, such as a web
+// page or a command's output, as untrusted for the model. Delimiters inside
+// the text are defused so it can't end the section itself.
+func WrapUntrusted(source string, text string) string {
+	text = untrustedTagRegex.ReplaceAllString(text, "<${1}_"+untrustedTag)
+	source = strings.ReplaceAll(source, `"`, "'")
+	return "<" + untrustedTag + ` source="` + source + `">` + "\n" +
+		strings.TrimRight(text, "\n") + "\n" +
+		"</" + untrustedTag + ">\n"
+}
+
+// FindInjections returns the phrases in text that look like instructions
+// aimed at a model, such as "ignore previous instructions", without
+// duplicates. It is a heuristic for warning the user, not a defence.
+func FindInjections(text string) []string {
+	var found []string
+	seen := make(map[string]bool)
+	for _, re := range injectionRegexes {
+		for _, match := range re.FindAllString(text, -1) {
+			match = strings.Join(strings.Fields(match), " ")
+			if !seen[strings.ToLower(match)] {
+				seen[strings.ToLower(match)] = true
+				found = append(found, match)
+			}
+		}
+	}
+	return found
+}
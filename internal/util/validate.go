@@ -0,0 +1,94 @@
+package util
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go/parser"
+	"go/token"
+	"io"
+	"os/exec"
+	"strings"
+
+	"github.com/rba100/aipipe/internal/parsing"
+	"gopkg.in/yaml.v3"
+)
+
+// validators check the syntax of code in a language, keyed by canonical
+// language name. They return nil if the code is valid or can't be checked.
+var validators = map[string]func(code string) error{
+	"go": func(code string) error {
+		// Snippets are often just declarations or statements without a package clause
+		if !strings.Contains(code, "package ") {
+			code = "package main\n" + code
+		}
+		_, err := parser.ParseFile(token.NewFileSet(), "code.go", code, parser.AllErrors)
+		return err
+	},
+	"json": func(code string) error {
+		var value interface{}
+		return json.Unmarshal([]byte(code), &value)
+	},
+	"yaml": func(code string) error {
+		decoder := yaml.NewDecoder(strings.NewReader(code))
+		for {
+			var value interface{}
+			if err := decoder.Decode(&value); err != nil {
+				if errors.Is(err, io.EOF) {
+					return nil
+				}
+				return err
+			}
+		}
+	},
+	"python": func(code string) error {
+		return runValidator(code, "python3", "-c", "import ast, sys; ast.parse(sys.stdin.read())")
+	},
+	"bash": func(code string) error {
+		return runValidator(code, "bash", "-n")
+	},
+}
+
+// ValidateCode checks that code in the given language parses, returning a
+// description of the syntax errors if it doesn't. Languages without a
+// validator, or whose checking tool isn't installed or can't be run in
+// read-only mode, are assumed valid.
+func ValidateCode(code string, language string) error {
+	validate, ok := validators[parsing.CanonicalLanguage(language)]
+	if !ok {
+		return nil
+	}
+	return validate(code)
+}
+
+// runValidator runs a syntax checking command with the code on stdin. It
+// returns nil if the command isn't installed, or in read-only mode, where
+// aipipe doesn't run commands.
+func runValidator(code string, name string, args ...string) error {
+	if readOnly, err := GetReadOnly(); err != nil || readOnly {
+		return nil
+	}
+
+	path, err := exec.LookPath(name)
+	if err != nil {
+		return nil
+	}
+
+	var output bytes.Buffer
+	cmd := exec.Command(path, args...)
+	cmd.Stdin = strings.NewReader(code)
+	cmd.Stdout = &output
+	cmd.Stderr = &output
+	if err := cmd.Run(); err != nil {
+		if _, ok := err.(*exec.ExitError); !ok {
+			return nil
+		}
+		message := strings.TrimSpace(output.String())
+		if message == "" {
+			message = err.Error()
+		}
+		return fmt.Errorf("%s", message)
+	}
+	return nil
+}
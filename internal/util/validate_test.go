@@ -0,0 +1,46 @@
+package util
+
+import (
+	"os/exec"
+	"testing"
+)
+
+func TestValidateCode(t *testing.T) {
+	tests := []struct {
+		name      string
+		code      string
+		language  string
+		expectErr bool
+		requires  string
+	}{
+		{name: "Valid Go", code: "func main() {\n\tfmt.Println(1)\n}", language: "golang"},
+		{name: "Invalid Go", code: "func main() {\n\tfmt.Println(1\n}", language: "go", expectErr: true},
+		{name: "Valid JSON", code: `{"a": [1, 2]}`, language: "json"},
+		{name: "Invalid JSON", code: `{"a": [1, 2}`, language: "json", expectErr: true},
+		{name: "Valid YAML", code: "a: 1\n---\nb: [2]\n", language: "yml"},
+		{name: "Invalid YAML", code: "a: [1, 2\n", language: "yaml", expectErr: true},
+		{name: "Valid Bash", code: "if true; then echo hi; fi", language: "sh", requires: "bash"},
+		{name: "Invalid Bash", code: "if true; then echo hi", language: "bash", expectErr: true, requires: "bash"},
+		{name: "Valid Python", code: "print('hi')", language: "py", requires: "python3"},
+		{name: "Invalid Python", code: "print('hi'", language: "python", expectErr: true, requires: "python3"},
+		{name: "Unknown language", code: "anything {", language: "brainfuck"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.requires != "" {
+				if _, err := exec.LookPath(tt.requires); err != nil {
+					t.Skipf("%s is not installed", tt.requires)
+				}
+			}
+
+			err := ValidateCode(tt.code, tt.language)
+			if tt.expectErr && err == nil {
+				t.Errorf("ValidateCode() = nil, want a syntax error")
+			}
+			if !tt.expectErr && err != nil {
+				t.Errorf("ValidateCode() = %v, want nil", err)
+			}
+		})
+	}
+}
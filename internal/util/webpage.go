@@ -0,0 +1,113 @@
+package util
+
+import (
+	"fmt"
+	"html"
+	"io"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// maxPageBytes limits how much of a response FetchURLText reads
+const maxPageBytes = 5 << 20
+
+var (
+	htmlTitleRegex   = regexp.MustCompile(`(?is)<title\b[^>]*>(.*?)</title\s*>`)
+	htmlCommentRegex = regexp.MustCompile(`(?s)<!--.*?-->`)
+	// htmlHiddenRegexes match elements whose content is never readable text
+	htmlHiddenRegexes = []*regexp.Regexp{
+		regexp.MustCompile(`(?is)<head\b.*?</head\s*>`),
+		regexp.MustCompile(`(?is)<script\b.*?</script\s*>`),
+		regexp.MustCompile(`(?is)<style\b.*?</style\s*>`),
+		regexp.MustCompile(`(?is)<noscript\b.*?</noscript\s*>`),
+		regexp.MustCompile(`(?is)<svg\b.*?</svg\s*>`),
+		regexp.MustCompile(`(?is)<template\b.*?</template\s*>`),
+	}
+	htmlListItemRegex  = regexp.MustCompile(`(?i)<li\b[^>]*>`)
+	htmlBlockRegex     = regexp.MustCompile(`(?i)</?(p|div|br|ul|ol|li|h[1-6]|tr|table|section|article|main|header|footer|nav|aside|blockquote|pre|hr|dl|dt|dd|figure|figcaption|form)\b[^>]*>`)
+	htmlCellRegex      = regexp.MustCompile(`(?i)</t[dh]\s*>`)
+	htmlTagRegex       = regexp.MustCompile(`<[^>]*>`)
+	horizontalRunRegex = regexp.MustCompile(`[ \t\f\v\x{00a0}]+`)
+	blankLinesRunRegex = regexp.MustCompile(`\n{3,}`)
+)
+
+// FetchURLText downloads a web page and returns its readable text, cut to at
+// most maxChars characters. HTML is converted to plain text; other text
+// content types are returned as they are.
+func FetchURLText(url string, maxChars int) (string, error) {
+	client := &http.Client{Timeout: 30 * time.Second}
+	resp, err := client.Get(url)
+	if err != nil {
+		return "", fmt.Errorf("error fetching %s: %v", url, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("error fetching %s: status %d", url, resp.StatusCode)
+	}
+
+	contentType := strings.ToLower(resp.Header.Get("Content-Type"))
+	isHTML := strings.Contains(contentType, "html")
+	if !isHTML && contentType != "" && !strings.HasPrefix(contentType, "text/") &&
+		!strings.Contains(contentType, "json") && !strings.Contains(contentType, "xml") {
+		return "", fmt.Errorf("error fetching %s: unsupported content type %q", url, contentType)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxPageBytes))
+	if err != nil {
+		return "", fmt.Errorf("error reading %s: %v", url, err)
+	}
+
+	text := string(data)
+	if isHTML {
+		text = HTMLToText(text)
+	}
+	return TruncateText(text, maxChars), nil
+}
+
+// HTMLToText extracts the readable text of an HTML document. Scripts, styles
+// and other hidden elements are dropped, block elements become line breaks
+// and list items are marked with "- ". The page title, if any, is put first.
+func HTMLToText(document string) string {
+	title := ""
+	if match := htmlTitleRegex.FindStringSubmatch(document); match != nil {
+		title = strings.TrimSpace(html.UnescapeString(htmlTagRegex.ReplaceAllString(match[1], "")))
+	}
+
+	text := htmlCommentRegex.ReplaceAllString(document, "")
+	for _, hidden := range htmlHiddenRegexes {
+		text = hidden.ReplaceAllString(text, "")
+	}
+	text = htmlListItemRegex.ReplaceAllString(text, "\n- ")
+	text = htmlBlockRegex.ReplaceAllString(text, "\n")
+	text = htmlCellRegex.ReplaceAllString(text, " ")
+	text = htmlTagRegex.ReplaceAllString(text, "")
+	text = html.UnescapeString(text)
+
+	// Tidy up the whitespace left behind by the markup
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimSpace(horizontalRunRegex.ReplaceAllString(line, " "))
+	}
+	text = strings.TrimSpace(strings.Join(lines, "\n"))
+	text = blankLinesRunRegex.ReplaceAllString(text, "\n\n")
+
+	if title != "" {
+		text = title + "\n\n" + text
+	}
+	return text
+}
+
+// TruncateText cuts text to at most maxChars characters, noting that it was
+// truncated. A maxChars of zero or less means no limit.
+func TruncateText(text string, maxChars int) string {
+	if maxChars <= 0 || utf8.RuneCountInString(text) <= maxChars {
+		return text
+	}
+
+	runes := []rune(text)
+	return string(runes[:maxChars]) + "\n[truncated]"
+}
@@ -0,0 +1,70 @@
+package util
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestHTMLToText(t *testing.T) {
+	document := `<!DOCTYPE html>
+<html>
+<head><title>Release &amp; notes</title><style>body { color: red; }</style></head>
+<body>
+  <script>var hidden = "<p>not text</p>";</script>
+  <!-- a comment -->
+  <h1>Version   2.0</h1>
+  <p>New <b>features</b>:</p>
+  <ul><li>Faster&nbsp;startup</li><li>Fewer <a href="/bugs">bugs</a></li></ul>
+</body>
+</html>`
+
+	expected := "Release & notes\n\nVersion 2.0\n\nNew features:\n\n- Faster startup\n\n- Fewer bugs"
+	if got := HTMLToText(document); got != expected {
+		t.Errorf("HTMLToText() = %q, want %q", got, expected)
+	}
+}
+
+func TestTruncateText(t *testing.T) {
+	if got := TruncateText("héllo", 10); got != "héllo" {
+		t.Errorf("TruncateText() = %q, want the text unchanged", got)
+	}
+	if got := TruncateText("héllo", 2); got != "hé\n[truncated]" {
+		t.Errorf("TruncateText() = %q, want %q", got, "hé\n[truncated]")
+	}
+	if got := TruncateText("héllo", 0); got != "héllo" {
+		t.Errorf("TruncateText() = %q, want the text unchanged", got)
+	}
+}
+
+func TestFetchURLText(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/page":
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+			w.Write([]byte("<html><body><p>Hello</p><p>World</p></body></html>"))
+		case "/image":
+			w.Header().Set("Content-Type", "image/png")
+			w.Write([]byte{0x89, 'P', 'N', 'G'})
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	text, err := FetchURLText(server.URL+"/page", 100)
+	if err != nil {
+		t.Fatalf("FetchURLText() error: %v", err)
+	}
+	if text != "Hello\n\nWorld" {
+		t.Errorf("FetchURLText() = %q, want %q", text, "Hello\n\nWorld")
+	}
+
+	if _, err := FetchURLText(server.URL+"/image", 100); err == nil || !strings.Contains(err.Error(), "content type") {
+		t.Errorf("FetchURLText() error = %v, want an unsupported content type error", err)
+	}
+	if _, err := FetchURLText(server.URL+"/missing", 100); err == nil || !strings.Contains(err.Error(), "404") {
+		t.Errorf("FetchURLText() error = %v, want a status error", err)
+	}
+}
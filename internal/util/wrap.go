@@ -0,0 +1,125 @@
+package util
+
+import (
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+var (
+	wrapFenceRegex      = regexp.MustCompile("^\\s*(\x60\x60\x60|~~~)")
+	wrapListItemRegex   = regexp.MustCompile(`^\s*([-*+]|\d+[.)])\s+`)
+	wrapBlockQuoteRegex = regexp.MustCompile(`^\s*(>\s?)+`)
+	wrapRuleRegex       = regexp.MustCompile(`^\s*([-*_])(\s*[-*_]){2,}\s*$`)
+)
+
+// paragraph is prose being collected for WrapText. first is written before
+// its first line and indent before the others.
+type paragraph struct {
+	first  string
+	indent string
+	words  []string
+}
+
+// WrapText reflows the prose paragraphs and list items of markdown text so
+// no line is longer than width characters, for commit messages and emails.
+// Code blocks, headings, tables and rules are left as they are, as are words
+// longer than the width. Lines ending in a hard line break are kept.
+func WrapText(text string, width int) string {
+	if width <= 0 {
+		return text
+	}
+
+	var result []string
+	var current *paragraph
+	flush := func() {
+		if current != nil {
+			result = append(result, fillParagraph(current, width)...)
+			current = nil
+		}
+	}
+	verbatim := func(line string) {
+		flush()
+		result = append(result, line)
+	}
+
+	inCodeBlock := false
+	fence := ""
+	for _, line := range strings.Split(text, "\n") {
+		trimmed := strings.TrimSpace(line)
+
+		if inCodeBlock {
+			if trimmed == fence {
+				inCodeBlock = false
+			}
+			verbatim(line)
+			continue
+		}
+
+		switch {
+		case wrapFenceRegex.MatchString(line):
+			inCodeBlock = true
+			fence = wrapFenceRegex.FindStringSubmatch(line)[1]
+			verbatim(line)
+		case trimmed == "":
+			verbatim("")
+		case strings.HasPrefix(trimmed, "#"), strings.HasPrefix(trimmed, "|"), wrapRuleRegex.MatchString(line):
+			verbatim(line)
+		case current == nil && (strings.HasPrefix(line, "    ") || strings.HasPrefix(line, "\t")):
+			// Indented code
+			verbatim(line)
+		case wrapListItemRegex.MatchString(line):
+			flush()
+			marker := wrapListItemRegex.FindString(line)
+			current = &paragraph{first: marker, indent: strings.Repeat(" ", utf8.RuneCountInString(marker))}
+			current.words = strings.Fields(line[len(marker):])
+		case wrapBlockQuoteRegex.MatchString(line):
+			prefix := wrapBlockQuoteRegex.FindString(line)
+			if current == nil || current.indent != prefix {
+				flush()
+				current = &paragraph{first: prefix, indent: prefix}
+			}
+			current.words = append(current.words, strings.Fields(line[len(prefix):])...)
+		default:
+			if current == nil {
+				indent := line[:len(line)-len(strings.TrimLeft(line, " "))]
+				current = &paragraph{first: indent, indent: indent}
+			}
+			current.words = append(current.words, strings.Fields(line)...)
+		}
+
+		// A hard line break ends the line where it is
+		if current != nil && (strings.HasSuffix(line, "  ") || strings.HasSuffix(line, "\\")) {
+			indent := current.indent
+			flush()
+			current = &paragraph{first: indent, indent: indent}
+		}
+	}
+	flush()
+
+	return strings.Join(result, "\n")
+}
+
+// fillParagraph returns the lines of a paragraph, with as many words on each
+// as fit in width
+func fillParagraph(p *paragraph, width int) []string {
+	if len(p.words) == 0 {
+		return nil
+	}
+
+	var lines []string
+	line := p.first + p.words[0]
+	length := utf8.RuneCountInString(line)
+	for _, word := range p.words[1:] {
+		wordLength := utf8.RuneCountInString(word)
+		if length+1+wordLength > width {
+			lines = append(lines, line)
+			line = p.indent + word
+			length = utf8.RuneCountInString(line)
+			continue
+		}
+		line += " " + word
+		length += 1 + wordLength
+	}
+	return append(lines, line)
+}
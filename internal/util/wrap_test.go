@@ -0,0 +1,87 @@
+package util
+
+import "testing"
+
+func TestWrapText(t *testing.T) {
+	tests := []struct {
+		name  string
+		text  string
+		width int
+		want  string
+	}{
+		{
+			name:  "reflows a paragraph",
+			text:  "The quick brown fox jumps over\nthe lazy dog.\n",
+			width: 20,
+			want:  "The quick brown fox\njumps over the lazy\ndog.\n",
+		},
+		{
+			name:  "keeps blank lines between paragraphs",
+			text:  "one two three\n\nfour five six",
+			width: 9,
+			want:  "one two\nthree\n\nfour five\nsix",
+		},
+		{
+			name:  "leaves code blocks alone",
+			text:  "Run this:\n```bash\nsome --very --long --command --line\n```\n",
+			width: 10,
+			want:  "Run this:\n```bash\nsome --very --long --command --line\n```\n",
+		},
+		{
+			name:  "hanging indent for list items",
+			text:  "- first item is long\n- second\n10. numbered item here",
+			width: 12,
+			want:  "- first item\n  is long\n- second\n10. numbered\n    item\n    here",
+		},
+		{
+			name:  "block quotes keep their marker",
+			text:  "> quoted text that wraps",
+			width: 12,
+			want:  "> quoted\n> text that\n> wraps",
+		},
+		{
+			name:  "headings, tables and rules are kept",
+			text:  "# A heading that is too long\n| a | b |\n---",
+			width: 10,
+			want:  "# A heading that is too long\n| a | b |\n---",
+		},
+		{
+			name:  "long words stay whole",
+			text:  "see https://example.com/a/very/long/path now",
+			width: 10,
+			want:  "see\nhttps://example.com/a/very/long/path\nnow",
+		},
+		{
+			name:  "hard line breaks are kept",
+			text:  "Signed off  \nAlice Example",
+			width: 72,
+			want:  "Signed off\nAlice Example",
+		},
+		{
+			name:  "indented code",
+			text:  "    indented code line here",
+			width: 10,
+			want:  "    indented code line here",
+		},
+		{
+			name:  "counts characters, not bytes",
+			text:  "héé héé héé",
+			width: 7,
+			want:  "héé héé\nhéé",
+		},
+		{
+			name:  "zero width does nothing",
+			text:  "a b c",
+			width: 0,
+			want:  "a b c",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := WrapText(tt.text, tt.width); got != tt.want {
+				t.Errorf("WrapText(%q, %d) = %q, want %q", tt.text, tt.width, got, tt.want)
+			}
+		})
+	}
+}